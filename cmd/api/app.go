@@ -1,26 +1,43 @@
 package api
 
 import (
+	"context"
 	"database/sql"
 	"log"
 	"net/http"
 
+	"stori-challenge/internal/application/accountmapping"
 	balanceapp "stori-challenge/internal/application/balance"
 	csvmigration "stori-challenge/internal/application/csvmigration"
+	"stori-challenge/internal/application/mappingprofile"
+	"stori-challenge/internal/application/migrationbatch"
+	"stori-challenge/internal/application/migrationjob"
+	"stori-challenge/internal/application/periodlock"
+	uploadapp "stori-challenge/internal/application/upload"
 	infradb "stori-challenge/internal/infrastructure/db"
 	"stori-challenge/internal/infrastructure/http/handlers"
 	oas "stori-challenge/internal/infrastructure/http/openapi"
+	"stori-challenge/internal/infrastructure/rules"
+	"stori-challenge/internal/infrastructure/storage"
+	portrules "stori-challenge/internal/ports/rules"
 
 	"github.com/gin-gonic/gin"
 )
 
 // NewServer assembles and returns the HTTP server engine.
+// When the database is available it also starts the in-process migration worker. Expired
+// resumable uploads are swept in the background either way.
 func NewServer() *gin.Engine {
 	sqlDB, err := infradb.Open()
 	if err != nil {
 		log.Printf("database not available at startup: %v", err)
 	}
-	return NewServerWithDB(sqlDB)
+	router := NewServerWithDB(sqlDB)
+	if sqlDB != nil {
+		go NewMigrationWorker(sqlDB).Run(context.Background())
+	}
+	go NewUploadSweeper().Run(context.Background())
+	return router
 }
 
 // NewServerWithDB assembles the HTTP server engine using the provided DB connection.
@@ -39,12 +56,54 @@ func NewServerWithDB(sqlDB *sql.DB) *gin.Engine {
 
 	// Infra wiring
 	transactionRepo := infradb.NewTransactionRepo(sqlDB)
-	migrationService := csvmigration.NewCsvMigrationService(transactionRepo)
+	migrationRepo := infradb.NewMigrationRepo(sqlDB)
+	profileRepo := infradb.NewMappingProfileRepo(sqlDB)
+	accountRepo := infradb.NewAccountMappingRepo(sqlDB)
+	batchRepo := infradb.NewMigrationBatchRepo(sqlDB)
+	userRepo := infradb.NewUserRepo(sqlDB)
+	fileStore := newFileStore()
+	migrationService := csvmigration.NewCsvMigrationService(transactionRepo, profileRepo, accountRepo, batchRepo, userRepo, newRuleSource())
 	migrateHandler := handlers.NewMigrateHandler(migrationService)
-	balanceService := balanceapp.NewBalanceService(transactionRepo)
+	migrationJobService := migrationjob.NewMigrationJobService(migrationRepo, fileStore, profileRepo, batchRepo, migrationService)
+	migrationHandler := handlers.NewMigrationHandler(migrationJobService)
+	profileService := mappingprofile.NewMappingProfileService(profileRepo)
+	profileHandler := handlers.NewMappingProfileHandler(profileService)
+	accountService := accountmapping.NewAccountMappingService(accountRepo)
+	accountHandler := handlers.NewAccountMappingHandler(accountService)
+	balanceService := balanceapp.NewBalanceService(transactionRepo, userRepo)
 	balanceHandler := handlers.NewBalanceHandler(balanceService)
+	batchService := migrationbatch.NewMigrationBatchService(batchRepo, transactionRepo)
+	batchHandler := handlers.NewMigrationBatchHandler(batchService)
+	lockService := periodlock.NewPeriodLockService(infradb.NewPeriodLockRepo(sqlDB))
+	lockHandler := handlers.NewPeriodLockHandler(lockService)
+	uploadService := uploadapp.NewUploadService(newUploadStore(), migrationJobService)
+	uploadHandler := handlers.NewUploadHandler(uploadService)
 	// Routes (v1)
 	v1.POST("/migrate", migrateHandler.PostMigrate)
+	v1.POST("/migrate/validate", migrateHandler.PostMigrateValidate)
+	v1.POST("/migrate-async", migrationHandler.PostMigrateAsync)
+	v1.POST("/uploads", uploadHandler.PostUpload)
+	v1.HEAD("/uploads/:id", uploadHandler.HeadUpload)
+	v1.PATCH("/uploads/:id", uploadHandler.PatchUpload)
+	v1.DELETE("/uploads/:id", uploadHandler.DeleteUpload)
+	v1.POST("/uploads/:id/finalize", uploadHandler.PostFinalizeUpload)
+	v1.GET("/migrations/:id", migrationHandler.GetMigration)
+	v1.GET("/migrations/:id/errors.csv", migrationHandler.GetMigrationErrorsCSV)
+	v1.DELETE("/migrations/:id", batchHandler.DeleteMigration)
+	v1.GET("/migration-batches/:id", batchHandler.GetMigrationBatch)
+	v1.POST("/migration-batches/:id/revert", batchHandler.PostRevertMigrationBatch)
+	v1.GET("/transactions/:id/source", batchHandler.GetTransactionSource)
+	v1.GET("/mapping-profiles", profileHandler.ListMappingProfiles)
+	v1.GET("/mapping-profiles/:name", profileHandler.GetMappingProfile)
+	v1.PUT("/mapping-profiles/:name", profileHandler.PutMappingProfile)
+	v1.DELETE("/mapping-profiles/:name", profileHandler.DeleteMappingProfile)
+	v1.GET("/account-mappings", accountHandler.ListAccountMappings)
+	v1.GET("/account-mappings/:account_id", accountHandler.GetAccountMapping)
+	v1.PUT("/account-mappings/:account_id", accountHandler.PutAccountMapping)
+	v1.DELETE("/account-mappings/:account_id", accountHandler.DeleteAccountMapping)
+	v1.GET("/period-locks", lockHandler.ListPeriodLocks)
+	v1.PUT("/period-locks/:period", lockHandler.PutPeriodLock)
+	v1.DELETE("/period-locks/:period", lockHandler.DeletePeriodLock)
 	v1.GET("/users/:user_id/balance", balanceHandler.GetBalance)
 
 	// OpenAPI (3.1) documentation endpoints
@@ -52,3 +111,49 @@ func NewServerWithDB(sqlDB *sql.DB) *gin.Engine {
 
 	return router
 }
+
+// NewMigrationWorker assembles the worker that processes asynchronous migrations.
+func NewMigrationWorker(sqlDB *sql.DB) *migrationjob.Worker {
+	transactionRepo := infradb.NewTransactionRepo(sqlDB)
+	migrationService := csvmigration.NewCsvMigrationService(transactionRepo, infradb.NewMappingProfileRepo(sqlDB), infradb.NewAccountMappingRepo(sqlDB), infradb.NewMigrationBatchRepo(sqlDB), infradb.NewUserRepo(sqlDB), newRuleSource())
+	return migrationjob.NewWorker(infradb.NewMigrationRepo(sqlDB), newFileStore(), migrationService)
+}
+
+func newFileStore() *storage.LocalFileStore {
+	dir := storage.DefaultDir()
+	store, err := storage.NewLocalFileStore(dir)
+	if err != nil {
+		log.Printf("migration storage not available at %s: %v", dir, err)
+		return &storage.LocalFileStore{Dir: dir}
+	}
+	return store
+}
+
+// NewUploadSweeper assembles the sweeper that discards expired resumable uploads.
+func NewUploadSweeper() *uploadapp.Sweeper {
+	return uploadapp.NewSweeper(uploadapp.NewUploadService(newUploadStore(), nil))
+}
+
+func newUploadStore() *storage.LocalUploadStore {
+	dir := storage.DefaultUploadDir()
+	store, err := storage.NewLocalUploadStore(dir)
+	if err != nil {
+		log.Printf("upload storage not available at %s: %v", dir, err)
+		return &storage.LocalUploadStore{Dir: dir}
+	}
+	return store
+}
+
+// newRuleSource returns the validation rules file named by MIGRATION_RULES_FILE, or nil when
+// none is configured. A file that cannot be loaded is logged; migrations fail until it is fixed.
+func newRuleSource() portrules.Source {
+	path := rules.DefaultPath()
+	if path == "" {
+		return nil
+	}
+	src := rules.NewFileSource(path)
+	if _, err := src.Rules(context.Background()); err != nil {
+		log.Printf("validation rules not available: %v", err)
+	}
+	return src
+}
@@ -9,15 +9,14 @@ import (
 	"time"
 
 	"stori-challenge/internal/domain"
-	infradb "stori-challenge/internal/infrastructure/db"
 	"stori-challenge/internal/infrastructure/http/responses"
+	testinfra "stori-challenge/internal/shared/test"
 
 	"github.com/shopspring/decimal"
 )
 
 func TestBalanceIntegration_Success200_NoFilters(t *testing.T) {
 	router, db := newTestRouter(t)
-	repo := infradb.NewTransactionRepo(db)
 	ctx := context.Background()
 	userID := int64(501)
 	now := time.Now().UTC()
@@ -27,7 +26,7 @@ func TestBalanceIntegration_Success200_NoFilters(t *testing.T) {
 		{ID: 90002, UserID: userID, Amount: decimal.NewFromFloat(-3.50), DateTime: now.Add(-24 * time.Hour), Type: domain.TransactionTypeDebit},
 		{ID: 90003, UserID: userID, Amount: decimal.NewFromFloat(5.71), DateTime: now.Add(-1 * time.Hour), Type: domain.TransactionTypeCredit},
 	}
-	if err := repo.BulkInsert(ctx, txs); err != nil {
+	if err := testinfra.SeedTransactions(ctx, db, txs); err != nil {
 		t.Fatalf("seed insert: %v", err)
 	}
 
@@ -74,7 +73,6 @@ func TestBalanceIntegration_BadRequest400_InvalidDate(t *testing.T) {
 
 func TestBalanceIntegration_Success200_OnlyFrom(t *testing.T) {
 	router, db := newTestRouter(t)
-	repo := infradb.NewTransactionRepo(db)
 	ctx := context.Background()
 	userID := int64(601)
 	now := time.Now().UTC()
@@ -85,7 +83,7 @@ func TestBalanceIntegration_Success200_OnlyFrom(t *testing.T) {
 		{ID: 91002, UserID: userID, Amount: decimal.NewFromFloat(2.00), DateTime: now.Add(-24 * time.Hour), Type: domain.TransactionTypeCredit}, // included
 		{ID: 91003, UserID: userID, Amount: decimal.NewFromFloat(-1.50), DateTime: now.Add(-1 * time.Hour), Type: domain.TransactionTypeDebit},  // included
 	}
-	if err := repo.BulkInsert(ctx, txs); err != nil {
+	if err := testinfra.SeedTransactions(ctx, db, txs); err != nil {
 		t.Fatalf("seed insert: %v", err)
 	}
 	w := httptest.NewRecorder()
@@ -106,7 +104,6 @@ func TestBalanceIntegration_Success200_OnlyFrom(t *testing.T) {
 
 func TestBalanceIntegration_Success200_OnlyTo(t *testing.T) {
 	router, db := newTestRouter(t)
-	repo := infradb.NewTransactionRepo(db)
 	ctx := context.Background()
 	userID := int64(602)
 	now := time.Now().UTC()
@@ -118,7 +115,7 @@ func TestBalanceIntegration_Success200_OnlyTo(t *testing.T) {
 		{ID: 92002, UserID: userID, Amount: decimal.NewFromFloat(3.00), DateTime: now.Add(-12 * time.Hour), Type: domain.TransactionTypeCredit}, // included
 		{ID: 92003, UserID: userID, Amount: decimal.NewFromFloat(-2.00), DateTime: now.Add(-2 * time.Hour), Type: domain.TransactionTypeDebit},  // included
 	}
-	if err := repo.BulkInsert(ctx, txs); err != nil {
+	if err := testinfra.SeedTransactions(ctx, db, txs); err != nil {
 		t.Fatalf("seed insert: %v", err)
 	}
 	w := httptest.NewRecorder()
@@ -139,7 +136,6 @@ func TestBalanceIntegration_Success200_OnlyTo(t *testing.T) {
 
 func TestBalanceIntegration_Success200_ReversedBounds(t *testing.T) {
 	router, db := newTestRouter(t)
-	repo := infradb.NewTransactionRepo(db)
 	ctx := context.Background()
 	userID := int64(603)
 	now := time.Now().UTC()
@@ -152,7 +148,7 @@ func TestBalanceIntegration_Success200_ReversedBounds(t *testing.T) {
 		{ID: 93002, UserID: userID, Amount: decimal.NewFromFloat(-1.00), DateTime: now.Add(-10 * time.Hour), Type: domain.TransactionTypeDebit}, // in-range
 		{ID: 93003, UserID: userID, Amount: decimal.NewFromFloat(9.00), DateTime: now.Add(-40 * time.Hour), Type: domain.TransactionTypeCredit}, // out-of-range
 	}
-	if err := repo.BulkInsert(ctx, txs); err != nil {
+	if err := testinfra.SeedTransactions(ctx, db, txs); err != nil {
 		t.Fatalf("seed insert: %v", err)
 	}
 	w := httptest.NewRecorder()
@@ -188,7 +184,6 @@ func TestBalanceIntegration_BadRequest400_UpperAfterNow(t *testing.T) {
 
 func TestBalanceIntegration_Success200_BothParamsOrdered(t *testing.T) {
 	router, db := newTestRouter(t)
-	repo := infradb.NewTransactionRepo(db)
 	ctx := context.Background()
 	userID := int64(604)
 	now := time.Now().UTC()
@@ -201,7 +196,7 @@ func TestBalanceIntegration_Success200_BothParamsOrdered(t *testing.T) {
 		{ID: 94003, UserID: userID, Amount: decimal.NewFromFloat(-1.25), DateTime: now.Add(-10 * time.Hour), Type: domain.TransactionTypeDebit}, // in
 		{ID: 94004, UserID: userID, Amount: decimal.NewFromFloat(9.00), DateTime: now.Add(-1 * time.Hour), Type: domain.TransactionTypeCredit},  // out (after)
 	}
-	if err := repo.BulkInsert(ctx, txs); err != nil {
+	if err := testinfra.SeedTransactions(ctx, db, txs); err != nil {
 		t.Fatalf("seed insert: %v", err)
 	}
 	w := httptest.NewRecorder()
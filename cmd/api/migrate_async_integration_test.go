@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	infradb "stori-challenge/internal/infrastructure/db"
+	"stori-challenge/internal/infrastructure/http/responses"
+)
+
+func TestMigrateAsyncIntegration_EnqueueProcessAndPoll(t *testing.T) {
+	t.Setenv("MIGRATIONS_STORAGE_DIR", t.TempDir())
+	router, db := newTestRouter(t)
+
+	csv := "id,user_id,amount,datetime\n" +
+		"40001,1,1.23,2023-01-01T00:00:00Z\n" +
+		"40002,2,-4.56,2023-01-02T00:00:00Z\n"
+	ct, body := makeMultipartCSV(t, "data.csv", csv)
+	req := httptest.NewRequest(http.MethodPost, "/v1/migrate-async", body)
+	req.Header.Set("Content-Type", ct)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("status: want 202 got %d; body=%s", w.Code, w.Body.String())
+	}
+	var accepted responses.MigrateAsyncAcceptedResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &accepted); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if accepted.Status != "PENDING" {
+		t.Fatalf("expected PENDING, got %s", accepted.Status)
+	}
+
+	// Drive the worker synchronously instead of starting the background loop.
+	worked, err := NewMigrationWorker(db).RunOnce(context.Background())
+	if err != nil || !worked {
+		t.Fatalf("worker: worked=%v err=%v", worked, err)
+	}
+
+	w2 := httptest.NewRecorder()
+	router.ServeHTTP(w2, httptest.NewRequest(http.MethodGet, "/v1/migrations/"+strconv.FormatInt(accepted.ID, 10), nil))
+	if w2.Code != http.StatusOK {
+		t.Fatalf("status: want 200 got %d; body=%s", w2.Code, w2.Body.String())
+	}
+	var got responses.MigrationResponse
+	if err := json.Unmarshal(w2.Body.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got.Status != "COMPLETED" || got.Inserted != 2 {
+		t.Fatalf("payload mismatch: %+v", got)
+	}
+
+	repo := infradb.NewTransactionRepo(db)
+	exists, err := repo.ExistsByIDs(context.Background(), []int64{40001, 40002})
+	if err != nil {
+		t.Fatalf("exists check: %v", err)
+	}
+	if len(exists) != 2 {
+		t.Fatalf("rows not found in db: %v", exists)
+	}
+}
+
+func TestMigrateAsyncIntegration_UnknownMigration404(t *testing.T) {
+	router, _ := newTestRouter(t)
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/v1/migrations/999999", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status: want 404 got %d; body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestUploadIntegration_ResumeAndFinalize(t *testing.T) {
+	t.Setenv("MIGRATIONS_STORAGE_DIR", t.TempDir())
+	t.Setenv("UPLOADS_STORAGE_DIR", t.TempDir())
+	router, db := newTestRouter(t)
+
+	csv := "id,user_id,amount,datetime\n" +
+		"40101,1,1.23,2023-01-01T00:00:00Z\n" +
+		"40102,2,-4.56,2023-01-02T00:00:00Z\n"
+	req := httptest.NewRequest(http.MethodPost, "/v1/uploads", nil)
+	req.Header.Set("Upload-Length", strconv.Itoa(len(csv)))
+	req.Header.Set("Upload-Metadata", "filename "+base64.StdEncoding.EncodeToString([]byte("data.csv")))
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("create: want 201 got %d; body=%s", w.Code, w.Body.String())
+	}
+	location := w.Header().Get("Location")
+
+	// The second chunk is sent as if the connection dropped after the first one, so the
+	// client asks for the offset before resuming.
+	patch := func(offset int, chunk string) *httptest.ResponseRecorder {
+		sum := sha256.Sum256([]byte(chunk))
+		req := httptest.NewRequest(http.MethodPatch, location, strings.NewReader(chunk))
+		req.Header.Set("Content-Type", "application/offset+octet-stream")
+		req.Header.Set("Upload-Offset", strconv.Itoa(offset))
+		req.Header.Set("Upload-Checksum", "sha256 "+base64.StdEncoding.EncodeToString(sum[:]))
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	if w := patch(0, csv[:40]); w.Code != http.StatusNoContent {
+		t.Fatalf("patch: want 204 got %d; body=%s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodHead, location, nil))
+	if got := w.Header().Get("Upload-Offset"); got != "40" {
+		t.Fatalf("head: want offset 40 got %q", got)
+	}
+	if w := patch(40, csv[40:]); w.Code != http.StatusNoContent {
+		t.Fatalf("patch: want 204 got %d; body=%s", w.Code, w.Body.String())
+	}
+
+	w = httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodPost, location+"/finalize?mode=strict", nil))
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("finalize: want 202 got %d; body=%s", w.Code, w.Body.String())
+	}
+	if worked, err := NewMigrationWorker(db).RunOnce(context.Background()); err != nil || !worked {
+		t.Fatalf("worker: worked=%v err=%v", worked, err)
+	}
+	exists, err := infradb.NewTransactionRepo(db).ExistsByIDs(context.Background(), []int64{40101, 40102})
+	if err != nil || len(exists) != 2 {
+		t.Fatalf("rows not found in db: %v, %v", exists, err)
+	}
+}
@@ -0,0 +1,18 @@
+-- migrate:up
+CREATE TABLE IF NOT EXISTS migrations (
+	id BIGSERIAL PRIMARY KEY,
+	status TEXT NOT NULL DEFAULT 'PENDING' CHECK (status IN ('PENDING','PROCESSING','COMPLETED','FAILED')),
+	file_name TEXT NOT NULL,
+	file_key TEXT NOT NULL,
+	inserted INTEGER NOT NULL DEFAULT 0,
+	error_code TEXT NOT NULL DEFAULT '',
+	errors JSONB NOT NULL DEFAULT '[]'::jsonb,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	started_at TIMESTAMPTZ,
+	finished_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_migrations_status_created_at ON migrations (status, created_at);
+
+-- migrate:down
+DROP INDEX IF EXISTS idx_migrations_status_created_at;
+DROP TABLE IF EXISTS migrations;
@@ -0,0 +1,9 @@
+-- migrate:up
+ALTER TABLE migrations
+	ADD COLUMN IF NOT EXISTS options JSONB NOT NULL DEFAULT '{}'::jsonb,
+	ADD COLUMN IF NOT EXISTS rejected INTEGER NOT NULL DEFAULT 0;
+
+-- migrate:down
+ALTER TABLE migrations
+	DROP COLUMN IF EXISTS rejected,
+	DROP COLUMN IF EXISTS options;
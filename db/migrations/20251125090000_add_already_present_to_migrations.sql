@@ -0,0 +1,7 @@
+-- migrate:up
+ALTER TABLE migrations
+	ADD COLUMN IF NOT EXISTS already_present INTEGER NOT NULL DEFAULT 0;
+
+-- migrate:down
+ALTER TABLE migrations
+	DROP COLUMN IF EXISTS already_present;
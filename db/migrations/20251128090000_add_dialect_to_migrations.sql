@@ -0,0 +1,7 @@
+-- migrate:up
+ALTER TABLE migrations
+	ADD COLUMN IF NOT EXISTS dialect JSONB;
+
+-- migrate:down
+ALTER TABLE migrations
+	DROP COLUMN IF EXISTS dialect;
@@ -0,0 +1,36 @@
+-- migrate:up
+CREATE TABLE IF NOT EXISTS migration_batches (
+	id BIGSERIAL PRIMARY KEY,
+	migration_id BIGINT REFERENCES migrations (id),
+	file_name TEXT NOT NULL,
+	sha256 TEXT NOT NULL DEFAULT '',
+	size_bytes BIGINT NOT NULL DEFAULT 0,
+	uploaded_by TEXT NOT NULL DEFAULT '',
+	client_ip TEXT NOT NULL DEFAULT '',
+	status TEXT NOT NULL DEFAULT 'PROCESSING' CHECK (status IN ('PROCESSING','SUCCEEDED','FAILED')),
+	inserted INTEGER NOT NULL DEFAULT 0,
+	rejected INTEGER NOT NULL DEFAULT 0,
+	already_present INTEGER NOT NULL DEFAULT 0,
+	error_code TEXT NOT NULL DEFAULT '',
+	started_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	finished_at TIMESTAMPTZ
+);
+-- Uploads are rejected when their content already succeeded; idempotent runs may repeat it.
+CREATE INDEX IF NOT EXISTS idx_migration_batches_sha256_succeeded ON migration_batches (sha256) WHERE status = 'SUCCEEDED';
+CREATE INDEX IF NOT EXISTS idx_migration_batches_migration_id ON migration_batches (migration_id);
+
+ALTER TABLE transactions
+	ADD COLUMN IF NOT EXISTS batch_id BIGINT REFERENCES migration_batches (id),
+	ADD COLUMN IF NOT EXISTS source_file TEXT,
+	ADD COLUMN IF NOT EXISTS source_row INTEGER;
+CREATE INDEX IF NOT EXISTS idx_transactions_batch_id ON transactions (batch_id);
+
+-- migrate:down
+DROP INDEX IF EXISTS idx_transactions_batch_id;
+ALTER TABLE transactions
+	DROP COLUMN IF EXISTS source_row,
+	DROP COLUMN IF EXISTS source_file,
+	DROP COLUMN IF EXISTS batch_id;
+DROP INDEX IF EXISTS idx_migration_batches_migration_id;
+DROP INDEX IF EXISTS idx_migration_batches_sha256_succeeded;
+DROP TABLE IF EXISTS migration_batches;
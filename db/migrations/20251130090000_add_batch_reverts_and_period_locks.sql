@@ -0,0 +1,29 @@
+-- migrate:up
+ALTER TABLE migration_batches DROP CONSTRAINT IF EXISTS migration_batches_status_check;
+ALTER TABLE migration_batches ADD CONSTRAINT migration_batches_status_check
+	CHECK (status IN ('PROCESSING','SUCCEEDED','FAILED','REVERTED'));
+
+CREATE TABLE IF NOT EXISTS migration_batch_reverts (
+	id BIGSERIAL PRIMARY KEY,
+	batch_id BIGINT NOT NULL UNIQUE REFERENCES migration_batches (id),
+	reverted_by TEXT NOT NULL,
+	reason TEXT NOT NULL,
+	transactions_deleted INTEGER NOT NULL,
+	reverted_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- A period is a calendar month, stored as its first day.
+CREATE TABLE IF NOT EXISTS period_locks (
+	period DATE PRIMARY KEY,
+	locked_by TEXT NOT NULL DEFAULT '',
+	reason TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- migrate:down
+DROP TABLE IF EXISTS period_locks;
+DROP TABLE IF EXISTS migration_batch_reverts;
+ALTER TABLE migration_batches DROP CONSTRAINT IF EXISTS migration_batches_status_check;
+UPDATE migration_batches SET status = 'FAILED' WHERE status = 'REVERTED';
+ALTER TABLE migration_batches ADD CONSTRAINT migration_batches_status_check
+	CHECK (status IN ('PROCESSING','SUCCEEDED','FAILED'));
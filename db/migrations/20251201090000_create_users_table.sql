@@ -0,0 +1,11 @@
+-- migrate:up
+CREATE TABLE IF NOT EXISTS users (
+	id BIGINT PRIMARY KEY,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- Everyone who already has transactions is a user.
+INSERT INTO users (id) SELECT DISTINCT user_id FROM transactions ON CONFLICT (id) DO NOTHING;
+
+-- migrate:down
+DROP TABLE IF EXISTS users;
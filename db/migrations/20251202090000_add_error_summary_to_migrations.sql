@@ -0,0 +1,7 @@
+-- migrate:up
+ALTER TABLE migrations
+	ADD COLUMN IF NOT EXISTS error_summary JSONB;
+
+-- migrate:down
+ALTER TABLE migrations
+	DROP COLUMN IF EXISTS error_summary;
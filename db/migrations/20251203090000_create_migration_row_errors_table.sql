@@ -0,0 +1,13 @@
+-- migrate:up
+CREATE TABLE IF NOT EXISTS migration_row_errors (
+	id BIGSERIAL PRIMARY KEY,
+	migration_id BIGINT NOT NULL REFERENCES migrations(id) ON DELETE CASCADE,
+	run_row INTEGER NOT NULL,
+	error JSONB NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_migration_row_errors_run_row ON migration_row_errors (migration_id, run_row, id);
+
+-- migrate:down
+DROP INDEX IF EXISTS idx_migration_row_errors_run_row;
+DROP TABLE IF EXISTS migration_row_errors;
@@ -0,0 +1,7 @@
+-- migrate:up
+ALTER TABLE migrations
+	ADD COLUMN IF NOT EXISTS heartbeat_at TIMESTAMPTZ;
+
+-- migrate:down
+ALTER TABLE migrations
+	DROP COLUMN IF EXISTS heartbeat_at;
@@ -0,0 +1,8 @@
+-- migrate:up
+-- Empty for batches recorded before the policy was.
+ALTER TABLE migration_batches
+	ADD COLUMN IF NOT EXISTS amount_rounding TEXT NOT NULL DEFAULT '';
+
+-- migrate:down
+ALTER TABLE migration_batches
+	DROP COLUMN IF EXISTS amount_rounding;
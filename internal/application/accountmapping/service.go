@@ -0,0 +1,70 @@
+package accountmapping
+
+import (
+	"context"
+	"regexp"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/ports/repositories"
+	"stori-challenge/internal/ports/services"
+	"stori-challenge/internal/shared"
+)
+
+// accountPattern accepts statement account numbers, masked ones included, while keeping them
+// safe to use as a path segment.
+var accountPattern = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+type accountMappingService struct {
+	Repo repositories.AccountMappingRepository
+}
+
+// Ensure interface compliance
+var _ services.AccountMappingService = (*accountMappingService)(nil)
+
+func NewAccountMappingService(repo repositories.AccountMappingRepository) services.AccountMappingService {
+	return &accountMappingService{Repo: repo}
+}
+
+func (s *accountMappingService) Save(ctx context.Context, m domain.AccountMapping) (domain.AccountMapping, error) {
+	if !accountPattern.MatchString(m.AccountID) {
+		return domain.AccountMapping{}, shared.NewBadRequest("invalid_account_mapping", "account id must be 1-64 letters, digits, '.', '_' or '-'", nil)
+	}
+	if m.UserID <= 0 {
+		return domain.AccountMapping{}, shared.NewBadRequest("invalid_account_mapping", "user_id must be a positive integer", nil)
+	}
+	saved, err := s.Repo.Save(ctx, m)
+	if err != nil {
+		return domain.AccountMapping{}, shared.NewInternal("db_failure", "database error", err)
+	}
+	return saved, nil
+}
+
+func (s *accountMappingService) Get(ctx context.Context, accountID string) (domain.AccountMapping, error) {
+	m, found, err := s.Repo.GetByAccount(ctx, accountID)
+	if err != nil {
+		return domain.AccountMapping{}, shared.NewInternal("db_failure", "database error", err)
+	}
+	if !found {
+		return domain.AccountMapping{}, shared.NewNotFound("account_mapping_not_found", "account mapping not found", nil)
+	}
+	return m, nil
+}
+
+func (s *accountMappingService) List(ctx context.Context) ([]domain.AccountMapping, error) {
+	out, err := s.Repo.List(ctx)
+	if err != nil {
+		return nil, shared.NewInternal("db_failure", "database error", err)
+	}
+	return out, nil
+}
+
+func (s *accountMappingService) Delete(ctx context.Context, accountID string) error {
+	found, err := s.Repo.Delete(ctx, accountID)
+	if err != nil {
+		return shared.NewInternal("db_failure", "database error", err)
+	}
+	if !found {
+		return shared.NewNotFound("account_mapping_not_found", "account mapping not found", nil)
+	}
+	return nil
+}
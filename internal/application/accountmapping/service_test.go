@@ -0,0 +1,80 @@
+package accountmapping
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/shared"
+)
+
+type fakeAccountRepo struct {
+	mappings map[string]domain.AccountMapping
+}
+
+func (f *fakeAccountRepo) Save(ctx context.Context, m domain.AccountMapping) (domain.AccountMapping, error) {
+	f.mappings[m.AccountID] = m
+	return m, nil
+}
+
+func (f *fakeAccountRepo) GetByAccount(ctx context.Context, accountID string) (domain.AccountMapping, bool, error) {
+	m, ok := f.mappings[accountID]
+	return m, ok, nil
+}
+
+func (f *fakeAccountRepo) List(ctx context.Context) ([]domain.AccountMapping, error) {
+	var out []domain.AccountMapping
+	for _, m := range f.mappings {
+		out = append(out, m)
+	}
+	return out, nil
+}
+
+func (f *fakeAccountRepo) Delete(ctx context.Context, accountID string) (bool, error) {
+	_, ok := f.mappings[accountID]
+	delete(f.mappings, accountID)
+	return ok, nil
+}
+
+func newSvc() (*accountMappingService, *fakeAccountRepo) {
+	repo := &fakeAccountRepo{mappings: map[string]domain.AccountMapping{}}
+	return NewAccountMappingService(repo).(*accountMappingService), repo
+}
+
+func TestSave_ValidMapping_Stored(t *testing.T) {
+	svc, repo := newSvc()
+	if _, err := svc.Save(context.Background(), domain.AccountMapping{AccountID: "XXXX-1234", UserID: 10}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.mappings["XXXX-1234"].UserID != 10 {
+		t.Fatalf("expected mapping to be stored")
+	}
+}
+
+func TestSave_InvalidMappings_BadRequest(t *testing.T) {
+	svc, _ := newSvc()
+	cases := map[string]domain.AccountMapping{
+		"empty account": {UserID: 10},
+		"slash":         {AccountID: "12/34", UserID: 10},
+		"no user":       {AccountID: "1234"},
+	}
+	for name, m := range cases {
+		_, err := svc.Save(context.Background(), m)
+		var ae *shared.AppError
+		if !errors.As(err, &ae) || ae.Kind != shared.BadRequestKind || ae.Code != "invalid_account_mapping" {
+			t.Fatalf("%s: expected invalid_account_mapping, got %v", name, err)
+		}
+	}
+}
+
+func TestGetAndDelete_Missing_NotFound(t *testing.T) {
+	svc, _ := newSvc()
+	var ae *shared.AppError
+	if _, err := svc.Get(context.Background(), "missing"); !errors.As(err, &ae) || ae.Kind != shared.NotFoundKind {
+		t.Fatalf("expected NotFound on get, got %v", err)
+	}
+	if err := svc.Delete(context.Background(), "missing"); !errors.As(err, &ae) || ae.Kind != shared.NotFoundKind {
+		t.Fatalf("expected NotFound on delete, got %v", err)
+	}
+}
@@ -12,14 +12,15 @@ import (
 )
 
 type balanceService struct {
-	Repo repositories.TransactionRepository
+	Repo  repositories.TransactionRepository
+	Users repositories.UserRepository
 }
 
 // Ensure interface compliance
 var _ services.BalanceService = (*balanceService)(nil)
 
-func NewBalanceService(repo repositories.TransactionRepository) services.BalanceService {
-	return &balanceService{Repo: repo}
+func NewBalanceService(repo repositories.TransactionRepository, users repositories.UserRepository) services.BalanceService {
+	return &balanceService{Repo: repo, Users: users}
 }
 
 func (s *balanceService) GetBalance(ctx context.Context, userID int64, from, to time.Time) (decimal.Decimal, decimal.Decimal, decimal.Decimal, error) {
@@ -29,7 +30,7 @@ func (s *balanceService) GetBalance(ctx context.Context, userID int64, from, to
 		return decimal.Zero, decimal.Zero, decimal.Zero, shared.NewInternal("db_failure", "database error", err)
 	}
 	if !hasAny {
-		return decimal.Zero, decimal.Zero, decimal.Zero, shared.NewNotFound("user_transactions_not_found", "user has no transactions", nil)
+		return decimal.Zero, decimal.Zero, decimal.Zero, s.noTransactions(ctx, userID)
 	}
 	// Aggregate within the provided window
 	bal, deb, cred, err := s.Repo.GetUserBalanceSummary(ctx, userID, from, to)
@@ -38,3 +39,18 @@ func (s *balanceService) GetBalance(ctx context.Context, userID int64, from, to
 	}
 	return bal, deb, cred, nil
 }
+
+// noTransactions tells apart a user that is not registered from a registered user without
+// transactions; without a users repository every such user is reported as having none.
+func (s *balanceService) noTransactions(ctx context.Context, userID int64) error {
+	if s.Users != nil {
+		exists, err := s.Users.Exists(ctx, userID)
+		if err != nil {
+			return shared.NewInternal("db_failure", "database error", err)
+		}
+		if !exists {
+			return shared.NewNotFound("user_not_found", "user not found", nil)
+		}
+	}
+	return shared.NewNotFound("user_transactions_not_found", "user has no transactions", nil)
+}
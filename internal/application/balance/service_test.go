@@ -0,0 +1,80 @@
+package balance
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"stori-challenge/internal/ports/repositories"
+	"stori-challenge/internal/shared"
+
+	"github.com/shopspring/decimal"
+)
+
+// fakeTxRepo only implements the methods the service uses.
+type fakeTxRepo struct {
+	repositories.TransactionRepository
+	hasAny  map[int64]bool
+	balance decimal.Decimal
+}
+
+func (f *fakeTxRepo) UserHasAnyTransaction(ctx context.Context, userID int64) (bool, error) {
+	return f.hasAny[userID], nil
+}
+
+func (f *fakeTxRepo) GetUserBalanceSummary(ctx context.Context, userID int64, from, to time.Time) (decimal.Decimal, decimal.Decimal, decimal.Decimal, error) {
+	return f.balance, decimal.Zero, f.balance, nil
+}
+
+// fakeUserRepo only implements Exists.
+type fakeUserRepo struct {
+	repositories.UserRepository
+	registered map[int64]bool
+}
+
+func (f *fakeUserRepo) Exists(ctx context.Context, id int64) (bool, error) {
+	return f.registered[id], nil
+}
+
+func notFoundCode(t *testing.T, err error) string {
+	t.Helper()
+	var ae *shared.AppError
+	if !errors.As(err, &ae) || ae.Kind != shared.NotFoundKind {
+		t.Fatalf("expected a not found error, got %v", err)
+	}
+	return ae.Code
+}
+
+func TestGetBalance_UnregisteredUser_UserNotFound(t *testing.T) {
+	svc := NewBalanceService(&fakeTxRepo{}, &fakeUserRepo{registered: map[int64]bool{}})
+	_, _, _, err := svc.GetBalance(context.Background(), 10, time.Time{}, time.Now())
+	if code := notFoundCode(t, err); code != "user_not_found" {
+		t.Fatalf("expected user_not_found, got %s", code)
+	}
+}
+
+func TestGetBalance_RegisteredUserWithoutTransactions_UserTransactionsNotFound(t *testing.T) {
+	svc := NewBalanceService(&fakeTxRepo{}, &fakeUserRepo{registered: map[int64]bool{10: true}})
+	_, _, _, err := svc.GetBalance(context.Background(), 10, time.Time{}, time.Now())
+	if code := notFoundCode(t, err); code != "user_transactions_not_found" {
+		t.Fatalf("expected user_transactions_not_found, got %s", code)
+	}
+}
+
+func TestGetBalance_WithoutUsersRepo_UserTransactionsNotFound(t *testing.T) {
+	svc := NewBalanceService(&fakeTxRepo{}, nil)
+	_, _, _, err := svc.GetBalance(context.Background(), 10, time.Time{}, time.Now())
+	if code := notFoundCode(t, err); code != "user_transactions_not_found" {
+		t.Fatalf("expected user_transactions_not_found, got %s", code)
+	}
+}
+
+func TestGetBalance_UserWithTransactions_ReturnsSummary(t *testing.T) {
+	repo := &fakeTxRepo{hasAny: map[int64]bool{10: true}, balance: decimal.NewFromInt(25)}
+	svc := NewBalanceService(repo, &fakeUserRepo{registered: map[int64]bool{10: true}})
+	bal, _, _, err := svc.GetBalance(context.Background(), 10, time.Time{}, time.Now())
+	if err != nil || !bal.Equal(decimal.NewFromInt(25)) {
+		t.Fatalf("expected balance 25, got %s, %v", bal, err)
+	}
+}
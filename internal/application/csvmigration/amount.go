@@ -0,0 +1,135 @@
+package csvmigration
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"stori-challenge/internal/domain"
+
+	"github.com/shopspring/decimal"
+)
+
+// amountParser parses amount values. The zero value only accepts plain machine decimals
+// such as -1234.56; with a locale, or a decimal comma detected in a CSV upload, it also
+// accepts grouping, currency marks and the accounting style (45.00) for negatives.
+type amountParser struct {
+	locale string
+	format domain.NumberFormat
+}
+
+func newAmountParser(locale string) (amountParser, bool) {
+	if locale == "" {
+		return amountParser{}, true
+	}
+	f, ok := domain.LookupNumberFormat(locale)
+	if !ok {
+		return amountParser{}, false
+	}
+	return amountParser{locale: locale, format: f}, true
+}
+
+func (p amountParser) parse(s string) (decimal.Decimal, bool) {
+	if p.format.Decimal == 0 {
+		d, err := decimal.NewFromString(s)
+		return d, err == nil
+	}
+
+	parens := strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")")
+	if parens {
+		s = s[1 : len(s)-1]
+	}
+	s = trimCurrency(s)
+	neg := parens
+	switch {
+	case parens && strings.ContainsAny(s, "+-"):
+		return decimal.Decimal{}, false
+	case strings.HasPrefix(s, "-"):
+		s, neg = s[1:], true
+	case strings.HasSuffix(s, "-"):
+		// Some ledgers print debits with a trailing minus: 45.00-
+		s, neg = s[:len(s)-1], true
+	case strings.HasPrefix(s, "+"):
+		s = s[1:]
+	}
+	num, ok := p.canonical(trimCurrency(s))
+	if !ok {
+		return decimal.Decimal{}, false
+	}
+	d, err := decimal.NewFromString(num)
+	if err != nil {
+		return decimal.Decimal{}, false
+	}
+	if neg {
+		d = d.Neg()
+	}
+	return d, true
+}
+
+// canonical rewrites an unsigned localized number as a machine decimal. Group separators are
+// only accepted in the integer part and in groups of three, so a value written in another
+// locale (1.234,56 read as en) is rejected rather than misread.
+func (p amountParser) canonical(s string) (string, bool) {
+	intPart, frac, hasFrac := strings.Cut(s, string(p.format.Decimal))
+	if hasFrac && !allDigits(frac) {
+		return "", false
+	}
+	var groups []string
+	start := 0
+	for i, r := range intPart {
+		if p.isGroup(r) {
+			groups = append(groups, intPart[start:i])
+			start = i + utf8.RuneLen(r)
+		}
+	}
+	groups = append(groups, intPart[start:])
+	for i, g := range groups {
+		if !allDigits(g) {
+			return "", false
+		}
+		if len(groups) > 1 && ((i == 0 && len(g) > 3) || (i > 0 && len(g) != 3)) {
+			return "", false
+		}
+	}
+	out := strings.Join(groups, "")
+	if hasFrac {
+		out += "." + frac
+	}
+	return out, true
+}
+
+// isGroup reports whether r separates thousands. Space grouping also accepts the
+// non-breaking spaces spreadsheets emit.
+func (p amountParser) isGroup(r rune) bool {
+	if p.format.Group == ' ' {
+		return r == ' ' || r == '\u00a0' || r == '\u202f'
+	}
+	return r == p.format.Group
+}
+
+// errorMessage is the row error for a value parse rejects.
+func (p amountParser) errorMessage() string {
+	if p.locale == "" {
+		return "not a valid number"
+	}
+	return "not a valid number for locale " + p.locale
+}
+
+// trimCurrency strips currency symbols and codes (such as $, € or MXN) and spaces around a value.
+func trimCurrency(s string) string {
+	return strings.TrimFunc(s, func(r rune) bool {
+		return unicode.IsSpace(r) || unicode.Is(unicode.Sc, r) || unicode.IsLetter(r)
+	})
+}
+
+func allDigits(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,102 @@
+package csvmigration
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"stori-challenge/internal/domain"
+)
+
+func Test_amountParser_Locales(t *testing.T) {
+	cases := []struct {
+		locale string
+		in     string
+		want   string
+		ok     bool
+	}{
+		{"", "-1234.56", "-1234.56", true},
+		{"", "1,234.56", "", false},
+		{"en-US", "$1,234.56", "1234.56", true},
+		{"en-US", "(45.00)", "-45", true},
+		{"en-US", "-$45.00", "-45", true},
+		{"en-US", "45.00-", "-45", true},
+		{"en-US", "USD 1,000,000", "1000000", true},
+		{"en-US", "1.234,56", "", false},
+		{"en-US", "12,34.5", "", false},
+		{"en-US", "(-45.00)", "", false},
+		{"es-ES", "1.234,56", "1234.56", true},
+		{"es-ES", "-1.234,56 €", "-1234.56", true},
+		{"es-MX", "$1,234.56", "1234.56", true},
+		{"pt_BR", "R$ 1.234,56", "1234.56", true},
+		{"fr-FR", "1 234,56", "1234.56", true},
+		{"de-CH", "1'234.56", "1234.56", true},
+		{"es-ES", "abc", "", false},
+	}
+	for _, tc := range cases {
+		p, ok := newAmountParser(tc.locale)
+		if !ok {
+			t.Fatalf("newAmountParser(%q) failed", tc.locale)
+		}
+		got, ok := p.parse(tc.in)
+		if ok != tc.ok {
+			t.Fatalf("%s %q: ok want %v got %v (%s)", tc.locale, tc.in, tc.ok, ok, got)
+		}
+		if ok && got.String() != tc.want {
+			t.Fatalf("%s %q: want %s got %s", tc.locale, tc.in, tc.want, got)
+		}
+	}
+	if _, ok := newAmountParser("xx-YY"); ok {
+		t.Fatalf("expected unknown locale to be rejected")
+	}
+}
+
+func TestProcess_AmountPrecision(t *testing.T) {
+	csv := "id,user_id,amount,datetime\n" +
+		"1,10,10.005,2024-06-01T00:00:00Z\n" +
+		"2,10,-10.015,2024-06-01T00:00:00Z\n" +
+		"3,10,10.500,2024-06-01T00:00:00Z\n" +
+		"4,10,10000000000000000,2024-06-01T00:00:00Z\n"
+	cases := []struct {
+		rounding domain.AmountRounding
+		want     []string
+		errs     []string
+	}{
+		{"", []string{"10.5"}, []string{"amount has more than 2 decimal places", "amount has more than 2 decimal places", "amount exceeds the maximum magnitude of 9999999999999999.99"}},
+		{domain.AmountRoundingHalfEven, []string{"10", "-10.02", "10.5"}, []string{"amount exceeds the maximum magnitude of 9999999999999999.99"}},
+		{domain.AmountRoundingHalfUp, []string{"10.01", "-10.02", "10.5"}, []string{"amount exceeds the maximum magnitude of 9999999999999999.99"}},
+	}
+	for _, tc := range cases {
+		repo := &fakeRepo{}
+		svc := newSvcWithRepo(t, repo, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+		res, err := svc.Process(context.Background(), r(csv), domain.MigrationOptions{Mode: domain.MigrationModePartial, AmountRounding: tc.rounding})
+		if err != nil {
+			t.Fatalf("%q: unexpected error: %v", tc.rounding, err)
+		}
+		var got []string
+		for _, tx := range repo.captured {
+			got = append(got, tx.Amount.String())
+		}
+		if !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("%q: want amounts %v got %v", tc.rounding, tc.want, got)
+		}
+		var msgs []string
+		for _, e := range res.Errors {
+			msgs = append(msgs, e.Message)
+		}
+		if !reflect.DeepEqual(msgs, tc.errs) {
+			t.Fatalf("%q: want errors %v got %+v", tc.rounding, tc.errs, res.Errors)
+		}
+	}
+}
+
+func TestProcess_AmountRoundedPastMaximum(t *testing.T) {
+	svc := newSvcWithRepo(t, &fakeRepo{}, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+
+	csv := "id,user_id,amount,datetime\n1,10,-9999999999999999.995,2024-06-01T00:00:00Z\n"
+	res, _ := svc.Process(context.Background(), r(csv), domain.MigrationOptions{AmountRounding: domain.AmountRoundingHalfUp})
+	if len(res.Errors) != 1 || res.Errors[0].Field != "amount" || res.Errors[0].Value != "-9999999999999999.995" {
+		t.Fatalf("expected the rounded amount rejected, got %+v", res.Errors)
+	}
+}
@@ -0,0 +1,163 @@
+package csvmigration
+
+import (
+	"archive/zip"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// MaxInflatedSize caps how far a compressed upload may expand in Process and Validate, which
+// hold every row in memory. ProcessStream reads compressed input without a cap.
+const MaxInflatedSize = 100 * 1024 * 1024
+
+// inflateLimit is a decompressed-bytes budget shared by every member of an upload.
+type inflateLimit struct {
+	max, left int64
+}
+
+func newInflateLimit(max int64) *inflateLimit {
+	// One byte over max is allowed so that input of exactly max bytes reaches EOF.
+	return &inflateLimit{max: max, left: max + 1}
+}
+
+// wrap charges the bytes read from r to the budget; a nil limit leaves r as is.
+func (l *inflateLimit) wrap(r io.Reader) io.Reader {
+	if l == nil {
+		return r
+	}
+	return &inflatedReader{r: r, l: l}
+}
+
+type inflatedReader struct {
+	r io.Reader
+	l *inflateLimit
+}
+
+func (r *inflatedReader) Read(p []byte) (int, error) {
+	if r.l.left <= 0 {
+		return 0, &fileError{msg: fmt.Sprintf("decompressed content exceeds %d MB", r.l.max/(1024*1024))}
+	}
+	if int64(len(p)) > r.l.left {
+		p = p[:r.l.left]
+	}
+	n, err := r.r.Read(p)
+	r.l.left -= int64(n)
+	return n, err
+}
+
+// zipRecordReader reads the CSV members of a zip archive, in archive order, as a single input.
+// Each member has its own header; its rows are numbered after those of the members before it
+// and cfg.locator maps them back.
+type zipRecordReader struct {
+	members []*zip.File
+	next    int
+	cfg     readConfig
+	parser  *rowParser
+
+	cur     *csvRecordReader
+	curName string
+	closer  io.Closer
+	offset  int
+}
+
+func newZipRecordReader(r io.Reader, cfg readConfig, parser *rowParser) (*zipRecordReader, error) {
+	ra, size, err := readerAt(r)
+	if err != nil {
+		return nil, err
+	}
+	zr, err := zip.NewReader(ra, size)
+	if err != nil {
+		return nil, &fileError{msg: "not a valid zip file"}
+	}
+	var members []*zip.File
+	for _, f := range zr.File {
+		if isCSVMember(f) {
+			members = append(members, f)
+		}
+	}
+	if len(members) == 0 {
+		return nil, &fileError{msg: "zip contains no .csv files"}
+	}
+	return &zipRecordReader{members: members, cfg: cfg, parser: parser}, nil
+}
+
+// isCSVMember skips directories and the metadata entries archivers add (__MACOSX/, dot files).
+func isCSVMember(f *zip.File) bool {
+	if f.FileInfo().IsDir() || strings.HasPrefix(f.Name, "__MACOSX/") || strings.HasPrefix(path.Base(f.Name), ".") {
+		return false
+	}
+	return strings.EqualFold(path.Ext(f.Name), ".csv")
+}
+
+func (r *zipRecordReader) Read() ([]string, int, error) {
+	for {
+		if r.cur == nil {
+			if r.next == len(r.members) {
+				return nil, 0, io.EOF
+			}
+			if err := r.open(r.members[r.next]); err != nil {
+				return nil, 0, err
+			}
+			r.next++
+		}
+		rec, row, err := r.cur.Read()
+		if err == io.EOF {
+			r.offset += r.cur.row
+			r.closer.Close()
+			r.cur = nil
+			continue
+		}
+		var rre *rowReadError
+		if errors.As(err, &rre) {
+			rre.Row += r.offset
+			return nil, rre.Row, rre
+		}
+		if err != nil {
+			return nil, 0, memberError(r.curName, err)
+		}
+		return rec, r.offset + row, nil
+	}
+}
+
+// columns returns the header of the member being read.
+func (r *zipRecordReader) columns() []string {
+	if r.cur == nil {
+		return nil
+	}
+	return r.cur.header
+}
+
+// open starts reading member f. The parser switches to its layout and amounts, which is safe
+// because records are parsed before the next Read.
+func (r *zipRecordReader) open(f *zip.File) error {
+	rc, err := f.Open()
+	if err != nil {
+		return &fileError{file: f.Name, msg: "member cannot be read"}
+	}
+	rd, layout, err := newCSVRecordReader(r.cfg.limit.wrap(rc), r.cfg)
+	if err != nil {
+		rc.Close()
+		return memberError(f.Name, err)
+	}
+	r.parser.layout, r.parser.amounts = layout, rd.amounts
+	r.cfg.locator.members = append(r.cfg.locator.members, memberSpan{name: f.Name, offset: r.offset})
+	r.cur, r.curName, r.closer = rd, f.Name, rc
+	return nil
+}
+
+// memberError attributes a read error to archive member name.
+func memberError(name string, err error) error {
+	var fe *fileError
+	if errors.As(err, &fe) {
+		return &fileError{file: name, msg: fe.msg}
+	}
+	var pe *csv.ParseError
+	if errors.As(err, &pe) {
+		return &fileError{file: name, msg: pe.Error()}
+	}
+	return &fileError{file: name, msg: "invalid or missing header"}
+}
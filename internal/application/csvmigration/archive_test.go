@@ -0,0 +1,152 @@
+package csvmigration
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"stori-challenge/internal/domain"
+)
+
+// zipOf builds an archive with the given members, in order.
+func zipOf(t *testing.T, members ...[2]string) *bytes.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, m := range members {
+		w, err := zw.Create(m[0])
+		if err != nil {
+			t.Fatalf("zip: %v", err)
+		}
+		if _, err := w.Write([]byte(m[1])); err != nil {
+			t.Fatalf("zip: %v", err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip: %v", err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestProcess_Zip_AllMembersInsertedTogether(t *testing.T) {
+	repo := &fakeRepo{}
+	svc := newSvcWithRepo(t, repo, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+
+	in := zipOf(t,
+		[2]string{"jan.csv", "id,user_id,amount,datetime\n1,10,1.00,2024-01-01T00:00:00Z\n2,10,2.00,2024-01-02T00:00:00Z\n"},
+		[2]string{"README.txt", "not a csv"},
+		[2]string{"__MACOSX/._feb.csv", "junk"},
+		[2]string{"2024/feb.CSV", "amount,datetime,user_id,id\n-3.00,2024-02-01T00:00:00Z,20,3\n"},
+	)
+	res, err := svc.Process(context.Background(), in, domain.MigrationOptions{Compression: domain.CompressionZip})
+	if err != nil {
+		t.Fatalf("unexpected error: %v %+v", err, res.Errors)
+	}
+	if res.Inserted != 3 || len(repo.captured) != 3 || repo.captured[2].ID != 3 {
+		t.Fatalf("unexpected result: %+v captured %+v", res, repo.captured)
+	}
+}
+
+func TestProcess_Zip_ErrorsPointAtMember_NothingInserted(t *testing.T) {
+	repo := &fakeRepo{}
+	svc := newSvcWithRepo(t, repo, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+
+	in := zipOf(t,
+		[2]string{"a.csv", "id,user_id,amount,datetime\n1,10,1.00,2024-01-01T00:00:00Z\n2,10,2.00,2024-01-02T00:00:00Z\n"},
+		[2]string{"b.csv", "id,user_id,amount,datetime\n2,20,1.00,2024-01-03T00:00:00Z\n3,20,abc,2024-01-03T00:00:00Z\n"},
+	)
+	res, err := svc.Process(context.Background(), in, domain.MigrationOptions{Compression: domain.CompressionZip})
+	if err == nil || len(res.Errors) != 2 {
+		t.Fatalf("expected two row errors, got %v %+v", err, res.Errors)
+	}
+	dup, amt := res.Errors[0], res.Errors[1]
+	if dup.File != "b.csv" || dup.Row != 1 || !strings.Contains(dup.Message, "first seen at row 2 of a.csv") {
+		t.Fatalf("unexpected duplicate error: %+v", dup)
+	}
+	if amt.File != "b.csv" || amt.Row != 2 || amt.Field != "amount" {
+		t.Fatalf("unexpected amount error: %+v", amt)
+	}
+	if len(repo.captured) != 0 {
+		t.Fatalf("expected nothing inserted, got %+v", repo.captured)
+	}
+}
+
+func TestProcess_Zip_BadMemberHeader_IsFileErrorForThatMember(t *testing.T) {
+	svc := newSvcWithRepo(t, &fakeRepo{}, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+
+	in := zipOf(t,
+		[2]string{"a.csv", "id,user_id,amount,datetime\n1,10,1.00,2024-01-01T00:00:00Z\n"},
+		[2]string{"b.csv", "id,amount\n2,1.00\n"},
+	)
+	res, err := svc.Process(context.Background(), in, domain.MigrationOptions{Compression: domain.CompressionZip})
+	if err == nil || len(res.Errors) != 1 {
+		t.Fatalf("expected one file error, got %v %+v", err, res.Errors)
+	}
+	if e := res.Errors[0]; e.Row != 0 || e.File != "b.csv" || !strings.Contains(e.Message, "missing required columns") {
+		t.Fatalf("unexpected error: %+v", e)
+	}
+
+	res, err = svc.Process(context.Background(), zipOf(t, [2]string{"notes.txt", "x"}), domain.MigrationOptions{Compression: domain.CompressionZip})
+	if err == nil || len(res.Errors) != 1 || res.Errors[0].Message != "zip contains no .csv files" {
+		t.Fatalf("expected no-csv error, got %v %+v", err, res.Errors)
+	}
+}
+
+func TestProcessStream_Zip_DuplicateAcrossMembers(t *testing.T) {
+	repo := &fakeRepo{}
+	svc := newSvcWithRepo(t, repo, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+	svc.ChunkSize = 1
+
+	in := zipOf(t,
+		[2]string{"a.csv", "id,user_id,amount,datetime\n7,10,1.00,2024-01-01T00:00:00Z\n"},
+		[2]string{"b.csv", "id,user_id,amount,datetime\n8,10,1.00,2024-01-01T00:00:00Z\n7,10,1.00,2024-01-01T00:00:00Z\n"},
+	)
+	res, err := svc.ProcessStream(context.Background(), in, domain.MigrationOptions{Compression: domain.CompressionZip})
+	if err == nil || len(res.Errors) != 1 {
+		t.Fatalf("expected one error, got %v %+v", err, res.Errors)
+	}
+	if e := res.Errors[0]; e.File != "b.csv" || e.Row != 2 || !strings.Contains(e.Message, "first seen at row 1 of a.csv") {
+		t.Fatalf("unexpected error: %+v", e)
+	}
+	if repo.imports[0].committed {
+		t.Fatalf("expected nothing committed")
+	}
+}
+
+func TestProcess_Gzip(t *testing.T) {
+	repo := &fakeRepo{}
+	svc := newSvcWithRepo(t, repo, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, _ = zw.Write([]byte("id,user_id,amount,datetime\n1,10,1.00,2024-01-01T00:00:00Z\n"))
+	_ = zw.Close()
+	res, err := svc.Process(context.Background(), &buf, domain.MigrationOptions{Compression: domain.CompressionGzip})
+	if err != nil || res.Inserted != 1 {
+		t.Fatalf("unexpected result: %+v %v", res, err)
+	}
+
+	res, err = svc.Process(context.Background(), r("id,user_id\n"), domain.MigrationOptions{Compression: domain.CompressionGzip})
+	if err == nil || len(res.Errors) != 1 || res.Errors[0].Message != "not a valid gzip file" {
+		t.Fatalf("expected gzip error, got %v %+v", err, res.Errors)
+	}
+}
+
+func Test_inflateLimit(t *testing.T) {
+	l := newInflateLimit(10)
+	if b, err := io.ReadAll(l.wrap(strings.NewReader("0123456789"))); err != nil || len(b) != 10 {
+		t.Fatalf("expected exactly the limit to pass, got %d %v", len(b), err)
+	}
+	// The budget is shared, so a second member has nothing left.
+	_, err := io.ReadAll(l.wrap(strings.NewReader("x")))
+	var fe *fileError
+	if !errors.As(err, &fe) || !strings.Contains(fe.msg, "decompressed content exceeds") {
+		t.Fatalf("expected limit error, got %v", err)
+	}
+}
@@ -0,0 +1,80 @@
+package csvmigration
+
+import (
+	"context"
+	"fmt"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/ports/repositories"
+	"stori-challenge/internal/ports/services"
+	"stori-challenge/internal/shared"
+
+	"github.com/shopspring/decimal"
+)
+
+// floorCheck flags the staged rows that take a balance below the overdraft floor. The replay
+// of each user's stored history with the staged rows runs in the database, inside the import
+// that will insert them, so neither the file nor the history is held in memory and the check
+// sees what the import will commit on top of. A nil check is disabled.
+type floorCheck struct {
+	floor decimal.Decimal
+}
+
+func newFloorCheck(floor *decimal.Decimal) *floorCheck {
+	if floor == nil {
+		return nil
+	}
+	return &floorCheck{floor: *floor}
+}
+
+// run passes one row error per breach to report, in row order, and returns how many there were.
+// Only debits read from the file are flagged; stored transactions are part of the replay but a
+// dip they cause on their own is not the file's doing.
+func (f *floorCheck) run(ctx context.Context, imp repositories.TransactionImport, report func(services.RowError)) (int, error) {
+	if f == nil {
+		return 0, nil
+	}
+	n := 0
+	err := imp.BelowFloor(ctx, f.floor, func(b repositories.FloorBreach) error {
+		n++
+		report(services.RowError{
+			Row:     b.Row,
+			Field:   "amount",
+			Value:   b.Amount.StringFixed(domain.AmountScale),
+			Message: fmt.Sprintf("balance of user %d drops to %s, below the overdraft floor of %s", b.UserID, b.Balance.StringFixed(2), f.floor.String()),
+		})
+		return nil
+	})
+	return n, err
+}
+
+// collect runs the check and returns the row errors.
+func (f *floorCheck) collect(ctx context.Context, imp repositories.TransactionImport) ([]services.RowError, error) {
+	var errs []services.RowError
+	_, err := f.run(ctx, imp, func(e services.RowError) { errs = append(errs, e) })
+	return errs, err
+}
+
+// dryRun stages txs in an import that is rolled back and returns the rows that break the floor.
+// rows holds the parsed row of each transaction.
+func (f *floorCheck) dryRun(ctx context.Context, repo repositories.TransactionRepository, txs []domain.Transaction, rows []ParsedRow) ([]services.RowError, error) {
+	if f == nil || len(txs) == 0 {
+		return nil, nil
+	}
+	imp, err := repo.BeginImport(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer func() {
+		_ = imp.Rollback()
+	}()
+	if err := stageAll(ctx, imp, txs, rows); err != nil {
+		return nil, err
+	}
+	return f.collect(ctx, imp)
+}
+
+// balanceFloorError rejects a run that takes a balance below the overdraft floor, in any mode.
+func balanceFloorError() *shared.AppError {
+	return shared.NewBadRequest("balance_below_floor", "transactions take balances below the overdraft floor", nil)
+}
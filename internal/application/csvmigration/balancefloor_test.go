@@ -0,0 +1,120 @@
+package csvmigration
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/shared"
+
+	"github.com/shopspring/decimal"
+)
+
+func floorOpts(mode domain.MigrationMode, floor string) domain.MigrationOptions {
+	d := decimal.RequireFromString(floor)
+	return domain.MigrationOptions{Mode: mode, OverdraftFloor: &d}
+}
+
+func at(s string) time.Time {
+	t, _ := time.Parse(time.RFC3339, s)
+	return t
+}
+
+func TestProcess_OverdraftFloor_ReplaysHistoryInDatetimeOrder(t *testing.T) {
+	repo := &fakeRepo{history: []domain.Transaction{
+		{ID: 100, UserID: 10, Amount: decimal.NewFromInt(50), DateTime: at("2024-01-01T00:00:00Z")},
+		{ID: 101, UserID: 10, Amount: decimal.NewFromInt(-30), DateTime: at("2024-06-01T12:00:00Z")},
+	}}
+	svc := newSvcWithRepo(t, repo, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+
+	// The file's rows are out of order: replayed by datetime, user 10 goes 50 -> 10 (row 2)
+	// -> -20 (stored 101) -> -25 (row 1); user 20 has no history and stays above the floor.
+	csv := "id,user_id,amount,datetime\n" +
+		"1,10,-5.00,2024-06-02T00:00:00Z\n" +
+		"2,10,-40.00,2024-06-01T00:00:00Z\n" +
+		"3,20,5.00,2024-06-01T00:00:00Z\n"
+	res, err := svc.Process(context.Background(), r(csv), floorOpts(domain.MigrationModePartial, "-10"))
+	var ae *shared.AppError
+	if !errors.As(err, &ae) || ae.Code != "balance_below_floor" {
+		t.Fatalf("expected balance_below_floor, got %v", err)
+	}
+	if len(repo.captured) != 0 {
+		t.Fatalf("expected nothing inserted, got %v", repo.captured)
+	}
+	if len(res.Errors) != 1 || res.Errors[0].Row != 1 || res.Errors[0].Value != "-5.00" ||
+		res.Errors[0].Message != "balance of user 10 drops to -25.00, below the overdraft floor of -10" {
+		t.Fatalf("unexpected errors: %+v", res.Errors)
+	}
+}
+
+func TestProcess_OverdraftFloor_PassesAboveFloor(t *testing.T) {
+	repo := &fakeRepo{history: []domain.Transaction{
+		{ID: 100, UserID: 10, Amount: decimal.NewFromInt(50), DateTime: at("2024-01-01T00:00:00Z")},
+	}}
+	svc := newSvcWithRepo(t, repo, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+
+	csv := "id,user_id,amount,datetime\n1,10,-50.00,2024-06-01T00:00:00Z\n"
+	res, err := svc.Process(context.Background(), r(csv), floorOpts(domain.MigrationModeStrict, "0"))
+	if err != nil || res.Inserted != 1 {
+		t.Fatalf("expected the row inserted, got %+v, %v", res, err)
+	}
+}
+
+func TestProcessStream_OverdraftFloor_NothingCommitted(t *testing.T) {
+	repo := &fakeRepo{}
+	svc := newSvcWithRepo(t, repo, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+	svc.ChunkSize = 1
+
+	// A sign-flipped export: the credit arrives as a debit.
+	csv := "id,user_id,amount,datetime\n1,10,-100.00,2024-06-01T00:00:00Z\n2,10,20.00,2024-06-02T00:00:00Z\n"
+	res, err := svc.ProcessStream(context.Background(), r(csv), floorOpts(domain.MigrationModeStrict, "0"))
+	var ae *shared.AppError
+	if !errors.As(err, &ae) || ae.Code != "balance_below_floor" {
+		t.Fatalf("expected balance_below_floor, got %v", err)
+	}
+	if repo.imports[0].committed {
+		t.Fatalf("expected the import not to be committed")
+	}
+	if len(res.Errors) != 1 || res.Errors[0].Row != 1 || res.Summary == nil {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+}
+
+func TestValidate_OverdraftFloor_WouldInsertNothing(t *testing.T) {
+	svc := newSvcWithRepo(t, &fakeRepo{}, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+
+	csv := "id,user_id,amount,datetime\n1,10,-1.00,2024-06-01T00:00:00Z\n2,20,1.00,2024-06-01T00:00:00Z\n"
+	res, err := svc.Validate(context.Background(), r(csv), floorOpts(domain.MigrationModePartial, "0"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.WouldInsert != 0 || res.Rejected != 1 || len(res.Errors) != 1 || res.Errors[0].Row != 1 {
+		t.Fatalf("unexpected validation: %+v", res)
+	}
+}
+
+func TestProcessAndValidate_OverdraftFloor_BreachOnTheRowThatReusesAnInvalidRowsID(t *testing.T) {
+	// Row 1 fails validation; row 2 reuses its id, is valid and breaks the floor.
+	csv := "id,user_id,amount,datetime\n5,10,abc,2024-06-01T00:00:00Z\n5,10,-50.00,2024-06-02T00:00:00Z\n"
+	opts := floorOpts(domain.MigrationModePartial, "0")
+	svc := newSvcWithRepo(t, &fakeRepo{}, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+
+	res, err := svc.Process(context.Background(), r(csv), opts)
+	var ae *shared.AppError
+	if !errors.As(err, &ae) || ae.Code != "balance_below_floor" {
+		t.Fatalf("expected balance_below_floor, got %v", err)
+	}
+	if len(res.Errors) != 2 || res.Errors[1].Row != 2 || res.Errors[1].Value != "-50.00" {
+		t.Fatalf("expected the breach reported on row 2, got %+v", res.Errors)
+	}
+
+	val, err := svc.Validate(context.Background(), r(csv), opts)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if val.WouldInsert != 0 || len(val.Errors) != 2 || val.Errors[1].Row != 2 || val.Errors[1].Value != "-50.00" {
+		t.Fatalf("expected the breach reported on row 2, got %+v", val)
+	}
+}
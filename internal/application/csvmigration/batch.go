@@ -0,0 +1,129 @@
+package csvmigration
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/ports/repositories"
+	"stori-challenge/internal/ports/services"
+	"stori-challenge/internal/shared"
+)
+
+// batchRun records a migration run as a domain.MigrationBatch. The batch is created before the
+// input is read; its content must not match an upload that already succeeded, which is checked
+// before anything is written, unless the run is idempotent; and the outcome is recorded when
+// the run ends. Without a batch repository every step is a no-op.
+type batchRun struct {
+	repo  repositories.MigrationBatchRepository
+	batch domain.MigrationBatch
+	// input is what the run reads. When the upload cannot be hashed up front it is hashed as
+	// it is read, into digest, and drained before the check.
+	input   io.Reader
+	digest  hash.Hash
+	counter *byteCounter
+	checked bool
+	// repeatable skips the check: an idempotent run may load the same file again.
+	repeatable bool
+}
+
+// startBatch creates the batch for the upload described by opts. Seekable input is hashed
+// first, so a repeated upload is rejected before it is parsed.
+func (s *csvMigrationService) startBatch(ctx context.Context, r io.Reader, opts domain.MigrationOptions) (*batchRun, []services.RowError, *shared.AppError) {
+	run := &batchRun{repo: s.Batches, input: r, repeatable: opts.Idempotent}
+	if s.Batches == nil {
+		return run, nil, nil
+	}
+	b, err := s.Batches.Create(ctx, domain.MigrationBatch{Source: opts.Source, AmountRounding: opts.Rounding()})
+	if err != nil {
+		return nil, nil, shared.NewInternal("db_failure", "database error", err)
+	}
+	run.batch = b
+	if rs, ok := r.(io.ReadSeeker); ok {
+		if run.batch.SHA256, run.batch.Size, err = domain.DigestSHA256(rs); err != nil {
+			appErr := shared.NewInternal("read_failure", "unable to read file", err)
+			run.finish(ctx, services.MigrationResult{}, appErr)
+			return nil, nil, appErr
+		}
+		if errs, appErr := run.check(ctx); appErr != nil {
+			run.finish(ctx, services.MigrationResult{}, appErr)
+			return nil, errs, appErr
+		}
+		return run, nil, nil
+	}
+	run.digest, run.counter = sha256.New(), &byteCounter{}
+	run.input = io.TeeReader(r, io.MultiWriter(run.digest, run.counter))
+	return run, nil, nil
+}
+
+// id is the batch id migrated transactions point to; zero without a batch.
+func (b *batchRun) id() int64 {
+	return b.batch.ID
+}
+
+// check rejects content that an earlier batch already migrated. Input hashed while reading is
+// drained first, so the digest covers all of it.
+func (b *batchRun) check(ctx context.Context) ([]services.RowError, *shared.AppError) {
+	if b.repo == nil || b.checked {
+		return nil, nil
+	}
+	b.checked = true
+	if b.digest != nil {
+		if _, err := io.Copy(io.Discard, b.input); err != nil {
+			return nil, shared.NewInternal("read_failure", "unable to read file", err)
+		}
+		b.batch.SHA256, b.batch.Size = hex.EncodeToString(b.digest.Sum(nil)), b.counter.n
+	}
+	if b.repeatable {
+		return nil, nil
+	}
+	prior, found, err := b.repo.FindSucceeded(ctx, b.batch.SHA256)
+	if err != nil {
+		return nil, shared.NewInternal("db_failure", "database error", err)
+	}
+	if !found {
+		return nil, nil
+	}
+	return []services.RowError{{
+		Row:     0,
+		Field:   "file",
+		Value:   prior.Source.FileName,
+		Message: fmt.Sprintf("the same file was already migrated by batch %d", prior.ID),
+	}}, shared.NewConflict("duplicate_upload", "file already migrated", nil)
+}
+
+// finish records the outcome of the run. The result has already been decided, so a failure to
+// record it is only logged.
+func (b *batchRun) finish(ctx context.Context, res services.MigrationResult, err error) {
+	if b.repo == nil {
+		return
+	}
+	b.batch.Status = domain.MigrationBatchSucceeded
+	if err != nil {
+		b.batch.Status = domain.MigrationBatchFailed
+		b.batch.ErrorCode = "internal_error"
+		var ae *shared.AppError
+		if errors.As(err, &ae) {
+			b.batch.ErrorCode = ae.Code
+		}
+	}
+	b.batch.Inserted, b.batch.Rejected, b.batch.AlreadyPresent = res.Inserted, res.Rejected, res.AlreadyPresent
+	if ferr := b.repo.Finish(ctx, b.batch); ferr != nil {
+		log.Printf("migration batch %d: recording the outcome failed: %v", b.batch.ID, ferr)
+	}
+}
+
+type byteCounter struct {
+	n int64
+}
+
+func (c *byteCounter) Write(p []byte) (int, error) {
+	c.n += int64(len(p))
+	return len(p), nil
+}
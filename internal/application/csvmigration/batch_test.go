@@ -0,0 +1,172 @@
+package csvmigration
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/ports/repositories"
+	"stori-challenge/internal/shared"
+)
+
+type fakeBatchRepo struct {
+	created   []domain.MigrationBatch
+	finished  []domain.MigrationBatch
+	succeeded map[string]domain.MigrationBatch
+}
+
+func (f *fakeBatchRepo) Create(ctx context.Context, b domain.MigrationBatch) (domain.MigrationBatch, error) {
+	b.ID = int64(len(f.created) + 1)
+	b.Status = domain.MigrationBatchProcessing
+	f.created = append(f.created, b)
+	return b, nil
+}
+
+func (f *fakeBatchRepo) Finish(ctx context.Context, b domain.MigrationBatch) error {
+	f.finished = append(f.finished, b)
+	return nil
+}
+
+func (f *fakeBatchRepo) GetByID(ctx context.Context, id int64) (domain.MigrationBatch, bool, error) {
+	return domain.MigrationBatch{}, false, nil
+}
+
+func (f *fakeBatchRepo) FindSucceeded(ctx context.Context, sha256 string) (domain.MigrationBatch, bool, error) {
+	b, ok := f.succeeded[sha256]
+	return b, ok, nil
+}
+
+func (f *fakeBatchRepo) GetByMigrationID(ctx context.Context, migrationID int64) (domain.MigrationBatch, bool, error) {
+	return domain.MigrationBatch{}, false, nil
+}
+
+func (f *fakeBatchRepo) Revert(ctx context.Context, rev domain.BatchRevert) (repositories.BatchRevertOutcome, error) {
+	return repositories.BatchRevertOutcome{}, nil
+}
+
+func (f *fakeBatchRepo) GetRevert(ctx context.Context, batchID int64) (domain.BatchRevert, bool, error) {
+	return domain.BatchRevert{}, false, nil
+}
+
+func newSvcWithBatches(t *testing.T, repo *fakeRepo, batches *fakeBatchRepo) *csvMigrationService {
+	t.Helper()
+	svc := newSvcWithRepo(t, repo, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+	svc.Batches = batches
+	return svc
+}
+
+const batchCSV = "id,user_id,amount,datetime\n1,10,1.00,2024-06-01T00:00:00Z\nx,10,1.00,2024-06-01T00:00:00Z\n3,10,2.00,2024-06-01T00:00:00Z\n"
+
+func TestProcess_Batch_LinksTransactionsAndRecordsOutcome(t *testing.T) {
+	repo := &fakeRepo{}
+	batches := &fakeBatchRepo{}
+	svc := newSvcWithBatches(t, repo, batches)
+
+	src := domain.UploadSource{FileName: "data.csv", UploadedBy: "ana"}
+	res, err := svc.Process(context.Background(), r(batchCSV), domain.MigrationOptions{Mode: domain.MigrationModePartial, Source: src})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.BatchID != 1 || len(batches.created) != 1 || batches.created[0].Source != src {
+		t.Fatalf("unexpected batch: id=%d created=%+v", res.BatchID, batches.created)
+	}
+	if got := batches.created[0].AmountRounding; got != domain.AmountRoundingReject {
+		t.Fatalf("expected the default rounding recorded, got %q", got)
+	}
+	if len(repo.captured) != 2 {
+		t.Fatalf("expected 2 inserted, got %d", len(repo.captured))
+	}
+	for i, row := range []int{1, 3} {
+		if got := repo.captured[i].Source; got.BatchID != 1 || got.Row != row || got.File != "" {
+			t.Fatalf("tx %d: unexpected source %+v", i, got)
+		}
+	}
+	sum, size, _ := domain.DigestSHA256(strings.NewReader(batchCSV))
+	if len(batches.finished) != 1 {
+		t.Fatalf("expected the batch to be finished once, got %d", len(batches.finished))
+	}
+	b := batches.finished[0]
+	if b.Status != domain.MigrationBatchSucceeded || b.SHA256 != sum || b.Size != size || b.Inserted != 2 || b.Rejected != 1 {
+		t.Fatalf("unexpected finished batch: %+v", b)
+	}
+}
+
+func TestProcessStream_Batch_RecordsAmountRounding(t *testing.T) {
+	batches := &fakeBatchRepo{}
+	svc := newSvcWithBatches(t, &fakeRepo{}, batches)
+
+	in := "id,user_id,amount,datetime\n1,10,10.005,2024-06-01T00:00:00Z\n"
+	if _, err := svc.ProcessStream(context.Background(), r(in), domain.MigrationOptions{AmountRounding: domain.AmountRoundingHalfEven}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(batches.created) != 1 || batches.created[0].AmountRounding != domain.AmountRoundingHalfEven {
+		t.Fatalf("unexpected batch: %+v", batches.created)
+	}
+}
+
+func TestProcess_Batch_AlreadyMigratedContent_Conflict(t *testing.T) {
+	repo := &fakeRepo{}
+	sum, _, _ := domain.DigestSHA256(strings.NewReader(batchCSV))
+	batches := &fakeBatchRepo{succeeded: map[string]domain.MigrationBatch{sum: {ID: 7, Source: domain.UploadSource{FileName: "june.csv"}}}}
+	svc := newSvcWithBatches(t, repo, batches)
+
+	res, err := svc.Process(context.Background(), r(batchCSV), domain.MigrationOptions{Mode: domain.MigrationModePartial})
+	var ae *shared.AppError
+	if !errors.As(err, &ae) || ae.Kind != shared.ConflictKind || ae.Code != "duplicate_upload" {
+		t.Fatalf("expected duplicate_upload, got %v", err)
+	}
+	if len(res.Errors) != 1 || res.Errors[0].Field != "file" || res.Errors[0].Value != "june.csv" {
+		t.Fatalf("unexpected errors: %+v", res.Errors)
+	}
+	if len(repo.captured) != 0 {
+		t.Fatalf("expected nothing inserted, got %d", len(repo.captured))
+	}
+	if len(batches.finished) != 1 || batches.finished[0].Status != domain.MigrationBatchFailed || batches.finished[0].ErrorCode != "duplicate_upload" {
+		t.Fatalf("unexpected finished batches: %+v", batches.finished)
+	}
+}
+
+func TestProcessStream_Batch_HashesUnseekableInputBeforeCommit(t *testing.T) {
+	repo := &fakeRepo{}
+	sum, size, _ := domain.DigestSHA256(strings.NewReader(batchCSV))
+	batches := &fakeBatchRepo{succeeded: map[string]domain.MigrationBatch{sum: {ID: 7}}}
+	svc := newSvcWithBatches(t, repo, batches)
+
+	// MultiReader hides Seek, so the content is hashed as it is read.
+	in := io.MultiReader(strings.NewReader(batchCSV))
+	_, err := svc.ProcessStream(context.Background(), in, domain.MigrationOptions{Mode: domain.MigrationModePartial})
+	var ae *shared.AppError
+	if !errors.As(err, &ae) || ae.Code != "duplicate_upload" {
+		t.Fatalf("expected duplicate_upload, got %v", err)
+	}
+	if len(repo.imports) != 1 || repo.imports[0].committed {
+		t.Fatalf("expected the import not to be committed")
+	}
+	for _, tx := range repo.imports[0].staged {
+		if tx.Source.BatchID != 1 || tx.Source.Row == 0 {
+			t.Fatalf("unexpected staged source: %+v", tx.Source)
+		}
+	}
+	if b := batches.finished[0]; b.SHA256 != sum || b.Size != size || b.Status != domain.MigrationBatchFailed {
+		t.Fatalf("unexpected finished batch: %+v", b)
+	}
+}
+
+func TestProcess_Batch_Idempotent_MayRepeatContent(t *testing.T) {
+	repo := &fakeRepo{}
+	sum, _, _ := domain.DigestSHA256(strings.NewReader(batchCSV))
+	batches := &fakeBatchRepo{succeeded: map[string]domain.MigrationBatch{sum: {ID: 7}}}
+	svc := newSvcWithBatches(t, repo, batches)
+
+	_, err := svc.Process(context.Background(), r(batchCSV), domain.MigrationOptions{Mode: domain.MigrationModePartial, Idempotent: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b := batches.finished[0]; b.Status != domain.MigrationBatchSucceeded || b.SHA256 != sum {
+		t.Fatalf("unexpected finished batch: %+v", b)
+	}
+}
@@ -0,0 +1,240 @@
+package csvmigration
+
+import (
+	"encoding/xml"
+	"errors"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/ports/services"
+)
+
+// camtRepeated are the camt elements that may occur more than once under their parent; their
+// XPath steps carry a 1-based position.
+var camtRepeated = map[string]bool{"Stmt": true, "Ntfctn": true, "Ntry": true, "NtryDtls": true, "TxDtls": true}
+
+// camtRecordReader streams the <Ntry> entries of an ISO 20022 camt.053 statement or camt.054
+// notification into jsonLayout records. Elements are matched by local name, so every message
+// version is accepted. Rows are 1-based entry positions in the document, and each entry's
+// element paths go to the locator so that errors point at the element instead.
+//
+// Ids are derived from the account and AcctSvcrRef, or EndToEndId when the bank gives none
+// (see domain.StableTransactionID), so an entry notified in a camt.054 and then reported in a
+// camt.053 is the same transaction.
+type camtRecordReader struct {
+	dec     *xml.Decoder
+	loc     *time.Location
+	owner   *statementOwner
+	locator *rowLocator
+
+	stack   []camtFrame
+	text    []byte
+	root    bool
+	stmt    int // stack index of the current Stmt/Ntfctn, -1 outside one
+	account string
+	entry   *camtEntry
+	index   int
+}
+
+type camtFrame struct {
+	name   string
+	path   string
+	counts map[string]int
+}
+
+// camtEntry collects the values of one <Ntry> and, by field, the paths they came from.
+type camtEntry struct {
+	depth                        int
+	amount, indicator, date, ref string
+	endToEnd                     string
+	paths                        map[string]string
+	indicatorPath, endToEndPath  string
+}
+
+func newCAMTRecordReader(r io.Reader, cfg readConfig) *camtRecordReader {
+	cfg.locator.paths, cfg.locator.lastPathRow = nil, 0
+	return &camtRecordReader{
+		dec:     xml.NewDecoder(r),
+		loc:     cfg.dates.location(),
+		owner:   newStatementOwner(cfg),
+		locator: cfg.locator,
+		stmt:    -1,
+	}
+}
+
+func (r *camtRecordReader) Read() ([]string, int, error) {
+	for {
+		tok, err := r.dec.Token()
+		if err == io.EOF {
+			if !r.root {
+				return nil, 0, &fileError{msg: "not a camt.053 or camt.054 document"}
+			}
+			return nil, 0, io.EOF
+		}
+		if err != nil {
+			var se *xml.SyntaxError
+			if errors.As(err, &se) {
+				return nil, 0, &fileError{msg: se.Error()}
+			}
+			return nil, 0, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if err := r.start(t.Name.Local); err != nil {
+				return nil, 0, err
+			}
+		case xml.CharData:
+			r.text = append(r.text, t...)
+		case xml.EndElement:
+			if rec, row, done, err := r.end(); done {
+				return rec, row, err
+			}
+		}
+	}
+}
+
+func (r *camtRecordReader) start(name string) error {
+	path := "/" + name
+	if n := len(r.stack); n > 0 {
+		parent := r.stack[n-1]
+		parent.counts[name]++
+		path = parent.path + "/" + name
+		if camtRepeated[name] {
+			path += "[" + strconv.Itoa(parent.counts[name]) + "]"
+		}
+	}
+	switch len(r.stack) {
+	case 0:
+		if name != "Document" {
+			return &fileError{msg: "not a camt.053 or camt.054 document"}
+		}
+	case 1:
+		if name != "BkToCstmrStmt" && name != "BkToCstmrDbtCdtNtfctn" {
+			return &fileError{msg: "not a camt.053 or camt.054 document: unexpected <" + name + ">"}
+		}
+		r.root = true
+	case 2:
+		if name == "Stmt" || name == "Ntfctn" {
+			r.stmt, r.account = 2, ""
+		}
+	}
+	if name == "Ntry" && r.stmt >= 0 && len(r.stack) == r.stmt+1 {
+		r.entry = &camtEntry{depth: len(r.stack), paths: map[string]string{"": path}}
+	}
+	r.stack = append(r.stack, camtFrame{name: name, path: path, counts: make(map[string]int)})
+	r.text = r.text[:0]
+	return nil
+}
+
+// end closes the innermost element, storing the values the reader needs. done is set when it
+// closes an entry, with the entry's record or row error.
+func (r *camtRecordReader) end() (rec []string, row int, done bool, err error) {
+	n := len(r.stack)
+	if n == 0 {
+		return nil, 0, false, nil
+	}
+	path := r.stack[n-1].path
+	// rel is the element's path below the open entry or statement, without positions.
+	var rel string
+	switch {
+	case r.entry != nil && n-1 > r.entry.depth:
+		rel = camtSteps(r.stack[r.entry.depth+1:])
+	case r.entry == nil && r.stmt >= 0 && n-1 > r.stmt:
+		rel = camtSteps(r.stack[r.stmt+1:])
+	}
+	r.stack = r.stack[:n-1]
+	text := strings.TrimSpace(string(r.text))
+	r.text = r.text[:0]
+
+	if e := r.entry; e != nil {
+		if n-1 == e.depth {
+			r.entry = nil
+			r.index++
+			rec, err := r.record(e)
+			r.locator.addPaths(r.index, e.paths)
+			return rec, r.index, true, err
+		}
+		switch rel {
+		case "Amt":
+			e.amount, e.paths["amount"] = text, path
+		case "CdtDbtInd":
+			e.indicator, e.indicatorPath = text, path
+		case "BookgDt/Dt", "BookgDt/DtTm":
+			e.date, e.paths["datetime"] = text, path
+		case "AcctSvcrRef":
+			e.ref, e.paths["id"] = text, path
+		case "NtryDtls/TxDtls/Refs/EndToEndId":
+			if e.endToEnd == "" {
+				e.endToEnd, e.endToEndPath = text, path
+			}
+		}
+		return nil, 0, false, nil
+	}
+	switch {
+	case n-1 == r.stmt:
+		r.stmt = -1
+	case rel == "Acct/Id/IBAN" || rel == "Acct/Id/Othr/Id":
+		r.account = text
+	}
+	return nil, 0, false, nil
+}
+
+func camtSteps(frames []camtFrame) string {
+	names := make([]string, len(frames))
+	for i, f := range frames {
+		names[i] = f.name
+	}
+	return strings.Join(names, "/")
+}
+
+// record maps an entry to id, user_id, signed amount and booking datetime.
+func (r *camtRecordReader) record(e *camtEntry) ([]string, error) {
+	ref := e.ref
+	if ref == "" && e.endToEnd != "" && e.endToEnd != "NOTPROVIDED" {
+		ref, e.paths["id"] = e.endToEnd, e.endToEndPath
+	}
+	if ref == "" {
+		return nil, &rowReadError{services.RowError{Row: r.index, Field: "id", Value: "", Message: "entry has no AcctSvcrRef or EndToEndId"}}
+	}
+	userID, err := r.owner.user(r.account)
+	if err != nil {
+		return nil, err
+	}
+	amount := e.amount
+	switch e.indicator {
+	case "CRDT":
+	case "DBIT":
+		amount = "-" + amount
+	default:
+		return nil, &rowReadError{services.RowError{Path: e.indicatorPath, Row: r.index, Field: "amount", Value: e.indicator, Message: "CdtDbtInd must be CRDT or DBIT"}}
+	}
+	dt, ok := parseCAMTDate(e.date, r.loc)
+	if !ok {
+		return nil, &rowReadError{services.RowError{Row: r.index, Field: "datetime", Value: e.date, Message: "booking date must be an ISO date (BookgDt/Dt) or date-time (BookgDt/DtTm)"}}
+	}
+	return []string{
+		strconv.FormatInt(domain.StableTransactionID(string(domain.InputFormatCAMT), r.account, ref), 10),
+		strconv.FormatInt(userID, 10),
+		amount,
+		dt.Format(time.RFC3339Nano),
+		"",
+	}, nil
+}
+
+// parseCAMTDate reads an ISODate or ISODateTime. Values without an offset are in loc.
+func parseCAMTDate(s string, loc *time.Location) (time.Time, bool) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02Z07:00"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t, true
+		}
+	}
+	for _, layout := range []string{"2006-01-02T15:04:05.999999999", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
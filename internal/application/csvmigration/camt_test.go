@@ -0,0 +1,222 @@
+package csvmigration
+
+import (
+	"context"
+	"io"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"stori-challenge/internal/domain"
+)
+
+// camt053 is a statement with a credit by AcctSvcrRef, a debit by EndToEndId, a value in a
+// nested amount that must be ignored, and an invalid entry.
+const camt053 = `<?xml version="1.0" encoding="UTF-8"?>
+<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.053.001.08">
+  <BkToCstmrStmt>
+    <GrpHdr><MsgId>M1</MsgId></GrpHdr>
+    <Stmt>
+      <Acct><Id><IBAN>MX00BANK0001</IBAN></Id></Acct>
+      <Bal><Amt Ccy="MXN">999.00</Amt></Bal>
+      <Ntry>
+        <Amt Ccy="MXN">100.50</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <BookgDt><Dt>2024-06-01</Dt></BookgDt>
+        <AcctSvcrRef>REF-1</AcctSvcrRef>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="MXN">20.00</Amt>
+        <CdtDbtInd>DBIT</CdtDbtInd>
+        <BookgDt><DtTm>2024-06-02T10:30:00+02:00</DtTm></BookgDt>
+        <NtryDtls><TxDtls>
+          <Refs><EndToEndId>E2E-2</EndToEndId></Refs>
+          <AmtDtls><TxAmt><Amt Ccy="MXN">20.00</Amt></TxAmt></AmtDtls>
+        </TxDtls></NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="MXN">abc</Amt>
+        <CdtDbtInd>CRDT</CdtDbtInd>
+        <BookgDt><Dt>2024-06-03</Dt></BookgDt>
+        <AcctSvcrRef>REF-3</AcctSvcrRef>
+      </Ntry>
+    </Stmt>
+  </BkToCstmrStmt>
+</Document>
+`
+
+// camt054 notifies, for an account without IBAN, an entry without references and one whose
+// indicator is wrong.
+const camt054 = `<Document xmlns="urn:iso:std:iso:20022:tech:xsd:camt.054.001.02">
+  <BkToCstmrDbtCdtNtfctn>
+    <Ntfctn>
+      <Acct><Id><Othr><Id>ACC-7</Id></Othr></Id></Acct>
+      <Ntry>
+        <Amt Ccy="EUR">1.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><BookgDt><Dt>2024-06-01</Dt></BookgDt>
+        <NtryDtls><TxDtls><Refs><EndToEndId>NOTPROVIDED</EndToEndId></Refs></TxDtls></NtryDtls>
+      </Ntry>
+      <Ntry>
+        <Amt Ccy="EUR">2.00</Amt><CdtDbtInd>X</CdtDbtInd><BookgDt><Dt>2024-06-01</Dt></BookgDt>
+        <AcctSvcrRef>R2</AcctSvcrRef>
+      </Ntry>
+    </Ntfctn>
+  </BkToCstmrDbtCdtNtfctn>
+</Document>
+`
+
+func TestProcess_CAMT053_SignedAmountsStableIdsAndPaths(t *testing.T) {
+	repo := &fakeRepo{}
+	svc := newSvcWithRepo(t, repo, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+	svc.Accounts = &fakeAccounts{users: map[string]int64{"MX00BANK0001": 5}}
+
+	res, err := svc.Process(context.Background(), r(camt053), domain.MigrationOptions{
+		Format: domain.InputFormatCAMT, Mode: domain.MigrationModePartial, Timezone: "America/Mexico_City",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Inserted != 2 || len(res.Errors) != 1 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if e := res.Errors[0]; e.Field != "amount" || e.Path != "/Document/BkToCstmrStmt/Stmt[1]/Ntry[3]/Amt" {
+		t.Fatalf("unexpected error: %+v", e)
+	}
+	credit, debit := repo.captured[0], repo.captured[1]
+	if credit.ID != domain.StableTransactionID("camt", "MX00BANK0001", "REF-1") || credit.UserID != 5 || credit.Amount.String() != "100.5" {
+		t.Fatalf("unexpected credit: %+v", credit)
+	}
+	if want := time.Date(2024, 6, 1, 6, 0, 0, 0, time.UTC); !credit.DateTime.Equal(want) {
+		t.Fatalf("expected %s, got %s", want, credit.DateTime)
+	}
+	if debit.ID != domain.StableTransactionID("camt", "MX00BANK0001", "E2E-2") || debit.Amount.String() != "-20" {
+		t.Fatalf("unexpected debit: %+v", debit)
+	}
+	if want := time.Date(2024, 6, 2, 8, 30, 0, 0, time.UTC); !debit.DateTime.Equal(want) {
+		t.Fatalf("expected %s, got %s", want, debit.DateTime)
+	}
+}
+
+func TestProcess_CAMT_DuplicateNamesFirstEntryPath(t *testing.T) {
+	svc := newSvcWithRepo(t, &fakeRepo{}, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+
+	dup := strings.Replace(camt053, "<AcctSvcrRef>REF-3</AcctSvcrRef>", "<AcctSvcrRef>REF-1</AcctSvcrRef>", 1)
+	dup = strings.Replace(dup, "abc", "3.00", 1)
+	res, err := svc.Process(context.Background(), r(dup), domain.MigrationOptions{Format: domain.InputFormatCAMT, UserID: 9})
+	if err == nil || len(res.Errors) != 1 {
+		t.Fatalf("expected one duplicate error, got %v %+v", err, res.Errors)
+	}
+	e := res.Errors[0]
+	if e.Path != "/Document/BkToCstmrStmt/Stmt[1]/Ntry[3]/AcctSvcrRef" || !strings.Contains(e.Message, "first seen at /Document/BkToCstmrStmt/Stmt[1]/Ntry[1]") {
+		t.Fatalf("unexpected error: %+v", e)
+	}
+}
+
+func TestValidate_CAMT054_EntryErrors(t *testing.T) {
+	svc := newSvcWithRepo(t, &fakeRepo{}, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+	svc.Accounts = &fakeAccounts{users: map[string]int64{"ACC-7": 1}}
+
+	report, err := svc.Validate(context.Background(), r(camt054), domain.MigrationOptions{Format: domain.InputFormatCAMT})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Rows != 2 || len(report.Errors) != 2 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if e := report.Errors[0]; e.Field != "id" || e.Path != "/Document/BkToCstmrDbtCdtNtfctn/Ntfctn[1]/Ntry[1]" {
+		t.Fatalf("unexpected id error: %+v", e)
+	}
+	if e := report.Errors[1]; e.Value != "X" || e.Path != "/Document/BkToCstmrDbtCdtNtfctn/Ntfctn[1]/Ntry[2]/CdtDbtInd" {
+		t.Fatalf("unexpected indicator error: %+v", e)
+	}
+}
+
+func TestProcess_CAMT_NotADocument(t *testing.T) {
+	svc := newSvcWithRepo(t, &fakeRepo{}, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+
+	for _, in := range []string{"<Document><BkToCstmrAcctRpt/></Document>", "<html/>", "<Document><BkToCstmrStmt><Stmt>"} {
+		res, err := svc.Process(context.Background(), r(in), domain.MigrationOptions{Format: domain.InputFormatCAMT, UserID: 1})
+		if err == nil || len(res.Errors) != 1 || res.Errors[0].Row != 0 {
+			t.Fatalf("%q: expected a file-level error, got %v %+v", in, err, res.Errors)
+		}
+	}
+}
+
+func Test_parseCAMTDate(t *testing.T) {
+	loc := time.FixedZone("", -6*3600)
+	cases := []struct {
+		in   string
+		want time.Time
+		ok   bool
+	}{
+		{"2024-06-01", time.Date(2024, 6, 1, 6, 0, 0, 0, time.UTC), true},
+		{"2024-06-01+02:00", time.Date(2024, 5, 31, 22, 0, 0, 0, time.UTC), true},
+		{"2024-06-01T10:00:00", time.Date(2024, 6, 1, 16, 0, 0, 0, time.UTC), true},
+		{"2024-06-01T10:00:00.5Z", time.Date(2024, 6, 1, 10, 0, 0, 500e6, time.UTC), true},
+		{"01/06/2024", time.Time{}, false},
+		{"", time.Time{}, false},
+	}
+	for _, c := range cases {
+		got, ok := parseCAMTDate(c.in, loc)
+		if ok != c.ok || (ok && !got.Equal(c.want)) {
+			t.Fatalf("%q: want %s %v got %s %v", c.in, c.want, c.ok, got, ok)
+		}
+	}
+}
+
+func TestCAMTRecordReader_PathsTakeOneSpanPerLayout(t *testing.T) {
+	svc := newSvcWithRepo(t, &fakeRepo{}, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+	svc.Accounts = &fakeAccounts{users: map[string]int64{"MX00BANK0001": 5}}
+	cfg, appErr := svc.loadReadConfig(context.Background(), domain.MigrationOptions{Format: domain.InputFormatCAMT})
+	if appErr != nil {
+		t.Fatalf("config: %v", appErr)
+	}
+
+	// 1000 entries with the same layout, then one booked with a DtTm and one more like the first.
+	var b strings.Builder
+	b.WriteString(`<Document><BkToCstmrStmt><Stmt><Acct><Id><IBAN>MX00BANK0001</IBAN></Id></Acct>`)
+	entry := func(n int, date string) {
+		b.WriteString(`<Ntry><Amt>1.00</Amt><CdtDbtInd>CRDT</CdtDbtInd><BookgDt>` + date + `</BookgDt><AcctSvcrRef>R` + strconv.Itoa(n) + `</AcctSvcrRef></Ntry>`)
+	}
+	for n := 1; n <= 1000; n++ {
+		entry(n, `<Dt>2024-06-01</Dt>`)
+	}
+	entry(1001, `<DtTm>2024-06-01T10:00:00Z</DtTm>`)
+	entry(1002, `<Dt>2024-06-01</Dt>`)
+	b.WriteString(`</Stmt></BkToCstmrStmt></Document>`)
+
+	rd := newCAMTRecordReader(strings.NewReader(b.String()), cfg)
+	rows := 0
+	for {
+		if _, _, err := rd.Read(); err == io.EOF {
+			break
+		} else if err != nil {
+			t.Fatalf("read: %v", err)
+		}
+		rows++
+	}
+	if rows != 1002 {
+		t.Fatalf("expected 1002 rows, got %d", rows)
+	}
+	if len(cfg.locator.paths) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(cfg.locator.paths))
+	}
+	const stmt = "/Document/BkToCstmrStmt/Stmt[1]"
+	for _, c := range []struct {
+		row   int
+		field string
+		want  string
+	}{
+		{1, "amount", stmt + "/Ntry[1]/Amt"},
+		{500, "datetime", stmt + "/Ntry[500]/BookgDt/Dt"},
+		{1000, "", stmt + "/Ntry[1000]"},
+		{1001, "datetime", stmt + "/Ntry[1001]/BookgDt/DtTm"},
+		{1002, "id", stmt + "/Ntry[1002]/AcctSvcrRef"},
+		{1002, "type", stmt + "/Ntry[1002]"},
+		{1003, "amount", ""},
+	} {
+		if got := cfg.locator.path(c.row, c.field); got != c.want {
+			t.Fatalf("row %d %q: expected %q, got %q", c.row, c.field, c.want, got)
+		}
+	}
+}
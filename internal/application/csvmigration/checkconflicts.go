@@ -3,18 +3,120 @@ package csvmigration
 import (
 	"context"
 	"strconv"
+	"time"
 
 	"stori-challenge/internal/domain"
 	"stori-challenge/internal/ports/services"
 )
 
-// checkConflicts asks the repository for existing ids.
-func (s *csvMigrationService) checkConflicts(ctx context.Context, txs []domain.Transaction) (map[int64]bool, error) {
+// idChecker is implemented by the repository and by an open import.
+type idChecker interface {
+	ExistsByIDs(ctx context.Context, ids []int64) (map[int64]bool, error)
+	GetByIDs(ctx context.Context, ids []int64) (map[int64]domain.Transaction, error)
+}
+
+// conflicts is the outcome of checking parsed transactions against the stored ones.
+type conflicts struct {
+	// conflicting holds stored ids that must be reported; they are never inserted.
+	conflicting map[int64]bool
+	// identical holds stored ids whose content matches the file (idempotent mode only).
+	identical map[int64]bool
+	errs      []services.RowError
+}
+
+// existing returns every stored id, conflicting or identical.
+func (c conflicts) existing() map[int64]bool {
+	if len(c.identical) == 0 {
+		return c.conflicting
+	}
+	out := make(map[int64]bool, len(c.conflicting)+len(c.identical))
+	for id := range c.conflicting {
+		out[id] = true
+	}
+	for id := range c.identical {
+		out[id] = true
+	}
+	return out
+}
+
+// findConflicts looks up the ids of txs. Without idempotent every stored id is a conflict; with it,
+// rows whose user_id, amount and datetime match the stored row are identical and only the rest are
+// reported, one error per differing field.
+func (s *csvMigrationService) findConflicts(ctx context.Context, checker idChecker, txs []domain.Transaction, rows []ParsedRow, idempotent bool) (conflicts, error) {
+	if !idempotent {
+		existing, err := existingIDs(ctx, checker, txs)
+		if err != nil {
+			return conflicts{}, err
+		}
+		return conflicts{conflicting: existing, errs: s.buildConflictErrors(rows, existing)}, nil
+	}
+
+	ids := make([]int64, 0, len(txs))
+	for _, tx := range txs {
+		ids = append(ids, tx.ID)
+	}
+	stored, err := checker.GetByIDs(ctx, ids)
+	if err != nil {
+		return conflicts{}, err
+	}
+	c := conflicts{conflicting: map[int64]bool{}, identical: map[int64]bool{}}
+	if len(stored) == 0 {
+		return c, nil
+	}
+	fileByID := make(map[int64]domain.Transaction, len(stored))
+	for _, tx := range txs {
+		st, ok := stored[tx.ID]
+		if !ok {
+			continue
+		}
+		// Later rows with the same id are in-file duplicates, reported when staged.
+		if _, seen := fileByID[tx.ID]; seen {
+			continue
+		}
+		fileByID[tx.ID] = tx
+		if sameContent(tx, st) {
+			c.identical[tx.ID] = true
+		} else {
+			c.conflicting[tx.ID] = true
+		}
+	}
+	for _, pr := range rows {
+		id, err := strconv.ParseInt(pr.IDStr, 10, 64)
+		if err != nil || !c.conflicting[id] {
+			continue
+		}
+		c.errs = append(c.errs, contentConflictErrors(pr, fileByID[id], stored[id])...)
+	}
+	return c, nil
+}
+
+// sameContent reports whether two transactions with the same id carry the same data.
+func sameContent(a, b domain.Transaction) bool {
+	return a.UserID == b.UserID && a.Amount.Equal(b.Amount) && a.DateTime.Equal(b.DateTime)
+}
+
+// contentConflictErrors describes how a file row differs from the stored transaction with its id.
+func contentConflictErrors(pr ParsedRow, file, stored domain.Transaction) []services.RowError {
+	prefix := "id already exists in DB with "
+	var errs []services.RowError
+	if file.UserID != stored.UserID {
+		errs = append(errs, services.RowError{Row: pr.RowNum, Field: "user_id", Value: pr.UserIDStr, Message: prefix + "user_id " + strconv.FormatInt(stored.UserID, 10)})
+	}
+	if !file.Amount.Equal(stored.Amount) {
+		errs = append(errs, services.RowError{Row: pr.RowNum, Field: "amount", Value: pr.AmountStr, Message: prefix + "amount " + stored.Amount.String()})
+	}
+	if !file.DateTime.Equal(stored.DateTime) {
+		errs = append(errs, services.RowError{Row: pr.RowNum, Field: "datetime", Value: pr.DatetimeStr, Message: prefix + "datetime " + stored.DateTime.UTC().Format(time.RFC3339)})
+	}
+	return errs
+}
+
+func existingIDs(ctx context.Context, checker idChecker, txs []domain.Transaction) (map[int64]bool, error) {
 	ids := make([]int64, 0, len(txs))
 	for _, tx := range txs {
 		ids = append(ids, tx.ID)
 	}
-	return s.Repo.ExistsByIDs(ctx, ids)
+	return checker.ExistsByIDs(ctx, ids)
 }
 
 // buildConflictErrors maps existing ids back to row numbers and values for error reporting.
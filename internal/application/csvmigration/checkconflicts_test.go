@@ -1,25 +1,6 @@
 package csvmigration
 
-import (
-	"context"
-	"testing"
-	"time"
-
-	"stori-challenge/internal/domain"
-)
-
-func Test_checkConflicts_ReturnsMap(t *testing.T) {
-	repo := &fakeRepo{exists: map[int64]bool{1: true, 3: true}}
-	s := &csvMigrationService{Repo: repo, NowFunc: func() time.Time { return time.Now().UTC() }}
-	txs := []domain.Transaction{{ID: 1}, {ID: 2}, {ID: 3}}
-	m, err := s.checkConflicts(context.Background(), txs)
-	if err != nil {
-		t.Fatalf("unexpected err: %v", err)
-	}
-	if !m[1] || !m[3] || m[2] {
-		t.Fatalf("unexpected map: %v", m)
-	}
-}
+import "testing"
 
 func Test_buildConflictErrors_MapsRows(t *testing.T) {
 	s := &csvMigrationService{}
@@ -0,0 +1,134 @@
+package csvmigration
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"stori-challenge/internal/domain"
+)
+
+// Naive layouts accepted by DatetimeFormatLocal; fractional seconds are accepted after the seconds.
+var localLayouts = []string{"2006-01-02 15:04:05", "2006-01-02T15:04:05"}
+
+const dateLayout = "2006-01-02"
+
+// epochMillisDigits is the length from which auto-detected epoch values are milliseconds:
+// 12 digits of seconds is past the year 5000, 12 digits of milliseconds is 1973.
+const epochMillisDigits = 12
+
+// datetimeParser parses datetime values in one format. The zero value parses RFC3339 in UTC.
+type datetimeParser struct {
+	format domain.DatetimeFormat
+	loc    *time.Location
+}
+
+func newDatetimeParser(format domain.DatetimeFormat, timezone string) (datetimeParser, error) {
+	loc, err := domain.LoadTimezone(timezone)
+	if err != nil {
+		return datetimeParser{}, err
+	}
+	return datetimeParser{format: format, loc: loc}, nil
+}
+
+// parse returns the instant s denotes, in UTC. Values with an explicit offset keep it;
+// naive ones are read in the parser's time zone.
+func (p datetimeParser) parse(s string) (time.Time, bool) {
+	var (
+		t  time.Time
+		ok bool
+	)
+	switch p.format {
+	case "", domain.DatetimeFormatRFC3339:
+		t, ok = parseLayouts(s, time.UTC, time.RFC3339)
+	case domain.DatetimeFormatLocal:
+		t, ok = parseLayouts(s, p.location(), localLayouts...)
+	case domain.DatetimeFormatDate:
+		t, ok = parseLayouts(s, p.location(), dateLayout)
+	case domain.DatetimeFormatUnix:
+		t, ok = parseEpoch(s, false)
+	case domain.DatetimeFormatUnixMillis:
+		t, ok = parseEpoch(s, true)
+	case domain.DatetimeFormatAuto:
+		if isEpoch(s) {
+			t, ok = parseEpoch(s, len(strings.TrimPrefix(s, "-")) >= epochMillisDigits)
+		} else if t, ok = parseLayouts(s, time.UTC, time.RFC3339); !ok {
+			t, ok = parseLayouts(s, p.location(), append(localLayouts, dateLayout)...)
+		}
+	}
+	return t.UTC(), ok
+}
+
+// render formats a wall-clock time so that parse reads it back, for sources such as spreadsheets
+// that store datetimes natively. Epoch formats render as RFC3339, which they do not accept.
+func (p datetimeParser) render(t time.Time) string {
+	switch p.format {
+	case domain.DatetimeFormatLocal:
+		return t.Format("2006-01-02 15:04:05.999999999")
+	case domain.DatetimeFormatDate:
+		return t.Format(dateLayout)
+	default:
+		return t.Format(time.RFC3339Nano)
+	}
+}
+
+// errorMessage is the row error for a value parse rejects.
+func (p datetimeParser) errorMessage() string {
+	switch p.format {
+	case domain.DatetimeFormatLocal:
+		return "not a valid 2006-01-02 15:04:05 datetime"
+	case domain.DatetimeFormatDate:
+		return "not a valid 2006-01-02 date"
+	case domain.DatetimeFormatUnix:
+		return "not a valid Unix timestamp in seconds"
+	case domain.DatetimeFormatUnixMillis:
+		return "not a valid Unix timestamp in milliseconds"
+	case domain.DatetimeFormatAuto:
+		return "not a recognized datetime format"
+	default:
+		return "not a valid RFC3339 datetime"
+	}
+}
+
+func (p datetimeParser) location() *time.Location {
+	if p.loc == nil {
+		return time.UTC
+	}
+	return p.loc
+}
+
+func parseLayouts(s string, loc *time.Location, layouts ...string) (time.Time, bool) {
+	for _, l := range layouts {
+		if t, err := time.ParseInLocation(l, s, loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
+
+func isEpoch(s string) bool {
+	s = strings.TrimPrefix(s, "-")
+	if s == "" {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+func parseEpoch(s string, millis bool) (time.Time, bool) {
+	if !isEpoch(s) {
+		return time.Time{}, false
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, false
+	}
+	if millis {
+		return time.UnixMilli(n), true
+	}
+	return time.Unix(n, 0), true
+}
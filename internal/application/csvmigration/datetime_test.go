@@ -0,0 +1,59 @@
+package csvmigration
+
+import (
+	"testing"
+	"time"
+
+	"stori-challenge/internal/domain"
+)
+
+func Test_datetimeParser_Formats(t *testing.T) {
+	cases := []struct {
+		format domain.DatetimeFormat
+		tz     string
+		in     string
+		want   string
+		ok     bool
+	}{
+		{"", "", "2024-06-01T10:00:00Z", "2024-06-01T10:00:00Z", true},
+		{"", "", "2024-06-01 10:00:00", "", false},
+		{domain.DatetimeFormatRFC3339, "America/Mexico_City", "2024-06-01T10:00:00+02:00", "2024-06-01T08:00:00Z", true},
+		{domain.DatetimeFormatLocal, "", "2024-06-01 10:00:00", "2024-06-01T10:00:00Z", true},
+		{domain.DatetimeFormatLocal, "America/Mexico_City", "2024-06-01 10:00:00", "2024-06-01T16:00:00Z", true},
+		{domain.DatetimeFormatLocal, "America/Mexico_City", "2024-06-01T10:00:00.5", "2024-06-01T16:00:00.5Z", true},
+		{domain.DatetimeFormatLocal, "", "2024-06-01T10:00:00Z", "", false},
+		{domain.DatetimeFormatDate, "America/Mexico_City", "2024-06-01", "2024-06-01T06:00:00Z", true},
+		{domain.DatetimeFormatDate, "", "01/06/2024", "", false},
+		{domain.DatetimeFormatUnix, "", "1717236000", "2024-06-01T10:00:00Z", true},
+		{domain.DatetimeFormatUnix, "", "1717236000.5", "", false},
+		{domain.DatetimeFormatUnixMillis, "", "1717236000123", "2024-06-01T10:00:00.123Z", true},
+		{domain.DatetimeFormatAuto, "", "1717236000", "2024-06-01T10:00:00Z", true},
+		{domain.DatetimeFormatAuto, "", "1717236000000", "2024-06-01T10:00:00Z", true},
+		{domain.DatetimeFormatAuto, "America/Mexico_City", "2024-06-01 10:00:00", "2024-06-01T16:00:00Z", true},
+		{domain.DatetimeFormatAuto, "America/Mexico_City", "2024-06-01T10:00:00Z", "2024-06-01T10:00:00Z", true},
+		{domain.DatetimeFormatAuto, "", "June 1st", "", false},
+	}
+	for _, tc := range cases {
+		p, err := newDatetimeParser(tc.format, tc.tz)
+		if err != nil {
+			t.Fatalf("newDatetimeParser(%q, %q): %v", tc.format, tc.tz, err)
+		}
+		got, ok := p.parse(tc.in)
+		if ok != tc.ok {
+			t.Fatalf("%s %q: ok want %v got %v", tc.format, tc.in, tc.ok, ok)
+		}
+		if !ok {
+			continue
+		}
+		want, _ := time.Parse(time.RFC3339Nano, tc.want)
+		if !got.Equal(want) || got.Location() != time.UTC {
+			t.Fatalf("%s %q: want %s got %s", tc.format, tc.in, want, got)
+		}
+	}
+}
+
+func Test_newDatetimeParser_UnknownTimezone(t *testing.T) {
+	if _, err := newDatetimeParser(domain.DatetimeFormatLocal, "Mars/Olympus"); err == nil {
+		t.Fatalf("expected error for unknown time zone")
+	}
+}
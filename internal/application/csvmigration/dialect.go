@@ -0,0 +1,120 @@
+package csvmigration
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"regexp"
+	"strings"
+
+	"stori-challenge/internal/domain"
+)
+
+const (
+	// dialectSample is how much of a CSV upload is inspected to detect its dialect.
+	dialectSample = 64 * 1024
+	// dialectRows is how many records after the header detection looks at.
+	dialectRows = 50
+)
+
+var (
+	// commaDecimal is an amount written with a decimal comma, optionally grouped: 1.234,56
+	commaDecimal = regexp.MustCompile(`^[-+]?([0-9]+|[0-9]{1,3}(\.[0-9]{3})+),[0-9]+$`)
+	// pointDecimal is an amount written with a decimal point: 1234.56
+	pointDecimal = regexp.MustCompile(`^[-+]?[0-9]*\.[0-9]+$`)
+)
+
+// sniffDelimiter picks the delimiter of a CSV sample. A delimiter that splits the header into
+// the required columns wins; then one that splits it at all, then the one whose records most
+// often have as many fields as the header, then the widest header. Ties keep the order of
+// domain.CSVDelimiters, so a sample with a single column is comma-separated.
+func sniffDelimiter(sample []byte, profile *domain.MappingProfile) rune {
+	best, bestScore := ',', []int{-1}
+	for _, d := range domain.CSVDelimiters {
+		comma := rune(d[0])
+		header, records := readSample(sample, comma)
+		if header == nil {
+			continue
+		}
+		score := []int{0, 0, 0, len(header)}
+		if _, err := resolveHeader(header, profile); err == nil {
+			score[0] = 1
+		}
+		if len(header) > 1 {
+			score[1] = 1
+		}
+		for _, rec := range records {
+			if len(rec) == len(header) {
+				score[2]++
+			}
+		}
+		if greater(score, bestScore) {
+			best, bestScore = comma, score
+		}
+	}
+	return best
+}
+
+// sniffDecimal returns "," when the amounts of a CSV sample are written with a decimal comma,
+// and "." otherwise. A single amount with a decimal point rules the comma out.
+func sniffDecimal(sample []byte, comma rune, layout columnLayout) string {
+	_, records := readSample(sample, comma)
+	commas := 0
+	for _, rec := range records {
+		if layout.amount >= len(rec) {
+			continue
+		}
+		v := strings.TrimSpace(rec[layout.amount])
+		switch {
+		case commaDecimal.MatchString(v):
+			commas++
+		case pointDecimal.MatchString(v):
+			return "."
+		}
+	}
+	if commas > 0 {
+		return ","
+	}
+	return "."
+}
+
+// peekSample returns the start of br for detection, cut after its last complete line when the
+// input is longer than the sample.
+func peekSample(br *bufio.Reader) []byte {
+	sample, _ := br.Peek(dialectSample)
+	if len(sample) == dialectSample {
+		sample = sample[:bytes.LastIndexByte(sample, '\n')+1]
+	}
+	return sample
+}
+
+// readSample reads the header and up to dialectRows records of sample.
+func readSample(sample []byte, comma rune) ([]string, [][]string) {
+	cr := csv.NewReader(bytes.NewReader(sample))
+	cr.Comma = comma
+	cr.LazyQuotes = true
+	cr.FieldsPerRecord = -1
+	header, err := cr.Read()
+	if err != nil {
+		return nil, nil
+	}
+	var records [][]string
+	for len(records) < dialectRows {
+		rec, err := cr.Read()
+		if err != nil {
+			break
+		}
+		records = append(records, rec)
+	}
+	return header, records
+}
+
+// greater compares scores lexicographically.
+func greater(a, b []int) bool {
+	for i := range a {
+		if i >= len(b) || a[i] != b[i] {
+			return i >= len(b) || a[i] > b[i]
+		}
+	}
+	return false
+}
@@ -0,0 +1,90 @@
+package csvmigration
+
+import (
+	"bufio"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"stori-challenge/internal/domain"
+)
+
+func TestProcess_SemicolonDecimalComma_ReportsDialect(t *testing.T) {
+	repo := &fakeRepo{}
+	svc := newSvcWithRepo(t, repo, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+
+	in := "id;user_id;monto;fecha\n1;10;1.234,50;2024-06-01T00:00:00Z\n2;10;-12,5;2024-06-01T00:00:00Z\n"
+	res, err := svc.Process(context.Background(), r(in), domain.MigrationOptions{})
+	if err != nil || res.Inserted != 2 {
+		t.Fatalf("unexpected result: %v %+v", err, res)
+	}
+	if d := res.Dialect; d == nil || d.Delimiter != ";" || d.DecimalSeparator != "," {
+		t.Fatalf("unexpected dialect: %+v", res.Dialect)
+	}
+	if repo.captured[0].Amount.String() != "1234.5" || repo.captured[1].Amount.String() != "-12.5" {
+		t.Fatalf("unexpected amounts: %+v", repo.captured)
+	}
+}
+
+func TestValidate_DelimiterOverrideAndLazyQuotes(t *testing.T) {
+	svc := newSvcWithRepo(t, &fakeRepo{}, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+
+	// The stray quote in the note is kept as text.
+	in := "id|user_id|amount|datetime|nota\n1|10|1.50|2024-06-01T00:00:00Z|TV 55\" a,b\n"
+	report, err := svc.Validate(context.Background(), r(in), domain.MigrationOptions{Delimiter: "|"})
+	if err != nil || report.WouldInsert != 1 || len(report.Errors) != 0 {
+		t.Fatalf("unexpected report: %v %+v", err, report)
+	}
+	if d := report.Dialect; d == nil || d.Delimiter != "|" || d.DecimalSeparator != "." {
+		t.Fatalf("unexpected dialect: %+v", report.Dialect)
+	}
+
+	_, err = svc.Validate(context.Background(), r(in), domain.MigrationOptions{Delimiter: ":"})
+	if err == nil {
+		t.Fatalf("expected an unsupported delimiter to be rejected")
+	}
+}
+
+func TestProcess_NonCSVHasNoDialect(t *testing.T) {
+	svc := newSvcWithRepo(t, &fakeRepo{}, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+
+	in := `{"id":1,"user_id":10,"amount":"1.00","datetime":"2024-06-01T00:00:00Z"}` + "\n"
+	res, err := svc.Process(context.Background(), r(in), domain.MigrationOptions{Format: domain.InputFormatJSONL})
+	if err != nil || res.Dialect != nil {
+		t.Fatalf("unexpected result: %v %+v", err, res)
+	}
+}
+
+func Test_sniffDelimiter(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want rune
+	}{
+		{"comma", "id,user_id,amount,datetime\n1,10,1.00,x\n", ','},
+		{"semicolon with decimal commas", "id;user_id;amount;datetime\n1;10;1,50;x\n2;10;2,50;x\n", ';'},
+		{"tab", "id\tuser_id\tamount\tdatetime\n1\t10\t1.00\tx\n", '\t'},
+		{"pipe with commas in a field", "id|user_id|amount|datetime|note\n1|10|1.00|x|a, b, c\n", '|'},
+		{"unknown header, consistent rows", "a;b;c\n1;2,5;3\n4;5;6\n", ';'},
+		{"single column", "id\n1\n", ','},
+		{"empty", "", ','},
+	}
+	for _, c := range cases {
+		if got := sniffDelimiter([]byte(c.in), nil); got != c.want {
+			t.Fatalf("%s: want %q got %q", c.name, c.want, got)
+		}
+	}
+}
+
+func Test_peekSample_CutsAtLastLine(t *testing.T) {
+	in := strings.Repeat("1;10;1,50;x\n", dialectSample/12+1)
+	sample := peekSample(bufio.NewReaderSize(strings.NewReader(in), dialectSample))
+	if len(sample) == 0 || len(sample) > dialectSample || sample[len(sample)-1] != '\n' {
+		t.Fatalf("unexpected sample of %d bytes", len(sample))
+	}
+	short := peekSample(bufio.NewReaderSize(strings.NewReader("id;amount"), dialectSample))
+	if string(short) != "id;amount" {
+		t.Fatalf("unexpected sample %q", short)
+	}
+}
@@ -0,0 +1,186 @@
+package csvmigration
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"unicode/utf16"
+	"unicode/utf8"
+
+	"stori-challenge/internal/domain"
+)
+
+// encodingSample is how much of an upload is inspected to detect its encoding.
+const encodingSample = 4096
+
+// windows1252 maps bytes 0x80-0x9F to runes; the other bytes are the Latin-1 code points.
+// Bytes with no character in the code page are utf8.RuneError.
+var windows1252 = [32]rune{
+	'€', utf8.RuneError, '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', utf8.RuneError, 'Ž', utf8.RuneError,
+	utf8.RuneError, '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', utf8.RuneError, 'ž', 'Ÿ',
+}
+
+// newTextDecoder returns r transcoded to UTF-8 without its byte order mark, and the encoding
+// it was read as. Bytes that cannot be decoded become utf8.RuneError, which record readers
+// report for the row and column they land in.
+func newTextDecoder(r io.Reader, enc domain.Encoding) (io.Reader, domain.Encoding) {
+	br := bufio.NewReaderSize(r, encodingSample)
+	sample, _ := br.Peek(encodingSample)
+	switch {
+	case bytes.HasPrefix(sample, []byte{0xEF, 0xBB, 0xBF}):
+		br.Discard(3)
+		enc = domain.EncodingUTF8
+	case bytes.HasPrefix(sample, []byte{0xFF, 0xFE}):
+		br.Discard(2)
+		enc = domain.EncodingUTF16LE
+	case bytes.HasPrefix(sample, []byte{0xFE, 0xFF}):
+		br.Discard(2)
+		enc = domain.EncodingUTF16BE
+	case enc == domain.EncodingAuto:
+		enc = detectEncoding(sample)
+	}
+	d := &textDecoder{src: br}
+	switch enc {
+	case domain.EncodingUTF16LE, domain.EncodingUTF16BE:
+		d.next = utf16Rune(enc == domain.EncodingUTF16BE)
+	case domain.EncodingWindows1252, domain.EncodingLatin1:
+		d.next = singleByteRune(enc == domain.EncodingWindows1252)
+	default:
+		d.next = utf8Rune
+	}
+	return d, enc
+}
+
+// detectEncoding guesses the encoding of a sample without a byte order mark. Text that is
+// mostly ASCII has a NUL in every other byte when it is UTF-16.
+func detectEncoding(sample []byte) domain.Encoding {
+	var even, odd int
+	for i, b := range sample {
+		if b == 0 {
+			if i%2 == 0 {
+				even++
+			} else {
+				odd++
+			}
+		}
+	}
+	if pairs := len(sample) / 2; pairs > 0 {
+		switch {
+		case odd*4 > pairs*3:
+			return domain.EncodingUTF16LE
+		case even*4 > pairs*3:
+			return domain.EncodingUTF16BE
+		}
+	}
+	// The sample may end inside a multi-byte character.
+	for i := 1; i < utf8.UTFMax && i <= len(sample); i++ {
+		if tail := sample[len(sample)-i:]; utf8.RuneStart(tail[0]) {
+			if !utf8.FullRune(tail) {
+				sample = sample[:len(sample)-i]
+			}
+			break
+		}
+	}
+	if utf8.Valid(sample) {
+		return domain.EncodingUTF8
+	}
+	return domain.EncodingWindows1252
+}
+
+// textDecoder writes the runes of src, decoded by next, as UTF-8.
+type textDecoder struct {
+	src  *bufio.Reader
+	next func(*bufio.Reader) (rune, error)
+	// pending holds the bytes of a rune that did not fit in the last Read.
+	pending []byte
+}
+
+func (d *textDecoder) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(d.pending) > 0 {
+			c := copy(p[n:], d.pending)
+			d.pending = d.pending[c:]
+			n += c
+			continue
+		}
+		r, err := d.next(d.src)
+		if err != nil {
+			if err == io.EOF && n > 0 {
+				return n, nil
+			}
+			return n, err
+		}
+		if r < utf8.RuneSelf {
+			p[n] = byte(r)
+			n++
+			continue
+		}
+		var buf [utf8.UTFMax]byte
+		k := utf8.EncodeRune(buf[:], r)
+		c := copy(p[n:], buf[:k])
+		d.pending = append(d.pending[:0], buf[c:k]...)
+		n += c
+	}
+	return n, nil
+}
+
+// utf8Rune reads one rune; an invalid byte is utf8.RuneError.
+func utf8Rune(br *bufio.Reader) (rune, error) {
+	r, _, err := br.ReadRune()
+	return r, err
+}
+
+func singleByteRune(cp1252 bool) func(*bufio.Reader) (rune, error) {
+	return func(br *bufio.Reader) (rune, error) {
+		b, err := br.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		if cp1252 && b >= 0x80 && b < 0xA0 {
+			return windows1252[b-0x80], nil
+		}
+		return rune(b), nil
+	}
+}
+
+func utf16Rune(bigEndian bool) func(*bufio.Reader) (rune, error) {
+	unit := func(br *bufio.Reader) (rune, error) {
+		var b [2]byte
+		if _, err := io.ReadFull(br, b[:]); err != nil {
+			if err == io.ErrUnexpectedEOF {
+				// A trailing odd byte.
+				return utf8.RuneError, nil
+			}
+			return 0, err
+		}
+		if bigEndian {
+			return rune(b[0])<<8 | rune(b[1]), nil
+		}
+		return rune(b[1])<<8 | rune(b[0]), nil
+	}
+	return func(br *bufio.Reader) (rune, error) {
+		u, err := unit(br)
+		if err != nil || !utf16.IsSurrogate(u) {
+			return u, err
+		}
+		if u >= 0xDC00 {
+			// A low surrogate without its high half.
+			return utf8.RuneError, nil
+		}
+		// Peek at the next unit so that an unpaired high surrogate does not swallow it.
+		next, err := br.Peek(2)
+		if err != nil {
+			return utf8.RuneError, nil
+		}
+		u2 := rune(next[1])<<8 | rune(next[0])
+		if bigEndian {
+			u2 = rune(next[0])<<8 | rune(next[1])
+		}
+		if u2 < 0xDC00 || u2 > 0xDFFF {
+			return utf8.RuneError, nil
+		}
+		br.Discard(2)
+		return utf16.DecodeRune(u, u2), nil
+	}
+}
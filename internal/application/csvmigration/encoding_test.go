@@ -0,0 +1,149 @@
+package csvmigration
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+	"unicode/utf16"
+
+	"stori-challenge/internal/domain"
+)
+
+func utf16LE(s string, bom bool) []byte {
+	var out []byte
+	if bom {
+		out = append(out, 0xFF, 0xFE)
+	}
+	for _, u := range utf16.Encode([]rune(s)) {
+		out = append(out, byte(u), byte(u>>8))
+	}
+	return out
+}
+
+func TestProcess_Encodings_DetectedOrGiven(t *testing.T) {
+	const text = "id,user_id,amount,datetime,descripción\n1,10,1.00,2024-06-01T00:00:00Z,Depósito €\n"
+	cp1252 := bytes.NewBufferString("id,user_id,amount,datetime,descripci\xf3n\n1,10,1.00,2024-06-01T00:00:00Z,Dep\xf3sito \x80\n").Bytes()
+	cases := []struct {
+		name string
+		in   []byte
+		enc  domain.Encoding
+	}{
+		{"utf-8 with BOM", append([]byte("\xef\xbb\xbf"), text...), ""},
+		{"windows-1252 detected", cp1252, ""},
+		{"windows-1252 given", cp1252, domain.EncodingWindows1252},
+		{"utf-16le with BOM", utf16LE(text, true), ""},
+		{"utf-16le without BOM", utf16LE(text, false), ""},
+		{"utf-16le BOM over parameter", utf16LE(text, true), domain.EncodingLatin1},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			repo := &fakeRepo{}
+			svc := newSvcWithRepo(t, repo, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+			res, err := svc.Process(context.Background(), bytes.NewReader(c.in), domain.MigrationOptions{Encoding: c.enc})
+			if err != nil || res.Inserted != 1 {
+				t.Fatalf("unexpected result: %v %+v", err, res)
+			}
+		})
+	}
+}
+
+func TestValidate_UndecodableBytes_ReportRowAndColumn(t *testing.T) {
+	svc := newSvcWithRepo(t, &fakeRepo{}, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+
+	// 0x81 has no character in Windows-1252.
+	in := "id,user_id,amount,datetime,nota\n1,10,1.00,2024-06-01T00:00:00Z,ok\n2,10,1.00,2024-06-01T00:00:00Z,a\x81b\n"
+	report, err := svc.Validate(context.Background(), r(in), domain.MigrationOptions{Encoding: domain.EncodingWindows1252})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(report.Errors) != 1 {
+		t.Fatalf("expected one error, got %+v", report.Errors)
+	}
+	if e := report.Errors[0]; e.Row != 2 || e.Field != "nota" || e.Message != "column 5 has bytes that cannot be decoded as windows-1252" {
+		t.Fatalf("unexpected error: %+v", e)
+	}
+
+	// Invalid UTF-8 past the detection sample.
+	var b strings.Builder
+	b.WriteString("id,user_id,amount,datetime\n")
+	for i := 1; i <= 200; i++ {
+		b.WriteString("1,10,1.00,2024-06-01T00:00:00Z\n")
+	}
+	b.WriteString("2,10,1.00\xff,2024-06-01T00:00:00Z\n")
+	report, err = svc.Validate(context.Background(), r(b.String()), domain.MigrationOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	found := false
+	for _, e := range report.Errors {
+		if e.Row == 201 && e.Field == "amount" && strings.Contains(e.Message, "column 3 has bytes that cannot be decoded as utf-8") {
+			found = true
+		}
+	}
+	if !found {
+		t.Fatalf("expected an undecodable error at row 201, got %+v", report.Errors)
+	}
+}
+
+func TestProcess_Zip_UndecodableMemberRow(t *testing.T) {
+	svc := newSvcWithRepo(t, &fakeRepo{}, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+
+	in := zipOf(t,
+		[2]string{"a.csv", "id,user_id,amount,datetime\n1,10,1.00,2024-01-01T00:00:00Z\n"},
+		[2]string{"b.csv", "id,user_id,amount,datetime\n2,10,1.00,2024-01-01T00:00:00Z\n3,1\xff,1.00,2024-01-01T00:00:00Z\n"},
+	)
+	res, err := svc.Process(context.Background(), in, domain.MigrationOptions{Compression: domain.CompressionZip, Encoding: domain.EncodingUTF8})
+	if err == nil || len(res.Errors) != 1 {
+		t.Fatalf("expected one row error, got %v %+v", err, res.Errors)
+	}
+	if e := res.Errors[0]; e.File != "b.csv" || e.Row != 2 || e.Field != "user_id" {
+		t.Fatalf("unexpected error: %+v", e)
+	}
+}
+
+func Test_newTextDecoder(t *testing.T) {
+	cases := []struct {
+		name string
+		in   []byte
+		enc  domain.Encoding
+		want string
+		as   domain.Encoding
+	}{
+		{"utf-8 BOM stripped", []byte("\xef\xbb\xbfid"), "", "id", domain.EncodingUTF8},
+		{"latin-1", []byte("a\xe9\x80"), domain.EncodingLatin1, "aé\u0080", domain.EncodingLatin1},
+		{"utf-16be BOM", []byte{0xFE, 0xFF, 0x00, 'h', 0x00, 0xE9}, "", "hé", domain.EncodingUTF16BE},
+		{"utf-16le surrogate pair", utf16LE("a😀", false), domain.EncodingUTF16LE, "a😀", domain.EncodingUTF16LE},
+		{"utf-16le unpaired surrogate", []byte{0x3D, 0xD8, 'x', 0x00}, domain.EncodingUTF16LE, "�x", domain.EncodingUTF16LE},
+		{"utf-16le odd trailing byte", []byte{'x', 0x00, 'y'}, domain.EncodingUTF16LE, "x�", domain.EncodingUTF16LE},
+	}
+	for _, c := range cases {
+		rd, as := newTextDecoder(bytes.NewReader(c.in), c.enc)
+		// One byte at a time, so multi-byte runes span reads.
+		got, err := io.ReadAll(iotest.OneByteReader(rd))
+		if err != nil || string(got) != c.want || as != c.as {
+			t.Fatalf("%s: want %q as %s, got %q as %s (%v)", c.name, c.want, c.as, got, as, err)
+		}
+	}
+}
+
+func Test_detectEncoding(t *testing.T) {
+	cases := map[string]domain.Encoding{
+		"id,amount\n":                         domain.EncodingUTF8,
+		"descripción":                         domain.EncodingUTF8,
+		"descripci\xf3n":                      domain.EncodingWindows1252,
+		string(utf16LE("id,amount\n", false)): domain.EncodingUTF16LE,
+		"\x00i\x00d\x00,":                     domain.EncodingUTF16BE,
+		"":                                    domain.EncodingUTF8,
+		// A sample cut inside "ó" is still UTF-8.
+		"descripci\xc3": domain.EncodingUTF8,
+	}
+	for in, want := range cases {
+		if got := detectEncoding([]byte(in)); got != want {
+			t.Fatalf("%q: want %s got %s", in, want, got)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package csvmigration
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/ports/services"
+)
+
+// errorSpill writes the row errors an errorCollector drops to a temporary file, created on the
+// first one, so the run can still hand all of them to the caller without holding them in memory.
+type errorSpill struct {
+	f   *os.File
+	w   *bufio.Writer
+	err error
+}
+
+func (s *errorSpill) add(e services.RowError) {
+	if s.err != nil {
+		return
+	}
+	if s.f == nil {
+		f, err := os.CreateTemp("", "row-errors-*.jsonl")
+		if err != nil {
+			s.err = err
+			return
+		}
+		s.f, s.w = f, bufio.NewWriter(f)
+	}
+	s.err = json.NewEncoder(s.w).Encode(e)
+}
+
+// each calls fn with the spilled errors in the order they were added.
+func (s *errorSpill) each(fn func(services.RowError) error) error {
+	if s.err != nil || s.f == nil {
+		return s.err
+	}
+	if err := s.w.Flush(); err != nil {
+		return err
+	}
+	if _, err := s.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	dec := json.NewDecoder(bufio.NewReader(s.f))
+	for {
+		var e services.RowError
+		err := dec.Decode(&e)
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+}
+
+func (s *errorSpill) close() error {
+	if s.f == nil {
+		return nil
+	}
+	name := s.f.Name()
+	err := s.f.Close()
+	s.f = nil
+	return errors.Join(err, os.Remove(name))
+}
+
+// collectedLog is the services.RowErrorLog of an errorCollector: the errors it kept followed
+// by the ones it spilled. Rows are located when read, once the run has seen every member.
+type collectedLog struct {
+	kept    []services.RowError
+	spill   *errorSpill
+	locator *rowLocator
+}
+
+var _ services.RowErrorLog = (*collectedLog)(nil)
+
+func (l *collectedLog) Each(fn func(domain.LoggedRowError) error) error {
+	emit := func(e services.RowError) error {
+		return fn(domain.LoggedRowError{RunRow: e.Row, RowError: l.locator.locate([]services.RowError{e})[0]})
+	}
+	for _, e := range l.kept {
+		if err := emit(e); err != nil {
+			return err
+		}
+	}
+	return l.spill.each(emit)
+}
+
+func (l *collectedLog) Close() error {
+	return l.spill.close()
+}
@@ -0,0 +1,257 @@
+package csvmigration
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"io"
+	"slices"
+	"strings"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/ports/services"
+	"stori-challenge/internal/shared"
+)
+
+// errorReportColumns are appended to the original columns of every reported row.
+var errorReportColumns = []string{"error_field", "error_message"}
+
+// WriteErrorReport reads r again with opts and writes the rows errs point at as CSV, in file
+// order, each followed by the fields and messages of its errors. CSV and xlsx rows keep their
+// original header and values; the other formats are written with the canonical columns they
+// are read as. Archive rows start with the member they come from, and a member whose columns
+// differ from the previous one's starts a new header section. The report is written as the
+// file is read, so neither the rows nor a logged error list are held in memory. The rows are
+// located by the stored errors alone, so the validation rules are not loaded.
+func (s *csvMigrationService) WriteErrorReport(ctx context.Context, r io.Reader, opts domain.MigrationOptions, errs services.ReportErrors, w io.Writer) error {
+	cfg, appErr := s.loadFileConfig(ctx, opts)
+	if appErr != nil {
+		return appErr
+	}
+	cfg.limit = newInflateLimit(MaxInflatedSize)
+
+	rd, _, err := s.newRecordReader(r, cfg)
+	if err != nil {
+		_, appErr := readFailure(err)
+		return appErr
+	}
+	src := &reportSource{rd: rd, locator: cfg.locator}
+	rep := &errorReport{w: w, archive: cfg.compression == domain.CompressionZip, dialect: cfg.dialect}
+	if errs.Each != nil {
+		err = writeLoggedErrors(src, rep, errs.Each)
+	} else {
+		err = writeListedErrors(src, rep, errs.List)
+	}
+	if err != nil {
+		return err
+	}
+	if err := rep.flush(reportColumns(rd)); err != nil {
+		return reportWriteFailure(err)
+	}
+	return nil
+}
+
+// writeListedErrors reports the rows of a stored error list, which locates them by member and
+// local row.
+func writeListedErrors(src *reportSource, rep *errorReport, errs []services.RowError) error {
+	failed := make(map[reportRow][]services.RowError)
+	for _, e := range errs {
+		if e.Row > 0 {
+			k := reportRow{file: e.File, row: e.Row}
+			failed[k] = append(failed[k], e)
+		}
+	}
+	for {
+		ok, err := src.next()
+		if err != nil || !ok {
+			return err
+		}
+		file, local := src.locator.find(src.row)
+		if rowErrs, ok := failed[reportRow{file: file, row: local}]; ok {
+			if err := rep.write(file, reportColumns(src.rd), src.rec, rowErrs); err != nil {
+				return reportWriteFailure(err)
+			}
+		}
+	}
+}
+
+// writeLoggedErrors reports the rows of a full error log, which each calls in run-row order,
+// reading the file up to each row it reaches.
+func writeLoggedErrors(src *reportSource, rep *errorReport, each func(fn func(domain.LoggedRowError) error) error) error {
+	var (
+		row     int
+		rowErrs []services.RowError
+	)
+	emit := func() error {
+		if len(rowErrs) == 0 {
+			return nil
+		}
+		ok, err := src.seek(row)
+		if err != nil {
+			return err
+		}
+		if ok {
+			file, _ := src.locator.find(row)
+			if err := rep.write(file, reportColumns(src.rd), src.rec, rowErrs); err != nil {
+				return reportWriteFailure(err)
+			}
+		}
+		rowErrs = rowErrs[:0]
+		return nil
+	}
+	err := each(func(e domain.LoggedRowError) error {
+		if e.RunRow <= 0 {
+			return nil
+		}
+		if e.RunRow != row {
+			if err := emit(); err != nil {
+				return err
+			}
+			row = e.RunRow
+		}
+		rowErrs = append(rowErrs, e.RowError)
+		return nil
+	})
+	if err != nil {
+		var ae *shared.AppError
+		if errors.As(err, &ae) {
+			return err
+		}
+		return shared.NewInternal("db_failure", "database error", err)
+	}
+	return emit()
+}
+
+func reportWriteFailure(err error) error {
+	return shared.NewInternal("write_failure", "unable to write error report", err)
+}
+
+// reportSource reads the upload again for a report, one row at a time.
+type reportSource struct {
+	rd      recordReader
+	locator *rowLocator
+	rec     []string
+	row     int
+	done    bool
+}
+
+// next reads the following row and returns false at the end of the file. Rows that could not
+// be read come back without a record.
+func (s *reportSource) next() (bool, error) {
+	if s.done {
+		return false, nil
+	}
+	rec, row, err := s.rd.Read()
+	if err == io.EOF {
+		s.done = true
+		return false, nil
+	}
+	var rre *rowReadError
+	if err != nil && !errors.As(err, &rre) {
+		var le *lookupError
+		if errors.As(err, &le) {
+			return false, shared.NewInternal("db_failure", "database error", le.err)
+		}
+		// The rest of the file could not be migrated either; that error is reported with the
+		// migration, not as a row.
+		s.done = true
+		return false, nil
+	}
+	s.rec, s.row = rec, row
+	return true, nil
+}
+
+// seek reads up to run row n and reports whether the file has it.
+func (s *reportSource) seek(n int) (bool, error) {
+	for s.row < n {
+		ok, err := s.next()
+		if err != nil || !ok {
+			return false, err
+		}
+	}
+	return s.row == n, nil
+}
+
+// reportRow is a row as row errors locate it: the archive member, if any, and its local row.
+type reportRow struct {
+	file string
+	row  int
+}
+
+// columnNamer is a recordReader whose records keep the columns of the file's header.
+type columnNamer interface {
+	columns() []string
+}
+
+// reportColumns returns the column names of the records of rd.
+func reportColumns(rd recordReader) []string {
+	if c, ok := rd.(columnNamer); ok {
+		if cols := c.columns(); cols != nil {
+			return cols
+		}
+	}
+	return jsonColumns
+}
+
+// errorReport writes a header before the first row, and again when an archive member has
+// other columns than the rows before it, with the delimiter of the upload when it was read as
+// CSV.
+type errorReport struct {
+	w       io.Writer
+	cw      *csv.Writer
+	header  []string
+	archive bool
+	dialect *domain.CSVDialect
+}
+
+// start writes the header for rows with cols.
+func (r *errorReport) start(cols []string) error {
+	if r.cw == nil {
+		r.cw = csv.NewWriter(r.w)
+		if r.dialect != nil && len(r.dialect.Delimiter) == 1 {
+			r.cw.Comma = rune(r.dialect.Delimiter[0])
+		}
+	}
+	r.header = cols
+	var head []string
+	if r.archive {
+		head = append(head, "file")
+	}
+	head = append(append(head, cols...), errorReportColumns...)
+	return r.cw.Write(head)
+}
+
+// write emits one failed row, whose columns are cols. Records shorter than the header, or
+// missing because the row could not be read, are padded so the error columns line up.
+func (r *errorReport) write(file string, cols, rec []string, errs []services.RowError) error {
+	if r.cw == nil || !slices.Equal(cols, r.header) {
+		if err := r.start(cols); err != nil {
+			return err
+		}
+	}
+	var out []string
+	if r.archive {
+		out = append(out, file)
+	}
+	out = append(out, rec...)
+	for i := len(rec); i < len(r.header); i++ {
+		out = append(out, "")
+	}
+	fields := make([]string, len(errs))
+	msgs := make([]string, len(errs))
+	for i, e := range errs {
+		fields[i], msgs[i] = e.Field, e.Message
+	}
+	return r.cw.Write(append(out, strings.Join(fields, "; "), strings.Join(msgs, "; ")))
+}
+
+// flush ends the report; one without rows still gets the header for cols.
+func (r *errorReport) flush(cols []string) error {
+	if r.cw == nil {
+		if err := r.start(cols); err != nil {
+			return err
+		}
+	}
+	r.cw.Flush()
+	return r.cw.Error()
+}
@@ -0,0 +1,183 @@
+package csvmigration
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/ports/services"
+)
+
+func TestWriteErrorReport_OriginalRowsWithErrorColumns(t *testing.T) {
+	svc := newSvcWithRepo(t, &fakeRepo{}, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+	in := "ID;User;amount;datetime;note\n" +
+		"1;10;1,50;2024-01-01T00:00:00Z;ok\n" +
+		"2;x;2,00;2024-01-02T00:00:00Z;bad user\n" +
+		"3;10;abc;2024-01-03T00:00:00Z;\"bad; amount\"\n" +
+		"1;10;4,00;2024-01-04T00:00:00Z\n"
+	opts := domain.MigrationOptions{Mode: domain.MigrationModePartial}
+	res, err := svc.Process(context.Background(), strings.NewReader(in), opts)
+	if err != nil || len(res.Errors) != 3 {
+		t.Fatalf("unexpected result: %+v, %v", res, err)
+	}
+
+	var out bytes.Buffer
+	if err := svc.WriteErrorReport(context.Background(), strings.NewReader(in), opts, services.ReportErrors{List: res.Errors}, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "ID;User;amount;datetime;note;error_field;error_message\n" +
+		"2;x;2,00;2024-01-02T00:00:00Z;bad user;user_id;not a valid integer\n" +
+		"3;10;abc;2024-01-03T00:00:00Z;\"bad; amount\";amount;" + res.Errors[1].Message + "\n" +
+		"1;10;4,00;2024-01-04T00:00:00Z;;id;" + res.Errors[2].Message + "\n"
+	if out.String() != want {
+		t.Fatalf("unexpected report:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestWriteErrorReport_ZipRowsNameTheirMember(t *testing.T) {
+	svc := newSvcWithRepo(t, &fakeRepo{}, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+	members := [][2]string{
+		{"jan.csv", "id,user_id,amount,datetime\n1,10,1.00,2024-01-01T00:00:00Z\n"},
+		{"feb.csv", "id,user_id,amount,datetime\n2,10,2.00,2024-02-01T00:00:00Z\n3,10,x,2024-02-02T00:00:00Z\n"},
+	}
+	opts := domain.MigrationOptions{Compression: domain.CompressionZip}
+	res, _ := svc.Process(context.Background(), zipOf(t, members...), opts)
+	if len(res.Errors) != 1 || res.Errors[0].File != "feb.csv" || res.Errors[0].Row != 2 {
+		t.Fatalf("unexpected errors: %+v", res.Errors)
+	}
+
+	var out bytes.Buffer
+	if err := svc.WriteErrorReport(context.Background(), zipOf(t, members...), opts, services.ReportErrors{List: res.Errors}, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "file,id,user_id,amount,datetime,error_field,error_message\n" +
+		"feb.csv,3,10,x,2024-02-02T00:00:00Z,amount," + res.Errors[0].Message + "\n"
+	if out.String() != want {
+		t.Fatalf("unexpected report:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestWriteErrorReport_JSONLinesUseCanonicalColumns(t *testing.T) {
+	svc := newSvcWithRepo(t, &fakeRepo{}, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+	in := `{"id":1,"user_id":10,"amount":"1.00","datetime":"2024-01-01T00:00:00Z"}` + "\n" +
+		`{"id":2,"user_id":10,"amount":"2.00","datetime":"yesterday"}` + "\n"
+	opts := domain.MigrationOptions{Format: domain.InputFormatJSONL}
+	res, _ := svc.Process(context.Background(), strings.NewReader(in), opts)
+	if len(res.Errors) != 1 {
+		t.Fatalf("unexpected errors: %+v", res.Errors)
+	}
+
+	var out bytes.Buffer
+	if err := svc.WriteErrorReport(context.Background(), strings.NewReader(in), opts, services.ReportErrors{List: res.Errors}, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "id,user_id,amount,datetime,type,error_field,error_message\n" +
+		"2,10,2.00,yesterday,,datetime," + res.Errors[0].Message + "\n"
+	if out.String() != want {
+		t.Fatalf("unexpected report:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestWriteErrorReport_ZipMembersWithOtherColumnsStartAHeaderSection(t *testing.T) {
+	svc := newSvcWithRepo(t, &fakeRepo{}, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+	members := [][2]string{
+		{"jan.csv", "id,user_id,amount,datetime\n1,10,x,2024-01-01T00:00:00Z\n"},
+		{"feb.csv", "datetime,amount,user_id,id\n2024-02-01T00:00:00Z,y,10,2\n"},
+		{"mar.csv", "datetime,amount,user_id,id\n2024-03-01T00:00:00Z,z,10,3\n"},
+	}
+	opts := domain.MigrationOptions{Compression: domain.CompressionZip, Mode: domain.MigrationModePartial}
+	res, _ := svc.Process(context.Background(), zipOf(t, members...), opts)
+	if len(res.Errors) != 3 {
+		t.Fatalf("unexpected errors: %+v", res.Errors)
+	}
+
+	var out bytes.Buffer
+	if err := svc.WriteErrorReport(context.Background(), zipOf(t, members...), opts, services.ReportErrors{List: res.Errors}, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	msg := res.Errors[0].Message
+	want := "file,id,user_id,amount,datetime,error_field,error_message\n" +
+		"jan.csv,1,10,x,2024-01-01T00:00:00Z,amount," + msg + "\n" +
+		"file,datetime,amount,user_id,id,error_field,error_message\n" +
+		"feb.csv,2024-02-01T00:00:00Z,y,10,2,amount," + msg + "\n" +
+		"mar.csv,2024-03-01T00:00:00Z,z,10,3,amount," + msg + "\n"
+	if out.String() != want {
+		t.Fatalf("unexpected report:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestWriteErrorReport_LogReportsRowsBeyondTheStoredList(t *testing.T) {
+	svc := newSvcWithRepo(t, &fakeRepo{}, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+	in := "id,user_id,amount,datetime\n" +
+		"1,10,x,2024-01-01T00:00:00Z\n" +
+		"2,10,2.00,2024-01-02T00:00:00Z\n" +
+		"3,y,3.00,2024-01-03T00:00:00Z\n" +
+		"4,10,z,2024-01-04T00:00:00Z\n"
+	opts := domain.MigrationOptions{Mode: domain.MigrationModePartial, MaxErrors: 1}
+	res, err := svc.ProcessStream(context.Background(), strings.NewReader(in), opts)
+	if err != nil || res.ErrorLog == nil {
+		t.Fatalf("expected a partial run with an error log, got %+v, %v", res, err)
+	}
+	defer res.ErrorLog.Close()
+	var logged []domain.LoggedRowError
+	if err := res.ErrorLog.Each(func(e domain.LoggedRowError) error {
+		logged = append(logged, e)
+		return nil
+	}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	// The repository returns the log ordered by run row.
+	sort.Slice(logged, func(i, j int) bool { return logged[i].RunRow < logged[j].RunRow })
+	each := func(fn func(domain.LoggedRowError) error) error {
+		for _, e := range logged {
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	var out bytes.Buffer
+	if err := svc.WriteErrorReport(context.Background(), strings.NewReader(in), opts, services.ReportErrors{List: res.Errors, Each: each}, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "id,user_id,amount,datetime,error_field,error_message\n" +
+		"1,10,x,2024-01-01T00:00:00Z,amount," + logged[0].Message + "\n" +
+		"3,y,3.00,2024-01-03T00:00:00Z,user_id," + logged[1].Message + "\n" +
+		"4,10,z,2024-01-04T00:00:00Z,amount," + logged[2].Message + "\n"
+	if out.String() != want {
+		t.Fatalf("unexpected report:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
+
+func TestWriteErrorReport_UsesTheProfileColumnsItRanWithAndNoRules(t *testing.T) {
+	svc := newSvcWithRepo(t, &fakeRepo{}, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+	columns := map[string][]string{domain.ColumnID: {"Reference"}, domain.ColumnUserID: {"Account Holder"}}
+	svc.Profiles = &fakeProfileRepo{profiles: map[string]domain.MappingProfile{"bank": {Name: "bank", Columns: columns}}}
+	in := "Reference,Account Holder,amount,datetime\n" +
+		"1,10,1.50,2024-01-01T00:00:00Z\n" +
+		"2,x,2.00,2024-01-02T00:00:00Z\n"
+	opts := domain.MigrationOptions{Mode: domain.MigrationModePartial, Profile: "bank", ProfileColumns: columns}
+	res, err := svc.Process(context.Background(), strings.NewReader(in), opts)
+	if err != nil || len(res.Errors) != 1 {
+		t.Fatalf("unexpected result: %+v, %v", res, err)
+	}
+
+	// The profile is deleted and the rules source fails after the run; neither is read again.
+	svc.Profiles = &fakeProfileRepo{profiles: map[string]domain.MappingProfile{}}
+	svc.Rules = staticRules{err: errors.New("rules down")}
+	var out bytes.Buffer
+	if err := svc.WriteErrorReport(context.Background(), strings.NewReader(in), opts, services.ReportErrors{List: res.Errors}, &out); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := "Reference,Account Holder,amount,datetime,error_field,error_message\n" +
+		"2,x,2.00,2024-01-02T00:00:00Z,user_id,not a valid integer\n"
+	if out.String() != want {
+		t.Fatalf("unexpected report:\n%s\nwant:\n%s", out.String(), want)
+	}
+}
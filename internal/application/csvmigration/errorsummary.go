@@ -0,0 +1,89 @@
+package csvmigration
+
+import (
+	"sort"
+
+	"stori-challenge/internal/ports/services"
+)
+
+const (
+	// MaxErrorGroups caps the groups of a row error summary; errors with other messages are
+	// only counted.
+	MaxErrorGroups = 100
+	// ErrorGroupSamples is how many rows each group of a summary lists.
+	ErrorGroupSamples = 5
+)
+
+// errorGroupKey identifies a group of a summary.
+type errorGroupKey struct {
+	file, field, message string
+}
+
+// errorSummary groups row errors by file, field and message as they are found. Rows are run
+// rows, which the locator turns into archive members and their local rows.
+type errorSummary struct {
+	locator   *rowLocator
+	total     int
+	groups    map[errorGroupKey]*services.RowErrorGroup
+	order     []errorGroupKey
+	ungrouped int
+}
+
+func newErrorSummary(locator *rowLocator) *errorSummary {
+	return &errorSummary{locator: locator, groups: make(map[errorGroupKey]*services.RowErrorGroup)}
+}
+
+func (s *errorSummary) add(e services.RowError) {
+	s.total++
+	file, row := e.File, e.Row
+	if file == "" {
+		file, row = s.locator.find(e.Row)
+	}
+	k := errorGroupKey{file: file, field: e.Field, message: e.Message}
+	g, ok := s.groups[k]
+	if !ok {
+		if len(s.order) == MaxErrorGroups {
+			s.ungrouped++
+			return
+		}
+		g = &services.RowErrorGroup{File: file, Field: e.Field, Message: e.Message}
+		s.groups[k] = g
+		s.order = append(s.order, k)
+	}
+	g.Count++
+	if row > 0 && len(g.SampleRows) < ErrorGroupSamples {
+		g.SampleRows = append(g.SampleRows, row)
+	}
+}
+
+// result returns the groups, largest first, or nil when no error was added. omitted is how
+// many errors the detailed list leaves out.
+func (s *errorSummary) result(omitted int) *services.RowErrorSummary {
+	if s.total == 0 {
+		return nil
+	}
+	out := &services.RowErrorSummary{
+		Total:     s.total,
+		Omitted:   omitted,
+		Groups:    make([]services.RowErrorGroup, 0, len(s.order)),
+		Ungrouped: s.ungrouped,
+	}
+	for _, k := range s.order {
+		out.Groups = append(out.Groups, *s.groups[k])
+	}
+	sort.SliceStable(out.Groups, func(i, j int) bool { return out.Groups[i].Count > out.Groups[j].Count })
+	return out
+}
+
+// limitRowErrors keeps the first cfg.maxErrors of errs, which are ordered by run row, and
+// summarizes all of them.
+func limitRowErrors(errs []services.RowError, cfg readConfig) ([]services.RowError, *services.RowErrorSummary) {
+	if len(errs) == 0 {
+		return errs, nil
+	}
+	c := newErrorCollector(cfg.maxErrors, cfg.locator)
+	for _, e := range errs {
+		c.add(e)
+	}
+	return c.result()
+}
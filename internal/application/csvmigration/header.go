@@ -0,0 +1,91 @@
+package csvmigration
+
+import (
+	"fmt"
+	"strings"
+
+	"stori-challenge/internal/domain"
+)
+
+// builtinAliases are accepted for every upload, on top of the canonical column names.
+var builtinAliases = map[string][]string{
+	domain.ColumnID:       {"transaction_id", "tx_id", "id_transaccion"},
+	domain.ColumnUserID:   {"user", "customer_id", "client_id", "id_usuario", "usuario"},
+	domain.ColumnAmount:   {"monto", "importe", "value"},
+	domain.ColumnDatetime: {"date", "timestamp", "created_at", "fecha", "fecha_hora"},
+	domain.ColumnType:     {"transaction_type", "tx_type", "dr_cr", "cr_dr", "debit_credit", "tipo"},
+}
+
+// columnLayout holds the index of each canonical column in the data records; optional
+// columns the file does not provide are -1.
+type columnLayout struct {
+	id, userID, amount, datetime, typ int
+	// width is the minimum number of fields a record needs to hold every column.
+	width int
+}
+
+// defaultLayout is the positional id,user_id,amount,datetime order.
+var defaultLayout = columnLayout{id: 0, userID: 1, amount: 2, datetime: 3, typ: -1, width: 4}
+
+// headerLookup maps normalized header names to canonical columns. Profile aliases take
+// precedence over the built-in ones.
+func headerLookup(profile *domain.MappingProfile) map[string]string {
+	lookup := make(map[string]string)
+	for _, col := range domain.MappableColumns {
+		lookup[domain.NormalizeHeader(col)] = col
+		for _, a := range builtinAliases[col] {
+			lookup[domain.NormalizeHeader(a)] = col
+		}
+	}
+	if profile != nil {
+		for col, aliases := range profile.Columns {
+			for _, a := range aliases {
+				lookup[domain.NormalizeHeader(a)] = col
+			}
+		}
+	}
+	return lookup
+}
+
+// resolveHeader maps header cells to canonical columns in any order. Profile aliases take
+// precedence over the built-in ones; columns that match nothing are ignored.
+func resolveHeader(rec []string, profile *domain.MappingProfile) (columnLayout, error) {
+	lookup := headerLookup(profile)
+	index := make(map[string]int, len(domain.MappableColumns))
+	for i, cell := range rec {
+		col, ok := lookup[domain.NormalizeHeader(cell)]
+		if !ok {
+			continue
+		}
+		if first, dup := index[col]; dup {
+			return columnLayout{}, &fileError{msg: fmt.Sprintf("columns %q and %q both map to %s", rec[first], cell, col)}
+		}
+		index[col] = i
+	}
+	var missing []string
+	for _, col := range domain.TransactionColumns {
+		if _, ok := index[col]; !ok {
+			missing = append(missing, col)
+		}
+	}
+	if len(missing) > 0 {
+		return columnLayout{}, &fileError{msg: "missing required columns: " + strings.Join(missing, ",")}
+	}
+
+	l := columnLayout{
+		id:       index[domain.ColumnID],
+		userID:   index[domain.ColumnUserID],
+		amount:   index[domain.ColumnAmount],
+		datetime: index[domain.ColumnDatetime],
+		typ:      -1,
+	}
+	if i, ok := index[domain.ColumnType]; ok {
+		l.typ = i
+	}
+	for _, i := range index {
+		if i+1 > l.width {
+			l.width = i + 1
+		}
+	}
+	return l, nil
+}
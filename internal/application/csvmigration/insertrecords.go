@@ -2,11 +2,25 @@ package csvmigration
 
 import (
 	"context"
+	"fmt"
 
 	"stori-challenge/internal/domain"
+	"stori-challenge/internal/ports/repositories"
 )
 
-// insertAll persists all transactions in a single transaction.
-func (s *csvMigrationService) insertAll(ctx context.Context, txs []domain.Transaction) error {
-	return s.Repo.BulkInsert(ctx, txs)
+// stageAll writes txs, whose ids are already unique, to imp. rows holds the parsed row of each
+// transaction.
+func stageAll(ctx context.Context, imp repositories.TransactionImport, txs []domain.Transaction, rows []ParsedRow) error {
+	rowNums := make([]int, len(rows))
+	for i, pr := range rows {
+		rowNums[i] = pr.RowNum
+	}
+	dups, err := imp.Stage(ctx, txs, rowNums)
+	if err != nil {
+		return err
+	}
+	if len(dups) > 0 {
+		return fmt.Errorf("stage: id %d repeated at row %d", dups[0].ID, dups[0].Row)
+	}
+	return nil
 }
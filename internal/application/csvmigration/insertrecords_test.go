@@ -3,19 +3,27 @@ package csvmigration
 import (
 	"context"
 	"testing"
-	"time"
 
 	"stori-challenge/internal/domain"
 )
 
-func Test_insertAll_CallsRepo(t *testing.T) {
+func Test_stageAll_StagesWithRowNumbers(t *testing.T) {
 	repo := &fakeRepo{}
-	s := &csvMigrationService{Repo: repo, NowFunc: func() time.Time { return time.Now().UTC() }}
+	imp, _ := repo.BeginImport(context.Background())
 	txs := []domain.Transaction{{ID: 1}, {ID: 2}}
-	if err := s.insertAll(context.Background(), txs); err != nil {
+	if err := stageAll(context.Background(), imp, txs, []ParsedRow{{RowNum: 1}, {RowNum: 3}}); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-	if len(repo.captured) != 2 {
-		t.Fatalf("expected 2 captured, got %d", len(repo.captured))
+	if fi := repo.imports[0]; len(fi.staged) != 2 || fi.firstRow[2] != 3 {
+		t.Fatalf("unexpected staging: %+v", fi)
+	}
+}
+
+func Test_stageAll_RepeatedIDIsAnError(t *testing.T) {
+	repo := &fakeRepo{}
+	imp, _ := repo.BeginImport(context.Background())
+	txs := []domain.Transaction{{ID: 1}, {ID: 1}}
+	if err := stageAll(context.Background(), imp, txs, []ParsedRow{{RowNum: 1}, {RowNum: 2}}); err == nil {
+		t.Fatalf("expected an error")
 	}
 }
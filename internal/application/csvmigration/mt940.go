@@ -0,0 +1,255 @@
+package csvmigration
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/ports/services"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	// mt940Tag is a field start such as ":61:" or ":60F:".
+	mt940Tag = regexp.MustCompile(`^:([0-9]{2}[A-Z]?):(.*)$`)
+	// mt940Line is a :61: statement line: value date, optional entry date (MMDD), debit/credit
+	// mark, optional funds code, amount, transaction type and references.
+	mt940Line = regexp.MustCompile(`^([0-9]{6})([0-9]{4})?(RC|RD|C|D)([A-Z])?([0-9]+,[0-9]*)([A-Z][A-Z0-9]{3})(.*)$`)
+	// mt940Balance is an opening or closing balance: mark, date, currency and amount.
+	mt940Balance = regexp.MustCompile(`^([CD])([0-9]{6})([A-Z]{3})([0-9]+,[0-9]*)$`)
+)
+
+// mt940RecordReader streams the :61: statement lines of SWIFT MT940 files into jsonLayout
+// records. A file may hold several statements, with or without the SWIFT block envelope
+// ({1:...}{4: ... -}). Rows are the line numbers of the :61: fields.
+//
+// Each statement's opening balance (:60F:/:60M:) plus its entries must equal its closing
+// balance (:62F:/:62M:); a statement that does not reconcile fails the whole file. :86:
+// narratives are read with their continuation lines but not stored, as transactions have no
+// description.
+//
+// Ids are derived from the account (:25:) and the bank reference, else the customer
+// reference, else the statement reference, number and entry position (see
+// domain.StableTransactionID), so importing a statement again reports conflicts.
+type mt940RecordReader struct {
+	sc    *bufio.Scanner
+	loc   *time.Location
+	owner *statementOwner
+
+	line    int
+	pending *mt940Field
+	seen    bool
+	stmt    *mt940Statement
+}
+
+// mt940Field is a tag with its value, continuation lines joined with "\n".
+type mt940Field struct {
+	tag, value string
+	line       int
+}
+
+// mt940Statement is the statement being read, from :20: to its closing balance.
+type mt940Statement struct {
+	ref, number, account string
+	line                 int
+	opening              *decimal.Decimal
+	sum                  decimal.Decimal
+	entries              int
+	// unchecked is set when an entry could not be read, so its balance cannot be verified.
+	unchecked bool
+}
+
+func newMT940RecordReader(r io.Reader, cfg readConfig) *mt940RecordReader {
+	return &mt940RecordReader{sc: bufio.NewScanner(r), loc: cfg.dates.location(), owner: newStatementOwner(cfg)}
+}
+
+func (r *mt940RecordReader) Read() ([]string, int, error) {
+	for {
+		f, err := r.next()
+		if err == io.EOF {
+			if !r.seen {
+				return nil, 0, &fileError{msg: "not an MT940 file: no :20: statement"}
+			}
+			if r.stmt != nil {
+				return nil, 0, &fileError{msg: fmt.Sprintf("statement %s (line %d) has no closing balance (:62F:)", r.stmt.ref, r.stmt.line)}
+			}
+			return nil, 0, io.EOF
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		switch f.tag {
+		case "20":
+			if r.stmt != nil {
+				return nil, 0, &fileError{msg: fmt.Sprintf("statement %s (line %d) has no closing balance (:62F:)", r.stmt.ref, r.stmt.line)}
+			}
+			r.seen = true
+			r.stmt = &mt940Statement{ref: firstLine(f.value), line: f.line}
+			continue
+		}
+		if r.stmt == nil {
+			continue
+		}
+		switch f.tag {
+		case "25":
+			r.stmt.account = firstLine(f.value)
+		case "28C", "28":
+			r.stmt.number = firstLine(f.value)
+		case "60F", "60M":
+			amount, err := mt940BalanceAmount(f)
+			if err != nil {
+				return nil, 0, err
+			}
+			r.stmt.opening = &amount
+		case "61":
+			rec, err := r.record(f)
+			return rec, f.line, err
+		case "62F", "62M":
+			if err := r.close(f); err != nil {
+				return nil, 0, err
+			}
+		}
+	}
+}
+
+// next returns the next field, reading ahead one line to collect continuation lines.
+func (r *mt940RecordReader) next() (*mt940Field, error) {
+	for r.sc.Scan() {
+		r.line++
+		text := strings.TrimRight(r.sc.Text(), "\r")
+		// The text block {4: holds the statement; the header and trailer blocks do not.
+		if strings.HasPrefix(text, "{") {
+			i := strings.Index(text, "{4:")
+			if i < 0 {
+				continue
+			}
+			text = text[i+3:]
+		}
+		if strings.HasPrefix(text, "-") {
+			// End of a message: "-" or "-}" with an optional trailer block.
+			text = ""
+		}
+		if m := mt940Tag.FindStringSubmatch(text); m != nil {
+			f := r.pending
+			r.pending = &mt940Field{tag: m[1], value: m[2], line: r.line}
+			if f != nil {
+				return f, nil
+			}
+			continue
+		}
+		if r.pending != nil && text != "" {
+			r.pending.value += "\n" + text
+		}
+	}
+	if err := r.sc.Err(); err != nil {
+		return nil, &fileError{msg: "MT940 file cannot be read: " + err.Error()}
+	}
+	if f := r.pending; f != nil {
+		r.pending = nil
+		return f, nil
+	}
+	return nil, io.EOF
+}
+
+// record maps a :61: line to id, user_id, signed amount and value date.
+func (r *mt940RecordReader) record(f *mt940Field) ([]string, error) {
+	st := r.stmt
+	st.entries++
+	first := firstLine(f.value)
+	m := mt940Line.FindStringSubmatch(first)
+	if m == nil {
+		st.unchecked = true
+		return nil, &rowReadError{services.RowError{Row: f.line, Field: "record", Value: truncate(first, 100), Message: "malformed :61: statement line"}}
+	}
+	amount, ok := mt940Amount(m[5])
+	if !ok {
+		st.unchecked = true
+		return nil, &rowReadError{services.RowError{Row: f.line, Field: "amount", Value: m[5], Message: "not a valid MT940 amount"}}
+	}
+	// A reversal of a credit is a debit and vice versa.
+	if m[3] == "D" || m[3] == "RC" {
+		amount = amount.Neg()
+	}
+	st.sum = st.sum.Add(amount)
+
+	userID, err := r.owner.user(st.account)
+	if err != nil {
+		return nil, err
+	}
+	date, err := time.ParseInLocation("060102", m[1], r.loc)
+	if err != nil {
+		return nil, &rowReadError{services.RowError{Row: f.line, Field: "datetime", Value: m[1], Message: "value date must be YYMMDD"}}
+	}
+	customer, bank, _ := strings.Cut(m[7], "//")
+	ref := strings.TrimSpace(bank)
+	if ref == "" {
+		ref = strings.TrimSpace(customer)
+	}
+	if ref == "" || ref == "NONREF" {
+		ref = st.ref + "/" + st.number + "#" + strconv.Itoa(st.entries)
+	}
+	return []string{
+		strconv.FormatInt(domain.StableTransactionID(string(domain.InputFormatMT940), st.account, ref), 10),
+		strconv.FormatInt(userID, 10),
+		amount.String(),
+		date.Format(time.RFC3339Nano),
+		"",
+	}, nil
+}
+
+// close checks the statement's balances and ends it.
+func (r *mt940RecordReader) close(f *mt940Field) error {
+	st := r.stmt
+	r.stmt = nil
+	closing, err := mt940BalanceAmount(f)
+	if err != nil {
+		return err
+	}
+	if st.opening == nil {
+		return &fileError{msg: fmt.Sprintf("statement %s (line %d) has no opening balance (:60F:)", st.ref, st.line)}
+	}
+	if st.unchecked {
+		return nil
+	}
+	if want := st.opening.Add(st.sum); !want.Equal(closing) {
+		return &fileError{msg: fmt.Sprintf("statement %s (line %d) does not balance: opening %s plus %d entries totalling %s is %s, but the closing balance is %s",
+			st.ref, st.line, st.opening.StringFixed(2), st.entries, st.sum.StringFixed(2), want.StringFixed(2), closing.StringFixed(2))}
+	}
+	return nil
+}
+
+// mt940BalanceAmount reads the signed amount of a :60a: or :62a: balance.
+func mt940BalanceAmount(f *mt940Field) (decimal.Decimal, error) {
+	m := mt940Balance.FindStringSubmatch(strings.TrimSpace(firstLine(f.value)))
+	if m == nil {
+		return decimal.Decimal{}, &fileError{msg: fmt.Sprintf("line %d: malformed :%s: balance", f.line, f.tag)}
+	}
+	amount, ok := mt940Amount(m[4])
+	if !ok {
+		return decimal.Decimal{}, &fileError{msg: fmt.Sprintf("line %d: malformed :%s: balance", f.line, f.tag)}
+	}
+	if m[1] == "D" {
+		amount = amount.Neg()
+	}
+	return amount, nil
+}
+
+// mt940Amount reads an amount with a decimal comma and optional decimals, e.g. 100,50 or 100,
+func mt940Amount(s string) (decimal.Decimal, bool) {
+	s = strings.TrimSuffix(strings.Replace(s, ",", ".", 1), ".")
+	d, err := decimal.NewFromString(s)
+	return d, err == nil
+}
+
+func firstLine(s string) string {
+	if i := strings.IndexByte(s, '\n'); i >= 0 {
+		s = s[:i]
+	}
+	return strings.TrimSpace(s)
+}
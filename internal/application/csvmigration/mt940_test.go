@@ -0,0 +1,119 @@
+package csvmigration
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"stori-challenge/internal/domain"
+)
+
+// mt940Statements holds two statements: the first in a SWIFT envelope with a multi-line :86:
+// narrative, the second with a reversal and an entry without references.
+const mt940Statements = "{1:F01BANKMXMMAXXX0000000000}{2:O9401200240602BANKMXMMAXXX00000000002406021200N}{4:\r\n" +
+	":20:STMT1\r\n" +
+	":25:ACC-1\r\n" +
+	":28C:1/1\r\n" +
+	":60F:C240531MXN1000,00\r\n" +
+	":61:2406010601C100,50NTRFINV-7//B1\r\n" +
+	":86:Payroll June\r\n" +
+	"second line of the narrative\r\n" +
+	":61:240602D20,NMSCNONREF\r\n" +
+	":86:Fee\r\n" +
+	":62F:C240602MXN1080,50\r\n" +
+	"-}\r\n" +
+	":20:STMT2\n" +
+	":25:ACC-1\n" +
+	":28C:2/1\n" +
+	":60M:C240602MXN1080,50\n" +
+	":61:240603RD5,00NTRFNONREF\n" +
+	":62M:C240603MXN1085,50\n" +
+	"-\n"
+
+func TestProcess_MT940_EntriesBalancesAndIds(t *testing.T) {
+	repo := &fakeRepo{}
+	svc := newSvcWithRepo(t, repo, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+
+	res, err := svc.Process(context.Background(), r(mt940Statements), domain.MigrationOptions{
+		Format: domain.InputFormatMT940, UserID: 3, Locale: "es-ES",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v %+v", err, res.Errors)
+	}
+	if res.Inserted != 3 || len(repo.captured) != 3 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	want := []struct {
+		ref    string
+		amount string
+		day    int
+	}{
+		{"B1", "100.5", 1},
+		{"STMT1/1/1#2", "-20", 2},
+		{"STMT2/2/1#1", "5", 3},
+	}
+	for i, w := range want {
+		tx := repo.captured[i]
+		if tx.ID != domain.StableTransactionID("mt940", "ACC-1", w.ref) || tx.Amount.String() != w.amount || tx.UserID != 3 {
+			t.Fatalf("entry %d: unexpected transaction %+v", i, tx)
+		}
+		if d := time.Date(2024, 6, w.day, 0, 0, 0, 0, time.UTC); !tx.DateTime.Equal(d) {
+			t.Fatalf("entry %d: expected %s, got %s", i, d, tx.DateTime)
+		}
+	}
+}
+
+func TestProcess_MT940_UnbalancedStatementFailsFile(t *testing.T) {
+	repo := &fakeRepo{}
+	svc := newSvcWithRepo(t, repo, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+
+	in := strings.Replace(mt940Statements, ":62F:C240602MXN1080,50", ":62F:C240602MXN1090,50", 1)
+	res, err := svc.Process(context.Background(), r(in), domain.MigrationOptions{Format: domain.InputFormatMT940, UserID: 3, Mode: domain.MigrationModePartial})
+	if err == nil || len(res.Errors) != 1 || res.Errors[0].Row != 0 {
+		t.Fatalf("expected a file-level error, got %v %+v", err, res.Errors)
+	}
+	if msg := res.Errors[0].Message; !strings.Contains(msg, "statement STMT1 (line 2) does not balance") || !strings.Contains(msg, "is 1080.50, but the closing balance is 1090.50") {
+		t.Fatalf("unexpected message %q", msg)
+	}
+	if len(repo.captured) != 0 {
+		t.Fatalf("expected nothing inserted, got %+v", repo.captured)
+	}
+}
+
+func TestValidate_MT940_RowErrorsAndAccountMapping(t *testing.T) {
+	svc := newSvcWithRepo(t, &fakeRepo{}, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+	svc.Accounts = &fakeAccounts{users: map[string]int64{"ACC-1": 8}}
+
+	in := ":20:S\n:25:ACC-1\n:60F:C240531EUR0,\n:61:240601C1,00NTRFA\n:61:garbage\n:61:240631C2,00NTRFB\n:62F:C240601EUR3,00\n"
+	report, err := svc.Validate(context.Background(), r(in), domain.MigrationOptions{Format: domain.InputFormatMT940})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Rows != 3 || len(report.Errors) != 2 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if e := report.Errors[0]; e.Row != 5 || e.Field != "record" {
+		t.Fatalf("unexpected error: %+v", e)
+	}
+	if e := report.Errors[1]; e.Row != 6 || e.Field != "datetime" {
+		t.Fatalf("unexpected error: %+v", e)
+	}
+}
+
+func TestProcess_MT940_FileErrors(t *testing.T) {
+	svc := newSvcWithRepo(t, &fakeRepo{}, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+
+	cases := map[string]string{
+		"id,user_id\n": "not an MT940 file",
+		":20:S\n:25:A\n:60F:C240531EUR0,\n:61:240601C1,00NTRFA\n":   "has no closing balance",
+		":20:S\n:25:A\n:61:240601C1,00NTRFA\n:62F:C240601EUR1,00\n": "has no opening balance",
+		":20:S\n:25:A\n:60F:X\n":                                    "line 3: malformed :60F: balance",
+	}
+	for in, want := range cases {
+		res, err := svc.Process(context.Background(), r(in), domain.MigrationOptions{Format: domain.InputFormatMT940, UserID: 1})
+		if err == nil || len(res.Errors) != 1 || !strings.Contains(res.Errors[0].Message, want) {
+			t.Fatalf("%q: expected %q, got %v %+v", in, want, err, res.Errors)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package csvmigration
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/ports/services"
+)
+
+// ofxRecordReader streams the <STMTTRN> entries of an OFX or QFX statement into jsonLayout
+// records. SGML (v1) and XML (v2) files are read alike: a leaf value runs to the next tag,
+// whether or not that tag closes it. Rows are 1-based transaction positions in the file.
+//
+// Ids are derived from the account and FITID (see domain.StableTransactionID), so importing an
+// overlapping statement again reports the repeated transactions as conflicts.
+type ofxRecordReader struct {
+	br    *bufio.Reader
+	loc   *time.Location
+	owner *statementOwner
+
+	sawOFX  bool
+	inFrom  bool
+	account string
+	index   int
+}
+
+func newOFXRecordReader(r io.Reader, cfg readConfig) *ofxRecordReader {
+	return &ofxRecordReader{
+		br:    bufio.NewReader(r),
+		loc:   cfg.dates.location(),
+		owner: newStatementOwner(cfg),
+	}
+}
+
+func (r *ofxRecordReader) Read() ([]string, int, error) {
+	var txn map[string]string
+	for {
+		tag, text, err := r.token()
+		if err == io.EOF {
+			if !r.sawOFX {
+				return nil, 0, &fileError{msg: "not an OFX file: no <OFX> element"}
+			}
+			if txn != nil {
+				return nil, 0, &fileError{msg: "OFX file ends inside a <STMTTRN>"}
+			}
+			return nil, 0, io.EOF
+		}
+		if err != nil {
+			return nil, 0, err
+		}
+		switch tag {
+		case "OFX":
+			r.sawOFX = true
+		case "STMTRS", "CCSTMTRS":
+			r.account = ""
+		case "BANKACCTFROM", "CCACCTFROM":
+			r.inFrom = true
+		case "/BANKACCTFROM", "/CCACCTFROM":
+			r.inFrom = false
+		case "STMTTRN":
+			txn = make(map[string]string)
+		case "/STMTTRN":
+			if txn == nil {
+				continue
+			}
+			r.index++
+			rec, err := r.record(txn)
+			return rec, r.index, err
+		default:
+			if tag == "ACCTID" && r.inFrom {
+				r.account = text
+			} else if txn != nil && !strings.HasPrefix(tag, "/") {
+				// Nested aggregates (PAYEE, BANKACCTTO) may repeat leaf names; the first one wins.
+				if _, ok := txn[tag]; !ok {
+					txn[tag] = text
+				}
+			}
+		}
+	}
+}
+
+// record maps a transaction's FITID, DTPOSTED and TRNAMT to id, user_id, amount and datetime.
+func (r *ofxRecordReader) record(txn map[string]string) ([]string, error) {
+	fitid := txn["FITID"]
+	if fitid == "" {
+		return nil, &rowReadError{services.RowError{Row: r.index, Field: "fitid", Value: "", Message: "missing FITID"}}
+	}
+	userID, err := r.owner.user(r.account)
+	if err != nil {
+		return nil, err
+	}
+	dt, ok := parseOFXDate(txn["DTPOSTED"], r.loc)
+	if !ok {
+		return nil, &rowReadError{services.RowError{Row: r.index, Field: "datetime", Value: txn["DTPOSTED"], Message: "not a valid OFX date (expected YYYYMMDD[HHMMSS[.XXX]][[offset:TZ]])"}}
+	}
+	return []string{
+		strconv.FormatInt(domain.StableTransactionID(string(domain.InputFormatOFX), r.account, fitid), 10),
+		strconv.FormatInt(userID, 10),
+		strings.TrimPrefix(txn["TRNAMT"], "+"),
+		dt.Format(time.RFC3339Nano),
+		"",
+	}, nil
+}
+
+var ofxEntities = strings.NewReplacer("&lt;", "<", "&gt;", ">", "&quot;", `"`, "&apos;", "'", "&nbsp;", " ", "&amp;", "&")
+
+// token returns the next element tag, upper-cased and with a "/" prefix for end tags, and the
+// text that follows it up to the next tag. Text before the first tag (the SGML header),
+// processing instructions and declarations are skipped.
+func (r *ofxRecordReader) token() (string, string, error) {
+	for {
+		if _, err := r.br.ReadString('<'); err != nil {
+			return "", "", err
+		}
+		tag, err := r.br.ReadString('>')
+		if err != nil {
+			return "", "", &fileError{msg: "OFX file ends inside a tag"}
+		}
+		tag = strings.TrimSpace(strings.TrimSuffix(tag, ">"))
+		if strings.HasPrefix(tag, "?") || strings.HasPrefix(tag, "!") {
+			continue
+		}
+		tag = strings.ToUpper(strings.TrimSpace(strings.TrimSuffix(tag, "/")))
+
+		text, err := r.br.ReadString('<')
+		switch {
+		case err == nil:
+			_ = r.br.UnreadByte()
+			text = text[:len(text)-1]
+		case err != io.EOF:
+			return "", "", err
+		}
+		return tag, ofxEntities.Replace(strings.TrimSpace(text)), nil
+	}
+}
+
+// parseOFXDate reads YYYYMMDD[HHMMSS[.XXX]] with an optional [offset:TZ] suffix in hours, such
+// as [-5:EST] or [5.5:IST]. Dates without an offset are in loc.
+func parseOFXDate(s string, loc *time.Location) (time.Time, bool) {
+	s = strings.TrimSpace(s)
+	if i := strings.IndexByte(s, '['); i >= 0 {
+		zone := strings.TrimSuffix(s[i+1:], "]")
+		s = s[:i]
+		if j := strings.IndexByte(zone, ':'); j >= 0 {
+			zone = zone[:j]
+		}
+		hours, err := strconv.ParseFloat(zone, 64)
+		if err != nil || hours < -14 || hours > 14 {
+			return time.Time{}, false
+		}
+		loc = time.FixedZone("", int(hours*3600))
+	}
+	for _, layout := range []string{"20060102150405.000", "20060102150405", "200601021504", "20060102"} {
+		if len(s) != len(layout) {
+			continue
+		}
+		if t, err := time.ParseInLocation(layout, s, loc); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -0,0 +1,173 @@
+package csvmigration
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/shared"
+)
+
+// fakeAccounts is an in-memory account mapping repository.
+type fakeAccounts struct {
+	users map[string]int64
+	err   error
+	calls int
+}
+
+func (f *fakeAccounts) Save(ctx context.Context, m domain.AccountMapping) (domain.AccountMapping, error) {
+	return m, nil
+}
+
+func (f *fakeAccounts) GetByAccount(ctx context.Context, accountID string) (domain.AccountMapping, bool, error) {
+	f.calls++
+	if f.err != nil {
+		return domain.AccountMapping{}, false, f.err
+	}
+	id, ok := f.users[accountID]
+	return domain.AccountMapping{AccountID: accountID, UserID: id}, ok, nil
+}
+
+func (f *fakeAccounts) List(ctx context.Context) ([]domain.AccountMapping, error) { return nil, nil }
+
+func (f *fakeAccounts) Delete(ctx context.Context, accountID string) (bool, error) { return false, nil }
+
+// sgmlStatement is an OFX 1.x file: SGML header, unclosed leaf elements.
+const sgmlStatement = `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+ENCODING:USASCII
+
+<OFX>
+<BANKMSGSRSV1><STMTTRNRS><STMTRS>
+<CURDEF>USD
+<BANKACCTFROM><BANKID>123<ACCTID>0042<ACCTTYPE>CHECKING</BANKACCTFROM>
+<BANKTRANLIST>
+<DTSTART>20240601
+<STMTTRN><TRNTYPE>CREDIT<DTPOSTED>20240601120000[-5:EST]<TRNAMT>+1,000.50<FITID>A1<NAME>Payroll &amp; Co</STMTTRN>
+<STMTTRN><TRNTYPE>DEBIT<DTPOSTED>20240602<TRNAMT>-20.00<FITID>A2</STMTTRN>
+</BANKTRANLIST>
+</STMTRS></STMTTRNRS></BANKMSGSRSV1>
+</OFX>
+`
+
+// xmlStatement is an OFX 2.x file with closed elements and a credit card account.
+const xmlStatement = `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220"?>
+<OFX>
+  <CREDITCARDMSGSRSV1><CCSTMTTRNRS><CCSTMTRS>
+    <CCACCTFROM><ACCTID>CARD-9</ACCTID></CCACCTFROM>
+    <BANKTRANLIST>
+      <STMTTRN><DTPOSTED>20240603093000.250</DTPOSTED><TRNAMT>-4.75</TRNAMT><FITID>X1</FITID></STMTTRN>
+      <STMTTRN><DTPOSTED>yesterday</DTPOSTED><TRNAMT>-1.00</TRNAMT><FITID>X2</FITID></STMTTRN>
+      <STMTTRN><DTPOSTED>20240604</DTPOSTED><TRNAMT>-2.00</TRNAMT></STMTTRN>
+    </BANKTRANLIST>
+  </CCSTMTRS></CCSTMTTRNRS></CREDITCARDMSGSRSV1>
+</OFX>
+`
+
+func TestProcess_OFX_SGMLWithUserID(t *testing.T) {
+	repo := &fakeRepo{}
+	svc := newSvcWithRepo(t, repo, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+
+	res, err := svc.Process(context.Background(), r(sgmlStatement), domain.MigrationOptions{
+		Format: domain.InputFormatOFX, UserID: 7, Locale: "en-US",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v %+v", err, res.Errors)
+	}
+	if res.Inserted != 2 || len(repo.captured) != 2 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	tx := repo.captured[0]
+	if tx.UserID != 7 || tx.Amount.String() != "1000.5" || tx.ID != domain.StableTransactionID("ofx", "0042", "A1") {
+		t.Fatalf("unexpected transaction: %+v", tx)
+	}
+	if want := time.Date(2024, 6, 1, 17, 0, 0, 0, time.UTC); !tx.DateTime.Equal(want) {
+		t.Fatalf("expected %s, got %s", want, tx.DateTime)
+	}
+}
+
+func TestProcess_OFX_ReimportIsConflict(t *testing.T) {
+	id := domain.StableTransactionID("ofx", "0042", "A2")
+	repo := &fakeRepo{exists: map[int64]bool{id: true}}
+	svc := newSvcWithRepo(t, repo, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+
+	res, err := svc.Process(context.Background(), r(sgmlStatement), domain.MigrationOptions{
+		Format: domain.InputFormatOFX, UserID: 7, Locale: "en-US",
+	})
+	if err == nil || len(res.Errors) != 1 || res.Errors[0].Row != 2 {
+		t.Fatalf("expected a conflict at transaction 2, got %v %+v", err, res.Errors)
+	}
+}
+
+func TestValidate_OFX_XMLWithAccountMapping(t *testing.T) {
+	accounts := &fakeAccounts{users: map[string]int64{"CARD-9": 33}}
+	svc := newSvcWithRepo(t, &fakeRepo{}, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+	svc.Accounts = accounts
+
+	report, err := svc.Validate(context.Background(), r(xmlStatement), domain.MigrationOptions{Format: domain.InputFormatOFX})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Rows != 3 || len(report.Errors) != 2 {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	if e := report.Errors[0]; e.Row != 2 || e.Field != "datetime" || e.Value != "yesterday" {
+		t.Fatalf("unexpected date error: %+v", e)
+	}
+	if e := report.Errors[1]; e.Row != 3 || e.Field != "fitid" {
+		t.Fatalf("unexpected fitid error: %+v", e)
+	}
+	if accounts.calls != 1 {
+		t.Fatalf("expected the mapping to be looked up once, got %d", accounts.calls)
+	}
+}
+
+func TestProcess_OFX_UnmappedAccountAndLookupFailure(t *testing.T) {
+	svc := newSvcWithRepo(t, &fakeRepo{}, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+	svc.Accounts = &fakeAccounts{}
+
+	res, err := svc.Process(context.Background(), r(xmlStatement), domain.MigrationOptions{Format: domain.InputFormatOFX})
+	if err == nil || len(res.Errors) != 1 || !strings.Contains(res.Errors[0].Message, "no user for account CARD-9") {
+		t.Fatalf("expected an unmapped account error, got %v %+v", err, res.Errors)
+	}
+
+	svc.Accounts = &fakeAccounts{err: errors.New("boom")}
+	_, err = svc.Process(context.Background(), r(xmlStatement), domain.MigrationOptions{Format: domain.InputFormatOFX})
+	var ae *shared.AppError
+	if !errors.As(err, &ae) || ae.Code != "db_failure" {
+		t.Fatalf("expected db_failure, got %v", err)
+	}
+
+	res, err = svc.Process(context.Background(), r("id,user_id\n"), domain.MigrationOptions{Format: domain.InputFormatOFX, UserID: 1})
+	if err == nil || len(res.Errors) != 1 || !strings.Contains(res.Errors[0].Message, "no <OFX> element") {
+		t.Fatalf("expected not-OFX error, got %v %+v", err, res.Errors)
+	}
+}
+
+func Test_parseOFXDate(t *testing.T) {
+	cases := []struct {
+		in   string
+		want time.Time
+		ok   bool
+	}{
+		{"20240601", time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC), true},
+		{"202406011230", time.Date(2024, 6, 1, 12, 30, 0, 0, time.UTC), true},
+		{"20240601123000.500", time.Date(2024, 6, 1, 12, 30, 0, 500e6, time.UTC), true},
+		{"20240601120000[-5:EST]", time.Date(2024, 6, 1, 17, 0, 0, 0, time.UTC), true},
+		{"20240601120000[5.5:IST]", time.Date(2024, 6, 1, 6, 30, 0, 0, time.UTC), true},
+		{"20240601120000[0]", time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC), true},
+		{"2024-06-01", time.Time{}, false},
+		{"20240601[x:EST]", time.Time{}, false},
+	}
+	for _, c := range cases {
+		got, ok := parseOFXDate(c.in, time.UTC)
+		if ok != c.ok || (ok && !got.Equal(c.want)) {
+			t.Fatalf("%q: want %s %v got %s %v", c.in, c.want, c.ok, got, ok)
+		}
+	}
+}
@@ -0,0 +1,493 @@
+package csvmigration
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/ports/services"
+)
+
+// recordReader yields data records laid out for a rowParser, with the row number errors refer to.
+// It returns io.EOF after the last record.
+type recordReader interface {
+	Read() (rec []string, row int, err error)
+}
+
+// rowReadError is a record that cannot be split into fields. It is reported for its row and
+// reading continues.
+type rowReadError struct {
+	services.RowError
+}
+
+func (e *rowReadError) Error() string { return e.Message }
+
+// rowLocator completes row errors with where the row lives in the upload, for formats where a
+// row number alone does not say it. Readers fill it in as they open the input.
+type rowLocator struct {
+	sheet string
+	// members are the archive members opened so far. Their rows are numbered one after the
+	// other during the run, so in-file duplicates and ordering work across members.
+	members []memberSpan
+	// paths are the XPath-style locations of the rows' elements, in row order. XML statements
+	// set them, since their rows are entries rather than lines. A run of entries of one parent
+	// with the same element layout is one span, so they take memory per statement rather than
+	// per entry.
+	paths []pathSpan
+	// lastPathRow is the last row with paths.
+	lastPathRow int
+}
+
+// pathSpan locates the rows from row on, up to the next span. Row row+k is the element
+// elem[pos+k], or elem itself when pos is 0, and fields are the paths of its field elements
+// relative to it.
+type pathSpan struct {
+	row    int
+	elem   string
+	pos    int
+	fields map[string]string
+}
+
+// own returns the path of row's element.
+func (p pathSpan) own(row int) string {
+	if p.pos == 0 {
+		return p.elem
+	}
+	return p.elem + "[" + strconv.Itoa(p.pos+row-p.row) + "]"
+}
+
+// addPaths records the element paths of row, which comes after every row added so far: paths[""]
+// is the row's own element and the other fields are elements below it.
+func (l *rowLocator) addPaths(row int, paths map[string]string) {
+	own := paths[""]
+	elem, pos := own, 0
+	if open := strings.LastIndexByte(own, '['); open > strings.LastIndexByte(own, '/') && strings.HasSuffix(own, "]") {
+		if n, err := strconv.Atoi(own[open+1 : len(own)-1]); err == nil {
+			elem, pos = own[:open], n
+		}
+	}
+	fields := make(map[string]string, len(paths))
+	for f, p := range paths {
+		if f != "" {
+			fields[f] = strings.TrimPrefix(p, own)
+		}
+	}
+	l.lastPathRow = row
+	if n := len(l.paths); n > 0 {
+		last := l.paths[n-1]
+		if pos > 0 && last.pos > 0 && last.elem == elem && pos-last.pos == row-last.row && maps.Equal(last.fields, fields) {
+			return
+		}
+	}
+	l.paths = append(l.paths, pathSpan{row: row, elem: elem, pos: pos, fields: fields})
+}
+
+// memberSpan is an archive member whose local row n is row offset+n of the run.
+type memberSpan struct {
+	name   string
+	offset int
+}
+
+// find returns the member and local row of a run row; name is empty outside archives.
+func (l *rowLocator) find(row int) (name string, local int) {
+	if l == nil || row <= 0 {
+		return "", row
+	}
+	for i := len(l.members) - 1; i >= 0; i-- {
+		if row > l.members[i].offset {
+			return l.members[i].name, row - l.members[i].offset
+		}
+	}
+	return "", row
+}
+
+// position describes a run row for messages, e.g. "row 3" or "row 3 of b.csv".
+func (l *rowLocator) position(row int) string {
+	if p := l.path(row, ""); p != "" {
+		return p
+	}
+	name, local := l.find(row)
+	if name == "" {
+		return "row " + strconv.Itoa(local)
+	}
+	return "row " + strconv.Itoa(local) + " of " + name
+}
+
+// path returns the location of a row's field element, or of the row when the field has none.
+func (l *rowLocator) path(row int, field string) string {
+	if l == nil || row <= 0 || row > l.lastPathRow {
+		return ""
+	}
+	i := sort.Search(len(l.paths), func(i int) bool { return l.paths[i].row > row }) - 1
+	if i < 0 {
+		return ""
+	}
+	span := l.paths[i]
+	own := span.own(row)
+	if rel, ok := span.fields[field]; ok {
+		return own + rel
+	}
+	return own
+}
+
+func (l *rowLocator) locate(errs []services.RowError) []services.RowError {
+	if l == nil || (l.sheet == "" && len(l.members) == 0 && len(l.paths) == 0) {
+		return errs
+	}
+	for i := range errs {
+		if l.sheet != "" {
+			errs[i].Sheet = l.sheet
+		}
+		if errs[i].Path == "" {
+			errs[i].Path = l.path(errs[i].Row, errs[i].Field)
+		}
+		if errs[i].Row > 0 && len(l.members) > 0 {
+			errs[i].File, errs[i].Row = l.find(errs[i].Row)
+		}
+	}
+	return errs
+}
+
+// fileError is input that cannot be read any further; msg is reported as a file-level error,
+// for the archive member file when there is one.
+type fileError struct {
+	file string
+	msg  string
+}
+
+func (e *fileError) Error() string { return e.msg }
+
+// lookupError is a database failure while resolving something the input refers to.
+type lookupError struct {
+	err error
+}
+
+func (e *lookupError) Error() string { return e.err.Error() }
+
+// statementOwner resolves the user a bank statement's transactions belong to: the upload's
+// user_id, else the mapping of the statement's account, looked up once per account.
+type statementOwner struct {
+	userID int64
+	lookup func(accountID string) (int64, bool, error)
+	users  map[string]int64
+}
+
+func newStatementOwner(cfg readConfig) *statementOwner {
+	return &statementOwner{userID: cfg.userID, lookup: cfg.accountUser, users: make(map[string]int64)}
+}
+
+func (o *statementOwner) user(account string) (int64, error) {
+	if o.userID > 0 {
+		return o.userID, nil
+	}
+	if account == "" {
+		return 0, &fileError{msg: "statement has no account id; pass user_id"}
+	}
+	if id, ok := o.users[account]; ok {
+		return id, nil
+	}
+	if o.lookup == nil {
+		return 0, &fileError{msg: fmt.Sprintf("no user for account %s: pass user_id or add an account mapping", account)}
+	}
+	id, found, err := o.lookup(account)
+	if err != nil {
+		return 0, &lookupError{err: err}
+	}
+	if !found {
+		return 0, &fileError{msg: fmt.Sprintf("no user for account %s: pass user_id or add an account mapping", account)}
+	}
+	o.users[account] = id
+	return id, nil
+}
+
+// newRecordReader returns a reader over the data records of r in cfg.format, and a parser for
+// those records. CSV input starts with a mandatory header that sets the column layout.
+func (s *csvMigrationService) newRecordReader(r io.Reader, cfg readConfig) (recordReader, *rowParser, error) {
+	parser := &rowParser{layout: defaultLayout, dates: cfg.dates, amounts: cfg.amounts, rounding: cfg.rounding, now: s.NowFunc()}
+	switch cfg.compression {
+	case domain.CompressionZip:
+		rd, err := newZipRecordReader(r, cfg, parser)
+		if err != nil {
+			return nil, nil, err
+		}
+		return rd, parser, nil
+	case domain.CompressionGzip:
+		gz, err := gzip.NewReader(r)
+		if err != nil {
+			return nil, nil, &fileError{msg: "not a valid gzip file"}
+		}
+		r = cfg.limit.wrap(gz)
+	}
+	switch cfg.format {
+	case domain.InputFormatJSONL, domain.InputFormatJSON, domain.InputFormatMT940:
+		// CSV is decoded in newCSVRecordReader, once per archive member. OFX, camt and xlsx
+		// declare their own encoding.
+		r, _ = newTextDecoder(r, cfg.encoding)
+	}
+	switch cfg.format {
+	case domain.InputFormatJSONL:
+		parser.layout = jsonLayout
+		sc := bufio.NewScanner(r)
+		sc.Buffer(make([]byte, 0, 64*1024), MaxJSONLineSize)
+		return &jsonlRecordReader{sc: sc, keys: headerLookup(cfg.profile)}, parser, nil
+	case domain.InputFormatJSON:
+		parser.layout = jsonLayout
+		dec := json.NewDecoder(r)
+		dec.UseNumber()
+		return &jsonArrayRecordReader{dec: dec, keys: headerLookup(cfg.profile)}, parser, nil
+	case domain.InputFormatOFX, domain.InputFormatCAMT:
+		parser.layout = jsonLayout
+		// Dates are normalised to RFC3339 while reading; the time zone applies to those without one.
+		parser.dates = datetimeParser{format: domain.DatetimeFormatRFC3339, loc: cfg.dates.location()}
+		if cfg.format == domain.InputFormatCAMT {
+			return newCAMTRecordReader(r, cfg), parser, nil
+		}
+		return newOFXRecordReader(r, cfg), parser, nil
+	case domain.InputFormatMT940:
+		parser.layout = jsonLayout
+		// Amounts and dates are normalised while reading, so the upload's locale does not apply.
+		parser.dates = datetimeParser{format: domain.DatetimeFormatRFC3339, loc: cfg.dates.location()}
+		parser.amounts = amountParser{}
+		return newMT940RecordReader(r, cfg), parser, nil
+	case domain.InputFormatXLSX:
+		rd, layout, err := newXLSXRecordReader(r, cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		parser.layout = layout
+		return rd, parser, nil
+	}
+
+	rd, layout, err := newCSVRecordReader(r, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	parser.layout, parser.amounts = layout, rd.amounts
+	return rd, parser, nil
+}
+
+// newCSVRecordReader transcodes r to UTF-8, detects its delimiter unless one is given, reads its
+// header and returns a reader over the data records that follow it, with their layout.
+func newCSVRecordReader(r io.Reader, cfg readConfig) (*csvRecordReader, columnLayout, error) {
+	r, enc := newTextDecoder(r, cfg.encoding)
+	br := bufio.NewReaderSize(r, dialectSample)
+	sample := peekSample(br)
+	comma := cfg.delimiter
+	if comma == 0 {
+		comma = sniffDelimiter(sample, cfg.profile)
+	}
+	cr := csv.NewReader(br)
+	cr.Comma = comma
+	cr.FieldsPerRecord = -1
+	// Stray quotes inside fields, common in hand-edited exports, are kept as text.
+	cr.LazyQuotes = true
+	// Records are consumed one at a time, so the backing array can be reused.
+	cr.ReuseRecord = true
+	rd := &csvRecordReader{cr: cr, encoding: enc, amounts: cfg.amounts}
+	rd.dialect = domain.CSVDialect{Delimiter: string(comma), DecimalSeparator: "."}
+	if cfg.amounts.locale != "" {
+		rd.dialect.DecimalSeparator = string(cfg.amounts.format.Decimal)
+	}
+	defer cfg.recordDialect(&rd.dialect)
+	rec, err := cr.Read()
+	if err == io.EOF {
+		// Empty input: later reads also return EOF and callers report "no data rows".
+		return rd, defaultLayout, nil
+	}
+	if err != nil {
+		return nil, columnLayout{}, err
+	}
+	layout, err := resolveHeader(rec, cfg.profile)
+	if err != nil {
+		return nil, columnLayout{}, err
+	}
+	rd.header = append([]string(nil), rec...)
+	// Without a locale, amounts of files not delimited by commas follow the decimal separator
+	// the sample is written with; a comma-separated file has to quote such amounts, so it is
+	// taken to use plain decimals.
+	if cfg.amounts.locale == "" && comma != ',' && sniffDecimal(sample, comma, layout) == "," {
+		rd.dialect.DecimalSeparator = ","
+		rd.amounts = amountParser{format: domain.NumberFormat{Decimal: ',', Group: '.'}}
+	}
+	return rd, layout, nil
+}
+
+// csvRecordReader numbers data rows from 1, after the header. A field with bytes that could
+// not be decoded is a row error naming its column.
+type csvRecordReader struct {
+	cr       *csv.Reader
+	row      int
+	encoding domain.Encoding
+	header   []string
+	// dialect is how the file is read, and amounts the parser for its amount column.
+	dialect domain.CSVDialect
+	amounts amountParser
+}
+
+func (r *csvRecordReader) Read() ([]string, int, error) {
+	rec, err := r.cr.Read()
+	if err != nil {
+		return nil, 0, err
+	}
+	r.row++
+	for i, v := range rec {
+		if strings.ContainsRune(v, utf8.RuneError) {
+			field := "column " + strconv.Itoa(i+1)
+			if i < len(r.header) {
+				field = r.header[i]
+			}
+			return nil, r.row, &rowReadError{services.RowError{Row: r.row, Field: field, Value: truncate(v, 100),
+				Message: fmt.Sprintf("column %d has bytes that cannot be decoded as %s", i+1, r.encoding)}}
+		}
+	}
+	return rec, r.row, nil
+}
+
+func (r *csvRecordReader) columns() []string { return r.header }
+
+// MaxJSONLineSize is the longest JSON Lines record accepted, in bytes.
+const MaxJSONLineSize = 1024 * 1024
+
+// jsonColumns is the field order JSON objects are flattened to; jsonLayout describes it.
+var jsonColumns = append(append([]string{}, domain.TransactionColumns...), domain.ColumnType)
+
+var jsonLayout = columnLayout{id: 0, userID: 1, amount: 2, datetime: 3, typ: 4, width: 5}
+
+// jsonlRecordReader reads one JSON object per line. Rows are line numbers; blank lines are
+// skipped and a line that is not an object is a row error.
+type jsonlRecordReader struct {
+	sc   *bufio.Scanner
+	keys map[string]string
+	line int
+}
+
+func (r *jsonlRecordReader) Read() ([]string, int, error) {
+	for r.sc.Scan() {
+		r.line++
+		b := bytes.TrimSpace(r.sc.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+		rec, err := jsonRecord(b, r.line, r.keys)
+		return rec, r.line, err
+	}
+	if err := r.sc.Err(); err != nil {
+		if errors.Is(err, bufio.ErrTooLong) {
+			return nil, 0, &fileError{msg: fmt.Sprintf("line %d is longer than %d bytes", r.line+1, MaxJSONLineSize)}
+		}
+		return nil, 0, err
+	}
+	return nil, 0, io.EOF
+}
+
+// jsonArrayRecordReader streams the elements of a top-level JSON array. Rows are 1-based
+// element positions (array index + 1), since row 0 is reserved for file-level errors.
+type jsonArrayRecordReader struct {
+	dec     *json.Decoder
+	keys    map[string]string
+	index   int
+	started bool
+	done    bool
+}
+
+func (r *jsonArrayRecordReader) Read() ([]string, int, error) {
+	if r.done {
+		return nil, 0, io.EOF
+	}
+	if !r.started {
+		tok, err := r.dec.Token()
+		if err == io.EOF {
+			r.done = true
+			return nil, 0, io.EOF
+		}
+		if d, ok := tok.(json.Delim); err != nil || !ok || d != '[' {
+			return nil, 0, &fileError{msg: "JSON input must be an array of transaction objects"}
+		}
+		r.started = true
+	}
+	if !r.dec.More() {
+		r.done = true
+		if _, err := r.dec.Token(); err != nil {
+			return nil, 0, &fileError{msg: "JSON array is not terminated"}
+		}
+		return nil, 0, io.EOF
+	}
+	var raw json.RawMessage
+	if err := r.dec.Decode(&raw); err != nil {
+		return nil, 0, &fileError{msg: fmt.Sprintf("invalid JSON after element %d: %v", r.index, err)}
+	}
+	r.index++
+	rec, err := jsonRecord(raw, r.index, r.keys)
+	return rec, r.index, err
+}
+
+// jsonRecord flattens a JSON object into jsonColumns order. Keys go through the same aliases as
+// CSV headers and unknown keys are ignored. Numbers keep their literal text, so amounts are
+// not rounded through float64.
+func jsonRecord(b []byte, row int, keys map[string]string) ([]string, error) {
+	dec := json.NewDecoder(bytes.NewReader(b))
+	dec.UseNumber()
+	var obj map[string]any
+	if err := dec.Decode(&obj); err != nil || obj == nil {
+		return nil, &rowReadError{services.RowError{Row: row, Field: "record", Value: truncate(string(b), 100), Message: "not a JSON object"}}
+	}
+
+	names := make([]string, 0, len(obj))
+	for k := range obj {
+		names = append(names, k)
+	}
+	sort.Strings(names)
+	rec := make([]string, len(jsonColumns))
+	from := make(map[string]string, len(jsonColumns))
+	for _, name := range names {
+		col, ok := keys[domain.NormalizeHeader(name)]
+		if !ok {
+			continue
+		}
+		if other, dup := from[col]; dup {
+			return nil, &rowReadError{services.RowError{Row: row, Field: col, Value: name, Message: fmt.Sprintf("fields %q and %q both map to %s", other, name, col)}}
+		}
+		from[col] = name
+		v, ok := jsonScalar(obj[name])
+		if !ok {
+			raw, _ := json.Marshal(obj[name])
+			return nil, &rowReadError{services.RowError{Row: row, Field: col, Value: truncate(string(raw), 100), Message: "must be a string or a number"}}
+		}
+		for i, c := range jsonColumns {
+			if c == col {
+				rec[i] = v
+			}
+		}
+	}
+	return rec, nil
+}
+
+func jsonScalar(v any) (string, bool) {
+	switch t := v.(type) {
+	case nil:
+		return "", true
+	case string:
+		return t, true
+	case json.Number:
+		return t.String(), true
+	}
+	return "", false
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
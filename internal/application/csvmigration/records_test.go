@@ -0,0 +1,85 @@
+package csvmigration
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"stori-challenge/internal/domain"
+)
+
+func TestProcess_JSONLines_LineNumberedErrors(t *testing.T) {
+	repo := &fakeRepo{}
+	svc := newSvcWithRepo(t, repo, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+
+	in := `{"id": 1, "user_id": 10, "amount": 1234567890123456.78, "datetime": "2024-06-01T00:00:00Z"}
+
+{"transaction_id": "2", "userId": 20, "monto": "-5.00", "fecha": "2024-06-02T00:00:00Z", "note": "ignored"}
+not json
+{"id": 1, "user_id": 10, "amount": 1, "datetime": "2024-06-01T00:00:00Z"}
+{"id": 4, "user_id": true, "amount": 1, "datetime": "2024-06-01T00:00:00Z"}
+`
+	res, err := svc.Process(context.Background(), r(in), domain.MigrationOptions{Format: domain.InputFormatJSONL, Mode: domain.MigrationModePartial})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Inserted != 2 || len(res.Errors) != 3 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	wantRows := []int{4, 5, 6}
+	for i, e := range res.Errors {
+		if e.Row != wantRows[i] {
+			t.Fatalf("error %d: want row %d got %+v", i, wantRows[i], e)
+		}
+	}
+	if res.Errors[0].Field != "record" || res.Errors[2].Field != "user_id" {
+		t.Fatalf("unexpected error fields: %+v", res.Errors)
+	}
+	if got := repo.captured[0].Amount.String(); got != "1234567890123456.78" {
+		t.Fatalf("expected exact amount, got %s", got)
+	}
+}
+
+func TestProcess_JSONArray_IndexedErrorsAndAtomicity(t *testing.T) {
+	repo := &fakeRepo{}
+	svc := newSvcWithRepo(t, repo, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+
+	in := `[
+  {"id": 1, "user_id": 10, "amount": "12.34", "datetime": "2024-06-01T00:00:00Z", "type": "CR"},
+  {"id": 2, "user_id": 10, "amount": "abc", "datetime": "2024-06-01T00:00:00Z"}
+]`
+	res, err := svc.Process(context.Background(), r(in), domain.MigrationOptions{Format: domain.InputFormatJSON})
+	if err == nil || len(res.Errors) != 1 || res.Errors[0].Row != 2 || res.Errors[0].Field != "amount" {
+		t.Fatalf("expected one amount error at element 2, got %v %+v", err, res.Errors)
+	}
+	if len(repo.captured) != 0 {
+		t.Fatalf("expected nothing inserted, got %+v", repo.captured)
+	}
+
+	res, err = svc.Process(context.Background(), r(`{"id": 1}`), domain.MigrationOptions{Format: domain.InputFormatJSON})
+	if err == nil || len(res.Errors) != 1 || res.Errors[0].Message != "JSON input must be an array of transaction objects" {
+		t.Fatalf("expected file-level error, got %v %+v", err, res.Errors)
+	}
+
+	res, err = svc.Process(context.Background(), r(`[]`), domain.MigrationOptions{Format: domain.InputFormatJSON})
+	if err == nil || len(res.Errors) != 1 || res.Errors[0].Message != "CSV contains no data rows" {
+		t.Fatalf("expected no data rows, got %v %+v", err, res.Errors)
+	}
+}
+
+func TestProcessStream_JSONLines(t *testing.T) {
+	repo := &fakeRepo{}
+	svc := newSvcWithRepo(t, repo, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+	svc.ChunkSize = 2
+
+	var b strings.Builder
+	for i := 1; i <= 5; i++ {
+		b.WriteString(`{"id":` + strconv.Itoa(i) + `,"user_id":1,"amount":"1.00","datetime":"2024-06-01T00:00:00Z"}` + "\n")
+	}
+	res, err := svc.ProcessStream(context.Background(), r(b.String()), domain.MigrationOptions{Format: domain.InputFormatJSONL})
+	if err != nil || res.Inserted != 5 {
+		t.Fatalf("unexpected result: %+v, %v", res, err)
+	}
+}
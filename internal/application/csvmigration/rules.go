@@ -0,0 +1,130 @@
+package csvmigration
+
+import (
+	"slices"
+	"strconv"
+	"time"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/ports/repositories"
+	"stori-challenge/internal/ports/services"
+)
+
+// ruleChecker applies the configured validation rules to the valid rows of one run. Broken
+// error rules reject the row; warnings are collected apart and the row is still migrated.
+// A nil checker applies no rules.
+type ruleChecker struct {
+	rules []domain.ValidationRule
+	// perDay counts the rows checked so far by user and day. A streamed run replaces it with the
+	// counts of each chunk's users and days (see seedPerDay), so it holds one chunk's keys.
+	perDay   map[repositories.UserDay]int
+	warnings *errorCollector
+}
+
+func newRuleChecker(set domain.RuleSet, maxWarnings int, locator *rowLocator) *ruleChecker {
+	if len(set.Rules) == 0 {
+		return nil
+	}
+	return &ruleChecker{
+		rules:    set.Rules,
+		perDay:   make(map[repositories.UserDay]int),
+		warnings: newErrorCollector(maxWarnings, locator),
+	}
+}
+
+// check returns the errors of the error rules tx breaks and keeps the warnings.
+func (c *ruleChecker) check(tx domain.Transaction, pr ParsedRow) []services.RowError {
+	if c == nil {
+		return nil
+	}
+	key := userDayOf(tx)
+	c.perDay[key]++
+
+	var errs []services.RowError
+	for _, r := range c.rules {
+		e, broken := breaks(r, tx, pr, c.perDay[key])
+		if !broken {
+			continue
+		}
+		e.Row = pr.RowNum
+		e.Rule = r.Code
+		e.Severity = r.Severity
+		if r.Message != "" {
+			e.Message = r.Message
+		}
+		if r.Severity == domain.RuleSeverityWarning {
+			c.warnings.add(e)
+			continue
+		}
+		errs = append(errs, e)
+	}
+	return errs
+}
+
+// perDayKeys returns the users and days of txs when a rule limits transactions per user and
+// day, and nil otherwise.
+func (c *ruleChecker) perDayKeys(txs []domain.Transaction) []repositories.UserDay {
+	if c == nil || !slices.ContainsFunc(c.rules, func(r domain.ValidationRule) bool { return r.Type == domain.RuleTypeMaxPerUserPerDay }) {
+		return nil
+	}
+	seen := make(map[repositories.UserDay]bool)
+	var keys []repositories.UserDay
+	for _, tx := range txs {
+		if key := userDayOf(tx); !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+// seedPerDay replaces the per-day counts with counts, those of the rows checked before.
+func (c *ruleChecker) seedPerDay(counts map[repositories.UserDay]int) {
+	c.perDay = counts
+}
+
+func userDayOf(tx domain.Transaction) repositories.UserDay {
+	return repositories.UserDay{UserID: tx.UserID, Day: tx.DateTime.UTC().Format(time.DateOnly)}
+}
+
+// breaks describes how tx breaks r; sameDay is how many transactions its user has on its day so far.
+func breaks(r domain.ValidationRule, tx domain.Transaction, pr ParsedRow, sameDay int) (services.RowError, bool) {
+	switch r.Type {
+	case domain.RuleTypeAmountRange:
+		if r.MinAmount != nil && tx.Amount.LessThan(*r.MinAmount) {
+			return services.RowError{Field: "amount", Value: pr.AmountStr, Message: "amount below the minimum of " + r.MinAmount.String()}, true
+		}
+		if r.MaxAmount != nil && tx.Amount.GreaterThan(*r.MaxAmount) {
+			return services.RowError{Field: "amount", Value: pr.AmountStr, Message: "amount above the maximum of " + r.MaxAmount.String()}, true
+		}
+	case domain.RuleTypeUserIDRange:
+		for _, u := range r.UserIDs {
+			if u.Contains(tx.UserID) {
+				return services.RowError{}, false
+			}
+		}
+		return services.RowError{Field: "user_id", Value: pr.UserIDStr, Message: "user_id outside the allowed ranges"}, true
+	case domain.RuleTypeEarliestDatetime:
+		if tx.DateTime.Before(r.Earliest) {
+			return services.RowError{Field: "datetime", Value: pr.DatetimeStr, Message: "datetime before the earliest allowed " + r.Earliest.Format(time.RFC3339)}, true
+		}
+	case domain.RuleTypeMaxPerUserPerDay:
+		if sameDay > r.Limit {
+			return services.RowError{Field: "datetime", Value: pr.DatetimeStr, Message: "more than " + strconv.Itoa(r.Limit) + " transactions for the user on the same day (UTC)"}, true
+		}
+	case domain.RuleTypeNonZeroAmount:
+		if tx.Amount.IsZero() {
+			return services.RowError{Field: "amount", Value: pr.AmountStr, Message: "amount must not be zero"}, true
+		}
+	}
+	return services.RowError{}, false
+}
+
+// warningList returns the kept warnings ordered by row; nil without a checker.
+func (c *ruleChecker) warningList() []services.RowError {
+	if c == nil || c.warnings.count() == 0 {
+		return nil
+	}
+	warnings, _ := c.warnings.result()
+	return warnings
+}
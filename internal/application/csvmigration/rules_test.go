@@ -0,0 +1,193 @@
+package csvmigration
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/ports/services"
+	"stori-challenge/internal/shared"
+
+	"github.com/shopspring/decimal"
+)
+
+type staticRules struct {
+	set domain.RuleSet
+	err error
+}
+
+func (s staticRules) Rules(ctx context.Context) (domain.RuleSet, error) {
+	return s.set, s.err
+}
+
+func newRulesSvc(t *testing.T, repo *fakeRepo, rules ...domain.ValidationRule) *csvMigrationService {
+	t.Helper()
+	svc := newSvcWithRepo(t, repo, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+	svc.Rules = staticRules{set: domain.RuleSet{Rules: rules}}
+	return svc
+}
+
+func TestProcess_ErrorRule_RejectsRow(t *testing.T) {
+	max := decimal.NewFromInt(1000)
+	svc := newRulesSvc(t, &fakeRepo{}, domain.ValidationRule{
+		Code: "max_amount", Type: domain.RuleTypeAmountRange, Severity: domain.RuleSeverityError, MaxAmount: &max,
+	})
+
+	csv := "id,user_id,amount,datetime\n1,10,12.34,2024-06-01T00:00:00Z\n2,10,5000,2024-06-02T00:00:00Z\n"
+	res, err := svc.Process(context.Background(), r(csv), domain.MigrationOptions{})
+	var ae *shared.AppError
+	if !errors.As(err, &ae) || ae.Code != "validation_error" {
+		t.Fatalf("expected validation_error, got %v", err)
+	}
+	if len(res.Errors) != 1 {
+		t.Fatalf("expected one row error, got %v", res.Errors)
+	}
+	e := res.Errors[0]
+	if e.Row != 2 || e.Field != "amount" || e.Rule != "max_amount" || e.Severity != domain.RuleSeverityError || e.Message != "amount above the maximum of 1000" {
+		t.Fatalf("unexpected rule error: %+v", e)
+	}
+}
+
+func TestProcess_WarningRule_MigratesRow(t *testing.T) {
+	repo := &fakeRepo{}
+	svc := newRulesSvc(t, repo, domain.ValidationRule{
+		Code: "old_data", Type: domain.RuleTypeEarliestDatetime, Severity: domain.RuleSeverityWarning,
+		Earliest: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), Message: "check with compliance",
+	})
+
+	csv := "id,user_id,amount,datetime\n1,10,12.34,2019-06-01T00:00:00Z\n2,10,1.00,2024-06-02T00:00:00Z\n"
+	res, err := svc.Process(context.Background(), r(csv), domain.MigrationOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Inserted != 2 || len(res.Errors) != 0 {
+		t.Fatalf("expected both rows inserted without errors, got %+v", res)
+	}
+	if len(res.Warnings) != 1 || res.Warnings[0].Row != 1 || res.Warnings[0].Rule != "old_data" || res.Warnings[0].Message != "check with compliance" {
+		t.Fatalf("unexpected warnings: %+v", res.Warnings)
+	}
+}
+
+func TestValidate_RulesReportEveryBrokenRule(t *testing.T) {
+	svc := newRulesSvc(t, &fakeRepo{},
+		domain.ValidationRule{Code: "non_zero", Type: domain.RuleTypeNonZeroAmount, Severity: domain.RuleSeverityError},
+		domain.ValidationRule{Code: "users", Type: domain.RuleTypeUserIDRange, Severity: domain.RuleSeverityError, UserIDs: []domain.UserIDRange{{Min: 1, Max: 99}}},
+	)
+
+	csv := "id,user_id,amount,datetime\n1,10,1.00,2024-06-01T00:00:00Z\n2,500,0,2024-06-01T00:00:00Z\n"
+	res, err := svc.Validate(context.Background(), r(csv), domain.MigrationOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Rows != 2 || res.Rejected != 1 || len(res.Errors) != 2 {
+		t.Fatalf("expected one rejected row with two errors, got %+v", res)
+	}
+	if res.Errors[0].Rule != "non_zero" || res.Errors[1].Rule != "users" || res.Errors[1].Field != "user_id" {
+		t.Fatalf("unexpected errors: %+v", res.Errors)
+	}
+}
+
+func TestProcessStream_MaxPerUserPerDay(t *testing.T) {
+	repo := &fakeRepo{}
+	svc := newRulesSvc(t, repo, domain.ValidationRule{
+		Code: "daily_limit", Type: domain.RuleTypeMaxPerUserPerDay, Severity: domain.RuleSeverityError, Limit: 2,
+	})
+	svc.ChunkSize = 2
+
+	csv := "id,user_id,amount,datetime\n" +
+		"1,10,1.00,2024-06-01T08:00:00Z\n" +
+		"2,10,1.00,2024-06-01T09:00:00Z\n" +
+		"3,20,1.00,2024-06-01T09:00:00Z\n" +
+		"4,10,1.00,2024-06-01T23:00:00Z\n" +
+		"5,10,1.00,2024-06-02T00:00:00Z\n"
+	res, err := svc.ProcessStream(context.Background(), r(csv), domain.MigrationOptions{Mode: domain.MigrationModePartial})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Inserted != 4 || res.Rejected != 1 {
+		t.Fatalf("expected 4 inserted and 1 rejected, got %+v", res)
+	}
+	if len(res.Errors) != 1 || res.Errors[0].Row != 4 || res.Errors[0].Rule != "daily_limit" {
+		t.Fatalf("unexpected errors: %+v", res.Errors)
+	}
+	// Counts of earlier chunks come from the import, asked for each chunk's users and days only.
+	if got := repo.imports[0].perDayKeys; !reflect.DeepEqual(got, []int{1, 2, 1}) {
+		t.Fatalf("expected per-day lookups of 1, 2 and 1 keys, got %v", got)
+	}
+}
+
+func TestProcessAndProcessStream_RulesRunAfterInFileDuplicates(t *testing.T) {
+	rules := []domain.ValidationRule{
+		{Code: "daily_limit", Type: domain.RuleTypeMaxPerUserPerDay, Severity: domain.RuleSeverityError, Limit: 1},
+		{Code: "non_zero", Type: domain.RuleTypeNonZeroAmount, Severity: domain.RuleSeverityError},
+	}
+	// Row 2 repeats row 1 and must not count toward the daily limit; row 5 repeats row 4, which
+	// breaks a rule, and is still a duplicate.
+	csv := "id,user_id,amount,datetime\n" +
+		"1,10,1.00,2024-06-01T08:00:00Z\n" +
+		"1,10,1.00,2024-06-01T09:00:00Z\n" +
+		"2,10,1.00,2024-06-02T08:00:00Z\n" +
+		"3,10,0,2024-06-03T08:00:00Z\n" +
+		"3,10,1.00,2024-06-04T08:00:00Z\n" +
+		"4,10,1.00,2024-06-01T10:00:00Z\n"
+	opts := domain.MigrationOptions{Mode: domain.MigrationModePartial}
+
+	syncRepo := &fakeRepo{}
+	syncRes, err := newRulesSvc(t, syncRepo, rules...).Process(context.Background(), r(csv), opts)
+	if err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	streamRepo := &fakeRepo{}
+	svc := newRulesSvc(t, streamRepo, rules...)
+	svc.ChunkSize = 2
+	streamRes, err := svc.ProcessStream(context.Background(), r(csv), opts)
+	if err != nil {
+		t.Fatalf("process stream: %v", err)
+	}
+
+	for name, res := range map[string]services.MigrationResult{"Process": syncRes, "ProcessStream": streamRes} {
+		if res.Inserted != 2 || res.Rejected != 4 {
+			t.Fatalf("%s: expected 2 inserted and 4 rejected, got %+v", name, res)
+		}
+	}
+	if !reflect.DeepEqual(syncRes.Errors, streamRes.Errors) {
+		t.Fatalf("row errors differ:\nProcess:       %+v\nProcessStream: %+v", syncRes.Errors, streamRes.Errors)
+	}
+	want := []struct {
+		row  int
+		rule string
+	}{{2, ""}, {4, "non_zero"}, {5, ""}, {6, "daily_limit"}}
+	if len(streamRes.Errors) != len(want) {
+		t.Fatalf("unexpected errors: %+v", streamRes.Errors)
+	}
+	for i, w := range want {
+		if e := streamRes.Errors[i]; e.Row != w.row || e.Rule != w.rule {
+			t.Fatalf("error %d: expected row %d rule %q, got %+v", i, w.row, w.rule, e)
+		}
+	}
+	if got := capturedIDs(streamRepo); !reflect.DeepEqual(got, capturedIDs(syncRepo)) || !reflect.DeepEqual(got, []int64{1, 2}) {
+		t.Fatalf("expected ids 1 and 2 inserted by both, got %v and %v", capturedIDs(syncRepo), got)
+	}
+}
+
+func capturedIDs(repo *fakeRepo) []int64 {
+	ids := make([]int64, len(repo.captured))
+	for i, tx := range repo.captured {
+		ids[i] = tx.ID
+	}
+	return ids
+}
+
+func TestProcess_RulesUnavailable(t *testing.T) {
+	svc := newSvcWithRepo(t, &fakeRepo{}, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+	svc.Rules = staticRules{err: errors.New("no such file")}
+
+	_, err := svc.Process(context.Background(), r("id,user_id,amount,datetime\n"), domain.MigrationOptions{})
+	var ae *shared.AppError
+	if !errors.As(err, &ae) || ae.Kind != shared.InternalKind || ae.Code != "rules_unavailable" {
+		t.Fatalf("expected rules_unavailable, got %v", err)
+	}
+}
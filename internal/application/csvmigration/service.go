@@ -2,17 +2,18 @@ package csvmigration
 
 import (
 	"context"
+	"errors"
 	"io"
+	"sort"
 	"time"
 
+	"stori-challenge/internal/domain"
 	"stori-challenge/internal/ports/repositories"
+	portrules "stori-challenge/internal/ports/rules"
 	"stori-challenge/internal/ports/services"
 	"stori-challenge/internal/shared"
 )
 
-// expectedHeaders defines the strict order of required columns.
-var expectedHeaders = []string{"id", "user_id", "amount", "datetime"}
-
 // ParsedRow holds raw string values and metadata for error reporting.
 type ParsedRow struct {
 	RowNum      int
@@ -20,62 +21,377 @@ type ParsedRow struct {
 	UserIDStr   string
 	AmountStr   string
 	DatetimeStr string
+	TypeStr     string
 	Cols        int
 }
 
-// csvMigrationService implements services.MigrationService for CSV inputs.
+// csvMigrationService implements services.MigrationService for CSV inputs, and for the other
+// formats that can be read as records (see records.go).
 type csvMigrationService struct {
-	Repo    repositories.TransactionRepository
-	NowFunc func() time.Time
+	Repo      repositories.TransactionRepository
+	Profiles  repositories.MappingProfileRepository
+	Accounts  repositories.AccountMappingRepository
+	Batches   repositories.MigrationBatchRepository
+	Users     repositories.UserRepository
+	Rules     portrules.Source
+	NowFunc   func() time.Time
+	ChunkSize int
 }
 
 // NewCsvMigrationService constructs a CSV migration service.
-func NewCsvMigrationService(repo repositories.TransactionRepository) services.MigrationService {
+func NewCsvMigrationService(repo repositories.TransactionRepository, profiles repositories.MappingProfileRepository, accounts repositories.AccountMappingRepository, batches repositories.MigrationBatchRepository, users repositories.UserRepository, rules portrules.Source) services.MigrationService {
 	return &csvMigrationService{
-		Repo:    repo,
-		NowFunc: func() time.Time { return time.Now().UTC() },
+		Repo:      repo,
+		Profiles:  profiles,
+		Accounts:  accounts,
+		Batches:   batches,
+		Users:     users,
+		Rules:     rules,
+		NowFunc:   func() time.Time { return time.Now().UTC() },
+		ChunkSize: DefaultChunkSize,
 	}
 }
 
 // Ensure interface compliance.
 var _ services.MigrationService = (*csvMigrationService)(nil)
 
-// Process runs intake (caller validates file), parse+validate (single pass), conflict check, and bulk insert.
-func (s *csvMigrationService) Process(ctx context.Context, r io.Reader) (int, []services.RowError, error) {
-	txs, rows, vErrs, parseErr := s.readAndValidate(r)
+// Process runs intake (caller validates file), parse+validate (single pass), conflict check, and insert.
+// In partial mode failing rows are dropped instead of rejecting the file, as long as at least one row is inserted.
+// In idempotent mode rows already stored with the same content are skipped; a file made only of such rows succeeds.
+func (s *csvMigrationService) Process(ctx context.Context, r io.Reader, opts domain.MigrationOptions) (services.MigrationResult, error) {
+	cfg, appErr := s.loadReadConfig(ctx, opts)
+	if appErr != nil {
+		return services.MigrationResult{}, appErr
+	}
+	cfg.limit = newInflateLimit(MaxInflatedSize)
+	batch, errs, appErr := s.startBatch(ctx, r, opts)
+	if appErr != nil {
+		return services.MigrationResult{Errors: errs}, appErr
+	}
+	cfg.batchID = batch.id()
+	res, err := s.process(ctx, batch, opts, cfg)
+	batch.finish(ctx, res, err)
+	res.Errors, res.Summary = limitRowErrors(res.Errors, cfg)
+	res.Errors = cfg.locator.locate(res.Errors)
+	res.Warnings = cfg.locator.locate(cfg.rules.warningList())
+	res.Dialect = cfg.csvDialect()
+	res.BatchID = batch.id()
+	return res, err
+}
+
+func (s *csvMigrationService) process(ctx context.Context, batch *batchRun, opts domain.MigrationOptions, cfg readConfig) (services.MigrationResult, error) {
+	r := batch.input
+	txs, rows, vErrs, parseErr := s.readAndValidate(r, cfg)
 	if parseErr != nil {
 		// Header or CSV malformed → treat as validation error
-		return 0, []services.RowError{{
-			Row:     0,
-			Field:   "file",
-			Value:   "",
-			Message: "invalid or missing header",
-		}}, shared.NewBadRequest("validation_error", "validation failed", parseErr)
+		errs, appErr := readFailure(parseErr)
+		return services.MigrationResult{Errors: errs}, appErr
+	}
+	txs, rows, uErrs, err := cfg.users.filter(ctx, txs, rows)
+	if err != nil {
+		return services.MigrationResult{}, shared.NewInternal("db_failure", "database error", err)
 	}
+	vErrs = mergeRowErrors(vErrs, uErrs)
 
-	if len(vErrs) > 0 {
-		return 0, vErrs, shared.NewBadRequest("validation_error", "validation failed", nil)
+	if len(vErrs) > 0 && (!opts.IsPartial() || len(txs) == 0) {
+		return services.MigrationResult{Errors: vErrs}, shared.NewBadRequest("validation_error", "validation failed", nil)
 	}
 	if len(txs) == 0 {
-		return 0, []services.RowError{{
+		return services.MigrationResult{Errors: []services.RowError{{
 			Row:     0,
 			Field:   "file",
 			Value:   "",
 			Message: "CSV contains no data rows",
-		}}, shared.NewBadRequest("validation_error", "validation failed", nil)
+		}}}, shared.NewBadRequest("validation_error", "validation failed", nil)
+	}
+
+	c, err := s.findConflicts(ctx, s.Repo, txs, rows, opts.Idempotent)
+	if err != nil {
+		return services.MigrationResult{}, shared.NewInternal("db_failure", "database error", err)
+	}
+	if len(c.errs) > 0 && !opts.IsPartial() {
+		return services.MigrationResult{Errors: c.errs}, shared.NewConflict("duplicate_id", "conflict", nil)
+	}
+	txs, rows = withoutRows(txs, rows, c.existing())
+
+	rowErrs := mergeRowErrors(vErrs, c.errs)
+	if len(txs) == 0 && len(c.identical) == 0 {
+		// Partial mode with nothing left to insert: every row was either invalid or conflicting.
+		if len(vErrs) > 0 {
+			return services.MigrationResult{Errors: rowErrs}, shared.NewBadRequest("validation_error", "validation failed", nil)
+		}
+		return services.MigrationResult{Errors: rowErrs}, shared.NewConflict("duplicate_id", "conflict", nil)
+	}
+
+	// The rows are inserted through an import, so the floor check runs in the transaction that
+	// inserts them.
+	imp, err := s.Repo.BeginImport(ctx)
+	if err != nil {
+		return services.MigrationResult{}, shared.NewInternal("db_failure", "database error", err)
+	}
+	defer func() {
+		_ = imp.Rollback()
+	}()
+	if err := stageAll(ctx, imp, txs, rows); err != nil {
+		return services.MigrationResult{}, shared.NewInternal("db_failure", "database error", err)
+	}
+	floorErrs, err := cfg.floor.collect(ctx, imp)
+	if err != nil {
+		return services.MigrationResult{}, shared.NewInternal("db_failure", "database error", err)
+	}
+	if len(floorErrs) > 0 {
+		return services.MigrationResult{Errors: mergeRowErrors(rowErrs, floorErrs)}, balanceFloorError()
+	}
+
+	if errs, appErr := batch.check(ctx); appErr != nil {
+		return services.MigrationResult{Errors: errs}, appErr
+	}
+	if err := cfg.users.provision(ctx, imp); err != nil {
+		return services.MigrationResult{}, shared.NewInternal("db_failure", "database error", err)
+	}
+	if _, err := imp.Commit(ctx); err != nil {
+		return services.MigrationResult{}, shared.NewInternal("db_failure", "database error", err)
+	}
+	return services.MigrationResult{
+		Inserted:       len(txs),
+		Rejected:       countRejectedRows(rowErrs),
+		AlreadyPresent: len(c.identical),
+		Errors:         rowErrs,
+	}, nil
+}
+
+// readFailure maps an error that stopped reading the input to what the migration reports: a
+// file-level validation error, or a database failure from a lookup made while reading.
+func readFailure(err error) ([]services.RowError, *shared.AppError) {
+	var le *lookupError
+	if errors.As(err, &le) {
+		return nil, shared.NewInternal("db_failure", "database error", le.err)
+	}
+	return invalidFileErrors(err), shared.NewBadRequest("validation_error", "validation failed", err)
+}
+
+// invalidFileErrors describes a file that cannot be read past its header.
+func invalidFileErrors(err error) []services.RowError {
+	msg, file := "invalid or missing header", ""
+	var fe *fileError
+	if errors.As(err, &fe) {
+		msg, file = fe.msg, fe.file
+	}
+	return []services.RowError{{
+		File:    file,
+		Row:     0,
+		Field:   "file",
+		Value:   "",
+		Message: msg,
+	}}
+}
+
+// readConfig holds what reading a file needs besides the input, resolved once per migration.
+type readConfig struct {
+	format      domain.InputFormat
+	profile     *domain.MappingProfile
+	dates       datetimeParser
+	amounts     amountParser
+	rounding    domain.AmountRounding
+	compression domain.Compression
+	encoding    domain.Encoding
+	// limit caps the decompressed size of compressed uploads.
+	limit *inflateLimit
+	// sheet selects the worksheet of an xlsx upload.
+	sheet string
+	// userID and accountUser resolve the owner of statement transactions (OFX).
+	userID      int64
+	accountUser func(accountID string) (int64, bool, error)
+	// locator is filled in by the record reader and applied to the reported row errors.
+	locator *rowLocator
+	// delimiter separates CSV fields; zero detects it per file.
+	delimiter rune
+	// dialect is set by the first CSV file read and reported with the result.
+	dialect *domain.CSVDialect
+	// batchID links migrated transactions to the batch of the run; zero without one.
+	batchID int64
+	// maxErrors caps the row errors reported in detail.
+	maxErrors int
+	// rules applies the configured validation rules to the valid rows; nil applies none.
+	rules *ruleChecker
+	// floor replays the inserted rows against the overdraft floor; nil when not requested.
+	floor *floorCheck
+	// users applies the unknown-user policy; nil without a users repository.
+	users *userCheck
+}
+
+// recordDialect keeps d as the dialect of the upload unless a file was already read.
+func (c readConfig) recordDialect(d *domain.CSVDialect) {
+	if c.dialect != nil && c.dialect.Delimiter == "" {
+		*c.dialect = *d
+	}
+}
+
+// source is where the transaction read at run row came from.
+func (c readConfig) source(row int) domain.TransactionSource {
+	src := domain.TransactionSource{BatchID: c.batchID}
+	src.File, src.Row = c.locator.find(row)
+	return src
+}
+
+// csvDialect returns the recorded dialect, or nil when nothing was read as CSV.
+func (c readConfig) csvDialect() *domain.CSVDialect {
+	if c.dialect == nil || c.dialect.Delimiter == "" {
+		return nil
+	}
+	d := *c.dialect
+	return &d
+}
+
+// loadReadConfig resolves the settings of opts and the configured validation rules.
+func (s *csvMigrationService) loadReadConfig(ctx context.Context, opts domain.MigrationOptions) (readConfig, *shared.AppError) {
+	cfg, appErr := s.loadFileConfig(ctx, opts)
+	if appErr != nil {
+		return readConfig{}, appErr
+	}
+	ruleSet, appErr := s.loadRules(ctx)
+	if appErr != nil {
+		return readConfig{}, appErr
 	}
+	cfg.rules = newRuleChecker(ruleSet, cfg.maxErrors, cfg.locator)
+	return cfg, nil
+}
 
-	existing, err := s.checkConflicts(ctx, txs)
+// loadFileConfig resolves the input format, mapping profile, datetime and amount settings of
+// opts. It applies no validation rules, which only reading the file does not need.
+func (s *csvMigrationService) loadFileConfig(ctx context.Context, opts domain.MigrationOptions) (readConfig, *shared.AppError) {
+	if opts.Format != "" && !opts.Format.IsValid() {
+		return readConfig{}, shared.NewBadRequest("invalid_format", "unsupported input format", nil)
+	}
+	if !opts.Compression.IsValid() {
+		return readConfig{}, shared.NewBadRequest("invalid_compression", "unsupported compression", nil)
+	}
+	if opts.DatetimeFormat != "" && !opts.DatetimeFormat.IsValid() {
+		return readConfig{}, shared.NewBadRequest("invalid_datetime_format", "unsupported datetime format", nil)
+	}
+	dates, err := newDatetimeParser(opts.DatetimeFormat, opts.Timezone)
 	if err != nil {
-		return 0, nil, shared.NewInternal("db_failure", "database error", err)
+		return readConfig{}, shared.NewBadRequest("invalid_timezone", "unknown time zone", err)
+	}
+	amounts, ok := newAmountParser(opts.Locale)
+	if !ok {
+		return readConfig{}, shared.NewBadRequest("invalid_locale", "unsupported locale", nil)
+	}
+	rounding := opts.Rounding()
+	if !rounding.IsValid() {
+		return readConfig{}, shared.NewBadRequest("invalid_amount_rounding", "unsupported amount rounding", nil)
+	}
+	var delimiter rune
+	if opts.Delimiter != "" {
+		d, ok := domain.ParseDelimiter(opts.Delimiter)
+		if !ok {
+			return readConfig{}, shared.NewBadRequest("invalid_delimiter", "unsupported delimiter", nil)
+		}
+		delimiter = rune(d[0])
 	}
-	if len(existing) > 0 {
-		cErrs := s.buildConflictErrors(rows, existing)
-		return 0, cErrs, shared.NewConflict("duplicate_id", "conflict", nil)
+	profile, appErr := s.loadProfile(ctx, opts)
+	if appErr != nil {
+		return readConfig{}, appErr
 	}
+	maxErrors := opts.MaxErrors
+	if maxErrors <= 0 {
+		maxErrors = domain.DefaultMaxErrors
+	}
+	locator := &rowLocator{}
+	return readConfig{
+		format:      opts.Format,
+		profile:     profile,
+		dates:       dates,
+		amounts:     amounts,
+		rounding:    rounding,
+		compression: opts.Compression,
+		encoding:    opts.Encoding,
+		sheet:       opts.Sheet,
+		userID:      opts.UserID,
+		accountUser: s.accountUser(ctx),
+		locator:     locator,
+		delimiter:   delimiter,
+		dialect:     &domain.CSVDialect{},
+		maxErrors:   maxErrors,
+		floor:       newFloorCheck(opts.OverdraftFloor),
+		users:       newUserCheck(s.Users, opts.UnknownUsers),
+	}, nil
+}
+
+// loadRules returns the configured validation rules, or none without a source.
+func (s *csvMigrationService) loadRules(ctx context.Context) (domain.RuleSet, *shared.AppError) {
+	if s.Rules == nil {
+		return domain.RuleSet{}, nil
+	}
+	set, err := s.Rules.Rules(ctx)
+	if err != nil {
+		return domain.RuleSet{}, shared.NewInternal("rules_unavailable", "validation rules unavailable", err)
+	}
+	return set, nil
+}
+
+// accountUser returns a lookup of the user a statement account is mapped to.
+func (s *csvMigrationService) accountUser(ctx context.Context) func(string) (int64, bool, error) {
+	if s.Accounts == nil {
+		return nil
+	}
+	return func(accountID string) (int64, bool, error) {
+		m, found, err := s.Accounts.GetByAccount(ctx, accountID)
+		return m.UserID, found, err
+	}
+}
+
+// loadProfile returns the mapping profile opts name, or nil when they name none. Columns stored
+// with opts are used without looking the profile up.
+func (s *csvMigrationService) loadProfile(ctx context.Context, opts domain.MigrationOptions) (*domain.MappingProfile, *shared.AppError) {
+	name := opts.Profile
+	if name == "" {
+		return nil, nil
+	}
+	if opts.ProfileColumns != nil {
+		return &domain.MappingProfile{Name: name, Columns: opts.ProfileColumns}, nil
+	}
+	if s.Profiles == nil {
+		return nil, shared.NewBadRequest("mapping_profile_not_found", "mapping profile not found", nil)
+	}
+	p, found, err := s.Profiles.GetByName(ctx, name)
+	if err != nil {
+		return nil, shared.NewInternal("db_failure", "database error", err)
+	}
+	if !found {
+		return nil, shared.NewBadRequest("mapping_profile_not_found", "mapping profile not found", nil)
+	}
+	return &p, nil
+}
+
+// mergeRowErrors combines validation and conflict errors ordered by row. A conflict is dropped when
+// its row already failed validation (e.g. an in-file duplicate of an id that also exists in DB).
+func mergeRowErrors(vErrs, cErrs []services.RowError) []services.RowError {
+	if len(cErrs) == 0 {
+		return vErrs
+	}
+	failed := make(map[int]bool, len(vErrs))
+	for _, e := range vErrs {
+		failed[e.Row] = true
+	}
+	out := make([]services.RowError, 0, len(vErrs)+len(cErrs))
+	out = append(out, vErrs...)
+	for _, e := range cErrs {
+		if !failed[e.Row] {
+			out = append(out, e)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Row < out[j].Row })
+	return out
+}
 
-	if err := s.insertAll(ctx, txs); err != nil {
-		return 0, nil, shared.NewInternal("db_failure", "database error", err)
+// countRejectedRows returns how many distinct data rows have at least one error.
+func countRejectedRows(errs []services.RowError) int {
+	rows := make(map[int]bool, len(errs))
+	for _, e := range errs {
+		if e.Row > 0 {
+			rows[e.Row] = true
+		}
 	}
-	return len(txs), nil, nil
+	return len(rows)
 }
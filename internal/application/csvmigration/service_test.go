@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"io"
+	"sort"
 	"strings"
 	"testing"
 	"time"
@@ -17,9 +18,13 @@ import (
 
 type fakeRepo struct {
 	exists    map[int64]bool
+	stored    map[int64]domain.Transaction
 	existsErr error
 	captured  []domain.Transaction
-	bulkErr   error
+	commitErr error
+	imports   []*fakeImport
+	// history is the stored transactions seen by the overdraft floor replay.
+	history []domain.Transaction
 }
 
 func (f *fakeRepo) ExistsByIDs(ctx context.Context, ids []int64) (map[int64]bool, error) {
@@ -33,24 +38,186 @@ func (f *fakeRepo) ExistsByIDs(ctx context.Context, ids []int64) (map[int64]bool
 	return out, nil
 }
 
-func (f *fakeRepo) BulkInsert(ctx context.Context, txs []domain.Transaction) error {
-	f.captured = append(f.captured, txs...)
-	return f.bulkErr
+func (f *fakeRepo) GetByIDs(ctx context.Context, ids []int64) (map[int64]domain.Transaction, error) {
+	if f.existsErr != nil {
+		return nil, f.existsErr
+	}
+	out := make(map[int64]domain.Transaction)
+	for _, id := range ids {
+		if tx, ok := f.stored[id]; ok {
+			out[id] = tx
+		}
+	}
+	return out, nil
+}
+
+func (f *fakeRepo) BeginImport(ctx context.Context) (repositories.TransactionImport, error) {
+	imp := &fakeImport{repo: f, firstRow: map[int64]int{}, present: map[int64]bool{}, rejected: map[int64]bool{}}
+	f.imports = append(f.imports, imp)
+	return imp, nil
+}
+
+// fakeImport stages in memory and appends to the repo's captured rows on Commit.
+type fakeImport struct {
+	repo      *fakeRepo
+	staged    []domain.Transaction
+	firstRow  map[int64]int
+	present   map[int64]bool
+	rejected  map[int64]bool
+	stageN    int
+	committed bool
+	// users holds the users registered by RegisterUsers, ordered by id.
+	users []int64
+	// perDayKeys holds how many keys each StagedPerDay call asked for.
+	perDayKeys []int
+}
+
+func (i *fakeImport) Stage(ctx context.Context, txs []domain.Transaction, rows []int) ([]repositories.StagedDuplicate, error) {
+	i.stageN++
+	var dups []repositories.StagedDuplicate
+	for k, tx := range txs {
+		if first, ok := i.firstRow[tx.ID]; ok {
+			dups = append(dups, repositories.StagedDuplicate{ID: tx.ID, Row: rows[k], FirstRow: first})
+			continue
+		}
+		i.firstRow[tx.ID] = rows[k]
+		i.staged = append(i.staged, tx)
+	}
+	return dups, nil
 }
 
+func (i *fakeImport) ExistsByIDs(ctx context.Context, ids []int64) (map[int64]bool, error) {
+	return i.repo.ExistsByIDs(ctx, ids)
+}
+
+func (i *fakeImport) GetByIDs(ctx context.Context, ids []int64) (map[int64]domain.Transaction, error) {
+	return i.repo.GetByIDs(ctx, ids)
+}
+
+func (i *fakeImport) MarkPresent(ctx context.Context, ids []int64) error {
+	for _, id := range ids {
+		i.present[id] = true
+	}
+	return nil
+}
+
+func (i *fakeImport) Reject(ctx context.Context, ids []int64) error {
+	for _, id := range ids {
+		i.rejected[id] = true
+	}
+	return nil
+}
+
+// skips reports whether Commit leaves out the staged id.
+func (i *fakeImport) skips(id int64) bool {
+	return i.present[id] || i.rejected[id]
+}
+
+func (i *fakeImport) StagedPerDay(ctx context.Context, keys []repositories.UserDay) (map[repositories.UserDay]int, error) {
+	i.perDayKeys = append(i.perDayKeys, len(keys))
+	want := make(map[repositories.UserDay]bool, len(keys))
+	for _, k := range keys {
+		want[k] = true
+	}
+	out := make(map[repositories.UserDay]int)
+	for _, tx := range i.staged {
+		if k := userDayOf(tx); want[k] {
+			out[k]++
+		}
+	}
+	return out, nil
+}
+
+// BelowFloor replays the repo's history of the users with staged rows in memory.
+func (i *fakeImport) BelowFloor(ctx context.Context, floor decimal.Decimal, fn func(repositories.FloorBreach) error) error {
+	type entry struct {
+		tx  domain.Transaction
+		row int
+	}
+	users := make(map[int64]bool)
+	var replay []entry
+	for _, tx := range i.staged {
+		if !i.skips(tx.ID) {
+			users[tx.UserID] = true
+			replay = append(replay, entry{tx: tx, row: i.firstRow[tx.ID]})
+		}
+	}
+	for _, tx := range i.repo.history {
+		if users[tx.UserID] {
+			replay = append(replay, entry{tx: tx})
+		}
+	}
+	sort.SliceStable(replay, func(a, b int) bool {
+		x, y := replay[a].tx, replay[b].tx
+		if !x.DateTime.Equal(y.DateTime) {
+			return x.DateTime.Before(y.DateTime)
+		}
+		return x.ID < y.ID
+	})
+	balances := make(map[int64]decimal.Decimal)
+	var breaches []repositories.FloorBreach
+	for _, e := range replay {
+		balance := balances[e.tx.UserID].Add(e.tx.Amount)
+		balances[e.tx.UserID] = balance
+		if e.row > 0 && e.tx.Amount.IsNegative() && balance.LessThan(floor) {
+			breaches = append(breaches, repositories.FloorBreach{Row: e.row, UserID: e.tx.UserID, Amount: e.tx.Amount, Balance: balance})
+		}
+	}
+	sort.SliceStable(breaches, func(a, b int) bool { return breaches[a].Row < breaches[b].Row })
+	for _, b := range breaches {
+		if err := fn(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (i *fakeImport) RegisterUsers(ctx context.Context) (int, error) {
+	seen := make(map[int64]bool)
+	for _, tx := range i.staged {
+		if !i.skips(tx.ID) && !seen[tx.UserID] {
+			seen[tx.UserID] = true
+			i.users = append(i.users, tx.UserID)
+		}
+	}
+	sort.Slice(i.users, func(a, b int) bool { return i.users[a] < i.users[b] })
+	return len(i.users), nil
+}
+
+func (i *fakeImport) Commit(ctx context.Context) (int, error) {
+	if i.repo.commitErr != nil {
+		return 0, i.repo.commitErr
+	}
+	i.committed = true
+	n := 0
+	for _, tx := range i.staged {
+		if !i.skips(tx.ID) {
+			i.repo.captured = append(i.repo.captured, tx)
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (i *fakeImport) Rollback() error { return nil }
+
 // Satisfy new interface methods; not used by csvmigration tests.
 func (f *fakeRepo) UserHasAnyTransaction(ctx context.Context, userID int64) (bool, error) {
 	// Consider user has transactions if exists map is non-empty for realism in tests using this fake.
 	return len(f.exists) > 0, nil
 }
 
+func (f *fakeRepo) GetSource(ctx context.Context, id int64) (domain.TransactionSource, bool, error) {
+	return domain.TransactionSource{}, false, nil
+}
+
 func (f *fakeRepo) GetUserBalanceSummary(ctx context.Context, userID int64, from, to time.Time) (balance decimal.Decimal, totalDebits decimal.Decimal, totalCredits decimal.Decimal, err error) {
 	return decimal.Zero, decimal.Zero, decimal.Zero, nil
 }
 
 func newSvcWithRepo(t *testing.T, repo repositories.TransactionRepository, now time.Time) *csvMigrationService {
 	t.Helper()
-	svc := NewCsvMigrationService(repo).(*csvMigrationService)
+	svc := NewCsvMigrationService(repo, nil, nil, nil, nil, nil).(*csvMigrationService)
 	svc.NowFunc = func() time.Time { return now }
 	return svc
 }
@@ -63,7 +230,8 @@ func TestProcess_HappyPath(t *testing.T) {
 	svc := newSvcWithRepo(t, repo, now)
 
 	csv := "id,user_id,amount,datetime\n1,10,12.34,2024-06-01T00:00:00Z\n2,20,-5.00,2024-06-02T00:00:00Z\n"
-	inserted, items, err := svc.Process(context.Background(), r(csv))
+	res, err := svc.Process(context.Background(), r(csv), domain.MigrationOptions{})
+	inserted, items := res.Inserted, res.Errors
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -84,7 +252,8 @@ func TestProcess_HeaderInvalid(t *testing.T) {
 	svc := newSvcWithRepo(t, repo, now)
 
 	csv := "bad,header,here,now\n"
-	inserted, items, err := svc.Process(context.Background(), r(csv))
+	res, err := svc.Process(context.Background(), r(csv), domain.MigrationOptions{})
+	inserted, items := res.Inserted, res.Errors
 	if inserted != 0 {
 		t.Fatalf("expected inserted 0, got %d", inserted)
 	}
@@ -103,7 +272,8 @@ func TestProcess_ConflictIDs(t *testing.T) {
 	svc := newSvcWithRepo(t, repo, now)
 
 	csv := "id,user_id,amount,datetime\n1,10,12.34,2024-06-01T00:00:00Z\n"
-	inserted, items, err := svc.Process(context.Background(), r(csv))
+	res, err := svc.Process(context.Background(), r(csv), domain.MigrationOptions{})
+	inserted, items := res.Inserted, res.Errors
 	if inserted != 0 {
 		t.Fatalf("expected inserted 0, got %d", inserted)
 	}
@@ -121,7 +291,7 @@ func TestProcess_DBErrorOnExists(t *testing.T) {
 	repo := &fakeRepo{existsErr: errors.New("db down")}
 	svc := newSvcWithRepo(t, repo, now)
 	csv := "id,user_id,amount,datetime\n1,10,12.34,2024-06-01T00:00:00Z\n"
-	_, _, err := svc.Process(context.Background(), r(csv))
+	_, err := svc.Process(context.Background(), r(csv), domain.MigrationOptions{})
 	var ae *shared.AppError
 	if !errors.As(err, &ae) || ae.Kind != shared.InternalKind {
 		t.Fatalf("expected Internal AppError, got %v", err)
@@ -135,7 +305,8 @@ func TestProcess_ValidationErrors(t *testing.T) {
 
 	// amount invalid, datetime future
 	csv := "id,user_id,amount,datetime\n1,10,xx,2025-06-01T00:00:00Z\n"
-	_, items, err := svc.Process(context.Background(), r(csv))
+	res, err := svc.Process(context.Background(), r(csv), domain.MigrationOptions{})
+	items := res.Errors
 	var ae *shared.AppError
 	if !errors.As(err, &ae) || ae.Kind != shared.BadRequestKind {
 		t.Fatalf("expected BadRequest AppError, got %v", err)
@@ -144,3 +315,299 @@ func TestProcess_ValidationErrors(t *testing.T) {
 		t.Fatalf("expected validation items, got none")
 	}
 }
+
+func TestProcess_PartialMode_InsertsValidRowsAndReportsRejected(t *testing.T) {
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	repo := &fakeRepo{exists: map[int64]bool{3: true}}
+	svc := newSvcWithRepo(t, repo, now)
+
+	csv := "id,user_id,amount,datetime\n" +
+		"1,10,12.34,2024-06-01T00:00:00Z\n" +
+		"2,10,xx,2024-06-01T00:00:00Z\n" +
+		"3,10,1.00,2024-06-01T00:00:00Z\n" +
+		"4,10,2.00,2024-06-01T00:00:00Z\n"
+	res, err := svc.Process(context.Background(), r(csv), domain.MigrationOptions{Mode: domain.MigrationModePartial})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Inserted != 2 || res.Rejected != 2 {
+		t.Fatalf("expected 2 inserted and 2 rejected, got %+v", res)
+	}
+	if len(res.Errors) != 2 || res.Errors[0].Row != 2 || res.Errors[1].Row != 3 {
+		t.Fatalf("expected errors for rows 2 and 3, got %v", res.Errors)
+	}
+	if len(repo.captured) != 2 || repo.captured[0].ID != 1 || repo.captured[1].ID != 4 {
+		t.Fatalf("unexpected inserted rows: %v", repo.captured)
+	}
+}
+
+func TestProcess_PartialMode_NoValidRows_ReturnsBadRequest(t *testing.T) {
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	repo := &fakeRepo{}
+	svc := newSvcWithRepo(t, repo, now)
+
+	csv := "id,user_id,amount,datetime\n1,10,xx,2024-06-01T00:00:00Z\n"
+	res, err := svc.Process(context.Background(), r(csv), domain.MigrationOptions{Mode: domain.MigrationModePartial})
+	var ae *shared.AppError
+	if !errors.As(err, &ae) || ae.Kind != shared.BadRequestKind {
+		t.Fatalf("expected BadRequest AppError, got %v", err)
+	}
+	if len(res.Errors) == 0 || len(repo.captured) != 0 {
+		t.Fatalf("expected errors and nothing inserted, got %+v captured=%v", res, repo.captured)
+	}
+}
+
+func TestProcess_PartialMode_CapsErrorsAndSummarizesThem(t *testing.T) {
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	repo := &fakeRepo{}
+	svc := newSvcWithRepo(t, repo, now)
+
+	csv := "id,user_id,amount,datetime\n" +
+		"1,x,xx,2024-06-01T00:00:00Z\n" +
+		"2,10,xx,2024-06-01T00:00:00Z\n" +
+		"3,10,1.00,2024-06-01T00:00:00Z\n" +
+		"4,10,xx,2024-06-01T00:00:00Z\n"
+	res, err := svc.Process(context.Background(), r(csv), domain.MigrationOptions{Mode: domain.MigrationModePartial, MaxErrors: 2})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Inserted != 1 || res.Rejected != 3 {
+		t.Fatalf("expected 1 inserted and 3 rejected, got %+v", res)
+	}
+	if len(res.Errors) != 3 || res.Errors[0].Field != "user_id" || res.Errors[1].Field != "amount" || res.Errors[2].Value != "2" {
+		t.Fatalf("expected both errors of row 1 and the omitted note, got %+v", res.Errors)
+	}
+	sum := res.Summary
+	if sum == nil || sum.Total != 4 || sum.Omitted != 2 || len(sum.Groups) != 2 {
+		t.Fatalf("unexpected summary: %+v", sum)
+	}
+	if g := sum.Groups[0]; g.Field != "amount" || g.Count != 3 || len(g.SampleRows) != 3 || g.SampleRows[2] != 4 {
+		t.Fatalf("unexpected first group: %+v", g)
+	}
+}
+
+func TestValidate_ReportsErrorsAndStatsWithoutWriting(t *testing.T) {
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	repo := &fakeRepo{exists: map[int64]bool{3: true}}
+	svc := newSvcWithRepo(t, repo, now)
+
+	csv := "id,user_id,amount,datetime\n" +
+		"1,10,12.50,2024-06-02T00:00:00Z\n" +
+		"2,10,-2.50,2024-06-01T00:00:00Z\n" +
+		"3,20,1.00,2024-06-03T00:00:00Z\n" +
+		"4,30,xx,2024-06-03T00:00:00Z\n" +
+		"5,20,4.00,2024-06-05T00:00:00Z\n"
+	rep, err := svc.Validate(context.Background(), r(csv), domain.MigrationOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repo.captured) != 0 || len(repo.imports) != 0 {
+		t.Fatalf("validate must not write")
+	}
+	if rep.Rows != 5 || rep.Rejected != 2 || rep.WouldInsert != 0 || len(rep.Errors) != 2 {
+		t.Fatalf("unexpected report: %+v", rep)
+	}
+	st := rep.Stats
+	if st.Rows != 3 || st.DistinctUsers != 2 {
+		t.Fatalf("unexpected counts: %+v", st)
+	}
+	if !st.From.Equal(time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)) || !st.To.Equal(time.Date(2024, 6, 5, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("unexpected range: %v - %v", st.From, st.To)
+	}
+	if !st.TotalCredits.Equal(decimal.RequireFromString("16.5")) || !st.TotalDebits.Equal(decimal.RequireFromString("2.5")) {
+		t.Fatalf("unexpected totals: credits=%s debits=%s", st.TotalCredits, st.TotalDebits)
+	}
+	if len(st.BalanceChanges) != 2 || st.BalanceChanges[0].UserID != 10 || !st.BalanceChanges[0].Change.Equal(decimal.NewFromInt(10)) ||
+		st.BalanceChanges[1].UserID != 20 || !st.BalanceChanges[1].Change.Equal(decimal.NewFromInt(4)) {
+		t.Fatalf("unexpected balance changes: %+v", st.BalanceChanges)
+	}
+
+	rep, err = svc.Validate(context.Background(), r(csv), domain.MigrationOptions{Mode: domain.MigrationModePartial})
+	if err != nil || rep.WouldInsert != 3 {
+		t.Fatalf("expected 3 rows to insert in partial mode, got %+v err=%v", rep, err)
+	}
+}
+
+func TestValidate_HeaderInvalid_ReturnsBadRequest(t *testing.T) {
+	svc := newSvcWithRepo(t, &fakeRepo{}, time.Now().UTC())
+	rep, err := svc.Validate(context.Background(), r("bad,header,here,now\n"), domain.MigrationOptions{})
+	var ae *shared.AppError
+	if !errors.As(err, &ae) || ae.Kind != shared.BadRequestKind {
+		t.Fatalf("expected BadRequest AppError, got %v", err)
+	}
+	if len(rep.Errors) != 1 || rep.Errors[0].Field != "file" {
+		t.Fatalf("expected one file-level error item, got %v", rep.Errors)
+	}
+}
+
+func storedTx(id, userID int64, amount, dt string) domain.Transaction {
+	amt := decimal.RequireFromString(amount)
+	t, _ := time.Parse(time.RFC3339, dt)
+	return domain.Transaction{ID: id, UserID: userID, Amount: amt, DateTime: t.UTC(), Type: domain.DetermineTransactionType(amt)}
+}
+
+func TestProcess_Idempotent_IdenticalRowsAreNoOps(t *testing.T) {
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	repo := &fakeRepo{stored: map[int64]domain.Transaction{
+		1: storedTx(1, 10, "12.34", "2024-06-01T00:00:00Z"),
+	}}
+	svc := newSvcWithRepo(t, repo, now)
+
+	// 12.340 and an offset datetime are the same values as stored.
+	csv := "id,user_id,amount,datetime\n1,10,12.340,2024-06-01T02:00:00+02:00\n2,20,-5.00,2024-06-02T00:00:00Z\n"
+	res, err := svc.Process(context.Background(), r(csv), domain.MigrationOptions{Idempotent: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Inserted != 1 || res.AlreadyPresent != 1 || len(res.Errors) != 0 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if len(repo.captured) != 1 || repo.captured[0].ID != 2 {
+		t.Fatalf("unexpected inserted rows: %v", repo.captured)
+	}
+
+	// Retrying a file that is fully stored succeeds without inserting anything.
+	csv = "id,user_id,amount,datetime\n1,10,12.34,2024-06-01T00:00:00Z\n"
+	res, err = svc.Process(context.Background(), r(csv), domain.MigrationOptions{Idempotent: true})
+	if err != nil || res.Inserted != 0 || res.AlreadyPresent != 1 {
+		t.Fatalf("expected no-op retry, got %+v err=%v", res, err)
+	}
+}
+
+func TestProcess_Idempotent_DifferentContentIsConflictWithStoredValues(t *testing.T) {
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	repo := &fakeRepo{stored: map[int64]domain.Transaction{
+		1: storedTx(1, 10, "12.34", "2024-06-01T00:00:00Z"),
+	}}
+	svc := newSvcWithRepo(t, repo, now)
+
+	csv := "id,user_id,amount,datetime\n1,11,12.35,2024-06-01T00:00:00Z\n"
+	res, err := svc.Process(context.Background(), r(csv), domain.MigrationOptions{Idempotent: true})
+	var ae *shared.AppError
+	if !errors.As(err, &ae) || ae.Kind != shared.ConflictKind {
+		t.Fatalf("expected Conflict AppError, got %v", err)
+	}
+	if len(res.Errors) != 2 {
+		t.Fatalf("expected one error per differing field, got %v", res.Errors)
+	}
+	if res.Errors[0].Field != "user_id" || res.Errors[0].Value != "11" || !strings.Contains(res.Errors[0].Message, "user_id 10") {
+		t.Fatalf("unexpected user_id error: %+v", res.Errors[0])
+	}
+	if res.Errors[1].Field != "amount" || res.Errors[1].Value != "12.35" || !strings.Contains(res.Errors[1].Message, "amount 12.34") {
+		t.Fatalf("unexpected amount error: %+v", res.Errors[1])
+	}
+}
+
+type fakeProfileRepo struct {
+	profiles map[string]domain.MappingProfile
+}
+
+func (f *fakeProfileRepo) Save(ctx context.Context, p domain.MappingProfile) (domain.MappingProfile, error) {
+	f.profiles[p.Name] = p
+	return p, nil
+}
+
+func (f *fakeProfileRepo) GetByName(ctx context.Context, name string) (domain.MappingProfile, bool, error) {
+	p, ok := f.profiles[name]
+	return p, ok, nil
+}
+
+func (f *fakeProfileRepo) List(ctx context.Context) ([]domain.MappingProfile, error) {
+	return nil, nil
+}
+
+func (f *fakeProfileRepo) Delete(ctx context.Context, name string) (bool, error) {
+	return false, nil
+}
+
+func TestProcess_WithProfile_MapsPartnerHeader(t *testing.T) {
+	repo := &fakeRepo{}
+	svc := newSvcWithRepo(t, repo, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+	svc.Profiles = &fakeProfileRepo{profiles: map[string]domain.MappingProfile{"bank": {
+		Name:    "bank",
+		Columns: map[string][]string{domain.ColumnID: {"Reference"}, domain.ColumnUserID: {"Account Holder"}, domain.ColumnDatetime: {"Booking Date"}},
+	}}}
+
+	csv := "Booking Date,Reference,Amount,Account Holder\n2024-06-01T00:00:00Z,1,12.34,10\n"
+	res, err := svc.Process(context.Background(), r(csv), domain.MigrationOptions{Profile: "bank"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v (%+v)", err, res.Errors)
+	}
+	if res.Inserted != 1 || len(repo.captured) != 1 || repo.captured[0].ID != 1 || repo.captured[0].UserID != 10 {
+		t.Fatalf("unexpected result %+v, captured %+v", res, repo.captured)
+	}
+
+	_, err = svc.Process(context.Background(), r(csv), domain.MigrationOptions{Profile: "missing"})
+	var ae *shared.AppError
+	if !errors.As(err, &ae) || ae.Code != "mapping_profile_not_found" {
+		t.Fatalf("expected mapping_profile_not_found, got %v", err)
+	}
+}
+
+func TestProcess_LocalDatetimes_NormalisedToUTCBeforeFutureCheck(t *testing.T) {
+	// 2024-07-01 00:00 UTC is 2024-06-30 18:00 in Mexico City.
+	repo := &fakeRepo{}
+	svc := newSvcWithRepo(t, repo, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+	opts := domain.MigrationOptions{DatetimeFormat: domain.DatetimeFormatLocal, Timezone: "America/Mexico_City"}
+
+	csv := "id,user_id,amount,datetime\n1,10,12.34,2024-06-30 17:59:59\n2,10,1.00,2024-06-30 18:00:01\n"
+	res, err := svc.Process(context.Background(), r(csv), opts)
+	var ae *shared.AppError
+	if !errors.As(err, &ae) || ae.Kind != shared.BadRequestKind {
+		t.Fatalf("expected BadRequest AppError, got %v", err)
+	}
+	if len(res.Errors) != 1 || res.Errors[0].Row != 2 || res.Errors[0].Message != "datetime is in the future" {
+		t.Fatalf("expected row 2 in the future, got %+v", res.Errors)
+	}
+
+	csv = "id,user_id,amount,datetime\n1,10,12.34,2024-06-30 17:59:59\n"
+	if _, err := svc.Process(context.Background(), r(csv), opts); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := time.Date(2024, 6, 30, 23, 59, 59, 0, time.UTC)
+	if len(repo.captured) != 1 || !repo.captured[0].DateTime.Equal(want) {
+		t.Fatalf("expected datetime %s, got %+v", want, repo.captured)
+	}
+}
+
+func TestProcess_TypeColumn_SignsAmountsAndRejectsConflicts(t *testing.T) {
+	repo := &fakeRepo{}
+	svc := newSvcWithRepo(t, repo, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+
+	csv := "id,user_id,amount,datetime,dr_cr\n" +
+		"1,10,45.00,2024-06-01T00:00:00Z,DR\n" +
+		"2,10,100.00,2024-06-01T00:00:00Z,CR\n" +
+		"3,10,-5.00,2024-06-01T00:00:00Z,debit\n" +
+		"4,10,7.00,2024-06-01T00:00:00Z,\n" +
+		"5,10,-1.00,2024-06-01T00:00:00Z,CR\n" +
+		"6,10,1.00,2024-06-01T00:00:00Z,XX\n"
+	res, err := svc.Process(context.Background(), r(csv), domain.MigrationOptions{Mode: domain.MigrationModePartial})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Inserted != 4 || len(res.Errors) != 2 || res.Errors[0].Row != 5 || res.Errors[1].Row != 6 || res.Errors[0].Field != "type" {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	want := map[int64]string{1: "-45", 2: "100", 3: "-5", 4: "7"}
+	for _, tx := range repo.captured {
+		if tx.Amount.String() != want[tx.ID] || tx.Type != domain.DetermineTransactionType(tx.Amount) {
+			t.Fatalf("tx %d: unexpected amount %s type %s", tx.ID, tx.Amount, tx.Type)
+		}
+	}
+}
+
+func TestProcess_Locale_ParsesGroupedAmounts(t *testing.T) {
+	repo := &fakeRepo{}
+	svc := newSvcWithRepo(t, repo, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+
+	csv := "id,user_id,amount,datetime\n1,10,\"1.234,56\",2024-06-01T00:00:00Z\n"
+	if _, err := svc.Process(context.Background(), r(csv), domain.MigrationOptions{}); err == nil {
+		t.Fatalf("expected plain decimals to reject 1.234,56")
+	}
+	if _, err := svc.Process(context.Background(), r(csv), domain.MigrationOptions{Locale: "es-ES"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(repo.captured) != 1 || repo.captured[0].Amount.String() != "1234.56" {
+		t.Fatalf("unexpected captured: %+v", repo.captured)
+	}
+}
@@ -0,0 +1,397 @@
+package csvmigration
+
+import (
+	"context"
+	"errors"
+	"io"
+	"sort"
+	"strconv"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/ports/repositories"
+	"stori-challenge/internal/ports/services"
+	"stori-challenge/internal/shared"
+)
+
+const (
+	// DefaultChunkSize is how many valid rows ProcessStream stages per round trip.
+	DefaultChunkSize = 1000
+)
+
+// ProcessStream has the same contract as Process but never holds more than one chunk of rows in memory.
+// Valid rows are staged through a repositories.TransactionImport and only become visible on Commit,
+// so a strict migration stays all-or-nothing.
+func (s *csvMigrationService) ProcessStream(ctx context.Context, r io.Reader, opts domain.MigrationOptions) (services.MigrationResult, error) {
+	cfg, appErr := s.loadReadConfig(ctx, opts)
+	if appErr != nil {
+		return services.MigrationResult{}, appErr
+	}
+	batch, errs, appErr := s.startBatch(ctx, r, opts)
+	if appErr != nil {
+		return services.MigrationResult{Errors: errs}, appErr
+	}
+	cfg.batchID = batch.id()
+	res, err := s.processStream(ctx, batch, opts, cfg)
+	batch.finish(ctx, res, err)
+	if res.Summary == nil {
+		res.Errors, res.Summary = limitRowErrors(res.Errors, cfg)
+	}
+	res.Errors = cfg.locator.locate(res.Errors)
+	res.Warnings = cfg.locator.locate(cfg.rules.warningList())
+	res.Dialect = cfg.csvDialect()
+	res.BatchID = batch.id()
+	return res, err
+}
+
+func (s *csvMigrationService) processStream(ctx context.Context, batch *batchRun, opts domain.MigrationOptions, cfg readConfig) (services.MigrationResult, error) {
+	rd, parser, err := s.newRecordReader(batch.input, cfg)
+	if err != nil {
+		errs, appErr := readFailure(err)
+		return services.MigrationResult{Errors: errs}, appErr
+	}
+
+	imp, err := s.Repo.BeginImport(ctx)
+	if err != nil {
+		return services.MigrationResult{}, shared.NewInternal("db_failure", "database error", err)
+	}
+	defer func() {
+		_ = imp.Rollback()
+	}()
+
+	st := &streamState{
+		partial:    opts.IsPartial(),
+		idempotent: opts.Idempotent,
+		locator:    cfg.locator,
+		floor:      cfg.floor,
+		rules:      cfg.rules,
+		users:      cfg.users,
+		vErrs:      newErrorCollector(cfg.maxErrors, cfg.locator),
+		cErrs:      newErrorCollector(cfg.maxErrors, cfg.locator),
+	}
+	if st.partial {
+		// Partial mode reports every rejected row in a single list.
+		st.cErrs = st.vErrs
+	}
+	// Dropped errors are spilled so the caller can keep all of them; the list that is not
+	// reported is discarded.
+	st.vErrs.spill, st.cErrs.spill = &errorSpill{}, &errorSpill{}
+	defer st.vErrs.discard()
+	defer st.cErrs.discard()
+	chunk := &streamChunk{}
+	flush := func() error {
+		if len(chunk.txs) == 0 {
+			return nil
+		}
+		err := s.stageChunk(ctx, imp, chunk, st)
+		chunk.reset()
+		return err
+	}
+
+	for {
+		rec, rowNum, err := rd.Read()
+		if err == io.EOF {
+			break
+		}
+		var rre *rowReadError
+		if errors.As(err, &rre) {
+			st.rejectInvalid(rre.RowError)
+			continue
+		}
+		if err != nil {
+			errs, appErr := readFailure(err)
+			return services.MigrationResult{Errors: errs}, appErr
+		}
+
+		tx, pr, rowErrs := parser.parse(rec, rowNum)
+		if len(rowErrs) > 0 {
+			st.rejectInvalid(rowErrs...)
+			continue
+		}
+		tx.Source = cfg.source(rowNum)
+		chunk.add(tx, *pr)
+		if len(chunk.txs) >= s.ChunkSize {
+			if err := flush(); err != nil {
+				return services.MigrationResult{}, shared.NewInternal("db_failure", "database error", err)
+			}
+		}
+	}
+	if err := flush(); err != nil {
+		return services.MigrationResult{}, shared.NewInternal("db_failure", "database error", err)
+	}
+
+	if st.staged == 0 && st.rejected == 0 && st.present == 0 {
+		return services.MigrationResult{Errors: []services.RowError{{
+			Row:     0,
+			Field:   "file",
+			Value:   "",
+			Message: "CSV contains no data rows",
+		}}}, shared.NewBadRequest("validation_error", "validation failed", nil)
+	}
+	if !st.partial || st.staged+st.present == 0 {
+		if st.invalid > 0 {
+			return st.vErrs.migrationResult(), shared.NewBadRequest("validation_error", "validation failed", nil)
+		}
+		if st.cErrs.count() > 0 {
+			return st.cErrs.migrationResult(), shared.NewConflict("duplicate_id", "conflict", nil)
+		}
+	}
+
+	breaches, err := st.floor.run(ctx, imp, st.vErrs.add)
+	if err != nil {
+		return services.MigrationResult{}, shared.NewInternal("db_failure", "database error", err)
+	}
+	if breaches > 0 {
+		return st.vErrs.migrationResult(), balanceFloorError()
+	}
+
+	if errs, appErr := batch.check(ctx); appErr != nil {
+		return services.MigrationResult{Errors: errs}, appErr
+	}
+	if err := st.users.provision(ctx, imp); err != nil {
+		return services.MigrationResult{}, shared.NewInternal("db_failure", "database error", err)
+	}
+	inserted, err := imp.Commit(ctx)
+	if err != nil {
+		return services.MigrationResult{}, shared.NewInternal("db_failure", "database error", err)
+	}
+	var res services.MigrationResult
+	if st.partial {
+		res = st.vErrs.migrationResult()
+	}
+	res.Inserted, res.Rejected, res.AlreadyPresent = inserted, st.rejected, st.present
+	return res, nil
+}
+
+// streamState accumulates counters and errors across chunks.
+type streamState struct {
+	partial    bool
+	idempotent bool
+	locator    *rowLocator
+	floor      *floorCheck
+	rules      *ruleChecker
+	users      *userCheck
+	vErrs      *errorCollector // validation errors, in-file duplicates included
+	cErrs      *errorCollector // ids that already exist in DB
+	staged     int
+	present    int // rows already stored with the same content (idempotent mode)
+	rejected   int
+	invalid    int // rows that failed validation
+}
+
+// rejectInvalid records the errors of one row.
+func (st *streamState) rejectInvalid(errs ...services.RowError) {
+	for _, e := range errs {
+		st.vErrs.add(e)
+	}
+	st.rejected++
+	st.invalid++
+}
+
+func (st *streamState) rejectConflicts(errs []services.RowError) {
+	for _, e := range errs {
+		st.cErrs.add(e)
+	}
+	st.rejected += countRejectedRows(errs)
+}
+
+// stageChunk stages a chunk, then checks the rows that are not in-file duplicates in the order
+// Process does: validation rules, known users, existing ids. Rows rejected by those checks stay
+// staged, so later duplicates of them are still caught, but are flagged so they are not imported.
+// In partial mode conflicting rows are rejected that way; in strict mode they are only reported.
+// Rows identical to the stored ones (idempotent mode) are marked so Commit skips them.
+// Per-day rule counts of earlier chunks are read back from the staged rows before staging.
+func (s *csvMigrationService) stageChunk(ctx context.Context, imp repositories.TransactionImport, chunk *streamChunk, st *streamState) error {
+	if keys := st.rules.perDayKeys(chunk.txs); len(keys) > 0 {
+		counts, err := imp.StagedPerDay(ctx, keys)
+		if err != nil {
+			return err
+		}
+		st.rules.seedPerDay(counts)
+	}
+	rowNums := make([]int, len(chunk.rows))
+	idStrByRow := make(map[int]string, len(chunk.rows))
+	for i, pr := range chunk.rows {
+		rowNums[i] = pr.RowNum
+		idStrByRow[pr.RowNum] = pr.IDStr
+	}
+	dups, err := imp.Stage(ctx, chunk.txs, rowNums)
+	if err != nil {
+		return err
+	}
+	dupRows := make(map[int]bool, len(dups))
+	for _, d := range dups {
+		dupRows[d.Row] = true
+		st.rejectInvalid(duplicateInFileError(d.Row, idStrByRow[d.Row], st.locator.position(d.FirstRow)))
+	}
+
+	txs := make([]domain.Transaction, 0, len(chunk.txs))
+	rows := make([]ParsedRow, 0, len(chunk.rows))
+	for i, tx := range chunk.txs {
+		pr := chunk.rows[i]
+		if dupRows[pr.RowNum] {
+			continue
+		}
+		if ruleErrs := st.rules.check(tx, pr); len(ruleErrs) > 0 {
+			st.rejectInvalid(ruleErrs...)
+			continue
+		}
+		txs = append(txs, tx)
+		rows = append(rows, pr)
+	}
+
+	txs, rows, uErrs, err := st.users.filter(ctx, txs, rows)
+	if err != nil {
+		return err
+	}
+	for _, e := range uErrs {
+		st.rejectInvalid(e)
+	}
+	// Conflicts are only reported when the file is otherwise valid, so skip the lookup once it is not.
+	// The lookup goes through the import so it shares its connection and snapshot.
+	var identical map[int64]bool
+	if st.partial || st.invalid == 0 {
+		c, err := s.findConflicts(ctx, imp, txs, rows, st.idempotent)
+		if err != nil {
+			return err
+		}
+		st.rejectConflicts(c.errs)
+		if st.partial && len(c.conflicting) > 0 {
+			txs, _ = withoutRows(txs, rows, c.conflicting)
+		}
+		identical = c.identical
+	}
+
+	kept := make(map[int64]bool, len(txs))
+	for _, tx := range txs {
+		kept[tx.ID] = true
+	}
+	var rejected []int64
+	for i, tx := range chunk.txs {
+		if !dupRows[rowNums[i]] && !kept[tx.ID] {
+			rejected = append(rejected, tx.ID)
+		}
+	}
+	if err := imp.Reject(ctx, rejected); err != nil {
+		return err
+	}
+
+	present := 0
+	if len(identical) > 0 {
+		ids := make([]int64, 0, len(identical))
+		for id := range identical {
+			ids = append(ids, id)
+		}
+		if err := imp.MarkPresent(ctx, ids); err != nil {
+			return err
+		}
+		for _, tx := range txs {
+			if identical[tx.ID] {
+				present++
+			}
+		}
+	}
+	st.present += present
+	st.staged += len(txs) - present
+	return nil
+}
+
+// withoutRows drops the transactions (and their parsed rows) whose id is in ids.
+func withoutRows(txs []domain.Transaction, rows []ParsedRow, ids map[int64]bool) ([]domain.Transaction, []ParsedRow) {
+	outTxs := make([]domain.Transaction, 0, len(txs))
+	outRows := make([]ParsedRow, 0, len(rows))
+	for i, tx := range txs {
+		if !ids[tx.ID] {
+			outTxs = append(outTxs, tx)
+			outRows = append(outRows, rows[i])
+		}
+	}
+	return outTxs, outRows
+}
+
+// streamChunk buffers the valid rows of the current chunk.
+type streamChunk struct {
+	txs  []domain.Transaction
+	rows []ParsedRow
+}
+
+func (c *streamChunk) add(tx domain.Transaction, pr ParsedRow) {
+	c.txs = append(c.txs, tx)
+	c.rows = append(c.rows, pr)
+}
+
+func (c *streamChunk) reset() {
+	c.txs = c.txs[:0]
+	c.rows = c.rows[:0]
+}
+
+// errorCollector keeps up to max row errors, counts the ones it drops and summarizes all of them.
+// When it has a spill, the dropped errors are also written there for its log.
+type errorCollector struct {
+	max     int
+	items   []services.RowError
+	omitted int
+	summary *errorSummary
+	spill   *errorSpill
+}
+
+func newErrorCollector(max int, locator *rowLocator) *errorCollector {
+	return &errorCollector{max: max, summary: newErrorSummary(locator)}
+}
+
+func (c *errorCollector) add(e services.RowError) {
+	c.summary.add(e)
+	if len(c.items) >= c.max {
+		c.omitted++
+		if c.spill != nil {
+			c.spill.add(e)
+		}
+		return
+	}
+	c.items = append(c.items, e)
+}
+
+func (c *errorCollector) count() int {
+	return len(c.items) + c.omitted
+}
+
+// result returns the kept errors ordered by row, plus a file-level note when some were dropped,
+// and the summary of all of them.
+func (c *errorCollector) result() ([]services.RowError, *services.RowErrorSummary) {
+	out := make([]services.RowError, len(c.items), len(c.items)+1)
+	copy(out, c.items)
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Row < out[j].Row })
+	if c.omitted > 0 {
+		out = append(out, services.RowError{
+			Row:     0,
+			Field:   "file",
+			Value:   strconv.Itoa(c.omitted),
+			Message: "more row errors omitted",
+		})
+	}
+	return out, c.summary.result(c.omitted)
+}
+
+// migrationResult reports the errors of c, with the log of all of them when some were dropped.
+func (c *errorCollector) migrationResult() services.MigrationResult {
+	errs, summary := c.result()
+	return services.MigrationResult{Errors: errs, Summary: summary, ErrorLog: c.log()}
+}
+
+// log hands the caller every error of c, or returns nil when none was dropped or c has no
+// spill.
+func (c *errorCollector) log() services.RowErrorLog {
+	if c.spill == nil || c.omitted == 0 {
+		return nil
+	}
+	l := &collectedLog{kept: c.items, spill: c.spill, locator: c.summary.locator}
+	c.spill = nil
+	return l
+}
+
+// discard removes the spill of c unless its log was handed out.
+func (c *errorCollector) discard() {
+	if c.spill != nil {
+		_ = c.spill.close()
+		c.spill = nil
+	}
+}
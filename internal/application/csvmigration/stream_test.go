@@ -0,0 +1,236 @@
+package csvmigration
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/shared"
+)
+
+func TestProcessStream_HappyPath_StagesInChunks(t *testing.T) {
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	repo := &fakeRepo{}
+	svc := newSvcWithRepo(t, repo, now)
+	svc.ChunkSize = 2
+
+	var sb strings.Builder
+	sb.WriteString("id,user_id,amount,datetime\n")
+	for i := 1; i <= 5; i++ {
+		fmt.Fprintf(&sb, "%d,10,1.00,2024-06-01T00:00:00Z\n", i)
+	}
+	res, err := svc.ProcessStream(context.Background(), r(sb.String()), domain.MigrationOptions{})
+	inserted, items := res.Inserted, res.Errors
+	if err != nil {
+		t.Fatalf("unexpected error: %v (items %v)", err, items)
+	}
+	if inserted != 5 || len(repo.captured) != 5 {
+		t.Fatalf("expected 5 inserted, got %d (captured %d)", inserted, len(repo.captured))
+	}
+	if imp := repo.imports[0]; imp.stageN != 3 || !imp.committed {
+		t.Fatalf("expected 3 staged chunks and a commit, got %d committed=%v", imp.stageN, imp.committed)
+	}
+}
+
+func TestProcessStream_DuplicateAcrossChunks_IsValidationError(t *testing.T) {
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	repo := &fakeRepo{}
+	svc := newSvcWithRepo(t, repo, now)
+	svc.ChunkSize = 1
+
+	csv := "id,user_id,amount,datetime\n7,10,1.00,2024-06-01T00:00:00Z\n8,10,1.00,2024-06-01T00:00:00Z\n7,10,1.00,2024-06-01T00:00:00Z\n"
+	res, err := svc.ProcessStream(context.Background(), r(csv), domain.MigrationOptions{})
+	items := res.Errors
+	var ae *shared.AppError
+	if !errors.As(err, &ae) || ae.Kind != shared.BadRequestKind {
+		t.Fatalf("expected BadRequest AppError, got %v", err)
+	}
+	if len(items) != 1 || items[0].Row != 3 || !strings.Contains(items[0].Message, "first seen at row 1") {
+		t.Fatalf("unexpected items: %v", items)
+	}
+	if repo.imports[0].committed || len(repo.captured) != 0 {
+		t.Fatalf("expected nothing committed")
+	}
+}
+
+func TestProcessStream_ConflictIDs_NothingCommitted(t *testing.T) {
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	repo := &fakeRepo{exists: map[int64]bool{2: true}}
+	svc := newSvcWithRepo(t, repo, now)
+
+	csv := "id,user_id,amount,datetime\n1,10,1.00,2024-06-01T00:00:00Z\n2,10,1.00,2024-06-01T00:00:00Z\n"
+	res, err := svc.ProcessStream(context.Background(), r(csv), domain.MigrationOptions{})
+	items := res.Errors
+	var ae *shared.AppError
+	if !errors.As(err, &ae) || ae.Kind != shared.ConflictKind {
+		t.Fatalf("expected Conflict AppError, got %v", err)
+	}
+	if len(items) != 1 || items[0].Row != 2 {
+		t.Fatalf("unexpected items: %v", items)
+	}
+	if repo.imports[0].committed {
+		t.Fatalf("expected nothing committed")
+	}
+}
+
+func TestProcessStream_HeaderInvalid(t *testing.T) {
+	svc := newSvcWithRepo(t, &fakeRepo{}, time.Now().UTC())
+	res, err := svc.ProcessStream(context.Background(), r("bad,header,here,now\n"), domain.MigrationOptions{})
+	items := res.Errors
+	var ae *shared.AppError
+	if !errors.As(err, &ae) || ae.Kind != shared.BadRequestKind {
+		t.Fatalf("expected BadRequest AppError, got %v", err)
+	}
+	if len(items) != 1 || items[0].Field != "file" {
+		t.Fatalf("expected one file-level error item, got %v", items)
+	}
+}
+
+func TestProcessStream_NoDataRows(t *testing.T) {
+	svc := newSvcWithRepo(t, &fakeRepo{}, time.Now().UTC())
+	res, err := svc.ProcessStream(context.Background(), r("id,user_id,amount,datetime\n"), domain.MigrationOptions{})
+	items := res.Errors
+	var ae *shared.AppError
+	if !errors.As(err, &ae) || ae.Kind != shared.BadRequestKind {
+		t.Fatalf("expected BadRequest AppError, got %v", err)
+	}
+	if len(items) != 1 || items[0].Message != "CSV contains no data rows" {
+		t.Fatalf("unexpected items: %v", items)
+	}
+}
+
+func TestProcessStream_CapsRowErrors(t *testing.T) {
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	svc := newSvcWithRepo(t, &fakeRepo{}, now)
+
+	var sb strings.Builder
+	sb.WriteString("id,user_id,amount,datetime\n")
+	for i := 0; i < domain.DefaultMaxErrors+5; i++ {
+		sb.WriteString("x,10,1.00,2024-06-01T00:00:00Z\n")
+	}
+	res, err := svc.ProcessStream(context.Background(), r(sb.String()), domain.MigrationOptions{})
+	items := res.Errors
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if len(items) != domain.DefaultMaxErrors+1 {
+		t.Fatalf("expected %d items, got %d", domain.DefaultMaxErrors+1, len(items))
+	}
+	last := items[len(items)-1]
+	if last.Field != "file" || last.Value != "5" {
+		t.Fatalf("expected omitted summary, got %+v", last)
+	}
+	sum := res.Summary
+	if sum == nil || sum.Total != domain.DefaultMaxErrors+5 || sum.Omitted != 5 || len(sum.Groups) != 1 {
+		t.Fatalf("unexpected summary: %+v", sum)
+	}
+	if g := sum.Groups[0]; g.Field != "id" || g.Count != domain.DefaultMaxErrors+5 || len(g.SampleRows) != ErrorGroupSamples || g.SampleRows[0] != 1 {
+		t.Fatalf("unexpected group: %+v", g)
+	}
+
+	// The log still holds every error, the dropped ones included.
+	if res.ErrorLog == nil {
+		t.Fatalf("expected an error log")
+	}
+	defer res.ErrorLog.Close()
+	rows := make(map[int]bool)
+	err = res.ErrorLog.Each(func(e domain.LoggedRowError) error {
+		rows[e.RunRow] = true
+		return nil
+	})
+	if err != nil || len(rows) != domain.DefaultMaxErrors+5 || !rows[domain.DefaultMaxErrors+5] {
+		t.Fatalf("expected every row in the log, got %d rows (%v)", len(rows), err)
+	}
+}
+
+func TestProcessStream_MaxErrorsOption(t *testing.T) {
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	svc := newSvcWithRepo(t, &fakeRepo{}, now)
+
+	in := "id,user_id,amount,datetime\nx,10,1.00,2024-06-01T00:00:00Z\n2,y,1.00,2024-06-01T00:00:00Z\nz,10,1.00,2024-06-01T00:00:00Z\n"
+	res, _ := svc.ProcessStream(context.Background(), r(in), domain.MigrationOptions{MaxErrors: 2})
+	if res.ErrorLog != nil {
+		defer res.ErrorLog.Close()
+	}
+	if len(res.Errors) != 3 || res.Errors[2].Value != "1" {
+		t.Fatalf("expected two errors and the omitted note, got %+v", res.Errors)
+	}
+	if res.Summary == nil || res.Summary.Total != 3 || len(res.Summary.Groups) != 2 || res.Summary.Groups[0].Count != 2 {
+		t.Fatalf("unexpected summary: %+v", res.Summary)
+	}
+}
+
+func TestProcessStream_CommitError_Internal(t *testing.T) {
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	repo := &fakeRepo{commitErr: errors.New("db down")}
+	svc := newSvcWithRepo(t, repo, now)
+	_, err := svc.ProcessStream(context.Background(), r("id,user_id,amount,datetime\n1,10,1.00,2024-06-01T00:00:00Z\n"), domain.MigrationOptions{})
+	var ae *shared.AppError
+	if !errors.As(err, &ae) || ae.Kind != shared.InternalKind {
+		t.Fatalf("expected Internal AppError, got %v", err)
+	}
+}
+
+func TestProcessStream_PartialMode_CommitsValidRows(t *testing.T) {
+	repo := &fakeRepo{exists: map[int64]bool{3: true}}
+	svc := newSvcWithRepo(t, repo, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+	svc.ChunkSize = 2
+
+	csv := "id,user_id,amount,datetime\n" +
+		"1,10,12.34,2024-06-01T00:00:00Z\n" +
+		"2,10,xx,2024-06-01T00:00:00Z\n" +
+		"3,10,1.00,2024-06-01T00:00:00Z\n" +
+		"1,10,2.00,2024-06-01T00:00:00Z\n" +
+		"5,10,2.00,2024-06-01T00:00:00Z\n"
+	res, err := svc.ProcessStream(context.Background(), r(csv), domain.MigrationOptions{Mode: domain.MigrationModePartial})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Inserted != 2 || res.Rejected != 3 {
+		t.Fatalf("expected 2 inserted and 3 rejected, got %+v", res)
+	}
+	if len(res.Errors) != 3 {
+		t.Fatalf("expected 3 row errors, got %v", res.Errors)
+	}
+	if len(repo.imports) != 1 || !repo.imports[0].committed {
+		t.Fatalf("expected committed import")
+	}
+}
+
+func TestProcessStream_Idempotent_SkipsIdenticalRowsAndCatchesDuplicates(t *testing.T) {
+	repo := &fakeRepo{stored: map[int64]domain.Transaction{
+		1: storedTx(1, 10, "1.00", "2024-06-01T00:00:00Z"),
+	}}
+	svc := newSvcWithRepo(t, repo, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+	svc.ChunkSize = 2
+
+	csv := "id,user_id,amount,datetime\n" +
+		"1,10,1.00,2024-06-01T00:00:00Z\n" +
+		"2,10,2.00,2024-06-01T00:00:00Z\n" +
+		"3,10,3.00,2024-06-01T00:00:00Z\n"
+	res, err := svc.ProcessStream(context.Background(), r(csv), domain.MigrationOptions{Idempotent: true})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Inserted != 2 || res.AlreadyPresent != 1 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+
+	// A second copy of a present row in a later chunk is still an in-file duplicate.
+	csv = "id,user_id,amount,datetime\n" +
+		"1,10,1.00,2024-06-01T00:00:00Z\n" +
+		"4,10,2.00,2024-06-01T00:00:00Z\n" +
+		"1,10,1.00,2024-06-01T00:00:00Z\n"
+	res, err = svc.ProcessStream(context.Background(), r(csv), domain.MigrationOptions{Idempotent: true})
+	var ae *shared.AppError
+	if !errors.As(err, &ae) || ae.Kind != shared.BadRequestKind {
+		t.Fatalf("expected BadRequest AppError, got %v", err)
+	}
+	if len(res.Errors) != 1 || res.Errors[0].Row != 3 {
+		t.Fatalf("expected duplicate error on row 3, got %v", res.Errors)
+	}
+}
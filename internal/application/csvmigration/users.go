@@ -0,0 +1,71 @@
+package csvmigration
+
+import (
+	"context"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/ports/repositories"
+	"stori-challenge/internal/ports/services"
+)
+
+// userCheck applies the unknown-user policy of a run: it either rejects the rows of unregistered
+// users or registers the users of the inserted rows, in the import that inserts them. A nil
+// check does neither.
+type userCheck struct {
+	repo   repositories.UserRepository
+	reject bool
+}
+
+func newUserCheck(repo repositories.UserRepository, policy domain.UnknownUserPolicy) *userCheck {
+	if repo == nil {
+		return nil
+	}
+	return &userCheck{
+		repo:   repo,
+		reject: policy == domain.UnknownUsersReject,
+	}
+}
+
+// filter drops the transactions of unregistered users, with one user_id error each, when the
+// policy rejects them. rows holds the parsed row of each transaction. Only the users of txs are
+// looked up and nothing is kept between calls, so a streamed run holds one chunk's users.
+func (u *userCheck) filter(ctx context.Context, txs []domain.Transaction, rows []ParsedRow) ([]domain.Transaction, []ParsedRow, []services.RowError, error) {
+	if u == nil || !u.reject || len(txs) == 0 {
+		return txs, rows, nil, nil
+	}
+	seen := make(map[int64]bool, len(txs))
+	var lookup []int64
+	for _, tx := range txs {
+		if !seen[tx.UserID] {
+			seen[tx.UserID] = true
+			lookup = append(lookup, tx.UserID)
+		}
+	}
+	known, err := u.repo.ExistingIDs(ctx, lookup)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	outTxs := make([]domain.Transaction, 0, len(txs))
+	outRows := make([]ParsedRow, 0, len(rows))
+	var errs []services.RowError
+	for i, tx := range txs {
+		if known[tx.UserID] {
+			outTxs = append(outTxs, tx)
+			outRows = append(outRows, rows[i])
+			continue
+		}
+		errs = append(errs, services.RowError{Row: rows[i].RowNum, Field: "user_id", Value: rows[i].UserIDStr, Message: "unknown user"})
+	}
+	return outTxs, outRows, errs, nil
+}
+
+// provision registers the users of the rows staged in imp that do not exist yet, so they are
+// only created if the rows are committed.
+func (u *userCheck) provision(ctx context.Context, imp repositories.TransactionImport) error {
+	if u == nil || u.reject {
+		return nil
+	}
+	_, err := imp.RegisterUsers(ctx)
+	return err
+}
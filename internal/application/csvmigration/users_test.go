@@ -0,0 +1,175 @@
+package csvmigration
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/ports/services"
+	"stori-challenge/internal/shared"
+)
+
+type fakeUsers struct {
+	registered map[int64]bool
+	// lookups holds the ids of each ExistingIDs call.
+	lookups [][]int64
+}
+
+func (f *fakeUsers) Exists(ctx context.Context, id int64) (bool, error) {
+	return f.registered[id], nil
+}
+
+func (f *fakeUsers) ExistingIDs(ctx context.Context, ids []int64) (map[int64]bool, error) {
+	f.lookups = append(f.lookups, ids)
+	out := make(map[int64]bool)
+	for _, id := range ids {
+		if f.registered[id] {
+			out[id] = true
+		}
+	}
+	return out, nil
+}
+
+func newUsersSvc(t *testing.T, repo *fakeRepo, users *fakeUsers) *csvMigrationService {
+	t.Helper()
+	svc := newSvcWithRepo(t, repo, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+	svc.Users = users
+	return svc
+}
+
+func TestProcess_UnknownUsersCreate_ProvisionsUsersInTheImport(t *testing.T) {
+	repo := &fakeRepo{}
+	svc := newUsersSvc(t, repo, &fakeUsers{registered: map[int64]bool{10: true}})
+
+	csv := "id,user_id,amount,datetime\n1,20,1.00,2024-06-01T00:00:00Z\n2,10,1.00,2024-06-01T00:00:00Z\n3,20,2.00,2024-06-02T00:00:00Z\n"
+	res, err := svc.Process(context.Background(), r(csv), domain.MigrationOptions{})
+	if err != nil || res.Inserted != 3 {
+		t.Fatalf("expected 3 rows inserted, got %+v, %v", res, err)
+	}
+	if imp := repo.imports[0]; !reflect.DeepEqual(imp.users, []int64{10, 20}) || !imp.committed {
+		t.Fatalf("expected users 10 and 20 provisioned by the committed import, got %+v", imp)
+	}
+}
+
+func TestProcess_UnknownUsersReject(t *testing.T) {
+	repo := &fakeRepo{}
+	svc := newUsersSvc(t, repo, &fakeUsers{registered: map[int64]bool{10: true}})
+
+	csv := "id,user_id,amount,datetime\n1,10,1.00,2024-06-01T00:00:00Z\n2,20,1.00,2024-06-01T00:00:00Z\n"
+	_, err := svc.Process(context.Background(), r(csv), domain.MigrationOptions{UnknownUsers: domain.UnknownUsersReject})
+	var ae *shared.AppError
+	if !errors.As(err, &ae) || ae.Code != "validation_error" {
+		t.Fatalf("expected validation_error, got %v", err)
+	}
+
+	res, err := svc.Process(context.Background(), r(csv), domain.MigrationOptions{Mode: domain.MigrationModePartial, UnknownUsers: domain.UnknownUsersReject})
+	if err != nil || res.Inserted != 1 || res.Rejected != 1 {
+		t.Fatalf("expected 1 inserted and 1 rejected, got %+v, %v", res, err)
+	}
+	e := res.Errors[0]
+	if e.Row != 2 || e.Field != "user_id" || e.Value != "20" || e.Message != "unknown user" {
+		t.Fatalf("unexpected error: %+v", e)
+	}
+	for _, imp := range repo.imports {
+		if len(imp.users) != 0 {
+			t.Fatalf("expected no users provisioned, got %v", imp.users)
+		}
+	}
+}
+
+func TestProcessStream_UnknownUsersReject_AcrossChunks(t *testing.T) {
+	repo := &fakeRepo{}
+	users := &fakeUsers{registered: map[int64]bool{10: true}}
+	svc := newUsersSvc(t, repo, users)
+	svc.ChunkSize = 1
+
+	csv := "id,user_id,amount,datetime\n1,30,1.00,2024-06-01T00:00:00Z\n2,10,1.00,2024-06-01T00:00:00Z\n3,30,1.00,2024-06-02T00:00:00Z\n"
+	res, err := svc.ProcessStream(context.Background(), r(csv), domain.MigrationOptions{Mode: domain.MigrationModePartial, UnknownUsers: domain.UnknownUsersReject})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Inserted != 1 || res.Rejected != 2 || len(res.Errors) != 2 || res.Errors[0].Row != 1 || res.Errors[1].Row != 3 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if !repo.imports[0].committed {
+		t.Fatalf("expected the import to be committed")
+	}
+}
+
+func TestProcess_UnknownUsersReject_IDOfAnInvalidRow(t *testing.T) {
+	// Row 2 reuses the id of row 1, which fails validation, so it is not a duplicate; its
+	// unknown user must be reported on row 2.
+	csv := "id,user_id,amount,datetime\n5,10,abc,2024-06-01T00:00:00Z\n5,20,1.00,2024-06-01T00:00:00Z\n6,10,1.00,2024-06-01T00:00:00Z\n"
+	opts := domain.MigrationOptions{Mode: domain.MigrationModePartial, UnknownUsers: domain.UnknownUsersReject}
+
+	syncRes, err := newUsersSvc(t, &fakeRepo{}, &fakeUsers{registered: map[int64]bool{10: true}}).Process(context.Background(), r(csv), opts)
+	if err != nil {
+		t.Fatalf("process: %v", err)
+	}
+	streamRes, err := newUsersSvc(t, &fakeRepo{}, &fakeUsers{registered: map[int64]bool{10: true}}).ProcessStream(context.Background(), r(csv), opts)
+	if err != nil {
+		t.Fatalf("process stream: %v", err)
+	}
+	for name, res := range map[string]services.MigrationResult{"Process": syncRes, "ProcessStream": streamRes} {
+		if res.Inserted != 1 || res.Rejected != 2 || len(res.Errors) != 2 {
+			t.Fatalf("%s: expected 1 inserted and 2 rejected, got %+v", name, res)
+		}
+		if e := res.Errors[1]; e.Row != 2 || e.Field != "user_id" || e.Value != "20" || e.Message != "unknown user" {
+			t.Fatalf("%s: unexpected error: %+v", name, e)
+		}
+	}
+}
+
+func TestValidate_UnknownUsersReject(t *testing.T) {
+	svc := newUsersSvc(t, &fakeRepo{}, &fakeUsers{})
+
+	csv := "id,user_id,amount,datetime\n1,10,1.00,2024-06-01T00:00:00Z\n"
+	res, err := svc.Validate(context.Background(), r(csv), domain.MigrationOptions{UnknownUsers: domain.UnknownUsersReject})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.WouldInsert != 0 || res.Rejected != 1 || len(res.Errors) != 1 || res.Errors[0].Field != "user_id" {
+		t.Fatalf("unexpected validation: %+v", res)
+	}
+}
+
+func TestProcessStream_UnknownUsersCreate_RejectedRunRegistersNoUsers(t *testing.T) {
+	repo := &fakeRepo{}
+	svc := newUsersSvc(t, repo, &fakeUsers{})
+	svc.ChunkSize = 1
+
+	csv := "id,user_id,amount,datetime\n1,30,-1.00,2024-06-01T00:00:00Z\n"
+	opts := floorOpts(domain.MigrationModeStrict, "0")
+	if _, err := svc.ProcessStream(context.Background(), r(csv), opts); err == nil {
+		t.Fatalf("expected the floor to reject the run")
+	}
+	if imp := repo.imports[0]; len(imp.users) != 0 || imp.committed {
+		t.Fatalf("expected no users registered, got %+v", imp)
+	}
+}
+
+func TestProcessStream_UnknownUsersReject_LooksUpEachChunksUsersOnly(t *testing.T) {
+	repo := &fakeRepo{}
+	users := &fakeUsers{registered: map[int64]bool{10: true}}
+	svc := newUsersSvc(t, repo, users)
+	svc.ChunkSize = 2
+
+	csv := "id,user_id,amount,datetime\n" +
+		"1,10,1.00,2024-06-01T00:00:00Z\n" +
+		"2,99,1.00,2024-06-01T00:00:00Z\n" +
+		"3,10,1.00,2024-06-02T00:00:00Z\n" +
+		"4,10,1.00,2024-06-03T00:00:00Z\n" +
+		"5,99,1.00,2024-06-04T00:00:00Z\n"
+	res, err := svc.ProcessStream(context.Background(), r(csv), domain.MigrationOptions{Mode: domain.MigrationModePartial, UnknownUsers: domain.UnknownUsersReject})
+	if err != nil || res.Inserted != 3 || res.Rejected != 2 {
+		t.Fatalf("unexpected result: %+v, %v", res, err)
+	}
+	// Nothing is cached between chunks, so a user seen before is looked up again.
+	want := [][]int64{{10, 99}, {10}, {99}}
+	if !reflect.DeepEqual(users.lookups, want) {
+		t.Fatalf("expected lookups %v, got %v", want, users.lookups)
+	}
+}
@@ -0,0 +1,116 @@
+package csvmigration
+
+import (
+	"context"
+	"io"
+	"sort"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/ports/services"
+	"stori-challenge/internal/shared"
+
+	"github.com/shopspring/decimal"
+)
+
+// Validate is a dry run of Process: same parsing and conflict checks, but nothing is written.
+func (s *csvMigrationService) Validate(ctx context.Context, r io.Reader, opts domain.MigrationOptions) (services.MigrationValidation, error) {
+	cfg, appErr := s.loadReadConfig(ctx, opts)
+	if appErr != nil {
+		return services.MigrationValidation{}, appErr
+	}
+	cfg.limit = newInflateLimit(MaxInflatedSize)
+	res, err := s.validate(ctx, r, opts, cfg)
+	res.Errors, res.Summary = limitRowErrors(res.Errors, cfg)
+	res.Errors = cfg.locator.locate(res.Errors)
+	res.Warnings = cfg.locator.locate(cfg.rules.warningList())
+	res.Dialect = cfg.csvDialect()
+	return res, err
+}
+
+func (s *csvMigrationService) validate(ctx context.Context, r io.Reader, opts domain.MigrationOptions, cfg readConfig) (services.MigrationValidation, error) {
+	txs, rows, vErrs, parseErr := s.readAndValidate(r, cfg)
+	if parseErr != nil {
+		errs, appErr := readFailure(parseErr)
+		return services.MigrationValidation{Errors: errs}, appErr
+	}
+	txs, rows, uErrs, err := cfg.users.filter(ctx, txs, rows)
+	if err != nil {
+		return services.MigrationValidation{}, shared.NewInternal("db_failure", "database error", err)
+	}
+	vErrs = mergeRowErrors(vErrs, uErrs)
+	// Every data row is either valid or has errors in vErrs.
+	total := len(txs) + countRejectedRows(vErrs)
+	if total == 0 {
+		return services.MigrationValidation{Errors: []services.RowError{{
+			Row:     0,
+			Field:   "file",
+			Value:   "",
+			Message: "CSV contains no data rows",
+		}}}, shared.NewBadRequest("validation_error", "validation failed", nil)
+	}
+
+	var c conflicts
+	if len(txs) > 0 {
+		var err error
+		c, err = s.findConflicts(ctx, s.Repo, txs, rows, opts.Idempotent)
+		if err != nil {
+			return services.MigrationValidation{}, shared.NewInternal("db_failure", "database error", err)
+		}
+		txs, rows = withoutRows(txs, rows, c.existing())
+	}
+
+	floorErrs, err := cfg.floor.dryRun(ctx, s.Repo, txs, rows)
+	if err != nil {
+		return services.MigrationValidation{}, shared.NewInternal("db_failure", "database error", err)
+	}
+
+	rowErrs := mergeRowErrors(mergeRowErrors(vErrs, c.errs), floorErrs)
+	wouldInsert := len(txs)
+	// A file that breaks the overdraft floor is rejected whatever the mode.
+	if len(rowErrs) > 0 && !opts.IsPartial() || len(floorErrs) > 0 {
+		wouldInsert = 0
+	}
+	return services.MigrationValidation{
+		Rows:           total,
+		WouldInsert:    wouldInsert,
+		Rejected:       countRejectedRows(rowErrs),
+		AlreadyPresent: len(c.identical),
+		Errors:         rowErrs,
+		Stats:          computeStats(txs),
+	}, nil
+}
+
+// computeStats aggregates counts, datetime range, totals and per-user net amounts.
+func computeStats(txs []domain.Transaction) services.MigrationStats {
+	stats := services.MigrationStats{
+		Rows:         len(txs),
+		TotalCredits: decimal.Zero,
+		TotalDebits:  decimal.Zero,
+	}
+	byUser := make(map[int64]decimal.Decimal)
+	for i := range txs {
+		tx := &txs[i]
+		if stats.From == nil || tx.DateTime.Before(*stats.From) {
+			stats.From = &tx.DateTime
+		}
+		if stats.To == nil || tx.DateTime.After(*stats.To) {
+			stats.To = &tx.DateTime
+		}
+		if tx.Type == domain.TransactionTypeDebit {
+			stats.TotalDebits = stats.TotalDebits.Sub(tx.Amount)
+		} else {
+			stats.TotalCredits = stats.TotalCredits.Add(tx.Amount)
+		}
+		byUser[tx.UserID] = byUser[tx.UserID].Add(tx.Amount)
+	}
+
+	stats.DistinctUsers = len(byUser)
+	stats.BalanceChanges = make([]services.UserBalanceChange, 0, len(byUser))
+	for userID, change := range byUser {
+		stats.BalanceChanges = append(stats.BalanceChanges, services.UserBalanceChange{UserID: userID, Change: change})
+	}
+	sort.Slice(stats.BalanceChanges, func(i, j int) bool {
+		return stats.BalanceChanges[i].UserID < stats.BalanceChanges[j].UserID
+	})
+	return stats
+}
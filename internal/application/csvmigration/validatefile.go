@@ -1,7 +1,6 @@
 package csvmigration
 
 import (
-	"encoding/csv"
 	"errors"
 	"io"
 	"strconv"
@@ -14,121 +13,171 @@ import (
 	"github.com/shopspring/decimal"
 )
 
-// readAndValidate performs a single pass over the CSV: header check, per-row validation, and build domain transactions.
-func (s *csvMigrationService) readAndValidate(r io.Reader) ([]domain.Transaction, []ParsedRow, []services.RowError, error) {
-	cr := csv.NewReader(r)
-	cr.FieldsPerRecord = -1
-
-	recordIdx := 0
-	now := s.NowFunc()
+// readAndValidate performs a single pass over the input: header check, per-row validation, and build domain transactions.
+// It returns the parsed row of each valid transaction alongside it.
+func (s *csvMigrationService) readAndValidate(r io.Reader, cfg readConfig) ([]domain.Transaction, []ParsedRow, []services.RowError, error) {
+	rd, parser, err := s.newRecordReader(r, cfg)
+	if err != nil {
+		return nil, nil, nil, err
+	}
 
 	var (
 		validTxs []domain.Transaction
 		errs     []services.RowError
-		rows     []ParsedRow
+		rows     []ParsedRow           // parsed row of each valid transaction
 		seenIDs  = make(map[int64]int) // id -> firstRow
 	)
 
 	for {
-		rec, err := cr.Read()
+		rec, rowNum, err := rd.Read()
 		if err == io.EOF {
 			break
 		}
-		if err != nil {
-			return nil, nil, nil, err
-		}
-		recordIdx++
-
-		// Header (mandatory): first 4 columns must match
-		if recordIdx == 1 {
-			if s.isHeader(rec) {
-				continue
-			}
-			return nil, nil, nil, errors.New("invalid or missing header")
-		}
-
-		rowNum := recordIdx - 1 // data rows start at 1
-		cols := len(rec)
-		// Require at least the first 4 columns (ignore extras)
-		if cols < len(expectedHeaders) {
-			errs = append(errs, services.RowError{
-				Row:     rowNum,
-				Field:   "columns",
-				Value:   strconv.Itoa(cols),
-				Message: "at least " + strconv.Itoa(len(expectedHeaders)) + " columns required: " + strings.Join(expectedHeaders, ","),
-			})
-			continue
-		}
-
-		idStr := strings.TrimSpace(rec[0])
-		userIDStr := strings.TrimSpace(rec[1])
-		amountStr := strings.TrimSpace(rec[2])
-		datetimeStr := strings.TrimSpace(rec[3])
-		rows = append(rows, ParsedRow{
-			RowNum:      rowNum,
-			IDStr:       idStr,
-			UserIDStr:   userIDStr,
-			AmountStr:   amountStr,
-			DatetimeStr: datetimeStr,
-			Cols:        cols,
-		})
-
-		// Parse and validate
-		id, err := strconv.ParseInt(idStr, 10, 64)
-		if err != nil {
-			errs = append(errs, services.RowError{Row: rowNum, Field: "id", Value: idStr, Message: "not a valid integer"})
-			continue
-		}
-		userID, err := strconv.ParseInt(userIDStr, 10, 64)
-		if err != nil {
-			errs = append(errs, services.RowError{Row: rowNum, Field: "user_id", Value: userIDStr, Message: "not a valid integer"})
+		var rre *rowReadError
+		if errors.As(err, &rre) {
+			errs = append(errs, rre.RowError)
 			continue
 		}
-		amt, err := decimal.NewFromString(amountStr)
 		if err != nil {
-			errs = append(errs, services.RowError{Row: rowNum, Field: "amount", Value: amountStr, Message: "not a valid number"})
-			continue
+			return nil, nil, nil, err
 		}
-		dt, err := time.Parse(time.RFC3339, datetimeStr)
-		if err != nil {
-			errs = append(errs, services.RowError{Row: rowNum, Field: "datetime", Value: datetimeStr, Message: "not a valid RFC3339 datetime"})
+
+		tx, pr, rowErrs := parser.parse(rec, rowNum)
+		if len(rowErrs) > 0 {
+			errs = append(errs, rowErrs...)
 			continue
 		}
-		if dt.After(now.UTC()) {
-			errs = append(errs, services.RowError{Row: rowNum, Field: "datetime", Value: datetimeStr, Message: "datetime is in the future"})
+		// Duplicate id within file
+		if firstRow, ok := seenIDs[tx.ID]; ok {
+			errs = append(errs, duplicateInFileError(rowNum, pr.IDStr, cfg.locator.position(firstRow)))
 			continue
 		}
-		// Duplicate id within file
-		if firstRow, ok := seenIDs[id]; ok {
-			errs = append(errs, services.RowError{
-				Row:     rowNum,
-				Field:   "id",
-				Value:   idStr,
-				Message: "duplicate id within file (first seen at row " + strconv.Itoa(firstRow) + ")",
-			})
+		seenIDs[tx.ID] = rowNum
+		if ruleErrs := cfg.rules.check(tx, *pr); len(ruleErrs) > 0 {
+			errs = append(errs, ruleErrs...)
 			continue
 		}
-		seenIDs[id] = rowNum
 
-		validTxs = append(validTxs, domain.Transaction{
-			ID:       id,
-			UserID:   userID,
-			Amount:   amt,
-			DateTime: dt.UTC(),
-			Type:     domain.DetermineTransactionType(amt),
-		})
+		tx.Source = cfg.source(rowNum)
+		validTxs = append(validTxs, tx)
+		rows = append(rows, *pr)
 	}
 	return validTxs, rows, errs, nil
 }
 
-func (s *csvMigrationService) isHeader(rec []string) bool {
-	if len(rec) < len(expectedHeaders) {
-		return false
+// rowParser turns data records into transactions for one migration run.
+type rowParser struct {
+	layout   columnLayout
+	dates    datetimeParser
+	amounts  amountParser
+	rounding domain.AmountRounding
+	now      time.Time
+}
+
+// parse validates a single data record and reports every field that fails. The ParsedRow is nil
+// when the record does not have enough columns; otherwise it is returned even if fields fail
+// validation, for error reporting.
+func (p *rowParser) parse(rec []string, rowNum int) (domain.Transaction, *ParsedRow, []services.RowError) {
+	cols := len(rec)
+	// Require every mapped column (ignore extras)
+	if cols < p.layout.width {
+		return domain.Transaction{}, nil, []services.RowError{{
+			Row:     rowNum,
+			Field:   "columns",
+			Value:   strconv.Itoa(cols),
+			Message: "at least " + strconv.Itoa(p.layout.width) + " columns required by the header",
+		}}
+	}
+
+	pr := &ParsedRow{
+		RowNum:      rowNum,
+		IDStr:       strings.TrimSpace(rec[p.layout.id]),
+		UserIDStr:   strings.TrimSpace(rec[p.layout.userID]),
+		AmountStr:   strings.TrimSpace(rec[p.layout.amount]),
+		DatetimeStr: strings.TrimSpace(rec[p.layout.datetime]),
+		Cols:        cols,
+	}
+	if p.layout.typ >= 0 {
+		pr.TypeStr = strings.TrimSpace(rec[p.layout.typ])
+	}
+
+	// Parse and validate
+	var errs []services.RowError
+	fail := func(field, value, message string) {
+		errs = append(errs, services.RowError{Row: rowNum, Field: field, Value: value, Message: message})
+	}
+	id, err := strconv.ParseInt(pr.IDStr, 10, 64)
+	if err != nil {
+		fail("id", pr.IDStr, "not a valid integer")
+	}
+	userID, err := strconv.ParseInt(pr.UserIDStr, 10, 64)
+	if err != nil {
+		fail("user_id", pr.UserIDStr, "not a valid integer")
+	}
+	amt, amtOK := p.amounts.parse(pr.AmountStr)
+	if !amtOK {
+		fail("amount", pr.AmountStr, p.amounts.errorMessage())
+	} else if msg := p.fitAmount(&amt); msg != "" {
+		fail("amount", pr.AmountStr, msg)
+		amtOK = false
 	}
-	for i, h := range expectedHeaders {
-		if strings.ToLower(strings.TrimSpace(rec[i])) != h {
-			return false
+	txType := domain.DetermineTransactionType(amt)
+	if pr.TypeStr != "" {
+		// An explicit type signs unsigned amounts; a sign that contradicts it is an error.
+		t, ok := domain.ParseTransactionType(pr.TypeStr)
+		switch {
+		case !ok:
+			fail("type", pr.TypeStr, "not a valid transaction type (expected credit/CR or debit/DR)")
+		case !amtOK:
+			// Without an amount there is no sign to check.
+		case t == domain.TransactionTypeCredit && amt.IsNegative():
+			fail("type", pr.TypeStr, "credit type conflicts with negative amount "+pr.AmountStr)
+		default:
+			if t == domain.TransactionTypeDebit {
+				amt = amt.Abs().Neg()
+			}
+			txType = t
 		}
 	}
-	return true
+	dt, ok := p.dates.parse(pr.DatetimeStr)
+	if !ok {
+		fail("datetime", pr.DatetimeStr, p.dates.errorMessage())
+	} else if dt.After(p.now.UTC()) {
+		fail("datetime", pr.DatetimeStr, "datetime is in the future")
+	}
+	if len(errs) > 0 {
+		return domain.Transaction{}, pr, errs
+	}
+
+	return domain.Transaction{
+		ID:       id,
+		UserID:   userID,
+		Amount:   amt,
+		DateTime: dt,
+		Type:     txType,
+	}, pr, nil
+}
+
+// fitAmount applies the rounding policy to amt and returns why it cannot be stored, if it can't.
+// The magnitude is checked after rounding, so an amount rounded past the maximum is rejected too.
+func (p *rowParser) fitAmount(amt *decimal.Decimal) string {
+	fitted, ok := p.rounding.Apply(*amt)
+	if !ok {
+		return "amount has more than " + strconv.Itoa(domain.AmountScale) + " decimal places"
+	}
+	if fitted.Abs().GreaterThan(domain.MaxAmount) {
+		return "amount exceeds the maximum magnitude of " + domain.MaxAmount.String()
+	}
+	*amt = fitted
+	return ""
+}
+
+// duplicateInFileError reports rowNum repeating the id first seen at position (see rowLocator.position).
+func duplicateInFileError(rowNum int, idStr string, first string) services.RowError {
+	return services.RowError{
+		Row:     rowNum,
+		Field:   "id",
+		Value:   idStr,
+		Message: "duplicate id within file (first seen at " + first + ")",
+	}
 }
@@ -1,24 +1,54 @@
 package csvmigration
 
 import (
+	"strconv"
 	"strings"
 	"testing"
 	"time"
+
+	"stori-challenge/internal/domain"
 )
 
-func Test_isHeader(t *testing.T) {
-	s := &csvMigrationService{}
-	ok := s.isHeader([]string{"id", "user_id", "amount", "datetime"})
-	if !ok {
-		t.Fatalf("expected true for exact header")
+func Test_resolveHeader(t *testing.T) {
+	l, err := resolveHeader([]string{"ID", "USER_ID", "Amount", "Datetime"}, nil)
+	if err != nil || l != defaultLayout {
+		t.Fatalf("expected default layout for canonical header, got %+v, %v", l, err)
+	}
+
+	l, err = resolveHeader([]string{"fecha", "Monto", "userId", "transaction-id", "note"}, nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := columnLayout{id: 3, userID: 2, amount: 1, datetime: 0, typ: -1, width: 4}
+	if l != want {
+		t.Fatalf("expected %+v for reordered aliases, got %+v", want, l)
+	}
+}
+
+func Test_resolveHeader_Profile(t *testing.T) {
+	profile := &domain.MappingProfile{Name: "bank", Columns: map[string][]string{
+		domain.ColumnID:     {"Ref"},
+		domain.ColumnAmount: {"Valor Operacion"},
+	}}
+	l, err := resolveHeader([]string{"user_id", "ref", "datetime", "valor operacion"}, profile)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
 	}
-	ok = s.isHeader([]string{"ID", "USER_ID", "Amount", "Datetime"})
-	if !ok {
-		t.Fatalf("expected true for case-insensitive header")
+	want := columnLayout{id: 1, userID: 0, amount: 3, datetime: 2, typ: -1, width: 4}
+	if l != want {
+		t.Fatalf("expected %+v, got %+v", want, l)
+	}
+}
+
+func Test_resolveHeader_Errors(t *testing.T) {
+	_, err := resolveHeader([]string{"id", "user_id", "datetime"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "missing required columns: amount") {
+		t.Fatalf("expected missing amount error, got %v", err)
 	}
-	ok = s.isHeader([]string{"id", "amount", "user_id", "datetime"})
-	if ok {
-		t.Fatalf("expected false for wrong order")
+
+	_, err = resolveHeader([]string{"id", "user_id", "amount", "monto", "datetime"}, nil)
+	if err == nil || !strings.Contains(err.Error(), "both map to amount") {
+		t.Fatalf("expected ambiguous amount error, got %v", err)
 	}
 }
 
@@ -26,7 +56,7 @@ func Test_readAndValidate_AtLeast4Columns(t *testing.T) {
 	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
 	s := &csvMigrationService{NowFunc: func() time.Time { return now }}
 	csv := "id,user_id,amount,datetime\n1,2,3\n"
-	_, _, errs, err := s.readAndValidate(strings.NewReader(csv))
+	_, _, errs, err := s.readAndValidate(strings.NewReader(csv), readConfig{})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -34,3 +64,33 @@ func Test_readAndValidate_AtLeast4Columns(t *testing.T) {
 		t.Fatalf("expected column count error")
 	}
 }
+
+func Test_readAndValidate_ReorderedHeader(t *testing.T) {
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	s := &csvMigrationService{NowFunc: func() time.Time { return now }}
+	csv := "amount,datetime,id,user_id\n10.50,2024-06-01T10:00:00Z,7,3\n"
+	txs, _, errs, err := s.readAndValidate(strings.NewReader(csv), readConfig{})
+	if err != nil || len(errs) != 0 {
+		t.Fatalf("unexpected errors: %v %+v", err, errs)
+	}
+	if len(txs) != 1 || txs[0].ID != 7 || txs[0].UserID != 3 || txs[0].Amount.String() != "10.5" {
+		t.Fatalf("unexpected transactions: %+v", txs)
+	}
+}
+
+func Test_readAndValidate_ReportsEveryFailingField(t *testing.T) {
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	s := &csvMigrationService{NowFunc: func() time.Time { return now }}
+	csv := "id,user_id,amount,datetime,type\n1,x,abc,2025-01-01T00:00:00Z,debit\n2,3,-1.00,2024-06-01T00:00:00Z,credit\n"
+	txs, _, errs, err := s.readAndValidate(strings.NewReader(csv), readConfig{})
+	if err != nil || len(txs) != 0 {
+		t.Fatalf("unexpected result: %v %+v", err, txs)
+	}
+	var got []string
+	for _, e := range errs {
+		got = append(got, strconv.Itoa(e.Row)+":"+e.Field)
+	}
+	if strings.Join(got, " ") != "1:user_id 1:amount 1:datetime 2:type" {
+		t.Fatalf("unexpected errors: %+v", errs)
+	}
+}
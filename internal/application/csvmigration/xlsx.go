@@ -0,0 +1,420 @@
+package csvmigration
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+
+	"stori-challenge/internal/domain"
+
+	"github.com/shopspring/decimal"
+)
+
+// An .xlsx workbook is a zip of XML parts: xl/workbook.xml lists the sheets, its relationships
+// point at each xl/worksheets/sheetN.xml, and text cells usually index xl/sharedStrings.xml.
+
+// xlsxRecordReader streams the rows of one worksheet. Rows are spreadsheet row numbers; the
+// first non-empty row is the header.
+type xlsxRecordReader struct {
+	dec      *xml.Decoder
+	sheet    io.Closer
+	shared   []string
+	date1904 bool
+	dates    datetimeParser
+	// dateCol is the record index of the datetime column; numeric cells there are Excel serial dates.
+	dateCol int
+	header  []string
+}
+
+type xlsxWorkbook struct {
+	WorkbookPr struct {
+		Date1904 string `xml:"date1904,attr"`
+	} `xml:"workbookPr"`
+	Sheets []struct {
+		Name string `xml:"name,attr"`
+		// RID is the r:id attribute; matching by local name also covers Strict OOXML namespaces.
+		RID string `xml:"id,attr"`
+	} `xml:"sheets>sheet"`
+}
+
+type xlsxRelationships struct {
+	Rels []struct {
+		ID     string `xml:"Id,attr"`
+		Target string `xml:"Target,attr"`
+	} `xml:"Relationship"`
+}
+
+// newXLSXRecordReader opens the sheet selected by name or 1-based index (first sheet when empty),
+// reads its header and returns the reader and the layout of its records.
+func newXLSXRecordReader(r io.Reader, cfg readConfig) (*xlsxRecordReader, columnLayout, error) {
+	ra, size, err := readerAt(r)
+	if err != nil {
+		return nil, columnLayout{}, err
+	}
+	zr, err := zip.NewReader(ra, size)
+	if err != nil {
+		return nil, columnLayout{}, &fileError{msg: "not a valid .xlsx file"}
+	}
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var wb xlsxWorkbook
+	if err := decodeZipXML(files, "xl/workbook.xml", &wb); err != nil {
+		return nil, columnLayout{}, &fileError{msg: "not a valid .xlsx file: missing workbook"}
+	}
+	var rels xlsxRelationships
+	if err := decodeZipXML(files, "xl/_rels/workbook.xml.rels", &rels); err != nil {
+		return nil, columnLayout{}, &fileError{msg: "not a valid .xlsx file: missing workbook relationships"}
+	}
+
+	idx, err := pickSheet(wb, cfg.sheet)
+	if err != nil {
+		return nil, columnLayout{}, err
+	}
+	name := wb.Sheets[idx].Name
+	cfg.locator.sheet = name
+	target := ""
+	for _, rel := range rels.Rels {
+		if rel.ID == wb.Sheets[idx].RID {
+			target = rel.Target
+		}
+	}
+	if strings.HasPrefix(target, "/") {
+		target = strings.TrimPrefix(target, "/")
+	} else {
+		target = path.Join("xl", target)
+	}
+	sheetFile, ok := files[target]
+	if !ok {
+		return nil, columnLayout{}, &fileError{msg: fmt.Sprintf("sheet %q has no worksheet data", name)}
+	}
+
+	var shared []string
+	if f, ok := files["xl/sharedStrings.xml"]; ok {
+		if shared, err = readSharedStrings(f); err != nil {
+			return nil, columnLayout{}, &fileError{msg: "not a valid .xlsx file: unreadable shared strings"}
+		}
+	}
+	rc, err := sheetFile.Open()
+	if err != nil {
+		return nil, columnLayout{}, &fileError{msg: fmt.Sprintf("sheet %q cannot be read", name)}
+	}
+
+	rd := &xlsxRecordReader{
+		dec:      xml.NewDecoder(rc),
+		sheet:    rc,
+		shared:   shared,
+		date1904: wb.WorkbookPr.Date1904 == "1" || wb.WorkbookPr.Date1904 == "true",
+		dates:    cfg.dates,
+		dateCol:  -1,
+	}
+	header, _, err := rd.Read()
+	if err == io.EOF {
+		// Empty sheet: later reads also return EOF and callers report "no data rows".
+		return rd, defaultLayout, nil
+	}
+	if err != nil {
+		rc.Close()
+		return nil, columnLayout{}, err
+	}
+	layout, err := resolveHeader(header, cfg.profile)
+	if err != nil {
+		rc.Close()
+		return nil, columnLayout{}, err
+	}
+	rd.dateCol = layout.datetime
+	rd.header = append([]string(nil), header...)
+	return rd, layout, nil
+}
+
+// pickSheet resolves a sheet by exact name, then case-insensitive name, then 1-based index.
+func pickSheet(wb xlsxWorkbook, sel string) (int, error) {
+	if len(wb.Sheets) == 0 {
+		return 0, &fileError{msg: "workbook has no sheets"}
+	}
+	if sel == "" {
+		return 0, nil
+	}
+	for i, s := range wb.Sheets {
+		if s.Name == sel {
+			return i, nil
+		}
+	}
+	for i, s := range wb.Sheets {
+		if strings.EqualFold(s.Name, sel) {
+			return i, nil
+		}
+	}
+	if n, err := strconv.Atoi(sel); err == nil && n >= 1 && n <= len(wb.Sheets) {
+		return n - 1, nil
+	}
+	names := make([]string, len(wb.Sheets))
+	for i, s := range wb.Sheets {
+		names[i] = s.Name
+	}
+	return 0, &fileError{msg: fmt.Sprintf("sheet %q not found (available: %s)", sel, strings.Join(names, ", "))}
+}
+
+// Read returns the next non-empty row. Cells are placed by their column reference, so gaps
+// left by empty cells become empty fields.
+func (r *xlsxRecordReader) columns() []string { return r.header }
+
+func (r *xlsxRecordReader) Read() ([]string, int, error) {
+	for {
+		tok, err := r.dec.Token()
+		if err == io.EOF {
+			r.sheet.Close()
+			return nil, 0, io.EOF
+		}
+		if err != nil {
+			return nil, 0, &fileError{msg: "worksheet XML is malformed"}
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "row" {
+			continue
+		}
+		rowNum, _ := strconv.Atoi(attr(start, "r"))
+		rec, err := r.readRow()
+		if err != nil {
+			return nil, 0, err
+		}
+		if rowNum == 0 {
+			return nil, 0, &fileError{msg: "worksheet row without a row number"}
+		}
+		if !allEmpty(rec) {
+			return rec, rowNum, nil
+		}
+	}
+}
+
+// readRow consumes the children of a <row> element.
+func (r *xlsxRecordReader) readRow() ([]string, error) {
+	var rec []string
+	next := 0
+	for {
+		tok, err := r.dec.Token()
+		if err != nil {
+			return nil, &fileError{msg: "worksheet XML is malformed"}
+		}
+		switch t := tok.(type) {
+		case xml.EndElement:
+			if t.Name.Local == "row" {
+				return rec, nil
+			}
+		case xml.StartElement:
+			if t.Name.Local != "c" {
+				continue
+			}
+			col := next
+			if ref := attr(t, "r"); ref != "" {
+				if c, ok := columnIndex(ref); ok {
+					col = c
+				}
+			}
+			var c struct {
+				V  string `xml:"v"`
+				IS struct {
+					T []string `xml:"t"`
+					R []struct {
+						T string `xml:"t"`
+					} `xml:"r"`
+				} `xml:"is"`
+			}
+			if err := r.dec.DecodeElement(&c, &t); err != nil {
+				return nil, &fileError{msg: "worksheet XML is malformed"}
+			}
+			var v string
+			switch attr(t, "t") {
+			case "s":
+				i, err := strconv.Atoi(strings.TrimSpace(c.V))
+				if err != nil || i < 0 || i >= len(r.shared) {
+					return nil, &fileError{msg: "worksheet references a missing shared string"}
+				}
+				v = r.shared[i]
+			case "inlineStr":
+				v = strings.Join(c.IS.T, "")
+				for _, run := range c.IS.R {
+					v += run.T
+				}
+			case "b":
+				v = "FALSE"
+				if strings.TrimSpace(c.V) == "1" {
+					v = "TRUE"
+				}
+			case "str", "e":
+				v = c.V
+			case "d":
+				v = r.isoDate(col, c.V)
+			default:
+				v = r.number(col, c.V)
+			}
+			for len(rec) <= col {
+				rec = append(rec, "")
+			}
+			rec[col] = v
+			next = col + 1
+		}
+	}
+}
+
+// number renders a numeric cell. In the datetime column it is an Excel serial date, unless the
+// upload declares epoch timestamps. Elsewhere it is rounded to the 15 significant digits Excel
+// keeps, so 0.1+0.2 stored as 0.30000000000000004 reads as 0.3.
+func (r *xlsxRecordReader) number(col int, v string) string {
+	v = strings.TrimSpace(v)
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return v
+	}
+	if col == r.dateCol && r.dates.format != domain.DatetimeFormatUnix && r.dates.format != domain.DatetimeFormatUnixMillis {
+		return r.dates.render(excelSerialTime(f, r.date1904, r.dates.location()))
+	}
+	d, err := decimal.NewFromString(strconv.FormatFloat(f, 'g', 15, 64))
+	if err != nil {
+		return v
+	}
+	return d.String()
+}
+
+// isoDate renders a cell of type d (an ISO 8601 value without offset) in the datetime column.
+func (r *xlsxRecordReader) isoDate(col int, v string) string {
+	if col != r.dateCol {
+		return v
+	}
+	t, ok := parseLayouts(v, r.dates.location(), "2006-01-02T15:04:05", dateLayout)
+	if !ok {
+		return v
+	}
+	return r.dates.render(t)
+}
+
+// excelSerialTime converts a serial date (days since the workbook epoch, fraction is the time of
+// day) to a wall-clock time in loc. Serials below 60 are shifted for Excel's fictitious 1900-02-29.
+func excelSerialTime(serial float64, date1904 bool, loc *time.Location) time.Time {
+	base := time.Date(1899, 12, 30, 0, 0, 0, 0, time.UTC)
+	if date1904 {
+		base = time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)
+	} else if serial < 60 {
+		serial++
+	}
+	days := math.Floor(serial)
+	ms := math.Round((serial - days) * 24 * 60 * 60 * 1000)
+	t := base.AddDate(0, 0, int(days)).Add(time.Duration(ms) * time.Millisecond)
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), loc)
+}
+
+// columnIndex turns a cell reference such as "AB12" into a 0-based column index.
+func columnIndex(ref string) (int, bool) {
+	col := 0
+	n := 0
+	for _, c := range ref {
+		if c < 'A' || c > 'Z' {
+			break
+		}
+		col = col*26 + int(c-'A'+1)
+		n++
+	}
+	return col - 1, n > 0
+}
+
+func readSharedStrings(f *zip.File) ([]string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	dec := xml.NewDecoder(rc)
+	var out []string
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return out, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		start, ok := tok.(xml.StartElement)
+		if !ok || start.Name.Local != "si" {
+			continue
+		}
+		// Rich text splits a string in runs; phonetic hints (rPh) are not part of the value.
+		var si struct {
+			T []string `xml:"t"`
+			R []struct {
+				T string `xml:"t"`
+			} `xml:"r"`
+		}
+		if err := dec.DecodeElement(&si, &start); err != nil {
+			return nil, err
+		}
+		s := strings.Join(si.T, "")
+		for _, run := range si.R {
+			s += run.T
+		}
+		out = append(out, s)
+	}
+}
+
+func decodeZipXML(files map[string]*zip.File, name string, v any) error {
+	f, ok := files[name]
+	if !ok {
+		return errors.New(name + " not found")
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
+
+func attr(e xml.StartElement, name string) string {
+	for _, a := range e.Attr {
+		if a.Name.Local == name {
+			return a.Value
+		}
+	}
+	return ""
+}
+
+func allEmpty(rec []string) bool {
+	for _, v := range rec {
+		if strings.TrimSpace(v) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// readerAt gives random access to r, which zip archives need. Uploaded and stored files
+// already support it; anything else is buffered.
+func readerAt(r io.Reader) (io.ReaderAt, int64, error) {
+	if rs, ok := r.(interface {
+		io.ReaderAt
+		io.Seeker
+	}); ok {
+		size, err := rs.Seek(0, io.SeekEnd)
+		if err != nil {
+			return nil, 0, err
+		}
+		if _, err := rs.Seek(0, io.SeekStart); err != nil {
+			return nil, 0, err
+		}
+		return rs, size, nil
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	return bytes.NewReader(b), int64(len(b)), nil
+}
@@ -0,0 +1,143 @@
+package csvmigration
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"stori-challenge/internal/domain"
+)
+
+// buildXLSX returns a minimal workbook. Each sheet body is the inner XML of <sheetData>.
+func buildXLSX(t *testing.T, shared []string, sheets [][2]string) *bytes.Reader {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	write := func(name, body string) {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("zip: %v", err)
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatalf("zip: %v", err)
+		}
+	}
+
+	var wb, rels strings.Builder
+	wb.WriteString(`<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"><sheets>`)
+	rels.WriteString(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">`)
+	for i, s := range sheets {
+		n := strconv.Itoa(i + 1)
+		wb.WriteString(`<sheet name="` + s[0] + `" sheetId="` + n + `" r:id="rId` + n + `"/>`)
+		rels.WriteString(`<Relationship Id="rId` + n + `" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet` + n + `.xml"/>`)
+		write("xl/worksheets/sheet"+n+".xml", `<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`+s[1]+`</sheetData></worksheet>`)
+	}
+	wb.WriteString(`</sheets></workbook>`)
+	rels.WriteString(`</Relationships>`)
+	write("xl/workbook.xml", wb.String())
+	write("xl/_rels/workbook.xml.rels", rels.String())
+
+	var ss strings.Builder
+	ss.WriteString(`<sst xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main">`)
+	for _, s := range shared {
+		ss.WriteString(`<si><t>` + s + `</t></si>`)
+	}
+	ss.WriteString(`</sst>`)
+	write("xl/sharedStrings.xml", ss.String())
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip: %v", err)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+// ledgerWorkbook has a cover sheet and a "Ledger" sheet with a float-noise amount, an Excel
+// serial date, a blank row and an invalid amount at row 5.
+func ledgerWorkbook(t *testing.T) *bytes.Reader {
+	shared := []string{"Transaction ID", "User ID", "Amount", "Date", "abc"}
+	ledger := `<row r="2"><c r="A2" t="s"><v>0</v></c><c r="B2" t="s"><v>1</v></c><c r="C2" t="s"><v>2</v></c><c r="D2" t="s"><v>3</v></c></row>` +
+		`<row r="3"><c r="A3"><v>1</v></c><c r="B3"><v>10</v></c><c r="C3"><v>0.30000000000000004</v></c><c r="D3"><v>45444.5</v></c></row>` +
+		`<row r="4"/>` +
+		`<row r="5"><c r="A5"><v>2</v></c><c r="B5"><v>10</v></c><c r="C5" t="s"><v>4</v></c><c r="D5" t="inlineStr"><is><t>2024-06-02T00:00:00Z</t></is></c></row>`
+	return buildXLSX(t, shared, [][2]string{
+		{"Cover", `<row r="1"><c r="A1" t="inlineStr"><is><t>nothing here</t></is></c></row>`},
+		{"Ledger", ledger},
+	})
+}
+
+func TestProcess_XLSX_SheetByNameSerialDatesAndLocatedErrors(t *testing.T) {
+	repo := &fakeRepo{}
+	svc := newSvcWithRepo(t, repo, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+
+	res, err := svc.Process(context.Background(), ledgerWorkbook(t), domain.MigrationOptions{
+		Format: domain.InputFormatXLSX, Mode: domain.MigrationModePartial, Sheet: "ledger",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if res.Inserted != 1 || len(res.Errors) != 1 {
+		t.Fatalf("unexpected result: %+v", res)
+	}
+	if e := res.Errors[0]; e.Sheet != "Ledger" || e.Row != 5 || e.Field != "amount" {
+		t.Fatalf("expected amount error at Ledger row 5, got %+v", e)
+	}
+	tx := repo.captured[0]
+	if tx.Amount.String() != "0.3" {
+		t.Fatalf("expected amount 0.3, got %s", tx.Amount)
+	}
+	if want := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC); !tx.DateTime.Equal(want) {
+		t.Fatalf("expected %s, got %s", want, tx.DateTime)
+	}
+}
+
+func TestValidate_XLSX_SheetByIndexAndTimezone(t *testing.T) {
+	svc := newSvcWithRepo(t, &fakeRepo{}, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+
+	report, err := svc.Validate(context.Background(), ledgerWorkbook(t), domain.MigrationOptions{
+		Format: domain.InputFormatXLSX, Sheet: "2", Timezone: "America/Mexico_City",
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if report.Rows != 2 || len(report.Errors) != 1 || report.Errors[0].Sheet != "Ledger" {
+		t.Fatalf("unexpected report: %+v", report)
+	}
+	// Serial dates are wall-clock times in the source time zone.
+	if want := time.Date(2024, 6, 1, 18, 0, 0, 0, time.UTC); report.Stats.From == nil || !report.Stats.From.Equal(want) {
+		t.Fatalf("expected %s, got %v", want, report.Stats.From)
+	}
+}
+
+func TestProcess_XLSX_UnknownSheet_ListsAvailable(t *testing.T) {
+	svc := newSvcWithRepo(t, &fakeRepo{}, time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC))
+
+	res, err := svc.Process(context.Background(), ledgerWorkbook(t), domain.MigrationOptions{Format: domain.InputFormatXLSX, Sheet: "3"})
+	if err == nil || len(res.Errors) != 1 || res.Errors[0].Row != 0 {
+		t.Fatalf("expected a file-level error, got %v %+v", err, res.Errors)
+	}
+	if msg := res.Errors[0].Message; !strings.Contains(msg, "Cover, Ledger") {
+		t.Fatalf("expected available sheets in %q", msg)
+	}
+}
+
+func Test_excelSerialTime(t *testing.T) {
+	cases := []struct {
+		serial   float64
+		date1904 bool
+		want     time.Time
+	}{
+		{45444, false, time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)},
+		{45444.75, false, time.Date(2024, 6, 1, 18, 0, 0, 0, time.UTC)},
+		{1, false, time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{61, false, time.Date(1900, 3, 1, 0, 0, 0, 0, time.UTC)},
+		{0, true, time.Date(1904, 1, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for _, c := range cases {
+		if got := excelSerialTime(c.serial, c.date1904, time.UTC); !got.Equal(c.want) {
+			t.Fatalf("serial %v (1904=%v): want %s got %s", c.serial, c.date1904, c.want, got)
+		}
+	}
+}
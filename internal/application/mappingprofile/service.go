@@ -0,0 +1,103 @@
+package mappingprofile
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/ports/repositories"
+	"stori-challenge/internal/ports/services"
+	"stori-challenge/internal/shared"
+)
+
+// namePattern keeps profile names safe to use as a path segment and a query value.
+var namePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+type mappingProfileService struct {
+	Repo repositories.MappingProfileRepository
+}
+
+// Ensure interface compliance
+var _ services.MappingProfileService = (*mappingProfileService)(nil)
+
+func NewMappingProfileService(repo repositories.MappingProfileRepository) services.MappingProfileService {
+	return &mappingProfileService{Repo: repo}
+}
+
+func (s *mappingProfileService) Save(ctx context.Context, p domain.MappingProfile) (domain.MappingProfile, error) {
+	if err := validateProfile(p); err != nil {
+		return domain.MappingProfile{}, err
+	}
+	saved, err := s.Repo.Save(ctx, p)
+	if err != nil {
+		return domain.MappingProfile{}, shared.NewInternal("db_failure", "database error", err)
+	}
+	return saved, nil
+}
+
+func (s *mappingProfileService) Get(ctx context.Context, name string) (domain.MappingProfile, error) {
+	p, found, err := s.Repo.GetByName(ctx, name)
+	if err != nil {
+		return domain.MappingProfile{}, shared.NewInternal("db_failure", "database error", err)
+	}
+	if !found {
+		return domain.MappingProfile{}, shared.NewNotFound("mapping_profile_not_found", "mapping profile not found", nil)
+	}
+	return p, nil
+}
+
+func (s *mappingProfileService) List(ctx context.Context) ([]domain.MappingProfile, error) {
+	out, err := s.Repo.List(ctx)
+	if err != nil {
+		return nil, shared.NewInternal("db_failure", "database error", err)
+	}
+	return out, nil
+}
+
+func (s *mappingProfileService) Delete(ctx context.Context, name string) error {
+	found, err := s.Repo.Delete(ctx, name)
+	if err != nil {
+		return shared.NewInternal("db_failure", "database error", err)
+	}
+	if !found {
+		return shared.NewNotFound("mapping_profile_not_found", "mapping profile not found", nil)
+	}
+	return nil
+}
+
+// validateProfile checks the name, that every key is a canonical column and that no header
+// name is claimed by two columns once normalized.
+func validateProfile(p domain.MappingProfile) *shared.AppError {
+	if !namePattern.MatchString(p.Name) {
+		return shared.NewBadRequest("invalid_mapping_profile", "name must be 1-64 lowercase letters, digits, '_' or '-'", nil)
+	}
+	if len(p.Columns) == 0 {
+		return shared.NewBadRequest("invalid_mapping_profile", "columns must map at least one column", nil)
+	}
+	canonical := make(map[string]bool, len(domain.MappableColumns))
+	for _, col := range domain.MappableColumns {
+		canonical[col] = true
+	}
+	owner := make(map[string]string)
+	for col, aliases := range p.Columns {
+		if !canonical[col] {
+			return shared.NewBadRequest("invalid_mapping_profile", fmt.Sprintf("unknown column %q, expected one of %s", col, strings.Join(domain.MappableColumns, ",")), nil)
+		}
+		if len(aliases) == 0 {
+			return shared.NewBadRequest("invalid_mapping_profile", fmt.Sprintf("column %q has no header names", col), nil)
+		}
+		for _, a := range aliases {
+			key := domain.NormalizeHeader(a)
+			if key == "" {
+				return shared.NewBadRequest("invalid_mapping_profile", fmt.Sprintf("column %q has an empty header name", col), nil)
+			}
+			if other, ok := owner[key]; ok && other != col {
+				return shared.NewBadRequest("invalid_mapping_profile", fmt.Sprintf("header %q is mapped to both %s and %s", a, other, col), nil)
+			}
+			owner[key] = col
+		}
+	}
+	return nil
+}
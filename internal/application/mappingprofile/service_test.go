@@ -0,0 +1,83 @@
+package mappingprofile
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/shared"
+)
+
+type fakeProfileRepo struct {
+	profiles map[string]domain.MappingProfile
+}
+
+func (f *fakeProfileRepo) Save(ctx context.Context, p domain.MappingProfile) (domain.MappingProfile, error) {
+	f.profiles[p.Name] = p
+	return p, nil
+}
+
+func (f *fakeProfileRepo) GetByName(ctx context.Context, name string) (domain.MappingProfile, bool, error) {
+	p, ok := f.profiles[name]
+	return p, ok, nil
+}
+
+func (f *fakeProfileRepo) List(ctx context.Context) ([]domain.MappingProfile, error) {
+	var out []domain.MappingProfile
+	for _, p := range f.profiles {
+		out = append(out, p)
+	}
+	return out, nil
+}
+
+func (f *fakeProfileRepo) Delete(ctx context.Context, name string) (bool, error) {
+	_, ok := f.profiles[name]
+	delete(f.profiles, name)
+	return ok, nil
+}
+
+func newSvc() (*mappingProfileService, *fakeProfileRepo) {
+	repo := &fakeProfileRepo{profiles: map[string]domain.MappingProfile{}}
+	return NewMappingProfileService(repo).(*mappingProfileService), repo
+}
+
+func TestSave_ValidProfile_Stored(t *testing.T) {
+	svc, repo := newSvc()
+	p := domain.MappingProfile{Name: "acme", Columns: map[string][]string{"amount": {"Monto"}, "datetime": {"Fecha Operacion"}}}
+	if _, err := svc.Save(context.Background(), p); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, ok := repo.profiles["acme"]; !ok {
+		t.Fatalf("expected profile to be stored")
+	}
+}
+
+func TestSave_InvalidProfiles_BadRequest(t *testing.T) {
+	svc, _ := newSvc()
+	cases := map[string]domain.MappingProfile{
+		"bad name":       {Name: "Acme Corp", Columns: map[string][]string{"amount": {"monto"}}},
+		"no columns":     {Name: "acme"},
+		"unknown column": {Name: "acme", Columns: map[string][]string{"currency": {"moneda"}}},
+		"empty alias":    {Name: "acme", Columns: map[string][]string{"amount": {" "}}},
+		"shared alias":   {Name: "acme", Columns: map[string][]string{"amount": {"valor"}, "user_id": {"VALOR"}}},
+	}
+	for name, p := range cases {
+		_, err := svc.Save(context.Background(), p)
+		var ae *shared.AppError
+		if !errors.As(err, &ae) || ae.Kind != shared.BadRequestKind || ae.Code != "invalid_mapping_profile" {
+			t.Fatalf("%s: expected invalid_mapping_profile, got %v", name, err)
+		}
+	}
+}
+
+func TestGetAndDelete_Missing_NotFound(t *testing.T) {
+	svc, _ := newSvc()
+	var ae *shared.AppError
+	if _, err := svc.Get(context.Background(), "missing"); !errors.As(err, &ae) || ae.Kind != shared.NotFoundKind {
+		t.Fatalf("expected NotFound on get, got %v", err)
+	}
+	if err := svc.Delete(context.Background(), "missing"); !errors.As(err, &ae) || ae.Kind != shared.NotFoundKind {
+		t.Fatalf("expected NotFound on delete, got %v", err)
+	}
+}
@@ -0,0 +1,96 @@
+package migrationbatch
+
+import (
+	"context"
+	"strings"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/ports/repositories"
+	"stori-challenge/internal/ports/services"
+	"stori-challenge/internal/shared"
+)
+
+type migrationBatchService struct {
+	Batches      repositories.MigrationBatchRepository
+	Transactions repositories.TransactionRepository
+}
+
+// Ensure interface compliance
+var _ services.MigrationBatchService = (*migrationBatchService)(nil)
+
+func NewMigrationBatchService(batches repositories.MigrationBatchRepository, transactions repositories.TransactionRepository) services.MigrationBatchService {
+	return &migrationBatchService{Batches: batches, Transactions: transactions}
+}
+
+func (s *migrationBatchService) Get(ctx context.Context, id int64) (domain.MigrationBatch, error) {
+	b, found, err := s.Batches.GetByID(ctx, id)
+	if err != nil {
+		return domain.MigrationBatch{}, shared.NewInternal("db_failure", "database error", err)
+	}
+	if !found {
+		return domain.MigrationBatch{}, shared.NewNotFound("migration_batch_not_found", "migration batch not found", nil)
+	}
+	if b.Status == domain.MigrationBatchReverted {
+		rev, found, err := s.Batches.GetRevert(ctx, id)
+		if err != nil {
+			return domain.MigrationBatch{}, shared.NewInternal("db_failure", "database error", err)
+		}
+		if found {
+			b.Revert = &rev
+		}
+	}
+	return b, nil
+}
+
+func (s *migrationBatchService) Revert(ctx context.Context, rev domain.BatchRevert) (domain.BatchRevert, error) {
+	rev.RevertedBy, rev.Reason = strings.TrimSpace(rev.RevertedBy), strings.TrimSpace(rev.Reason)
+	if rev.RevertedBy == "" || rev.Reason == "" {
+		return domain.BatchRevert{}, shared.NewBadRequest("invalid_revert_request", "reverted_by and reason are required", nil)
+	}
+	out, err := s.Batches.Revert(ctx, rev)
+	if err != nil {
+		return domain.BatchRevert{}, shared.NewInternal("db_failure", "database error", err)
+	}
+	switch {
+	case !out.Found:
+		return domain.BatchRevert{}, shared.NewNotFound("migration_batch_not_found", "migration batch not found", nil)
+	case out.Revert != nil:
+		return *out.Revert, nil
+	case out.LockedPeriod != "":
+		return domain.BatchRevert{}, shared.NewConflict("period_locked", "migration batch has transactions in locked period "+out.LockedPeriod, nil)
+	case out.Status == domain.MigrationBatchReverted:
+		return domain.BatchRevert{}, shared.NewConflict("batch_already_reverted", "migration batch was already reverted", nil)
+	default:
+		return domain.BatchRevert{}, shared.NewConflict("batch_not_revertible", "only SUCCEEDED migration batches can be reverted, this one is "+string(out.Status), nil)
+	}
+}
+
+func (s *migrationBatchService) RevertMigration(ctx context.Context, migrationID int64, rev domain.BatchRevert) (domain.BatchRevert, error) {
+	b, found, err := s.Batches.GetByMigrationID(ctx, migrationID)
+	if err != nil {
+		return domain.BatchRevert{}, shared.NewInternal("db_failure", "database error", err)
+	}
+	if !found {
+		return domain.BatchRevert{}, shared.NewNotFound("migration_batch_not_found", "migration has no batch to revert", nil)
+	}
+	rev.BatchID = b.ID
+	return s.Revert(ctx, rev)
+}
+
+func (s *migrationBatchService) GetTransactionSource(ctx context.Context, transactionID int64) (domain.TransactionSource, *domain.MigrationBatch, error) {
+	src, found, err := s.Transactions.GetSource(ctx, transactionID)
+	if err != nil {
+		return domain.TransactionSource{}, nil, shared.NewInternal("db_failure", "database error", err)
+	}
+	if !found {
+		return domain.TransactionSource{}, nil, shared.NewNotFound("transaction_not_found", "transaction not found", nil)
+	}
+	if src.BatchID == 0 {
+		return src, nil, nil
+	}
+	b, err := s.Get(ctx, src.BatchID)
+	if err != nil {
+		return domain.TransactionSource{}, nil, err
+	}
+	return src, &b, nil
+}
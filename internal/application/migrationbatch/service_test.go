@@ -0,0 +1,204 @@
+package migrationbatch
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/ports/repositories"
+	"stori-challenge/internal/shared"
+)
+
+type fakeBatchRepo struct {
+	batches map[int64]domain.MigrationBatch
+	reverts map[int64]domain.BatchRevert
+	// locked maps a batch to the locked period one of its transactions falls in.
+	locked map[int64]string
+}
+
+func (f *fakeBatchRepo) Create(ctx context.Context, b domain.MigrationBatch) (domain.MigrationBatch, error) {
+	return b, nil
+}
+
+func (f *fakeBatchRepo) Finish(ctx context.Context, b domain.MigrationBatch) error {
+	return nil
+}
+
+func (f *fakeBatchRepo) GetByID(ctx context.Context, id int64) (domain.MigrationBatch, bool, error) {
+	b, ok := f.batches[id]
+	return b, ok, nil
+}
+
+func (f *fakeBatchRepo) FindSucceeded(ctx context.Context, sha256 string) (domain.MigrationBatch, bool, error) {
+	return domain.MigrationBatch{}, false, nil
+}
+
+func (f *fakeBatchRepo) GetByMigrationID(ctx context.Context, migrationID int64) (domain.MigrationBatch, bool, error) {
+	for _, b := range f.batches {
+		if b.Source.MigrationID == migrationID {
+			return b, true, nil
+		}
+	}
+	return domain.MigrationBatch{}, false, nil
+}
+
+func (f *fakeBatchRepo) Revert(ctx context.Context, rev domain.BatchRevert) (repositories.BatchRevertOutcome, error) {
+	b, ok := f.batches[rev.BatchID]
+	if !ok {
+		return repositories.BatchRevertOutcome{}, nil
+	}
+	out := repositories.BatchRevertOutcome{Found: true, Status: b.Status, LockedPeriod: f.locked[rev.BatchID]}
+	if b.Status != domain.MigrationBatchSucceeded || out.LockedPeriod != "" {
+		return out, nil
+	}
+	rev.TransactionsDeleted = b.Inserted
+	rev.RevertedAt = time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	b.Status = domain.MigrationBatchReverted
+	f.batches[b.ID] = b
+	f.reverts[b.ID] = rev
+	out.Revert = &rev
+	return out, nil
+}
+
+func (f *fakeBatchRepo) GetRevert(ctx context.Context, batchID int64) (domain.BatchRevert, bool, error) {
+	rev, ok := f.reverts[batchID]
+	return rev, ok, nil
+}
+
+// fakeTxRepo only implements GetSource; the other methods are not used by the service.
+type fakeTxRepo struct {
+	repositories.TransactionRepository
+	sources map[int64]domain.TransactionSource
+	err     error
+}
+
+func (f *fakeTxRepo) GetSource(ctx context.Context, id int64) (domain.TransactionSource, bool, error) {
+	src, ok := f.sources[id]
+	return src, ok, f.err
+}
+
+func newSvc() (*migrationBatchService, *fakeBatchRepo, *fakeTxRepo) {
+	batches := &fakeBatchRepo{batches: map[int64]domain.MigrationBatch{}, reverts: map[int64]domain.BatchRevert{}, locked: map[int64]string{}}
+	txs := &fakeTxRepo{sources: map[int64]domain.TransactionSource{}}
+	return NewMigrationBatchService(batches, txs).(*migrationBatchService), batches, txs
+}
+
+func TestGet_NotFound(t *testing.T) {
+	svc, _, _ := newSvc()
+	_, err := svc.Get(context.Background(), 9)
+	var ae *shared.AppError
+	if !errors.As(err, &ae) || ae.Kind != shared.NotFoundKind || ae.Code != "migration_batch_not_found" {
+		t.Fatalf("expected migration_batch_not_found, got %v", err)
+	}
+}
+
+func TestGetTransactionSource_ReturnsRowAndBatch(t *testing.T) {
+	svc, batches, txs := newSvc()
+	batches.batches[3] = domain.MigrationBatch{ID: 3, Source: domain.UploadSource{FileName: "jan.zip", UploadedBy: "ana"}, SHA256: "abc"}
+	txs.sources[10] = domain.TransactionSource{BatchID: 3, File: "a.csv", Row: 7}
+
+	src, b, err := svc.GetTransactionSource(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if src.File != "a.csv" || src.Row != 7 || b == nil || b.Source.UploadedBy != "ana" {
+		t.Fatalf("unexpected source: %+v, %+v", src, b)
+	}
+}
+
+func TestGetTransactionSource_WithoutBatch_NilBatch(t *testing.T) {
+	svc, _, txs := newSvc()
+	txs.sources[10] = domain.TransactionSource{}
+
+	_, b, err := svc.GetTransactionSource(context.Background(), 10)
+	if err != nil || b != nil {
+		t.Fatalf("expected no batch, got %+v, %v", b, err)
+	}
+}
+
+func TestGetTransactionSource_UnknownTransaction_NotFound(t *testing.T) {
+	svc, _, _ := newSvc()
+	_, _, err := svc.GetTransactionSource(context.Background(), 10)
+	var ae *shared.AppError
+	if !errors.As(err, &ae) || ae.Code != "transaction_not_found" {
+		t.Fatalf("expected transaction_not_found, got %v", err)
+	}
+}
+
+func TestGetTransactionSource_DBError_Internal(t *testing.T) {
+	svc, _, txs := newSvc()
+	txs.err = errors.New("db down")
+	_, _, err := svc.GetTransactionSource(context.Background(), 10)
+	var ae *shared.AppError
+	if !errors.As(err, &ae) || ae.Code != "db_failure" {
+		t.Fatalf("expected db_failure, got %v", err)
+	}
+}
+
+func TestRevert_DeletesAndRecordsAudit(t *testing.T) {
+	svc, batches, _ := newSvc()
+	batches.batches[3] = domain.MigrationBatch{ID: 3, Status: domain.MigrationBatchSucceeded, Inserted: 12}
+
+	rev, err := svc.Revert(context.Background(), domain.BatchRevert{BatchID: 3, RevertedBy: " ana ", Reason: "wrong partner file"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if rev.TransactionsDeleted != 12 || rev.RevertedBy != "ana" || rev.RevertedAt.IsZero() {
+		t.Fatalf("unexpected revert: %+v", rev)
+	}
+	b, err := svc.Get(context.Background(), 3)
+	if err != nil || b.Status != domain.MigrationBatchReverted || b.Revert == nil || b.Revert.Reason != "wrong partner file" {
+		t.Fatalf("expected the batch to show its revert, got %+v, %v", b, err)
+	}
+
+	_, err = svc.Revert(context.Background(), domain.BatchRevert{BatchID: 3, RevertedBy: "ana", Reason: "again"})
+	var ae *shared.AppError
+	if !errors.As(err, &ae) || ae.Kind != shared.ConflictKind || ae.Code != "batch_already_reverted" {
+		t.Fatalf("expected batch_already_reverted, got %v", err)
+	}
+}
+
+func TestRevert_Refusals(t *testing.T) {
+	svc, batches, _ := newSvc()
+	batches.batches[3] = domain.MigrationBatch{ID: 3, Status: domain.MigrationBatchSucceeded}
+	batches.batches[4] = domain.MigrationBatch{ID: 4, Status: domain.MigrationBatchFailed}
+	batches.locked[3] = "2024-06"
+
+	cases := map[string]struct {
+		rev  domain.BatchRevert
+		code string
+	}{
+		"no reason":     {domain.BatchRevert{BatchID: 3, RevertedBy: "ana"}, "invalid_revert_request"},
+		"no author":     {domain.BatchRevert{BatchID: 3, Reason: "wrong file"}, "invalid_revert_request"},
+		"unknown batch": {domain.BatchRevert{BatchID: 9, RevertedBy: "ana", Reason: "wrong file"}, "migration_batch_not_found"},
+		"locked period": {domain.BatchRevert{BatchID: 3, RevertedBy: "ana", Reason: "wrong file"}, "period_locked"},
+		"failed batch":  {domain.BatchRevert{BatchID: 4, RevertedBy: "ana", Reason: "wrong file"}, "batch_not_revertible"},
+	}
+	for name, tc := range cases {
+		_, err := svc.Revert(context.Background(), tc.rev)
+		var ae *shared.AppError
+		if !errors.As(err, &ae) || ae.Code != tc.code {
+			t.Fatalf("%s: expected %s, got %v", name, tc.code, err)
+		}
+	}
+	if batches.batches[3].Status != domain.MigrationBatchSucceeded {
+		t.Fatalf("a refused revert must not change the batch")
+	}
+}
+
+func TestRevertMigration_ResolvesItsBatch(t *testing.T) {
+	svc, batches, _ := newSvc()
+	batches.batches[3] = domain.MigrationBatch{ID: 3, Source: domain.UploadSource{MigrationID: 12}, Status: domain.MigrationBatchSucceeded}
+
+	rev, err := svc.RevertMigration(context.Background(), 12, domain.BatchRevert{RevertedBy: "ana", Reason: "wrong file"})
+	if err != nil || rev.BatchID != 3 {
+		t.Fatalf("unexpected result: %+v, %v", rev, err)
+	}
+	_, err = svc.RevertMigration(context.Background(), 13, domain.BatchRevert{RevertedBy: "ana", Reason: "wrong file"})
+	var ae *shared.AppError
+	if !errors.As(err, &ae) || ae.Kind != shared.NotFoundKind {
+		t.Fatalf("expected NotFound, got %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package migrationjob
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/ports/repositories"
+	"stori-challenge/internal/ports/services"
+	"stori-challenge/internal/ports/storage"
+	"stori-challenge/internal/shared"
+)
+
+// migrationJobService implements services.MigrationJobService on top of a file store and the migrations table.
+type migrationJobService struct {
+	Repo     repositories.MigrationRepository
+	Store    storage.FileStore
+	Profiles repositories.MappingProfileRepository
+	Batches  repositories.MigrationBatchRepository
+	// Migrator reads stored files again to build error reports.
+	Migrator services.MigrationService
+}
+
+// Ensure interface compliance.
+var _ services.MigrationJobService = (*migrationJobService)(nil)
+
+// NewMigrationJobService constructs the asynchronous migration service.
+func NewMigrationJobService(repo repositories.MigrationRepository, store storage.FileStore, profiles repositories.MappingProfileRepository, batches repositories.MigrationBatchRepository, migrator services.MigrationService) services.MigrationJobService {
+	return &migrationJobService{Repo: repo, Store: store, Profiles: profiles, Batches: batches, Migrator: migrator}
+}
+
+// Enqueue saves the file first so a PENDING row always points to readable content.
+// An unknown mapping profile, or a seekable file whose content an earlier batch already
+// migrated (unless the run is idempotent), is rejected up front rather than failing in the worker.
+// The profile's columns are stored with the options, so the worker and the error report use
+// the profile as it is now.
+func (s *migrationJobService) Enqueue(ctx context.Context, fileName string, r io.Reader, opts domain.MigrationOptions) (domain.Migration, error) {
+	if opts.Profile != "" {
+		p, found, err := s.Profiles.GetByName(ctx, opts.Profile)
+		if err != nil {
+			return domain.Migration{}, shared.NewInternal("db_failure", "database error", err)
+		}
+		if !found {
+			return domain.Migration{}, shared.NewBadRequest("mapping_profile_not_found", "mapping profile not found", nil)
+		}
+		opts.ProfileColumns = p.Columns
+	}
+	if rs, ok := r.(io.ReadSeeker); ok && s.Batches != nil && !opts.Idempotent {
+		sum, _, err := domain.DigestSHA256(rs)
+		if err != nil {
+			return domain.Migration{}, shared.NewInternal("read_failure", "unable to read file", err)
+		}
+		prior, found, err := s.Batches.FindSucceeded(ctx, sum)
+		if err != nil {
+			return domain.Migration{}, shared.NewInternal("db_failure", "database error", err)
+		}
+		if found {
+			return domain.Migration{}, shared.NewConflict("duplicate_upload", fmt.Sprintf("file already migrated by batch %d", prior.ID), nil)
+		}
+	}
+	key, err := s.Store.Save(ctx, fileName, r)
+	if err != nil {
+		return domain.Migration{}, shared.NewInternal("storage_failure", "unable to store file", err)
+	}
+	m, err := s.Repo.Create(ctx, fileName, key, opts)
+	if err != nil {
+		return domain.Migration{}, shared.NewInternal("db_failure", "database error", err)
+	}
+	return m, nil
+}
+
+func (s *migrationJobService) Get(ctx context.Context, id int64) (domain.Migration, error) {
+	m, found, err := s.Repo.GetByID(ctx, id)
+	if err != nil {
+		return domain.Migration{}, shared.NewInternal("db_failure", "database error", err)
+	}
+	if !found {
+		return domain.Migration{}, shared.NewNotFound("migration_not_found", "migration not found", nil)
+	}
+	return m, nil
+}
+
+// WriteErrorReport is only available once the worker is done with the migration, and needs
+// at least one row error; file-level errors have no rows to report.
+func (s *migrationJobService) WriteErrorReport(ctx context.Context, id int64, w io.Writer) error {
+	m, err := s.Get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if m.Status == domain.MigrationStatusPending || m.Status == domain.MigrationStatusProcessing {
+		return shared.NewConflict("migration_not_finished", "migration is still "+strings.ToLower(string(m.Status)), nil)
+	}
+	// Warning rows were migrated, so they do not belong in a file meant to be fixed and re-uploaded.
+	errs := services.ReportErrors{List: withoutWarnings(m.Errors)}
+	if m.Summary != nil && m.Summary.Omitted > 0 {
+		// The stored list leaves errors out; the worker logged all of them, warnings aside.
+		errs.Each = func(fn func(domain.LoggedRowError) error) error {
+			return s.Repo.EachRowError(ctx, m.ID, fn)
+		}
+	} else if !hasRowErrors(errs.List) {
+		return shared.NewNotFound("no_row_errors", "migration has no row errors", nil)
+	}
+	f, err := s.Store.Open(ctx, m.FileKey)
+	if err != nil {
+		return shared.NewInternal("file_unavailable", "stored file could not be opened", err)
+	}
+	defer f.Close()
+	return s.Migrator.WriteErrorReport(ctx, f, m.Options, errs, w)
+}
+
+func withoutWarnings(errs []domain.RowError) []domain.RowError {
+	out := make([]domain.RowError, 0, len(errs))
+	for _, e := range errs {
+		if e.Severity != domain.RuleSeverityWarning {
+			out = append(out, e)
+		}
+	}
+	return out
+}
+
+func hasRowErrors(errs []domain.RowError) bool {
+	for _, e := range errs {
+		if e.Row > 0 {
+			return true
+		}
+	}
+	return false
+}
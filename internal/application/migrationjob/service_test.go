@@ -0,0 +1,423 @@
+package migrationjob
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/ports/repositories"
+	"stori-challenge/internal/shared"
+)
+
+type fakeMigrationRepo struct {
+	mu         sync.Mutex // the heartbeat goroutine reads migrations
+	migrations map[int64]domain.Migration
+	logged     map[int64][]domain.LoggedRowError
+	staleAfter time.Duration
+	heartbeats chan int64
+	finishErr  error // ctx error seen when recording the outcome
+	nextID     int64
+	createErr  error
+	getErr     error
+	claimErr   error
+}
+
+func newFakeMigrationRepo() *fakeMigrationRepo {
+	return &fakeMigrationRepo{migrations: map[int64]domain.Migration{}, logged: map[int64][]domain.LoggedRowError{}}
+}
+
+func (f *fakeMigrationRepo) Create(ctx context.Context, fileName, fileKey string, opts domain.MigrationOptions) (domain.Migration, error) {
+	if f.createErr != nil {
+		return domain.Migration{}, f.createErr
+	}
+	f.nextID++
+	m := domain.Migration{ID: f.nextID, Status: domain.MigrationStatusPending, FileName: fileName, FileKey: fileKey, Options: opts, CreatedAt: time.Now().UTC()}
+	f.migrations[m.ID] = m
+	return m, nil
+}
+
+func (f *fakeMigrationRepo) GetByID(ctx context.Context, id int64) (domain.Migration, bool, error) {
+	if f.getErr != nil {
+		return domain.Migration{}, false, f.getErr
+	}
+	m, ok := f.migrations[id]
+	return m, ok, nil
+}
+
+func (f *fakeMigrationRepo) ClaimNext(ctx context.Context, staleAfter time.Duration) (domain.Migration, bool, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.staleAfter = staleAfter
+	if f.claimErr != nil {
+		return domain.Migration{}, false, f.claimErr
+	}
+	for id := int64(1); id <= f.nextID; id++ {
+		m, ok := f.migrations[id]
+		if ok && m.Status == domain.MigrationStatusPending {
+			m.Status = domain.MigrationStatusProcessing
+			m.Attempt++
+			f.migrations[id] = m
+			return m, true, nil
+		}
+	}
+	return domain.Migration{}, false, nil
+}
+
+// reclaim claims migration id again, as another worker would once its lease runs out.
+func (f *fakeMigrationRepo) reclaim(id int64) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	m := f.migrations[id]
+	m.Attempt++
+	f.migrations[id] = m
+}
+
+// held reports whether migration id is PROCESSING under attempt; f.mu must be held.
+func (f *fakeMigrationRepo) held(id int64, attempt int) error {
+	m := f.migrations[id]
+	if m.Status != domain.MigrationStatusProcessing || m.Attempt != attempt {
+		return repositories.ErrLeaseLost
+	}
+	return nil
+}
+
+func (f *fakeMigrationRepo) Heartbeat(ctx context.Context, id int64, attempt int) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.held(id, attempt); err != nil {
+		return err
+	}
+	select {
+	case f.heartbeats <- id:
+	default:
+	}
+	return nil
+}
+
+func (f *fakeMigrationRepo) Complete(ctx context.Context, id int64, attempt int, counts domain.MigrationCounts, items []domain.RowError, summary *domain.RowErrorSummary) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.held(id, attempt); err != nil {
+		return err
+	}
+	f.finishErr = ctx.Err()
+	m := f.migrations[id]
+	m.Status = domain.MigrationStatusCompleted
+	m.Inserted = counts.Inserted
+	m.Rejected = counts.Rejected
+	m.AlreadyPresent = counts.AlreadyPresent
+	m.Errors = items
+	m.Summary = summary
+	f.migrations[id] = m
+	return nil
+}
+
+func (f *fakeMigrationRepo) Fail(ctx context.Context, id int64, attempt int, code string, items []domain.RowError, summary *domain.RowErrorSummary) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.held(id, attempt); err != nil {
+		return err
+	}
+	f.finishErr = ctx.Err()
+	m := f.migrations[id]
+	m.Status = domain.MigrationStatusFailed
+	m.ErrorCode = code
+	m.Errors = items
+	m.Summary = summary
+	f.migrations[id] = m
+	return nil
+}
+
+func (f *fakeMigrationRepo) AddRowErrors(ctx context.Context, id int64, attempt int, errs []domain.LoggedRowError) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.held(id, attempt); err != nil {
+		return err
+	}
+	f.logged[id] = append(f.logged[id], errs...)
+	return nil
+}
+
+func (f *fakeMigrationRepo) EachRowError(ctx context.Context, id int64, fn func(domain.LoggedRowError) error) error {
+	for _, e := range f.logged[id] {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type fakeStore struct {
+	files   map[string][]byte
+	saveErr error
+}
+
+func newFakeStore() *fakeStore { return &fakeStore{files: map[string][]byte{}} }
+
+func (f *fakeStore) Save(ctx context.Context, name string, r io.Reader) (string, error) {
+	if f.saveErr != nil {
+		return "", f.saveErr
+	}
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return "", err
+	}
+	key := "key-" + name
+	f.files[key] = b
+	return key, nil
+}
+
+func (f *fakeStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	b, ok := f.files[key]
+	if !ok {
+		return nil, errors.New("not found")
+	}
+	return io.NopCloser(bytes.NewReader(b)), nil
+}
+
+type fakeProfileRepo struct {
+	profiles map[string]domain.MappingProfile
+}
+
+func (f *fakeProfileRepo) Save(ctx context.Context, p domain.MappingProfile) (domain.MappingProfile, error) {
+	f.profiles[p.Name] = p
+	return p, nil
+}
+
+func (f *fakeProfileRepo) GetByName(ctx context.Context, name string) (domain.MappingProfile, bool, error) {
+	p, ok := f.profiles[name]
+	return p, ok, nil
+}
+
+func (f *fakeProfileRepo) List(ctx context.Context) ([]domain.MappingProfile, error) {
+	return nil, nil
+}
+
+func (f *fakeProfileRepo) Delete(ctx context.Context, name string) (bool, error) {
+	return false, nil
+}
+
+type fakeBatchRepo struct {
+	succeeded map[string]domain.MigrationBatch
+}
+
+func (f *fakeBatchRepo) Create(ctx context.Context, b domain.MigrationBatch) (domain.MigrationBatch, error) {
+	return b, nil
+}
+
+func (f *fakeBatchRepo) Finish(ctx context.Context, b domain.MigrationBatch) error {
+	return nil
+}
+
+func (f *fakeBatchRepo) GetByID(ctx context.Context, id int64) (domain.MigrationBatch, bool, error) {
+	return domain.MigrationBatch{}, false, nil
+}
+
+func (f *fakeBatchRepo) FindSucceeded(ctx context.Context, sha256 string) (domain.MigrationBatch, bool, error) {
+	b, ok := f.succeeded[sha256]
+	return b, ok, nil
+}
+
+func (f *fakeBatchRepo) GetByMigrationID(ctx context.Context, migrationID int64) (domain.MigrationBatch, bool, error) {
+	return domain.MigrationBatch{}, false, nil
+}
+
+func (f *fakeBatchRepo) Revert(ctx context.Context, rev domain.BatchRevert) (repositories.BatchRevertOutcome, error) {
+	return repositories.BatchRevertOutcome{}, nil
+}
+
+func (f *fakeBatchRepo) GetRevert(ctx context.Context, batchID int64) (domain.BatchRevert, bool, error) {
+	return domain.BatchRevert{}, false, nil
+}
+
+func TestEnqueue_StoresFileAndCreatesPending(t *testing.T) {
+	repo := newFakeMigrationRepo()
+	store := newFakeStore()
+	svc := NewMigrationJobService(repo, store, nil, nil, nil)
+
+	m, err := svc.Enqueue(context.Background(), "data.csv", strings.NewReader("id,user_id,amount,datetime\n"), domain.MigrationOptions{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.ID == 0 || m.Status != domain.MigrationStatusPending {
+		t.Fatalf("unexpected migration: %+v", m)
+	}
+	if _, ok := store.files[m.FileKey]; !ok {
+		t.Fatalf("expected file stored under %q", m.FileKey)
+	}
+}
+
+func TestEnqueue_UnknownProfile_BadRequestWithoutStoring(t *testing.T) {
+	repo := newFakeMigrationRepo()
+	store := newFakeStore()
+	columns := map[string][]string{domain.ColumnID: {"Reference"}}
+	profiles := &fakeProfileRepo{profiles: map[string]domain.MappingProfile{"bank": {Name: "bank", Columns: columns}}}
+	svc := NewMigrationJobService(repo, store, profiles, nil, nil)
+
+	_, err := svc.Enqueue(context.Background(), "data.csv", strings.NewReader(""), domain.MigrationOptions{Profile: "other"})
+	var ae *shared.AppError
+	if !errors.As(err, &ae) || ae.Kind != shared.BadRequestKind || ae.Code != "mapping_profile_not_found" {
+		t.Fatalf("expected mapping_profile_not_found, got %v", err)
+	}
+	if len(store.files) != 0 || len(repo.migrations) != 0 {
+		t.Fatalf("expected nothing stored, got %d files and %d migrations", len(store.files), len(repo.migrations))
+	}
+
+	m, err := svc.Enqueue(context.Background(), "data.csv", strings.NewReader(""), domain.MigrationOptions{Profile: "bank"})
+	if err != nil || m.Options.Profile != "bank" {
+		t.Fatalf("expected migration with profile, got %+v, %v", m, err)
+	}
+	if got := m.Options.ProfileColumns[domain.ColumnID]; len(got) != 1 || got[0] != "Reference" {
+		t.Fatalf("expected the profile columns stored with the migration, got %+v", m.Options.ProfileColumns)
+	}
+}
+
+func TestEnqueue_AlreadyMigratedContent_ConflictWithoutStoring(t *testing.T) {
+	repo := newFakeMigrationRepo()
+	store := newFakeStore()
+	body := "id,user_id,amount,datetime\n"
+	sum, _, _ := domain.DigestSHA256(strings.NewReader(body))
+	batches := &fakeBatchRepo{succeeded: map[string]domain.MigrationBatch{sum: {ID: 5}}}
+	svc := NewMigrationJobService(repo, store, nil, batches, nil)
+
+	_, err := svc.Enqueue(context.Background(), "data.csv", strings.NewReader(body), domain.MigrationOptions{})
+	var ae *shared.AppError
+	if !errors.As(err, &ae) || ae.Kind != shared.ConflictKind || ae.Code != "duplicate_upload" {
+		t.Fatalf("expected duplicate_upload, got %v", err)
+	}
+	if len(store.files) != 0 || len(repo.migrations) != 0 {
+		t.Fatalf("expected nothing stored, got %d files and %d migrations", len(store.files), len(repo.migrations))
+	}
+
+	m, err := svc.Enqueue(context.Background(), "data.csv", strings.NewReader(body+"1,1,10,2024-01-01T00:00:00Z\n"), domain.MigrationOptions{})
+	if err != nil || m.ID == 0 {
+		t.Fatalf("expected new content to be enqueued, got %+v, %v", m, err)
+	}
+	if string(store.files[m.FileKey]) != body+"1,1,10,2024-01-01T00:00:00Z\n" {
+		t.Fatalf("stored file was not rewound after hashing: %q", store.files[m.FileKey])
+	}
+}
+
+func TestEnqueue_StorageError_Internal(t *testing.T) {
+	store := newFakeStore()
+	store.saveErr = errors.New("disk full")
+	svc := NewMigrationJobService(newFakeMigrationRepo(), store, nil, nil, nil)
+
+	_, err := svc.Enqueue(context.Background(), "data.csv", strings.NewReader(""), domain.MigrationOptions{})
+	var ae *shared.AppError
+	if !errors.As(err, &ae) || ae.Kind != shared.InternalKind || ae.Code != "storage_failure" {
+		t.Fatalf("expected storage_failure, got %v", err)
+	}
+}
+
+func TestEnqueue_DBError_Internal(t *testing.T) {
+	repo := newFakeMigrationRepo()
+	repo.createErr = errors.New("db down")
+	svc := NewMigrationJobService(repo, newFakeStore(), nil, nil, nil)
+
+	_, err := svc.Enqueue(context.Background(), "data.csv", strings.NewReader(""), domain.MigrationOptions{})
+	var ae *shared.AppError
+	if !errors.As(err, &ae) || ae.Code != "db_failure" {
+		t.Fatalf("expected db_failure, got %v", err)
+	}
+}
+
+func TestGet_NotFound(t *testing.T) {
+	svc := NewMigrationJobService(newFakeMigrationRepo(), newFakeStore(), nil, nil, nil)
+	_, err := svc.Get(context.Background(), 99)
+	var ae *shared.AppError
+	if !errors.As(err, &ae) || ae.Kind != shared.NotFoundKind {
+		t.Fatalf("expected NotFound, got %v", err)
+	}
+}
+
+func TestGet_ReturnsMigration(t *testing.T) {
+	repo := newFakeMigrationRepo()
+	svc := NewMigrationJobService(repo, newFakeStore(), nil, nil, nil)
+	created, _ := svc.Enqueue(context.Background(), "data.csv", strings.NewReader(""), domain.MigrationOptions{})
+
+	got, err := svc.Get(context.Background(), created.ID)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got.ID != created.ID || got.FileName != "data.csv" {
+		t.Fatalf("unexpected migration: %+v", got)
+	}
+}
+
+func TestWriteErrorReport_ReadsStoredFileWithOptions(t *testing.T) {
+	repo := newFakeMigrationRepo()
+	migrator := &fakeMigrator{}
+	svc := NewMigrationJobService(repo, newFakeStore(), nil, nil, migrator)
+	m, _ := svc.Enqueue(context.Background(), "data.csv", strings.NewReader("body\n"), domain.MigrationOptions{Delimiter: ";"})
+
+	var buf bytes.Buffer
+	err := svc.WriteErrorReport(context.Background(), m.ID, &buf)
+	var ae *shared.AppError
+	if !errors.As(err, &ae) || ae.Kind != shared.ConflictKind || ae.Code != "migration_not_finished" {
+		t.Fatalf("expected migration_not_finished, got %v", err)
+	}
+
+	m.Status = domain.MigrationStatusFailed
+	m.Errors = []domain.RowError{{Row: 0, Field: "file", Message: "invalid or missing header"}}
+	repo.migrations[m.ID] = m
+	err = svc.WriteErrorReport(context.Background(), m.ID, &buf)
+	if !errors.As(err, &ae) || ae.Kind != shared.NotFoundKind || ae.Code != "no_row_errors" {
+		t.Fatalf("expected no_row_errors, got %v", err)
+	}
+
+	// Rows that only broke warning rules were migrated and stay out of the report.
+	m.Errors = append(m.Errors, domain.RowError{Row: 1, Field: "amount", Rule: "max_amount", Severity: domain.RuleSeverityWarning})
+	repo.migrations[m.ID] = m
+	err = svc.WriteErrorReport(context.Background(), m.ID, &buf)
+	if !errors.As(err, &ae) || ae.Code != "no_row_errors" {
+		t.Fatalf("expected no_row_errors with only warnings, got %v", err)
+	}
+
+	m.Errors = append(m.Errors, domain.RowError{Row: 2, Field: "amount"})
+	repo.migrations[m.ID] = m
+	if err := svc.WriteErrorReport(context.Background(), m.ID, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.String() != "body\n2 errors" || migrator.opts.Delimiter != ";" {
+		t.Fatalf("unexpected report %q with options %+v", buf.String(), migrator.opts)
+	}
+}
+
+func TestWriteErrorReport_OmittedErrors_ReadsTheLog(t *testing.T) {
+	repo := newFakeMigrationRepo()
+	migrator := &fakeMigrator{}
+	svc := NewMigrationJobService(repo, newFakeStore(), nil, nil, migrator)
+	m, _ := svc.Enqueue(context.Background(), "data.csv", strings.NewReader("body\n"), domain.MigrationOptions{})
+
+	m.Status = domain.MigrationStatusFailed
+	m.Errors = []domain.RowError{{Row: 1, Field: "amount"}, {Row: 0, Field: "file", Value: "2", Message: "more row errors omitted"}}
+	m.Summary = &domain.RowErrorSummary{Total: 3, Omitted: 2}
+	repo.migrations[m.ID] = m
+	for row := 1; row <= 3; row++ {
+		repo.logged[m.ID] = append(repo.logged[m.ID], domain.LoggedRowError{RunRow: row, RowError: domain.RowError{Row: row, Field: "amount"}})
+	}
+
+	var buf bytes.Buffer
+	if err := svc.WriteErrorReport(context.Background(), m.ID, &buf); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if buf.String() != "body\n3 errors" {
+		t.Fatalf("expected the report built from the log, got %q", buf.String())
+	}
+}
+
+func TestWriteErrorReport_NotFound(t *testing.T) {
+	svc := NewMigrationJobService(newFakeMigrationRepo(), newFakeStore(), nil, nil, &fakeMigrator{})
+	err := svc.WriteErrorReport(context.Background(), 99, io.Discard)
+	var ae *shared.AppError
+	if !errors.As(err, &ae) || ae.Code != "migration_not_found" {
+		t.Fatalf("expected migration_not_found, got %v", err)
+	}
+}
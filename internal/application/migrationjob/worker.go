@@ -0,0 +1,190 @@
+package migrationjob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/ports/repositories"
+	"stori-challenge/internal/ports/services"
+	"stori-challenge/internal/ports/storage"
+	"stori-challenge/internal/shared"
+)
+
+const (
+	// DefaultPollInterval is how long an idle worker waits before looking for new migrations.
+	DefaultPollInterval = 2 * time.Second
+	// DefaultLease is how long a migration stays PROCESSING without a heartbeat before another
+	// worker reclaims it. Workers send one every third of it.
+	DefaultLease = 5 * time.Minute
+	// finalizeTimeout bounds recording the outcome of a migration, which is done even when the
+	// worker is being stopped.
+	finalizeTimeout = 30 * time.Second
+)
+
+// Worker claims PENDING migrations and streams them through the migration service.
+// Claiming is delegated to the repository, so any number of workers (in one or many
+// instances) can run against the same table. While processing, a worker renews its lease
+// with heartbeats; a migration whose worker crashed is reclaimed once the lease runs out.
+type Worker struct {
+	Repo         repositories.MigrationRepository
+	Store        storage.FileStore
+	Migrator     services.MigrationService
+	PollInterval time.Duration
+	// Lease is zero to never reclaim migrations, nor send heartbeats.
+	Lease time.Duration
+}
+
+// NewWorker constructs a worker with the default poll interval and lease.
+func NewWorker(repo repositories.MigrationRepository, store storage.FileStore, migrator services.MigrationService) *Worker {
+	return &Worker{Repo: repo, Store: store, Migrator: migrator, PollInterval: DefaultPollInterval, Lease: DefaultLease}
+}
+
+// Run processes migrations until ctx is cancelled. It drains the queue before sleeping.
+func (w *Worker) Run(ctx context.Context) {
+	for {
+		worked, err := w.RunOnce(ctx)
+		if err != nil {
+			log.Printf("migration worker: %v", err)
+		}
+		if worked && err == nil {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(w.PollInterval):
+		}
+	}
+}
+
+// RunOnce claims and processes at most one migration. It returns false when the queue is empty.
+func (w *Worker) RunOnce(ctx context.Context) (bool, error) {
+	m, ok, err := w.Repo.ClaimNext(ctx, w.Lease)
+	if err != nil {
+		return false, err
+	}
+	if !ok {
+		return false, nil
+	}
+	return true, w.process(ctx, m)
+}
+
+func (w *Worker) process(ctx context.Context, m domain.Migration) error {
+	// The outcome is recorded even if ctx is cancelled meanwhile, so a stopping worker does not
+	// leave the migration PROCESSING until its lease runs out.
+	fctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), finalizeTimeout)
+	defer cancel()
+
+	f, err := w.Store.Open(ctx, m.FileKey)
+	if err != nil {
+		return w.leaseErr(m, w.Repo.Fail(fctx, m.ID, m.Attempt, "file_unavailable", []domain.RowError{{
+			Row:     0,
+			Field:   "file",
+			Value:   m.FileName,
+			Message: "stored file could not be opened",
+		}}, nil))
+	}
+	defer f.Close()
+
+	opts := m.Options
+	opts.Source.FileName, opts.Source.MigrationID = m.FileName, m.ID
+	// Losing the lease stops the run: another worker has the migration and its import would
+	// only be rolled back.
+	rctx, stopRun := context.WithCancelCause(ctx)
+	defer stopRun(nil)
+	stop := w.heartbeat(rctx, m, stopRun)
+	res, err := w.Migrator.ProcessStream(rctx, f, opts)
+	stop()
+	if res.ErrorLog != nil {
+		defer res.ErrorLog.Close()
+	}
+	if errors.Is(context.Cause(rctx), repositories.ErrLeaseLost) {
+		return w.leaseErr(m, repositories.ErrLeaseLost)
+	}
+	var logErr error
+	if res.ErrorLog != nil {
+		// The stored list leaves errors out, so the error report reads all of them from the log.
+		logErr = w.saveErrorLog(fctx, m, res.ErrorLog)
+		if errors.Is(logErr, repositories.ErrLeaseLost) {
+			return w.leaseErr(m, logErr)
+		}
+	}
+	if err != nil {
+		code := "internal_error"
+		var ae *shared.AppError
+		if errors.As(err, &ae) {
+			code = ae.Code
+		}
+		return errors.Join(w.leaseErr(m, w.Repo.Fail(fctx, m.ID, m.Attempt, code, append(res.Errors, res.Warnings...), res.Summary)), logErr)
+	}
+	counts := domain.MigrationCounts{Inserted: res.Inserted, Rejected: res.Rejected, AlreadyPresent: res.AlreadyPresent, Dialect: res.Dialect}
+	// Warnings are stored with the errors; their Severity tells them apart.
+	return errors.Join(w.leaseErr(m, w.Repo.Complete(fctx, m.ID, m.Attempt, counts, append(res.Errors, res.Warnings...), res.Summary)), logErr)
+}
+
+// leaseErr names the migration in err when it is repositories.ErrLeaseLost.
+func (w *Worker) leaseErr(m domain.Migration, err error) error {
+	if errors.Is(err, repositories.ErrLeaseLost) {
+		return fmt.Errorf("migration %d attempt %d: %w", m.ID, m.Attempt, err)
+	}
+	return err
+}
+
+// heartbeat renews the lease on m until the returned function is called. When the lease turns
+// out to be lost it calls lost with repositories.ErrLeaseLost and stops.
+func (w *Worker) heartbeat(ctx context.Context, m domain.Migration, lost context.CancelCauseFunc) (stop func()) {
+	if w.Lease <= 0 {
+		return func() {}
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		t := time.NewTicker(w.Lease / 3)
+		defer t.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-t.C:
+				err := w.Repo.Heartbeat(ctx, m.ID, m.Attempt)
+				if errors.Is(err, repositories.ErrLeaseLost) {
+					log.Printf("migration worker: migration %d was reclaimed, stopping attempt %d", m.ID, m.Attempt)
+					lost(err)
+					return
+				}
+				if err != nil && ctx.Err() == nil {
+					log.Printf("migration worker: heartbeat of migration %d: %v", m.ID, err)
+				}
+			}
+		}
+	}()
+	return func() {
+		cancel()
+		<-done
+	}
+}
+
+// errorLogBatch is how many logged row errors the worker stores at a time.
+const errorLogBatch = 1000
+
+func (w *Worker) saveErrorLog(ctx context.Context, m domain.Migration, l services.RowErrorLog) error {
+	batch := make([]domain.LoggedRowError, 0, errorLogBatch)
+	err := l.Each(func(e domain.LoggedRowError) error {
+		batch = append(batch, e)
+		if len(batch) < errorLogBatch {
+			return nil
+		}
+		err := w.Repo.AddRowErrors(ctx, m.ID, m.Attempt, batch)
+		batch = batch[:0]
+		return err
+	})
+	if err != nil || len(batch) == 0 {
+		return err
+	}
+	return w.Repo.AddRowErrors(ctx, m.ID, m.Attempt, batch)
+}
@@ -0,0 +1,319 @@
+package migrationjob
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/ports/repositories"
+	"stori-challenge/internal/ports/services"
+	"stori-challenge/internal/shared"
+)
+
+type fakeMigrator struct {
+	result services.MigrationResult
+	err    error
+	body   string
+	opts   domain.MigrationOptions
+	during func(ctx context.Context) // runs while the migration is processed
+}
+
+func (f *fakeMigrator) Process(ctx context.Context, r io.Reader, opts domain.MigrationOptions) (services.MigrationResult, error) {
+	panic("worker must use ProcessStream")
+}
+
+func (f *fakeMigrator) ProcessStream(ctx context.Context, r io.Reader, opts domain.MigrationOptions) (services.MigrationResult, error) {
+	b, _ := io.ReadAll(r)
+	f.body = string(b)
+	f.opts = opts
+	if f.during != nil {
+		f.during(ctx)
+	}
+	return f.result, f.err
+}
+
+func (f *fakeMigrator) Validate(ctx context.Context, r io.Reader, opts domain.MigrationOptions) (services.MigrationValidation, error) {
+	panic("worker must use ProcessStream")
+}
+
+// WriteErrorReport echoes the stored file followed by the number of row errors.
+func (f *fakeMigrator) WriteErrorReport(ctx context.Context, r io.Reader, opts domain.MigrationOptions, errs services.ReportErrors, w io.Writer) error {
+	f.opts = opts
+	if _, err := io.Copy(w, r); err != nil {
+		return err
+	}
+	n := len(errs.List)
+	if errs.Each != nil {
+		n = 0
+		if err := errs.Each(func(domain.LoggedRowError) error { n++; return nil }); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%d errors", n)
+	return err
+}
+
+// fakeErrorLog is a services.RowErrorLog over a slice.
+type fakeErrorLog struct {
+	errs   []domain.LoggedRowError
+	closed bool
+}
+
+func (l *fakeErrorLog) Each(fn func(domain.LoggedRowError) error) error {
+	for _, e := range l.errs {
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (l *fakeErrorLog) Close() error {
+	l.closed = true
+	return nil
+}
+
+func newEnqueued(t *testing.T, content string) (*fakeMigrationRepo, *fakeStore, domain.Migration) {
+	t.Helper()
+	return newEnqueuedWithOptions(t, content, domain.MigrationOptions{})
+}
+
+func newEnqueuedWithOptions(t *testing.T, content string, opts domain.MigrationOptions) (*fakeMigrationRepo, *fakeStore, domain.Migration) {
+	t.Helper()
+	repo := newFakeMigrationRepo()
+	store := newFakeStore()
+	m, err := NewMigrationJobService(repo, store, nil, nil, nil).Enqueue(context.Background(), "data.csv", strings.NewReader(content), opts)
+	if err != nil {
+		t.Fatalf("enqueue: %v", err)
+	}
+	return repo, store, m
+}
+
+func TestRunOnce_EmptyQueue_ReturnsFalse(t *testing.T) {
+	w := NewWorker(newFakeMigrationRepo(), newFakeStore(), &fakeMigrator{})
+	worked, err := w.RunOnce(context.Background())
+	if err != nil || worked {
+		t.Fatalf("expected no work, got worked=%v err=%v", worked, err)
+	}
+}
+
+func TestRunOnce_Success_MarksCompleted(t *testing.T) {
+	repo, store, m := newEnqueued(t, "csv-body")
+	migrator := &fakeMigrator{result: services.MigrationResult{Inserted: 7}}
+	w := NewWorker(repo, store, migrator)
+
+	worked, err := w.RunOnce(context.Background())
+	if err != nil || !worked {
+		t.Fatalf("expected work, got worked=%v err=%v", worked, err)
+	}
+	got := repo.migrations[m.ID]
+	if got.Status != domain.MigrationStatusCompleted || got.Inserted != 7 {
+		t.Fatalf("unexpected migration: %+v", got)
+	}
+	if migrator.body != "csv-body" {
+		t.Fatalf("migrator got %q", migrator.body)
+	}
+}
+
+func TestRunOnce_PartialMode_PassesOptionsAndStoresRejected(t *testing.T) {
+	repo, store, m := newEnqueuedWithOptions(t, "csv-body", domain.MigrationOptions{Mode: domain.MigrationModePartial})
+	items := []services.RowError{{Row: 3, Field: "amount", Value: "x", Message: "not a valid number"}}
+	migrator := &fakeMigrator{result: services.MigrationResult{Inserted: 4, Rejected: 1, Errors: items}}
+	w := NewWorker(repo, store, migrator)
+
+	if _, err := w.RunOnce(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !migrator.opts.IsPartial() {
+		t.Fatalf("expected partial options, got %+v", migrator.opts)
+	}
+	if migrator.opts.Source.MigrationID != m.ID || migrator.opts.Source.FileName != "data.csv" {
+		t.Fatalf("expected the batch source of the migration, got %+v", migrator.opts.Source)
+	}
+	got := repo.migrations[m.ID]
+	if got.Status != domain.MigrationStatusCompleted || got.Inserted != 4 || got.Rejected != 1 || len(got.Errors) != 1 {
+		t.Fatalf("unexpected migration: %+v", got)
+	}
+}
+
+func TestRunOnce_StoresRuleWarningsWithErrors(t *testing.T) {
+	repo, store, m := newEnqueuedWithOptions(t, "csv-body", domain.MigrationOptions{Mode: domain.MigrationModePartial})
+	migrator := &fakeMigrator{result: services.MigrationResult{
+		Inserted: 4,
+		Rejected: 1,
+		Errors:   []services.RowError{{Row: 3, Field: "amount", Message: "not a valid number"}},
+		Warnings: []services.RowError{{Row: 1, Field: "amount", Message: "amount above the maximum of 100", Rule: "max_amount", Severity: domain.RuleSeverityWarning}},
+	}}
+	w := NewWorker(repo, store, migrator)
+
+	if _, err := w.RunOnce(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := repo.migrations[m.ID]
+	if len(got.Errors) != 2 || got.Errors[1].Severity != domain.RuleSeverityWarning {
+		t.Fatalf("expected the error and the warning to be stored, got %+v", got.Errors)
+	}
+}
+
+func TestRunOnce_ServiceError_MarksFailedWithCodeAndItems(t *testing.T) {
+	repo, store, m := newEnqueued(t, "csv-body")
+	items := []services.RowError{{Row: 2, Field: "amount", Value: "x", Message: "not a valid number"}}
+	w := NewWorker(repo, store, &fakeMigrator{result: services.MigrationResult{Errors: items}, err: shared.NewBadRequest("validation_error", "validation failed", nil)})
+
+	if _, err := w.RunOnce(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := repo.migrations[m.ID]
+	if got.Status != domain.MigrationStatusFailed || got.ErrorCode != "validation_error" || len(got.Errors) != 1 {
+		t.Fatalf("unexpected migration: %+v", got)
+	}
+}
+
+func TestRunOnce_ServiceError_StoresSummary(t *testing.T) {
+	repo, store, m := newEnqueued(t, "csv-body")
+	summary := &services.RowErrorSummary{Total: 5000, Omitted: 4000, Groups: []services.RowErrorGroup{{Field: "amount", Message: "not a valid number", Count: 5000}}}
+	res := services.MigrationResult{Errors: []services.RowError{{Row: 2, Field: "amount", Value: "x", Message: "not a valid number"}}, Summary: summary}
+	w := NewWorker(repo, store, &fakeMigrator{result: res, err: shared.NewBadRequest("validation_error", "validation failed", nil)})
+
+	if _, err := w.RunOnce(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := repo.migrations[m.ID]; got.Summary != summary {
+		t.Fatalf("expected the summary stored, got %+v", got.Summary)
+	}
+}
+
+func TestRunOnce_ErrorLog_StoredAndClosed(t *testing.T) {
+	repo, store, m := newEnqueued(t, "csv-body")
+	errLog := &fakeErrorLog{}
+	for row := 1; row <= errorLogBatch+1; row++ {
+		errLog.errs = append(errLog.errs, domain.LoggedRowError{RunRow: row, RowError: domain.RowError{Row: row, Field: "amount"}})
+	}
+	res := services.MigrationResult{Rejected: errorLogBatch + 1, Summary: &services.RowErrorSummary{Total: errorLogBatch + 1, Omitted: errorLogBatch}, ErrorLog: errLog}
+	w := NewWorker(repo, store, &fakeMigrator{result: res})
+
+	if _, err := w.RunOnce(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := repo.logged[m.ID]; len(got) != errorLogBatch+1 || got[errorLogBatch].RunRow != errorLogBatch+1 {
+		t.Fatalf("expected every logged error stored, got %d", len(got))
+	}
+	if !errLog.closed || repo.migrations[m.ID].Status != domain.MigrationStatusCompleted {
+		t.Fatalf("expected a closed log and a completed migration, got closed=%v %+v", errLog.closed, repo.migrations[m.ID])
+	}
+}
+
+func TestRunOnce_ClaimsWithTheLease(t *testing.T) {
+	repo, store, _ := newEnqueued(t, "csv-body")
+	w := NewWorker(repo, store, &fakeMigrator{})
+	if _, err := w.RunOnce(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.staleAfter != DefaultLease {
+		t.Fatalf("expected stale migrations reclaimed after %v, got %v", DefaultLease, repo.staleAfter)
+	}
+}
+
+func TestRunOnce_SendsHeartbeatsWhileProcessing(t *testing.T) {
+	repo, store, m := newEnqueued(t, "csv-body")
+	repo.heartbeats = make(chan int64, 1)
+	migrator := &fakeMigrator{during: func(ctx context.Context) {
+		select {
+		case id := <-repo.heartbeats:
+			if id != m.ID {
+				t.Errorf("heartbeat for migration %d, want %d", id, m.ID)
+			}
+		case <-time.After(time.Second):
+			t.Errorf("no heartbeat while processing")
+		}
+	}}
+	w := NewWorker(repo, store, migrator)
+	w.Lease = 3 * time.Millisecond
+
+	if _, err := w.RunOnce(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.migrations[m.ID].Status != domain.MigrationStatusCompleted {
+		t.Fatalf("unexpected migration: %+v", repo.migrations[m.ID])
+	}
+}
+
+func TestRunOnce_CancelledWhileProcessing_StillRecordsOutcome(t *testing.T) {
+	repo, store, m := newEnqueued(t, "csv-body")
+	ctx, cancel := context.WithCancel(context.Background())
+	migrator := &fakeMigrator{err: shared.NewInternal("db_failure", "database error", context.Canceled), during: func(context.Context) { cancel() }}
+	w := NewWorker(repo, store, migrator)
+
+	if _, err := w.RunOnce(ctx); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := repo.migrations[m.ID]; got.Status != domain.MigrationStatusFailed || repo.finishErr != nil {
+		t.Fatalf("expected the failure recorded with a live context, got %+v (ctx err %v)", got, repo.finishErr)
+	}
+}
+
+func TestRunOnce_LeaseLost_StopsProcessingAndRecordsNothing(t *testing.T) {
+	repo, store, m := newEnqueued(t, "csv-body")
+	migrator := &fakeMigrator{err: shared.NewInternal("db_failure", "database error", context.Canceled), during: func(ctx context.Context) {
+		repo.reclaim(m.ID)
+		select {
+		case <-ctx.Done():
+		case <-time.After(time.Second):
+			t.Errorf("processing not stopped after the lease was lost")
+		}
+	}}
+	w := NewWorker(repo, store, migrator)
+	w.Lease = 3 * time.Millisecond
+
+	_, err := w.RunOnce(context.Background())
+	if !errors.Is(err, repositories.ErrLeaseLost) {
+		t.Fatalf("expected ErrLeaseLost, got %v", err)
+	}
+	if got := repo.migrations[m.ID]; got.Status != domain.MigrationStatusProcessing || got.Attempt != 2 || got.ErrorCode != "" {
+		t.Fatalf("expected the new attempt's migration untouched, got %+v", got)
+	}
+}
+
+func TestRunOnce_ReclaimedBeforeFinishing_OutcomeAndLogRejected(t *testing.T) {
+	repo, store, m := newEnqueued(t, "csv-body")
+	errLog := &fakeErrorLog{errs: []domain.LoggedRowError{{RunRow: 1, RowError: domain.RowError{Row: 1, Field: "id"}}}}
+	migrator := &fakeMigrator{result: services.MigrationResult{Inserted: 1, ErrorLog: errLog}, during: func(context.Context) { repo.reclaim(m.ID) }}
+	w := NewWorker(repo, store, migrator)
+	w.Lease = 0
+
+	_, err := w.RunOnce(context.Background())
+	if !errors.Is(err, repositories.ErrLeaseLost) {
+		t.Fatalf("expected ErrLeaseLost, got %v", err)
+	}
+	if got := repo.migrations[m.ID]; got.Status != domain.MigrationStatusProcessing || got.Inserted != 0 || len(repo.logged[m.ID]) != 0 || !errLog.closed {
+		t.Fatalf("expected nothing recorded for the old attempt, got %+v, log %v", got, repo.logged[m.ID])
+	}
+}
+
+func TestRunOnce_MissingFile_MarksFailed(t *testing.T) {
+	repo, store, m := newEnqueued(t, "csv-body")
+	delete(store.files, m.FileKey)
+	w := NewWorker(repo, store, &fakeMigrator{})
+
+	if _, err := w.RunOnce(context.Background()); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got := repo.migrations[m.ID]
+	if got.Status != domain.MigrationStatusFailed || got.ErrorCode != "file_unavailable" {
+		t.Fatalf("unexpected migration: %+v", got)
+	}
+}
+
+func TestRunOnce_ClaimError_Propagates(t *testing.T) {
+	repo := newFakeMigrationRepo()
+	repo.claimErr = errors.New("db down")
+	w := NewWorker(repo, newFakeStore(), &fakeMigrator{})
+	if _, err := w.RunOnce(context.Background()); err == nil {
+		t.Fatalf("expected error")
+	}
+}
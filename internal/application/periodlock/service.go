@@ -0,0 +1,60 @@
+package periodlock
+
+import (
+	"context"
+	"strings"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/ports/repositories"
+	"stori-challenge/internal/ports/services"
+	"stori-challenge/internal/shared"
+)
+
+type periodLockService struct {
+	Repo repositories.PeriodLockRepository
+}
+
+// Ensure interface compliance
+var _ services.PeriodLockService = (*periodLockService)(nil)
+
+func NewPeriodLockService(repo repositories.PeriodLockRepository) services.PeriodLockService {
+	return &periodLockService{Repo: repo}
+}
+
+func (s *periodLockService) Lock(ctx context.Context, l domain.PeriodLock) (domain.PeriodLock, error) {
+	if _, err := domain.ParsePeriod(l.Period); err != nil {
+		return domain.PeriodLock{}, invalidPeriod()
+	}
+	l.LockedBy, l.Reason = strings.TrimSpace(l.LockedBy), strings.TrimSpace(l.Reason)
+	saved, err := s.Repo.Save(ctx, l)
+	if err != nil {
+		return domain.PeriodLock{}, shared.NewInternal("db_failure", "database error", err)
+	}
+	return saved, nil
+}
+
+func (s *periodLockService) List(ctx context.Context) ([]domain.PeriodLock, error) {
+	out, err := s.Repo.List(ctx)
+	if err != nil {
+		return nil, shared.NewInternal("db_failure", "database error", err)
+	}
+	return out, nil
+}
+
+func (s *periodLockService) Unlock(ctx context.Context, period string) error {
+	if _, err := domain.ParsePeriod(period); err != nil {
+		return invalidPeriod()
+	}
+	found, err := s.Repo.Delete(ctx, period)
+	if err != nil {
+		return shared.NewInternal("db_failure", "database error", err)
+	}
+	if !found {
+		return shared.NewNotFound("period_lock_not_found", "period is not locked", nil)
+	}
+	return nil
+}
+
+func invalidPeriod() *shared.AppError {
+	return shared.NewBadRequest("invalid_period", "period must be a month as YYYY-MM", nil)
+}
@@ -0,0 +1,75 @@
+package periodlock
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/shared"
+)
+
+type fakeLockRepo struct {
+	locks map[string]domain.PeriodLock
+}
+
+func (f *fakeLockRepo) Save(ctx context.Context, l domain.PeriodLock) (domain.PeriodLock, error) {
+	f.locks[l.Period] = l
+	return l, nil
+}
+
+func (f *fakeLockRepo) List(ctx context.Context) ([]domain.PeriodLock, error) {
+	var out []domain.PeriodLock
+	for _, l := range f.locks {
+		out = append(out, l)
+	}
+	return out, nil
+}
+
+func (f *fakeLockRepo) Delete(ctx context.Context, period string) (bool, error) {
+	_, ok := f.locks[period]
+	delete(f.locks, period)
+	return ok, nil
+}
+
+func newSvc() (*periodLockService, *fakeLockRepo) {
+	repo := &fakeLockRepo{locks: map[string]domain.PeriodLock{}}
+	return NewPeriodLockService(repo).(*periodLockService), repo
+}
+
+func TestLock_ValidPeriod_Stored(t *testing.T) {
+	svc, repo := newSvc()
+	if _, err := svc.Lock(context.Background(), domain.PeriodLock{Period: "2024-06", LockedBy: " ana ", Reason: "June closed"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if repo.locks["2024-06"].LockedBy != "ana" {
+		t.Fatalf("expected lock to be stored, got %+v", repo.locks)
+	}
+}
+
+func TestLock_InvalidPeriod_BadRequest(t *testing.T) {
+	svc, repo := newSvc()
+	for _, p := range []string{"", "2024-6", "2024-13", "2024-06-01", "june"} {
+		_, err := svc.Lock(context.Background(), domain.PeriodLock{Period: p})
+		var ae *shared.AppError
+		if !errors.As(err, &ae) || ae.Code != "invalid_period" {
+			t.Fatalf("%q: expected invalid_period, got %v", p, err)
+		}
+	}
+	if len(repo.locks) != 0 {
+		t.Fatalf("expected nothing stored")
+	}
+}
+
+func TestUnlock_NotLocked_NotFound(t *testing.T) {
+	svc, repo := newSvc()
+	repo.locks["2024-06"] = domain.PeriodLock{Period: "2024-06"}
+	if err := svc.Unlock(context.Background(), "2024-06"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	err := svc.Unlock(context.Background(), "2024-06")
+	var ae *shared.AppError
+	if !errors.As(err, &ae) || ae.Kind != shared.NotFoundKind {
+		t.Fatalf("expected NotFound, got %v", err)
+	}
+}
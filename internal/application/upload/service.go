@@ -0,0 +1,227 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"hash"
+	"io"
+	"log"
+	"time"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/ports/services"
+	"stori-challenge/internal/ports/storage"
+	"stori-challenge/internal/shared"
+)
+
+// DefaultTTL is how long an upload is kept without receiving a chunk.
+const DefaultTTL = 24 * time.Hour
+
+// uploadService implements services.UploadService on top of an upload store, handing
+// finalized files to the asynchronous migration service.
+type uploadService struct {
+	Store storage.UploadStore
+	Jobs  services.MigrationJobService
+	// TTL is how long an upload is kept after its last chunk.
+	TTL     time.Duration
+	NowFunc func() time.Time
+}
+
+// Ensure interface compliance.
+var _ services.UploadService = (*uploadService)(nil)
+
+// NewUploadService constructs the resumable upload service with the default expiry.
+func NewUploadService(store storage.UploadStore, jobs services.MigrationJobService) services.UploadService {
+	return &uploadService{
+		Store:   store,
+		Jobs:    jobs,
+		TTL:     DefaultTTL,
+		NowFunc: func() time.Time { return time.Now().UTC() },
+	}
+}
+
+func (s *uploadService) Create(ctx context.Context, fileName, contentType string, length int64) (domain.Upload, error) {
+	if length <= 0 {
+		return domain.Upload{}, shared.NewBadRequest("invalid_upload_length", "upload length must be a positive number of bytes", nil)
+	}
+	id, err := newUploadID()
+	if err != nil {
+		return domain.Upload{}, shared.NewInternal("storage_failure", "unable to create upload", err)
+	}
+	now := s.NowFunc()
+	u := domain.Upload{ID: id, FileName: fileName, ContentType: contentType, Length: length, CreatedAt: now, UpdatedAt: now}
+	if err := s.Store.Create(ctx, u); err != nil {
+		return domain.Upload{}, shared.NewInternal("storage_failure", "unable to create upload", err)
+	}
+	return s.withExpiry(u), nil
+}
+
+// Get treats an expired upload as missing, and discards it right away instead of waiting
+// for the next purge.
+func (s *uploadService) Get(ctx context.Context, id string) (domain.Upload, error) {
+	u, found, err := s.Store.Get(ctx, id)
+	if err != nil {
+		return domain.Upload{}, shared.NewInternal("storage_failure", "unable to read upload", err)
+	}
+	if !found {
+		return domain.Upload{}, uploadNotFound()
+	}
+	u = s.withExpiry(u)
+	if s.expired(u) {
+		if err := s.Store.Delete(ctx, id); err != nil {
+			log.Printf("upload %s: discard expired upload: %v", id, err)
+		}
+		return domain.Upload{}, uploadNotFound()
+	}
+	return u, nil
+}
+
+// AppendChunk checks the offset before reading the body, so a client resuming from a stale
+// offset learns it without sending the chunk; the store checks it again when adding the chunk.
+func (s *uploadService) AppendChunk(ctx context.Context, id string, offset int64, r io.Reader, checksum *domain.Checksum) (domain.Upload, error) {
+	u, err := s.Get(ctx, id)
+	if err != nil {
+		return domain.Upload{}, err
+	}
+	if offset != u.Offset {
+		return domain.Upload{}, offsetMismatch(u.Offset)
+	}
+	var h hash.Hash
+	if checksum != nil {
+		var ok bool
+		if h, ok = checksum.NewHash(); !ok {
+			return domain.Upload{}, shared.NewBadRequest("unsupported_checksum_algorithm", "checksum algorithm must be md5, sha1 or sha256", nil)
+		}
+		r = io.TeeReader(r, h)
+	}
+	remaining := u.Length - offset
+	commit := func(n int64) error {
+		if n > remaining {
+			return shared.NewBadRequest("upload_length_exceeded", fmt.Sprintf("chunk goes past the declared length of %d bytes", u.Length), nil)
+		}
+		if h != nil && !bytes.Equal(h.Sum(nil), checksum.Sum) {
+			return shared.NewBadRequest("checksum_mismatch", "chunk does not match its "+checksum.Algorithm+" checksum", nil)
+		}
+		return nil
+	}
+	// One byte past the remaining length is enough to tell the chunk is too long.
+	newOffset, err := s.Store.Append(ctx, id, offset, io.LimitReader(r, remaining+1), commit)
+	if err != nil {
+		var ae *shared.AppError
+		if errors.As(err, &ae) {
+			return domain.Upload{}, ae
+		}
+		if errors.Is(err, storage.ErrOffsetMismatch) {
+			current, getErr := s.Get(ctx, id)
+			if getErr != nil {
+				return domain.Upload{}, getErr
+			}
+			return domain.Upload{}, offsetMismatch(current.Offset)
+		}
+		return domain.Upload{}, shared.NewInternal("storage_failure", "unable to store chunk", err)
+	}
+	u.Offset, u.UpdatedAt = newOffset, s.NowFunc()
+	return s.withExpiry(u), nil
+}
+
+// Finalize claims the upload before enqueueing it, so of concurrent requests to finalize the
+// same upload only one enqueues a migration; to the others it is already gone. It releases the
+// upload when the migration cannot be enqueued (e.g. an unknown mapping profile), so the client
+// can fix the options and finalize again.
+func (s *uploadService) Finalize(ctx context.Context, id string, opts domain.MigrationOptions) (domain.Migration, error) {
+	u, err := s.Get(ctx, id)
+	if err != nil {
+		return domain.Migration{}, err
+	}
+	if !u.IsComplete() {
+		return domain.Migration{}, shared.NewConflict("upload_incomplete", fmt.Sprintf("upload has %d of %d bytes", u.Offset, u.Length), nil)
+	}
+	claimed, err := s.Store.Claim(ctx, id)
+	if err != nil {
+		return domain.Migration{}, shared.NewInternal("storage_failure", "unable to finalize upload", err)
+	}
+	if !claimed {
+		return domain.Migration{}, uploadNotFound()
+	}
+	m, err := s.enqueue(ctx, u, opts)
+	if err != nil {
+		if relErr := s.Store.Release(ctx, id); relErr != nil {
+			log.Printf("upload %s: release upload after failed finalize: %v", id, relErr)
+		}
+		return domain.Migration{}, err
+	}
+	if err := s.Store.Delete(ctx, id); err != nil {
+		// The migration owns a copy of the file; the upload expires on its own.
+		log.Printf("upload %s: discard finalized upload: %v", id, err)
+	}
+	return m, nil
+}
+
+// enqueue hands the bytes of u to an asynchronous migration.
+func (s *uploadService) enqueue(ctx context.Context, u domain.Upload, opts domain.MigrationOptions) (domain.Migration, error) {
+	f, err := s.Store.Open(ctx, u.ID)
+	if err != nil {
+		return domain.Migration{}, shared.NewInternal("storage_failure", "unable to read upload", err)
+	}
+	defer f.Close()
+	return s.Jobs.Enqueue(ctx, u.FileName, f, opts)
+}
+
+func (s *uploadService) Delete(ctx context.Context, id string) error {
+	if _, err := s.Get(ctx, id); err != nil {
+		return err
+	}
+	if err := s.Store.Delete(ctx, id); err != nil {
+		return shared.NewInternal("storage_failure", "unable to delete upload", err)
+	}
+	return nil
+}
+
+func (s *uploadService) PurgeExpired(ctx context.Context) (int, error) {
+	uploads, err := s.Store.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+	purged := 0
+	for _, u := range uploads {
+		if !s.expired(s.withExpiry(u)) {
+			continue
+		}
+		if err := s.Store.Delete(ctx, u.ID); err != nil {
+			return purged, err
+		}
+		purged++
+	}
+	return purged, nil
+}
+
+// withExpiry sets the expiry of u from its last activity.
+func (s *uploadService) withExpiry(u domain.Upload) domain.Upload {
+	u.ExpiresAt = u.UpdatedAt.Add(s.TTL)
+	return u
+}
+
+func (s *uploadService) expired(u domain.Upload) bool {
+	return !s.NowFunc().Before(u.ExpiresAt)
+}
+
+func uploadNotFound() *shared.AppError {
+	return shared.NewNotFound("upload_not_found", "upload not found", nil)
+}
+
+func offsetMismatch(current int64) *shared.AppError {
+	return shared.NewConflict("upload_offset_mismatch", fmt.Sprintf("upload offset is %d", current), nil)
+}
+
+// newUploadID returns a random 128-bit hex token.
+func newUploadID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
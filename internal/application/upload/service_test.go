@@ -0,0 +1,307 @@
+package upload
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"errors"
+	"io"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/shared"
+)
+
+// memStore is an in-memory storage.UploadStore.
+type memStore struct {
+	mu      sync.Mutex
+	uploads map[string]domain.Upload
+	data    map[string][]byte
+	claimed map[string]bool
+}
+
+func newMemStore() *memStore {
+	return &memStore{uploads: map[string]domain.Upload{}, data: map[string][]byte{}, claimed: map[string]bool{}}
+}
+
+func (m *memStore) Create(ctx context.Context, u domain.Upload) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.uploads[u.ID] = u
+	return nil
+}
+
+func (m *memStore) Get(ctx context.Context, id string) (domain.Upload, bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	u, ok := m.get(id)
+	return u, ok && !m.claimed[id], nil
+}
+
+func (m *memStore) get(id string) (domain.Upload, bool) {
+	u, ok := m.uploads[id]
+	u.Offset = int64(len(m.data[id]))
+	return u, ok
+}
+
+func (m *memStore) List(ctx context.Context) ([]domain.Upload, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var out []domain.Upload
+	for id := range m.uploads {
+		u, _ := m.get(id)
+		out = append(out, u)
+	}
+	return out, nil
+}
+
+func (m *memStore) Append(ctx context.Context, id string, offset int64, r io.Reader, commit func(n int64) error) (int64, error) {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return 0, err
+	}
+	if err := commit(int64(len(b))); err != nil {
+		return 0, err
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.data[id] = append(m.data[id], b...)
+	return int64(len(m.data[id])), nil
+}
+
+func (m *memStore) Open(ctx context.Context, id string) (io.ReadCloser, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return io.NopCloser(bytes.NewReader(m.data[id])), nil
+}
+
+func (m *memStore) Claim(ctx context.Context, id string) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.uploads[id]; !ok || m.claimed[id] {
+		return false, nil
+	}
+	m.claimed[id] = true
+	return true, nil
+}
+
+func (m *memStore) Release(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.claimed, id)
+	return nil
+}
+
+func (m *memStore) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.uploads, id)
+	delete(m.data, id)
+	delete(m.claimed, id)
+	return nil
+}
+
+type fakeJobs struct {
+	mu       sync.Mutex
+	enqueued string
+	calls    int
+	err      error
+	// during, when set, runs while a migration is being enqueued.
+	during func()
+}
+
+func (f *fakeJobs) Enqueue(ctx context.Context, fileName string, r io.Reader, opts domain.MigrationOptions) (domain.Migration, error) {
+	if f.during != nil {
+		f.during()
+	}
+	if f.err != nil {
+		return domain.Migration{}, f.err
+	}
+	b, _ := io.ReadAll(r)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.enqueued = string(b)
+	f.calls++
+	return domain.Migration{ID: 7, FileName: fileName, Status: domain.MigrationStatusPending}, nil
+}
+
+func (f *fakeJobs) Get(ctx context.Context, id int64) (domain.Migration, error) {
+	return domain.Migration{}, nil
+}
+
+func (f *fakeJobs) WriteErrorReport(ctx context.Context, id int64, w io.Writer) error {
+	return nil
+}
+
+func newTestService(store *memStore, jobs *fakeJobs, now *time.Time) *uploadService {
+	svc := NewUploadService(store, jobs).(*uploadService)
+	svc.NowFunc = func() time.Time { return *now }
+	return svc
+}
+
+func appCode(err error) string {
+	var ae *shared.AppError
+	if errors.As(err, &ae) {
+		return ae.Code
+	}
+	return ""
+}
+
+func TestUploadService_AppendAndFinalize(t *testing.T) {
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	store, jobs := newMemStore(), &fakeJobs{}
+	svc := newTestService(store, jobs, &now)
+	ctx := context.Background()
+
+	u, err := svc.Create(ctx, "data.csv", "", 10)
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if !u.ExpiresAt.Equal(now.Add(DefaultTTL)) {
+		t.Fatalf("unexpected expiry: %v", u.ExpiresAt)
+	}
+	if _, err := svc.AppendChunk(ctx, u.ID, 0, strings.NewReader("hello"), nil); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+	if _, err := svc.Finalize(ctx, u.ID, domain.MigrationOptions{}); appCode(err) != "upload_incomplete" {
+		t.Fatalf("expected upload_incomplete, got %v", err)
+	}
+	sum := sha256.Sum256([]byte("world"))
+	got, err := svc.AppendChunk(ctx, u.ID, 5, strings.NewReader("world"), &domain.Checksum{Algorithm: "sha256", Sum: sum[:]})
+	if err != nil || got.Offset != 10 || !got.IsComplete() {
+		t.Fatalf("unexpected upload: %+v, %v", got, err)
+	}
+
+	m, err := svc.Finalize(ctx, u.ID, domain.MigrationOptions{})
+	if err != nil || m.ID != 7 || jobs.enqueued != "helloworld" {
+		t.Fatalf("unexpected finalize: %+v, %q, %v", m, jobs.enqueued, err)
+	}
+	if _, err := svc.Get(ctx, u.ID); appCode(err) != "upload_not_found" {
+		t.Fatalf("expected the finalized upload to be discarded, got %v", err)
+	}
+}
+
+func TestUploadService_AppendChunk_Rejections(t *testing.T) {
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	store := newMemStore()
+	svc := newTestService(store, &fakeJobs{}, &now)
+	ctx := context.Background()
+	u, _ := svc.Create(ctx, "data.csv", "", 5)
+
+	if _, err := svc.AppendChunk(ctx, u.ID, 2, strings.NewReader("abc"), nil); appCode(err) != "upload_offset_mismatch" {
+		t.Fatalf("expected upload_offset_mismatch, got %v", err)
+	}
+	if _, err := svc.AppendChunk(ctx, u.ID, 0, strings.NewReader("abcdef"), nil); appCode(err) != "upload_length_exceeded" {
+		t.Fatalf("expected upload_length_exceeded, got %v", err)
+	}
+	sum := sha256.Sum256([]byte("other"))
+	if _, err := svc.AppendChunk(ctx, u.ID, 0, strings.NewReader("abcde"), &domain.Checksum{Algorithm: "sha256", Sum: sum[:]}); appCode(err) != "checksum_mismatch" {
+		t.Fatalf("expected checksum_mismatch, got %v", err)
+	}
+	if got, _ := svc.Get(ctx, u.ID); got.Offset != 0 {
+		t.Fatalf("expected no bytes kept, got offset %d", got.Offset)
+	}
+}
+
+func TestUploadService_Finalize_KeepsUploadWhenEnqueueFails(t *testing.T) {
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	store := newMemStore()
+	jobs := &fakeJobs{err: shared.NewBadRequest("mapping_profile_not_found", "mapping profile not found", nil)}
+	svc := newTestService(store, jobs, &now)
+	ctx := context.Background()
+	u, _ := svc.Create(ctx, "data.csv", "", 2)
+	_, _ = svc.AppendChunk(ctx, u.ID, 0, strings.NewReader("ok"), nil)
+
+	if _, err := svc.Finalize(ctx, u.ID, domain.MigrationOptions{Profile: "missing"}); appCode(err) != "mapping_profile_not_found" {
+		t.Fatalf("expected mapping_profile_not_found, got %v", err)
+	}
+	if _, err := svc.Get(ctx, u.ID); err != nil {
+		t.Fatalf("expected the upload to be kept, got %v", err)
+	}
+}
+
+func TestUploadService_Finalize_ConcurrentRequestsEnqueueOnce(t *testing.T) {
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	store, jobs := newMemStore(), &fakeJobs{}
+	svc := newTestService(store, jobs, &now)
+	ctx := context.Background()
+	u, _ := svc.Create(ctx, "data.csv", "", 2)
+	_, _ = svc.AppendChunk(ctx, u.ID, 0, strings.NewReader("ok"), nil)
+
+	// A second request arrives while the first is still enqueueing.
+	var during error
+	jobs.during = func() {
+		jobs.during = nil
+		_, during = svc.Finalize(ctx, u.ID, domain.MigrationOptions{})
+	}
+	if _, err := svc.Finalize(ctx, u.ID, domain.MigrationOptions{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if appCode(during) != "upload_not_found" || jobs.calls != 1 {
+		t.Fatalf("expected upload_not_found and one migration, got %v and %d", during, jobs.calls)
+	}
+
+	u, _ = svc.Create(ctx, "data.csv", "", 2)
+	_, _ = svc.AppendChunk(ctx, u.ID, 0, strings.NewReader("ok"), nil)
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, _ = svc.Finalize(ctx, u.ID, domain.MigrationOptions{})
+		}()
+	}
+	wg.Wait()
+	if jobs.calls != 2 {
+		t.Fatalf("expected one more migration, got %d in total", jobs.calls)
+	}
+}
+
+func TestUploadService_Finalize_ReleasesClaimWhenEnqueueFails(t *testing.T) {
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	store := newMemStore()
+	jobs := &fakeJobs{err: shared.NewConflict("duplicate_upload", "file already migrated", nil)}
+	svc := newTestService(store, jobs, &now)
+	ctx := context.Background()
+	u, _ := svc.Create(ctx, "data.csv", "", 2)
+	_, _ = svc.AppendChunk(ctx, u.ID, 0, strings.NewReader("ok"), nil)
+
+	if _, err := svc.Finalize(ctx, u.ID, domain.MigrationOptions{}); appCode(err) != "duplicate_upload" {
+		t.Fatalf("expected duplicate_upload, got %v", err)
+	}
+	jobs.err = nil
+	if m, err := svc.Finalize(ctx, u.ID, domain.MigrationOptions{Idempotent: true}); err != nil || m.ID != 7 {
+		t.Fatalf("expected the upload to be finalized again, got %+v, %v", m, err)
+	}
+}
+
+func TestUploadService_Expiry(t *testing.T) {
+	now := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	store := newMemStore()
+	svc := newTestService(store, &fakeJobs{}, &now)
+	ctx := context.Background()
+	stale, _ := svc.Create(ctx, "old.csv", "", 5)
+	now = now.Add(DefaultTTL / 2)
+	fresh, _ := svc.Create(ctx, "new.csv", "", 5)
+
+	now = now.Add(DefaultTTL / 2)
+	n, err := svc.PurgeExpired(ctx)
+	if err != nil || n != 1 {
+		t.Fatalf("expected one purged upload, got %d, %v", n, err)
+	}
+	if _, err := svc.Get(ctx, stale.ID); appCode(err) != "upload_not_found" {
+		t.Fatalf("expected the stale upload to be gone, got %v", err)
+	}
+	if _, err := svc.Get(ctx, fresh.ID); err != nil {
+		t.Fatalf("expected the fresh upload to be kept, got %v", err)
+	}
+
+	// An expired upload is not found even before the sweep runs.
+	now = now.Add(DefaultTTL)
+	if _, err := svc.AppendChunk(ctx, fresh.ID, 0, strings.NewReader("x"), nil); appCode(err) != "upload_not_found" {
+		t.Fatalf("expected upload_not_found, got %v", err)
+	}
+}
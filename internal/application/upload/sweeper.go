@@ -0,0 +1,39 @@
+package upload
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"stori-challenge/internal/ports/services"
+)
+
+// DefaultSweepInterval is how often expired uploads are looked for.
+const DefaultSweepInterval = 10 * time.Minute
+
+// Sweeper periodically discards the uploads that expired before being finalized.
+type Sweeper struct {
+	Service  services.UploadService
+	Interval time.Duration
+}
+
+// NewSweeper constructs a sweeper with the default interval.
+func NewSweeper(svc services.UploadService) *Sweeper {
+	return &Sweeper{Service: svc, Interval: DefaultSweepInterval}
+}
+
+// Run purges expired uploads until ctx is cancelled, starting right away.
+func (s *Sweeper) Run(ctx context.Context) {
+	for {
+		if n, err := s.Service.PurgeExpired(ctx); err != nil {
+			log.Printf("upload sweeper: %v", err)
+		} else if n > 0 {
+			log.Printf("upload sweeper: discarded %d expired uploads", n)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(s.Interval):
+		}
+	}
+}
@@ -0,0 +1,32 @@
+package domain
+
+import (
+	"hash/fnv"
+	"math"
+	"time"
+)
+
+// AccountMapping assigns the transactions of a bank account statement to a user.
+type AccountMapping struct {
+	// AccountID is the account number as the statement reports it (OFX ACCTID).
+	AccountID string
+	UserID    int64
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// StableTransactionID derives a positive transaction id from the identifiers a statement gives a
+// transaction (e.g. format, account and FITID), so importing the same statement twice yields
+// the same ids and the usual conflict checks apply.
+func StableTransactionID(parts ...string) int64 {
+	h := fnv.New64a()
+	for _, p := range parts {
+		_, _ = h.Write([]byte(p))
+		_, _ = h.Write([]byte{0})
+	}
+	id := int64(h.Sum64() & math.MaxInt64)
+	if id == 0 {
+		return 1
+	}
+	return id
+}
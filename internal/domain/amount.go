@@ -0,0 +1,46 @@
+package domain
+
+import "github.com/shopspring/decimal"
+
+// AmountScale is the number of decimal places stored for an amount (NUMERIC(18,2)).
+const AmountScale = 2
+
+// MaxAmount is the largest absolute amount NUMERIC(18,2) can store.
+var MaxAmount = decimal.RequireFromString("9999999999999999.99")
+
+// AmountRounding is what a migration does with amounts that have more than AmountScale decimals.
+type AmountRounding string
+
+const (
+	// AmountRoundingReject rejects the row with an error on amount (default).
+	AmountRoundingReject AmountRounding = "reject"
+	// AmountRoundingHalfEven rounds ties to the even cent (banker's rounding): 10.005 -> 10.00.
+	AmountRoundingHalfEven AmountRounding = "half_even"
+	// AmountRoundingHalfUp rounds ties away from zero: 10.005 -> 10.01, -10.005 -> -10.01.
+	AmountRoundingHalfUp AmountRounding = "half_up"
+)
+
+// IsValid reports whether r is a known policy.
+func (r AmountRounding) IsValid() bool {
+	switch r {
+	case AmountRoundingReject, AmountRoundingHalfEven, AmountRoundingHalfUp:
+		return true
+	}
+	return false
+}
+
+// Apply fits d to AmountScale decimals. Amounts that already fit are returned unchanged; the
+// others are rounded, or reported as not fitting when the policy rejects them. Trailing zeros
+// do not count as precision: 10.500 fits.
+func (r AmountRounding) Apply(d decimal.Decimal) (decimal.Decimal, bool) {
+	if d.Equal(d.Truncate(AmountScale)) {
+		return d, true
+	}
+	switch r {
+	case AmountRoundingHalfEven:
+		return d.RoundBank(AmountScale), true
+	case AmountRoundingHalfUp:
+		return d.Round(AmountScale), true
+	}
+	return d, false
+}
@@ -0,0 +1,32 @@
+package domain
+
+import "strings"
+
+// CSVDelimiters are the field separators a CSV upload may use, in the order detection
+// prefers them.
+var CSVDelimiters = []string{",", ";", "\t", "|"}
+
+// CSVDialect is how a CSV upload is written: the field delimiter and the decimal separator of
+// its amounts ("." or ",").
+type CSVDialect struct {
+	Delimiter        string
+	DecimalSeparator string
+}
+
+// delimiterAliases maps the names a delimiter may be given by to the character.
+var delimiterAliases = map[string]string{
+	",": ",", "comma": ",",
+	";": ";", "semicolon": ";",
+	"\t": "\t", "\\t": "\t", "tab": "\t",
+	"|": "|", "pipe": "|",
+}
+
+// ParseDelimiter resolves a delimiter given as its character or its name (comma, semicolon,
+// tab, pipe), ignoring case.
+func ParseDelimiter(label string) (string, bool) {
+	if label == "\t" {
+		return label, true
+	}
+	d, ok := delimiterAliases[strings.ToLower(strings.TrimSpace(label))]
+	return d, ok
+}
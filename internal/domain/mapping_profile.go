@@ -0,0 +1,46 @@
+package domain
+
+import (
+	"strings"
+	"time"
+)
+
+// Canonical transaction columns a CSV header is mapped to.
+const (
+	ColumnID       = "id"
+	ColumnUserID   = "user_id"
+	ColumnAmount   = "amount"
+	ColumnDatetime = "datetime"
+	ColumnType     = "type"
+)
+
+// TransactionColumns lists the canonical columns every migration file must provide.
+var TransactionColumns = []string{ColumnID, ColumnUserID, ColumnAmount, ColumnDatetime}
+
+// OptionalColumns lists the canonical columns a migration file may provide.
+var OptionalColumns = []string{ColumnType}
+
+// MappableColumns lists every canonical column, required ones first.
+var MappableColumns = append(append([]string{}, TransactionColumns...), OptionalColumns...)
+
+// MappingProfile is a named, server-side set of header aliases for a partner's export format.
+type MappingProfile struct {
+	Name string
+	// Columns maps a canonical column to the header names that feed it.
+	Columns   map[string][]string
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// NormalizeHeader folds a header name for matching: case, surrounding spaces and the
+// separators " ", "_", "-" and "." are ignored, so "User Id", "user_id" and "userId" match.
+func NormalizeHeader(s string) string {
+	s = strings.ToLower(strings.TrimSpace(s))
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ' ', '_', '-', '.':
+			return -1
+		}
+		return r
+	}, s)
+}
@@ -0,0 +1,44 @@
+package domain
+
+import "time"
+
+// MigrationStatus is the lifecycle state of an asynchronous migration job.
+type MigrationStatus string
+
+const (
+	MigrationStatusPending    MigrationStatus = "PENDING"
+	MigrationStatusProcessing MigrationStatus = "PROCESSING"
+	MigrationStatusCompleted  MigrationStatus = "COMPLETED"
+	MigrationStatusFailed     MigrationStatus = "FAILED"
+)
+
+// Migration is an asynchronous migration job. FileKey points to the uploaded file in storage.
+type Migration struct {
+	ID             int64
+	Status         MigrationStatus
+	FileName       string
+	FileKey        string
+	Options        MigrationOptions
+	Inserted       int
+	Rejected       int
+	AlreadyPresent int
+	ErrorCode      string
+	Errors         []RowError
+	Summary        *RowErrorSummary
+	Dialect        *CSVDialect
+	CreatedAt      time.Time
+	StartedAt      *time.Time
+	FinishedAt     *time.Time
+	// Attempt counts the claims of the migration; only the worker holding the latest one may
+	// record its outcome.
+	Attempt int
+}
+
+// MigrationCounts are the row counters recorded when a migration completes.
+type MigrationCounts struct {
+	Inserted       int
+	Rejected       int
+	AlreadyPresent int
+	// Dialect is how a CSV upload was read; nil for the other formats.
+	Dialect *CSVDialect
+}
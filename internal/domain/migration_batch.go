@@ -0,0 +1,88 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"time"
+)
+
+// MigrationBatchStatus is the outcome of processing an upload.
+type MigrationBatchStatus string
+
+const (
+	MigrationBatchProcessing MigrationBatchStatus = "PROCESSING"
+	MigrationBatchSucceeded  MigrationBatchStatus = "SUCCEEDED"
+	MigrationBatchFailed     MigrationBatchStatus = "FAILED"
+	// MigrationBatchReverted is a SUCCEEDED batch whose transactions were deleted.
+	MigrationBatchReverted MigrationBatchStatus = "REVERTED"
+)
+
+// UploadSource identifies an uploaded file and who sent it.
+type UploadSource struct {
+	FileName string
+	// UploadedBy is the identity the client declared (X-Uploaded-By); ClientIP is where the
+	// request came from.
+	UploadedBy string
+	ClientIP   string
+	// MigrationID is the asynchronous migration that processed the file; zero for /migrate.
+	MigrationID int64
+}
+
+// MigrationBatch records one processed upload: the file, who sent it and what it produced.
+// Inserted transactions point back to their batch and source row.
+type MigrationBatch struct {
+	ID     int64
+	Source UploadSource
+	// SHA256 is the hex digest of the uploaded bytes and Size their length.
+	SHA256         string
+	Size           int64
+	Status         MigrationBatchStatus
+	Inserted       int
+	Rejected       int
+	AlreadyPresent int
+	ErrorCode      string
+	// AmountRounding is the policy amounts were read with; empty for batches recorded before
+	// it was.
+	AmountRounding AmountRounding
+	StartedAt      time.Time
+	FinishedAt     *time.Time
+	// Revert is the audit record of a REVERTED batch, when loaded with it.
+	Revert *BatchRevert
+}
+
+// BatchRevert is the audit record of undoing a batch: who did it, why and how many
+// transactions were deleted.
+type BatchRevert struct {
+	BatchID             int64
+	RevertedBy          string
+	Reason              string
+	TransactionsDeleted int
+	RevertedAt          time.Time
+}
+
+// TransactionSource is where a migrated transaction came from. BatchID is zero for
+// transactions stored without a batch, such as those migrated before batches were recorded.
+type TransactionSource struct {
+	BatchID int64
+	// File is the archive member of a .zip upload; Row is the row within that file, numbered
+	// as in row errors.
+	File string
+	Row  int
+}
+
+// DigestSHA256 hashes r from its start and rewinds it, returning the hex digest and size.
+func DigestSHA256(r io.ReadSeeker) (string, int64, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return "", 0, err
+	}
+	h := sha256.New()
+	n, err := io.Copy(h, r)
+	if err != nil {
+		return "", 0, err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return "", 0, err
+	}
+	return hex.EncodeToString(h.Sum(nil)), n, nil
+}
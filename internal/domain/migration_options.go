@@ -0,0 +1,231 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"time"
+	// Embedded zone database so source time zones resolve on hosts without one.
+	_ "time/tzdata"
+
+	"github.com/shopspring/decimal"
+)
+
+// MigrationMode controls what a migration does with invalid or conflicting rows.
+type MigrationMode string
+
+const (
+	// MigrationModeStrict rejects the whole file when any row fails (default).
+	MigrationModeStrict MigrationMode = "strict"
+	// MigrationModePartial inserts the valid rows and reports the rejected ones.
+	MigrationModePartial MigrationMode = "partial"
+)
+
+// InputFormat is the encoding of a migration file.
+type InputFormat string
+
+const (
+	// InputFormatCSV is a CSV file with a header row (default).
+	InputFormatCSV InputFormat = "csv"
+	// InputFormatJSONL is JSON Lines: one transaction object per line.
+	InputFormatJSONL InputFormat = "jsonl"
+	// InputFormatJSON is a JSON array of transaction objects.
+	InputFormatJSON InputFormat = "json"
+	// InputFormatXLSX is an Excel workbook; one sheet is read, with a header row.
+	InputFormatXLSX InputFormat = "xlsx"
+	// InputFormatOFX is an OFX or QFX bank statement (SGML v1 or XML v2).
+	InputFormatOFX InputFormat = "ofx"
+	// InputFormatCAMT is an ISO 20022 camt.053 statement or camt.054 notification (XML).
+	InputFormatCAMT InputFormat = "camt"
+	// InputFormatMT940 is a SWIFT MT940 customer statement.
+	InputFormatMT940 InputFormat = "mt940"
+)
+
+// IsValid reports whether f is a supported format.
+func (f InputFormat) IsValid() bool {
+	switch f {
+	case InputFormatCSV, InputFormatJSONL, InputFormatJSON, InputFormatXLSX, InputFormatOFX, InputFormatCAMT, InputFormatMT940:
+		return true
+	}
+	return false
+}
+
+// Compression is how a migration file is packed.
+type Compression string
+
+const (
+	// CompressionNone is a plain file (default).
+	CompressionNone Compression = ""
+	// CompressionGzip is a single gzip-compressed file, e.g. transactions.csv.gz.
+	CompressionGzip Compression = "gzip"
+	// CompressionZip is a zip archive whose CSV members are migrated together.
+	CompressionZip Compression = "zip"
+)
+
+// IsValid reports whether c is a supported compression.
+func (c Compression) IsValid() bool {
+	switch c {
+	case CompressionNone, CompressionGzip, CompressionZip:
+		return true
+	}
+	return false
+}
+
+// Encoding is the character set of a text upload. The empty value detects it: a byte order
+// mark wins, then UTF-16 without one, then UTF-8, falling back to Windows-1252.
+type Encoding string
+
+const (
+	EncodingAuto        Encoding = ""
+	EncodingUTF8        Encoding = "utf-8"
+	EncodingUTF16LE     Encoding = "utf-16le"
+	EncodingUTF16BE     Encoding = "utf-16be"
+	EncodingWindows1252 Encoding = "windows-1252"
+	EncodingLatin1      Encoding = "iso-8859-1"
+)
+
+// encodingAliases maps the labels exporters commonly use to an Encoding.
+var encodingAliases = map[string]Encoding{
+	"utf-8": EncodingUTF8, "utf8": EncodingUTF8,
+	"utf-16le": EncodingUTF16LE, "utf16le": EncodingUTF16LE, "utf-16": EncodingUTF16LE, "unicode": EncodingUTF16LE,
+	"utf-16be": EncodingUTF16BE, "utf16be": EncodingUTF16BE,
+	"windows-1252": EncodingWindows1252, "cp1252": EncodingWindows1252, "ansi": EncodingWindows1252,
+	"iso-8859-1": EncodingLatin1, "latin1": EncodingLatin1, "latin-1": EncodingLatin1,
+}
+
+// ParseEncoding resolves an encoding label, ignoring case. "utf-16" is little-endian, as
+// written by Windows ("Unicode text" in Excel).
+func ParseEncoding(label string) (Encoding, bool) {
+	e, ok := encodingAliases[strings.ToLower(strings.TrimSpace(label))]
+	return e, ok
+}
+
+// DatetimeFormat selects how the datetime column of a migration file is parsed.
+type DatetimeFormat string
+
+const (
+	// DatetimeFormatRFC3339 requires an explicit offset, e.g. 2024-06-01T10:00:00-06:00 (default).
+	DatetimeFormatRFC3339 DatetimeFormat = "rfc3339"
+	// DatetimeFormatLocal is a naive 2006-01-02 15:04:05 (or T-separated) timestamp in the source time zone.
+	DatetimeFormatLocal DatetimeFormat = "local"
+	// DatetimeFormatDate is a 2006-01-02 date, taken as midnight in the source time zone.
+	DatetimeFormatDate DatetimeFormat = "date"
+	// DatetimeFormatUnix is Unix epoch seconds.
+	DatetimeFormatUnix DatetimeFormat = "unix"
+	// DatetimeFormatUnixMillis is Unix epoch milliseconds.
+	DatetimeFormatUnixMillis DatetimeFormat = "unix_ms"
+	// DatetimeFormatAuto accepts any of the above per row; epoch values of 12 or more digits are milliseconds.
+	DatetimeFormatAuto DatetimeFormat = "auto"
+)
+
+// DatetimeFormats lists every supported DatetimeFormat.
+var DatetimeFormats = []DatetimeFormat{
+	DatetimeFormatRFC3339,
+	DatetimeFormatLocal,
+	DatetimeFormatDate,
+	DatetimeFormatUnix,
+	DatetimeFormatUnixMillis,
+	DatetimeFormatAuto,
+}
+
+// IsValid reports whether f is a supported format.
+func (f DatetimeFormat) IsValid() bool {
+	for _, v := range DatetimeFormats {
+		if f == v {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadTimezone resolves an IANA zone name such as America/Mexico_City; empty means UTC.
+// "Local" is rejected so results never depend on the server's zone.
+func LoadTimezone(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	if name == "Local" {
+		return nil, errors.New("unknown time zone Local")
+	}
+	return time.LoadLocation(name)
+}
+
+// UnknownUserPolicy is what a migration does with rows of users that are not registered.
+type UnknownUserPolicy string
+
+const (
+	// UnknownUsersCreate registers the missing users before inserting their rows (default).
+	UnknownUsersCreate UnknownUserPolicy = "create"
+	// UnknownUsersReject rejects the rows of unregistered users with an error on user_id.
+	UnknownUsersReject UnknownUserPolicy = "reject"
+)
+
+// IsValid reports whether p is a known policy.
+func (p UnknownUserPolicy) IsValid() bool {
+	return p == UnknownUsersCreate || p == UnknownUsersReject
+}
+
+const (
+	// DefaultMaxErrors is how many row errors a migration details when MaxErrors is not set.
+	DefaultMaxErrors = 1000
+	// MaxErrorsLimit is the largest MaxErrors accepted.
+	MaxErrorsLimit = 10000
+)
+
+// MigrationOptions are the per-upload settings of a migration.
+type MigrationOptions struct {
+	// Format is the encoding of the file; empty means CSV.
+	Format InputFormat
+	// Compression is how the file is packed; the format applies to its content.
+	Compression Compression
+	// Encoding is the character set of text formats; empty detects it.
+	Encoding Encoding
+	// Delimiter separates CSV fields (one of CSVDelimiters); empty detects it.
+	Delimiter string
+	// UserID owns every transaction of a statement upload (OFX, camt, MT940); zero looks the
+	// user up in the account mappings.
+	UserID int64
+	// Sheet selects the worksheet of an xlsx upload by name or 1-based index; empty is the first.
+	Sheet string
+	Mode  MigrationMode
+	// Idempotent treats rows already stored with the same content as no-ops instead of conflicts.
+	Idempotent bool
+	// Profile names the MappingProfile used to match the header; empty uses the built-in aliases only.
+	Profile string
+	// ProfileColumns are the aliases of Profile as it was when an asynchronous migration was
+	// enqueued. When set they are read instead of the stored profile, so the run and its error
+	// report match the header the same way even if the profile is edited or deleted meanwhile.
+	ProfileColumns map[string][]string
+	// DatetimeFormat is how the datetime column is parsed; empty means RFC3339.
+	DatetimeFormat DatetimeFormat
+	// Timezone is the IANA zone of values without an offset; empty means UTC.
+	Timezone string
+	// Source identifies the upload for its migration batch record.
+	Source UploadSource
+	// Locale selects the decimal and grouping separators of amounts (e.g. es-ES reads 1.234,56);
+	// empty only accepts plain decimals.
+	Locale string
+	// MaxErrors caps the row errors reported in detail; the rest are only summarized. Zero
+	// means DefaultMaxErrors.
+	MaxErrors int
+	// OverdraftFloor, when set, rejects the file if replaying its transactions with each user's
+	// stored history in datetime order takes a balance below it; nil skips the check.
+	OverdraftFloor *decimal.Decimal
+	// UnknownUsers is what happens to rows of unregistered users; empty means UnknownUsersCreate.
+	UnknownUsers UnknownUserPolicy
+	// AmountRounding is what happens to amounts with more than AmountScale decimals; empty
+	// means AmountRoundingReject.
+	AmountRounding AmountRounding
+}
+
+// IsPartial reports whether valid rows should be inserted even when other rows fail.
+func (o MigrationOptions) IsPartial() bool {
+	return o.Mode == MigrationModePartial
+}
+
+// Rounding is the AmountRounding policy of the migration, AmountRoundingReject when unset.
+func (o MigrationOptions) Rounding() AmountRounding {
+	if o.AmountRounding == "" {
+		return AmountRoundingReject
+	}
+	return o.AmountRounding
+}
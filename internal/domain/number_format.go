@@ -0,0 +1,52 @@
+package domain
+
+import "strings"
+
+// NumberFormat holds the separators a locale writes amounts with.
+type NumberFormat struct {
+	Decimal rune
+	Group   rune
+}
+
+var (
+	decimalPoint = NumberFormat{Decimal: '.', Group: ','}
+	decimalComma = NumberFormat{Decimal: ',', Group: '.'}
+)
+
+// numberFormatsByLanguage covers the languages our sources export in; regions that differ from
+// their language are listed in numberFormatsByRegion.
+var numberFormatsByLanguage = map[string]NumberFormat{
+	"en": decimalPoint,
+	"ja": decimalPoint,
+	"zh": decimalPoint,
+	"ko": decimalPoint,
+	"es": decimalComma,
+	"pt": decimalComma,
+	"de": decimalComma,
+	"fr": {Decimal: ',', Group: ' '},
+	"it": decimalComma,
+	"nl": decimalComma,
+	"pl": {Decimal: ',', Group: ' '},
+	"ru": {Decimal: ',', Group: ' '},
+	"tr": decimalComma,
+}
+
+var numberFormatsByRegion = map[string]NumberFormat{
+	"es-mx": decimalPoint,
+	"es-us": decimalPoint,
+	"de-ch": {Decimal: '.', Group: '\''},
+	"fr-ch": {Decimal: '.', Group: '\''},
+	"fr-ca": {Decimal: ',', Group: ' '},
+}
+
+// LookupNumberFormat resolves a locale tag such as es-MX, pt_BR or de to its separators.
+// Unknown regions fall back to their language.
+func LookupNumberFormat(locale string) (NumberFormat, bool) {
+	tag := strings.ToLower(strings.ReplaceAll(strings.TrimSpace(locale), "_", "-"))
+	if f, ok := numberFormatsByRegion[tag]; ok {
+		return f, true
+	}
+	lang, _, _ := strings.Cut(tag, "-")
+	f, ok := numberFormatsByLanguage[lang]
+	return f, ok
+}
@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+// PeriodLayout is the format of a locked period: a calendar month.
+const PeriodLayout = "2006-01"
+
+// PeriodLock closes a calendar month (UTC). A migration batch with a transaction dated in a
+// locked month can no longer be reverted.
+type PeriodLock struct {
+	// Period is the month as YYYY-MM.
+	Period    string
+	LockedBy  string
+	Reason    string
+	CreatedAt time.Time
+}
+
+// ParsePeriod returns the start of a YYYY-MM month in UTC.
+func ParsePeriod(s string) (time.Time, error) {
+	return time.Parse(PeriodLayout, s)
+}
@@ -0,0 +1,51 @@
+package domain
+
+// RowError represents a single validation/conflict detail for an input row in a migration.
+type RowError struct {
+	// File is the archive member the row belongs to, for zip uploads.
+	File string
+	// Sheet is the worksheet the row belongs to, for spreadsheet uploads.
+	Sheet string
+	// Path is the XPath-style location of the offending element, for XML statement uploads.
+	Path    string
+	Row     int
+	Field   string
+	Value   string
+	Message string
+	// Rule is the code of the configured validation rule that produced the error, if any.
+	Rule string
+	// Severity is the severity of that rule; warnings do not reject the row.
+	Severity RuleSeverity
+}
+
+// RowErrorSummary groups every row error of a migration, including the ones left out of the
+// detailed list.
+type RowErrorSummary struct {
+	// Total is the number of row errors found.
+	Total int
+	// Omitted is how many of them the detailed list leaves out.
+	Omitted int
+	// Groups are ordered by count, largest first.
+	Groups []RowErrorGroup
+	// Ungrouped counts the errors of messages beyond the first groups, which are not listed.
+	Ungrouped int
+}
+
+// RowErrorGroup counts the errors with the same file, field and message.
+type RowErrorGroup struct {
+	// File is the archive member, for .zip uploads.
+	File    string
+	Field   string
+	Message string
+	Count   int
+	// SampleRows are the first rows found with the error.
+	SampleRows []int
+}
+
+// LoggedRowError is a row error of the full log kept for an asynchronous migration. RunRow is
+// the position of the row in the upload, counted across the members of an archive, so it
+// orders the log as the file is read.
+type LoggedRowError struct {
+	RunRow int
+	RowError
+}
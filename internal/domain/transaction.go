@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"strings"
 	"time"
 
 	"github.com/shopspring/decimal"
@@ -22,12 +23,26 @@ func DetermineTransactionType(amount decimal.Decimal) TransactionType {
 	return TransactionTypeCredit
 }
 
+// ParseTransactionType reads a credit/debit indicator as exported by banks, e.g. CR/DR,
+// C/D, credit/debit, abono/cargo. Case and surrounding spaces are ignored.
+func ParseTransactionType(s string) (TransactionType, bool) {
+	switch strings.ToLower(strings.TrimSpace(s)) {
+	case "cr", "c", "credit", "credito", "crédito", "abono", "deposit":
+		return TransactionTypeCredit, true
+	case "dr", "d", "db", "debit", "debito", "débito", "cargo", "withdrawal":
+		return TransactionTypeDebit, true
+	}
+	return "", false
+}
+
 type Transaction struct {
 	ID       int64
 	UserID   int64
 	Amount   decimal.Decimal
 	DateTime time.Time
 	Type     TransactionType
+	// Source is set on migrated transactions.
+	Source TransactionSource
 }
 
 
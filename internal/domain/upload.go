@@ -0,0 +1,56 @@
+package domain
+
+import (
+	"crypto/md5"
+	"crypto/sha1"
+	"crypto/sha256"
+	"hash"
+	"strings"
+	"time"
+)
+
+// Upload is a resumable upload of a migration file. The client sends the file in chunks at
+// increasing offsets and finalizes the upload into an asynchronous migration once Offset
+// reaches Length.
+type Upload struct {
+	// ID is an opaque random token; it is the only credential of the upload.
+	ID          string
+	FileName    string
+	ContentType string
+	// Length is the declared size of the file in bytes.
+	Length int64
+	// Offset is how many bytes were received so far.
+	Offset    int64
+	CreatedAt time.Time
+	// UpdatedAt is when the upload was created or last received a chunk.
+	UpdatedAt time.Time
+	// ExpiresAt is when the upload is discarded unless it receives another chunk.
+	ExpiresAt time.Time
+}
+
+// IsComplete reports whether every declared byte was received.
+func (u Upload) IsComplete() bool {
+	return u.Offset == u.Length
+}
+
+// ChecksumAlgorithms are the chunk checksum algorithms accepted, by name.
+var ChecksumAlgorithms = map[string]func() hash.Hash{
+	"md5":    md5.New,
+	"sha1":   sha1.New,
+	"sha256": sha256.New,
+}
+
+// Checksum is the expected digest of an upload chunk.
+type Checksum struct {
+	Algorithm string
+	Sum       []byte
+}
+
+// NewHash returns a hash for c's algorithm; ok is false for an unknown algorithm.
+func (c Checksum) NewHash() (h hash.Hash, ok bool) {
+	newHash, ok := ChecksumAlgorithms[strings.ToLower(c.Algorithm)]
+	if !ok {
+		return nil, false
+	}
+	return newHash(), true
+}
@@ -0,0 +1,131 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// RuleType is the check a validation rule applies to each migrated transaction.
+type RuleType string
+
+const (
+	// RuleTypeAmountRange bounds the amount with MinAmount and/or MaxAmount.
+	RuleTypeAmountRange RuleType = "amount_range"
+	// RuleTypeUserIDRange only allows user ids inside one of UserIDs.
+	RuleTypeUserIDRange RuleType = "user_id_range"
+	// RuleTypeEarliestDatetime rejects transactions dated before Earliest.
+	RuleTypeEarliestDatetime RuleType = "earliest_datetime"
+	// RuleTypeMaxPerUserPerDay allows at most Limit transactions per user and UTC day in a file.
+	RuleTypeMaxPerUserPerDay RuleType = "max_per_user_per_day"
+	// RuleTypeNonZeroAmount rejects zero amounts.
+	RuleTypeNonZeroAmount RuleType = "non_zero_amount"
+)
+
+// RuleSeverity is what a broken rule does to the row.
+type RuleSeverity string
+
+const (
+	// RuleSeverityError rejects the row like any other validation error (default).
+	RuleSeverityError RuleSeverity = "error"
+	// RuleSeverityWarning reports the row but still migrates it.
+	RuleSeverityWarning RuleSeverity = "warning"
+)
+
+// IsValid reports whether s is a known severity.
+func (s RuleSeverity) IsValid() bool {
+	return s == RuleSeverityError || s == RuleSeverityWarning
+}
+
+// UserIDRange is an inclusive range of user ids.
+type UserIDRange struct {
+	Min int64
+	Max int64
+}
+
+// Contains reports whether id is in the range.
+func (r UserIDRange) Contains(id int64) bool {
+	return id >= r.Min && id <= r.Max
+}
+
+// ValidationRule is a configurable check applied to every migrated transaction. Only the
+// parameters of its Type are used.
+type ValidationRule struct {
+	// Code identifies the rule in the row errors it produces.
+	Code     string
+	Type     RuleType
+	Severity RuleSeverity
+	// Message replaces the default description of a broken rule.
+	Message string
+	// MinAmount and MaxAmount bound the signed amount (amount_range); nil leaves that side open.
+	MinAmount *decimal.Decimal
+	MaxAmount *decimal.Decimal
+	// UserIDs are the allowed user ids (user_id_range).
+	UserIDs []UserIDRange
+	// Earliest is the earliest allowed datetime (earliest_datetime).
+	Earliest time.Time
+	// Limit is the most transactions a user may have on one UTC day (max_per_user_per_day).
+	Limit int
+}
+
+// RuleSet is the validation rules every migration applies after parsing its rows.
+type RuleSet struct {
+	Rules []ValidationRule
+}
+
+// Validate checks that every rule has a unique code, a known type and severity, and the
+// parameters its type needs.
+func (s RuleSet) Validate() error {
+	codes := make(map[string]bool, len(s.Rules))
+	for i, r := range s.Rules {
+		if r.Code == "" {
+			return fmt.Errorf("rule %d: code is required", i+1)
+		}
+		if codes[r.Code] {
+			return fmt.Errorf("rule %q: duplicate code", r.Code)
+		}
+		codes[r.Code] = true
+		if !r.Severity.IsValid() {
+			return fmt.Errorf("rule %q: unknown severity %q", r.Code, r.Severity)
+		}
+		if err := r.validateParams(); err != nil {
+			return fmt.Errorf("rule %q: %w", r.Code, err)
+		}
+	}
+	return nil
+}
+
+func (r ValidationRule) validateParams() error {
+	switch r.Type {
+	case RuleTypeAmountRange:
+		if r.MinAmount == nil && r.MaxAmount == nil {
+			return errors.New("min or max is required")
+		}
+		if r.MinAmount != nil && r.MaxAmount != nil && r.MinAmount.GreaterThan(*r.MaxAmount) {
+			return errors.New("min is greater than max")
+		}
+	case RuleTypeUserIDRange:
+		if len(r.UserIDs) == 0 {
+			return errors.New("user_ids is required")
+		}
+		for _, u := range r.UserIDs {
+			if u.Min > u.Max {
+				return fmt.Errorf("user id range %d-%d is empty", u.Min, u.Max)
+			}
+		}
+	case RuleTypeEarliestDatetime:
+		if r.Earliest.IsZero() {
+			return errors.New("earliest is required")
+		}
+	case RuleTypeMaxPerUserPerDay:
+		if r.Limit <= 0 {
+			return errors.New("limit must be positive")
+		}
+	case RuleTypeNonZeroAmount:
+	default:
+		return fmt.Errorf("unknown type %q", r.Type)
+	}
+	return nil
+}
@@ -0,0 +1,80 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/ports/repositories"
+)
+
+type AccountMappingRepo struct {
+	DB *sql.DB
+}
+
+var _ repositories.AccountMappingRepository = (*AccountMappingRepo)(nil)
+
+func NewAccountMappingRepo(db *sql.DB) *AccountMappingRepo {
+	return &AccountMappingRepo{DB: db}
+}
+
+const accountMappingColumns = `account_id, user_id, created_at, updated_at`
+
+func (r *AccountMappingRepo) Save(ctx context.Context, m domain.AccountMapping) (domain.AccountMapping, error) {
+	row := r.DB.QueryRowContext(ctx, `
+INSERT INTO account_mappings (account_id, user_id) VALUES ($1, $2)
+ON CONFLICT (account_id) DO UPDATE SET user_id = EXCLUDED.user_id, updated_at = now()
+RETURNING `+accountMappingColumns, m.AccountID, m.UserID)
+	return scanAccountMapping(row)
+}
+
+func (r *AccountMappingRepo) GetByAccount(ctx context.Context, accountID string) (domain.AccountMapping, bool, error) {
+	row := r.DB.QueryRowContext(ctx, `SELECT `+accountMappingColumns+` FROM account_mappings WHERE account_id = $1`, accountID)
+	m, err := scanAccountMapping(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return domain.AccountMapping{}, false, nil
+		}
+		return domain.AccountMapping{}, false, err
+	}
+	return m, true, nil
+}
+
+func (r *AccountMappingRepo) List(ctx context.Context) ([]domain.AccountMapping, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT `+accountMappingColumns+` FROM account_mappings ORDER BY account_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []domain.AccountMapping
+	for rows.Next() {
+		m, err := scanAccountMapping(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
+
+func (r *AccountMappingRepo) Delete(ctx context.Context, accountID string) (bool, error) {
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM account_mappings WHERE account_id = $1`, accountID)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func scanAccountMapping(row rowScanner) (domain.AccountMapping, error) {
+	var m domain.AccountMapping
+	if err := row.Scan(&m.AccountID, &m.UserID, &m.CreatedAt, &m.UpdatedAt); err != nil {
+		return domain.AccountMapping{}, err
+	}
+	m.CreatedAt = m.CreatedAt.UTC()
+	m.UpdatedAt = m.UpdatedAt.UTC()
+	return m, nil
+}
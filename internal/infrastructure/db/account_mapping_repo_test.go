@@ -0,0 +1,56 @@
+package db
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"stori-challenge/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var accountMappingCols = []string{"account_id", "user_id", "created_at", "updated_at"}
+
+func TestAccountMappingSave_Upserts(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer sqlDB.Close()
+	repo := NewAccountMappingRepo(sqlDB)
+
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	queryRe := regexp.MustCompile(`INSERT INTO account_mappings \(account_id, user_id\) VALUES \(\$1, \$2\)\s+ON CONFLICT \(account_id\) DO UPDATE`)
+	mock.ExpectQuery(queryRe.String()).WithArgs("1234", int64(10)).
+		WillReturnRows(sqlmock.NewRows(accountMappingCols).AddRow("1234", int64(10), ts, ts))
+
+	m, err := repo.Save(context.Background(), domain.AccountMapping{AccountID: "1234", UserID: 10})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.AccountID != "1234" || m.UserID != 10 || !m.CreatedAt.Equal(ts) {
+		t.Fatalf("unexpected mapping: %+v", m)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestAccountMappingGetByAccount_NotFound(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer sqlDB.Close()
+	repo := NewAccountMappingRepo(sqlDB)
+
+	mock.ExpectQuery(`SELECT .* FROM account_mappings WHERE account_id = \$1`).WithArgs("missing").
+		WillReturnRows(sqlmock.NewRows(accountMappingCols))
+
+	_, found, err := repo.GetByAccount(context.Background(), "missing")
+	if err != nil || found {
+		t.Fatalf("expected not found, got found=%v err=%v", found, err)
+	}
+}
@@ -0,0 +1,96 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/ports/repositories"
+)
+
+type MappingProfileRepo struct {
+	DB *sql.DB
+}
+
+var _ repositories.MappingProfileRepository = (*MappingProfileRepo)(nil)
+
+func NewMappingProfileRepo(db *sql.DB) *MappingProfileRepo {
+	return &MappingProfileRepo{DB: db}
+}
+
+const mappingProfileColumns = `name, columns, created_at, updated_at`
+
+func (r *MappingProfileRepo) Save(ctx context.Context, p domain.MappingProfile) (domain.MappingProfile, error) {
+	cols, err := json.Marshal(p.Columns)
+	if err != nil {
+		return domain.MappingProfile{}, err
+	}
+	row := r.DB.QueryRowContext(ctx, `
+INSERT INTO mapping_profiles (name, columns) VALUES ($1, $2)
+ON CONFLICT (name) DO UPDATE SET columns = EXCLUDED.columns, updated_at = now()
+RETURNING `+mappingProfileColumns, p.Name, string(cols))
+	return scanMappingProfile(row)
+}
+
+func (r *MappingProfileRepo) GetByName(ctx context.Context, name string) (domain.MappingProfile, bool, error) {
+	row := r.DB.QueryRowContext(ctx, `SELECT `+mappingProfileColumns+` FROM mapping_profiles WHERE name = $1`, name)
+	p, err := scanMappingProfile(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return domain.MappingProfile{}, false, nil
+		}
+		return domain.MappingProfile{}, false, err
+	}
+	return p, true, nil
+}
+
+func (r *MappingProfileRepo) List(ctx context.Context) ([]domain.MappingProfile, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT `+mappingProfileColumns+` FROM mapping_profiles ORDER BY name`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []domain.MappingProfile
+	for rows.Next() {
+		p, err := scanMappingProfile(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, p)
+	}
+	return out, rows.Err()
+}
+
+func (r *MappingProfileRepo) Delete(ctx context.Context, name string) (bool, error) {
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM mapping_profiles WHERE name = $1`, name)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanMappingProfile(row rowScanner) (domain.MappingProfile, error) {
+	var (
+		p    domain.MappingProfile
+		cols []byte
+	)
+	if err := row.Scan(&p.Name, &cols, &p.CreatedAt, &p.UpdatedAt); err != nil {
+		return domain.MappingProfile{}, err
+	}
+	if err := json.Unmarshal(cols, &p.Columns); err != nil {
+		return domain.MappingProfile{}, err
+	}
+	p.CreatedAt = p.CreatedAt.UTC()
+	p.UpdatedAt = p.UpdatedAt.UTC()
+	return p, nil
+}
@@ -0,0 +1,76 @@
+package db
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"stori-challenge/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var mappingProfileCols = []string{"name", "columns", "created_at", "updated_at"}
+
+func TestMappingProfileSave_Upserts(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer sqlDB.Close()
+	repo := NewMappingProfileRepo(sqlDB)
+
+	ts := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	cols := `{"amount":["monto"]}`
+	queryRe := regexp.MustCompile(`INSERT INTO mapping_profiles \(name, columns\) VALUES \(\$1, \$2\)\s+ON CONFLICT \(name\) DO UPDATE`)
+	mock.ExpectQuery(queryRe.String()).WithArgs("acme", cols).
+		WillReturnRows(sqlmock.NewRows(mappingProfileCols).AddRow("acme", []byte(cols), ts, ts))
+
+	p, err := repo.Save(context.Background(), domain.MappingProfile{Name: "acme", Columns: map[string][]string{"amount": {"monto"}}})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if p.Name != "acme" || len(p.Columns["amount"]) != 1 || p.Columns["amount"][0] != "monto" {
+		t.Fatalf("unexpected profile: %+v", p)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestMappingProfileGetByName_NotFound(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer sqlDB.Close()
+	repo := NewMappingProfileRepo(sqlDB)
+
+	mock.ExpectQuery(`SELECT .* FROM mapping_profiles WHERE name = \$1`).WithArgs("missing").
+		WillReturnRows(sqlmock.NewRows(mappingProfileCols))
+
+	_, found, err := repo.GetByName(context.Background(), "missing")
+	if err != nil || found {
+		t.Fatalf("expected not found, got found=%v err=%v", found, err)
+	}
+}
+
+func TestMappingProfileDelete_ReportsExistence(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer sqlDB.Close()
+	repo := NewMappingProfileRepo(sqlDB)
+
+	mock.ExpectExec(`DELETE FROM mapping_profiles WHERE name = \$1`).WithArgs("acme").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`DELETE FROM mapping_profiles WHERE name = \$1`).WithArgs("acme").WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if ok, err := repo.Delete(context.Background(), "acme"); err != nil || !ok {
+		t.Fatalf("expected deleted, got ok=%v err=%v", ok, err)
+	}
+	if ok, err := repo.Delete(context.Background(), "acme"); err != nil || ok {
+		t.Fatalf("expected not found, got ok=%v err=%v", ok, err)
+	}
+}
@@ -0,0 +1,175 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/ports/repositories"
+)
+
+type MigrationBatchRepo struct {
+	DB *sql.DB
+}
+
+var _ repositories.MigrationBatchRepository = (*MigrationBatchRepo)(nil)
+
+func NewMigrationBatchRepo(db *sql.DB) *MigrationBatchRepo {
+	return &MigrationBatchRepo{DB: db}
+}
+
+const migrationBatchColumns = `id, migration_id, file_name, sha256, size_bytes, uploaded_by, client_ip, status, inserted, rejected, already_present, error_code, amount_rounding, started_at, finished_at`
+
+func (r *MigrationBatchRepo) Create(ctx context.Context, b domain.MigrationBatch) (domain.MigrationBatch, error) {
+	var migrationID any
+	if b.Source.MigrationID > 0 {
+		migrationID = b.Source.MigrationID
+	}
+	row := r.DB.QueryRowContext(ctx,
+		`INSERT INTO migration_batches (migration_id, file_name, uploaded_by, client_ip, amount_rounding) VALUES ($1, $2, $3, $4, $5) RETURNING `+migrationBatchColumns,
+		migrationID, b.Source.FileName, b.Source.UploadedBy, b.Source.ClientIP, string(b.AmountRounding))
+	return scanMigrationBatch(row)
+}
+
+func (r *MigrationBatchRepo) Finish(ctx context.Context, b domain.MigrationBatch) error {
+	_, err := r.DB.ExecContext(ctx,
+		`UPDATE migration_batches SET status = $2, sha256 = $3, size_bytes = $4, inserted = $5, rejected = $6, already_present = $7, error_code = $8, finished_at = now() WHERE id = $1 AND status = 'PROCESSING'`,
+		b.ID, string(b.Status), b.SHA256, b.Size, b.Inserted, b.Rejected, b.AlreadyPresent, b.ErrorCode)
+	return err
+}
+
+func (r *MigrationBatchRepo) GetByID(ctx context.Context, id int64) (domain.MigrationBatch, bool, error) {
+	row := r.DB.QueryRowContext(ctx, `SELECT `+migrationBatchColumns+` FROM migration_batches WHERE id = $1`, id)
+	return optionalMigrationBatch(scanMigrationBatch(row))
+}
+
+func (r *MigrationBatchRepo) FindSucceeded(ctx context.Context, sha256 string) (domain.MigrationBatch, bool, error) {
+	row := r.DB.QueryRowContext(ctx, `SELECT `+migrationBatchColumns+` FROM migration_batches WHERE sha256 = $1 AND status = 'SUCCEEDED' ORDER BY id LIMIT 1`, sha256)
+	return optionalMigrationBatch(scanMigrationBatch(row))
+}
+
+func (r *MigrationBatchRepo) GetByMigrationID(ctx context.Context, migrationID int64) (domain.MigrationBatch, bool, error) {
+	row := r.DB.QueryRowContext(ctx, `SELECT `+migrationBatchColumns+` FROM migration_batches WHERE migration_id = $1 ORDER BY id DESC LIMIT 1`, migrationID)
+	return optionalMigrationBatch(scanMigrationBatch(row))
+}
+
+// lockedPeriodOfBatch finds the first locked month holding a transaction of the batch. Months
+// are compared in UTC.
+const lockedPeriodOfBatch = `
+SELECT to_char(l.period, 'YYYY-MM')
+FROM period_locks l
+WHERE EXISTS (
+	SELECT 1 FROM transactions t
+	WHERE t.batch_id = $1
+	  AND t.datetime >= l.period::timestamp AT TIME ZONE 'UTC'
+	  AND t.datetime < (l.period + INTERVAL '1 month') AT TIME ZONE 'UTC'
+)
+ORDER BY l.period
+LIMIT 1`
+
+func (r *MigrationBatchRepo) Revert(ctx context.Context, rev domain.BatchRevert) (repositories.BatchRevertOutcome, error) {
+	var out repositories.BatchRevertOutcome
+	tx, err := r.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return out, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+
+	// Locking the batch row serializes concurrent reverts of the same batch.
+	var status string
+	err = tx.QueryRowContext(ctx, `SELECT status FROM migration_batches WHERE id = $1 FOR UPDATE`, rev.BatchID).Scan(&status)
+	if err == sql.ErrNoRows {
+		return out, nil
+	}
+	if err != nil {
+		return out, err
+	}
+	out.Found, out.Status = true, domain.MigrationBatchStatus(status)
+	if out.Status != domain.MigrationBatchSucceeded {
+		return out, nil
+	}
+	// The SHARE lock keeps periods from being locked until the revert commits, so the check
+	// below holds for the delete; PeriodLockRepo.Save takes a conflicting lock.
+	if _, err := tx.ExecContext(ctx, `LOCK TABLE period_locks IN SHARE MODE`); err != nil {
+		return out, err
+	}
+	err = tx.QueryRowContext(ctx, lockedPeriodOfBatch, rev.BatchID).Scan(&out.LockedPeriod)
+	if err != nil && err != sql.ErrNoRows {
+		return out, err
+	}
+	if out.LockedPeriod != "" {
+		return out, nil
+	}
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM transactions WHERE batch_id = $1`, rev.BatchID)
+	if err != nil {
+		return out, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return out, err
+	}
+	rev.TransactionsDeleted = int(n)
+	err = tx.QueryRowContext(ctx,
+		`INSERT INTO migration_batch_reverts (batch_id, reverted_by, reason, transactions_deleted) VALUES ($1, $2, $3, $4) RETURNING reverted_at`,
+		rev.BatchID, rev.RevertedBy, rev.Reason, rev.TransactionsDeleted).Scan(&rev.RevertedAt)
+	if err != nil {
+		return out, err
+	}
+	if _, err := tx.ExecContext(ctx, `UPDATE migration_batches SET status = 'REVERTED' WHERE id = $1`, rev.BatchID); err != nil {
+		return out, err
+	}
+	if err := tx.Commit(); err != nil {
+		return out, err
+	}
+	rev.RevertedAt = rev.RevertedAt.UTC()
+	out.Revert = &rev
+	return out, nil
+}
+
+func (r *MigrationBatchRepo) GetRevert(ctx context.Context, batchID int64) (domain.BatchRevert, bool, error) {
+	var rev domain.BatchRevert
+	err := r.DB.QueryRowContext(ctx,
+		`SELECT batch_id, reverted_by, reason, transactions_deleted, reverted_at FROM migration_batch_reverts WHERE batch_id = $1`, batchID).
+		Scan(&rev.BatchID, &rev.RevertedBy, &rev.Reason, &rev.TransactionsDeleted, &rev.RevertedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return domain.BatchRevert{}, false, nil
+		}
+		return domain.BatchRevert{}, false, err
+	}
+	rev.RevertedAt = rev.RevertedAt.UTC()
+	return rev, true, nil
+}
+
+func optionalMigrationBatch(b domain.MigrationBatch, err error) (domain.MigrationBatch, bool, error) {
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return domain.MigrationBatch{}, false, nil
+		}
+		return domain.MigrationBatch{}, false, err
+	}
+	return b, true, nil
+}
+
+func scanMigrationBatch(row rowScanner) (domain.MigrationBatch, error) {
+	var (
+		b           domain.MigrationBatch
+		migrationID sql.NullInt64
+		status      string
+		rounding    string
+		finishedAt  sql.NullTime
+	)
+	if err := row.Scan(&b.ID, &migrationID, &b.Source.FileName, &b.SHA256, &b.Size, &b.Source.UploadedBy, &b.Source.ClientIP,
+		&status, &b.Inserted, &b.Rejected, &b.AlreadyPresent, &b.ErrorCode, &rounding, &b.StartedAt, &finishedAt); err != nil {
+		return domain.MigrationBatch{}, err
+	}
+	b.Source.MigrationID = migrationID.Int64
+	b.Status = domain.MigrationBatchStatus(status)
+	b.AmountRounding = domain.AmountRounding(rounding)
+	b.StartedAt = b.StartedAt.UTC()
+	b.FinishedAt = nullTimePtr(finishedAt)
+	return b, nil
+}
@@ -0,0 +1,147 @@
+package db
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"stori-challenge/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var migrationBatchCols = []string{"id", "migration_id", "file_name", "sha256", "size_bytes", "uploaded_by", "client_ip", "status", "inserted", "rejected", "already_present", "error_code", "amount_rounding", "started_at", "finished_at"}
+
+func TestMigrationBatchCreate_ReturnsProcessing(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer sqlDB.Close()
+	repo := NewMigrationBatchRepo(sqlDB)
+
+	started := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	queryRe := regexp.MustCompile(`INSERT INTO migration_batches \(migration_id, file_name, uploaded_by, client_ip, amount_rounding\) VALUES \(\$1, \$2, \$3, \$4, \$5\) RETURNING`)
+	mock.ExpectQuery(queryRe.String()).WithArgs(nil, "data.csv", "ana", "10.0.0.1", "half_even").
+		WillReturnRows(sqlmock.NewRows(migrationBatchCols).AddRow(int64(1), nil, "data.csv", "", 0, "ana", "10.0.0.1", "PROCESSING", 0, 0, 0, "", "half_even", started, nil))
+
+	src := domain.UploadSource{FileName: "data.csv", UploadedBy: "ana", ClientIP: "10.0.0.1"}
+	b, err := repo.Create(context.Background(), domain.MigrationBatch{Source: src, AmountRounding: domain.AmountRoundingHalfEven})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b.ID != 1 || b.Status != domain.MigrationBatchProcessing || b.Source.MigrationID != 0 || b.FinishedAt != nil || b.AmountRounding != domain.AmountRoundingHalfEven {
+		t.Fatalf("unexpected batch: %+v", b)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestMigrationBatchFinish_RecordsOutcome(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer sqlDB.Close()
+	repo := NewMigrationBatchRepo(sqlDB)
+
+	stmtRe := regexp.MustCompile(`UPDATE migration_batches SET status = \$2, sha256 = \$3, size_bytes = \$4, inserted = \$5, rejected = \$6, already_present = \$7, error_code = \$8, finished_at = now\(\) WHERE id = \$1 AND status = 'PROCESSING'`)
+	mock.ExpectExec(stmtRe.String()).
+		WithArgs(int64(2), "SUCCEEDED", "abc", int64(120), 3, 1, 0, "").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	b := domain.MigrationBatch{ID: 2, Status: domain.MigrationBatchSucceeded, SHA256: "abc", Size: 120, Inserted: 3, Rejected: 1}
+	if err := repo.Finish(context.Background(), b); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestMigrationBatchFindSucceeded(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer sqlDB.Close()
+	repo := NewMigrationBatchRepo(sqlDB)
+
+	started := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	queryRe := regexp.MustCompile(`SELECT .* FROM migration_batches WHERE sha256 = \$1 AND status = 'SUCCEEDED'`)
+	mock.ExpectQuery(queryRe.String()).WithArgs("abc").
+		WillReturnRows(sqlmock.NewRows(migrationBatchCols).AddRow(int64(4), int64(9), "data.csv", "abc", 120, "", "", "SUCCEEDED", 3, 0, 0, "", "reject", started, started.Add(time.Second)))
+	mock.ExpectQuery(queryRe.String()).WithArgs("def").WillReturnRows(sqlmock.NewRows(migrationBatchCols))
+
+	b, found, err := repo.FindSucceeded(context.Background(), "abc")
+	if err != nil || !found {
+		t.Fatalf("unexpected result: found=%v err=%v", found, err)
+	}
+	if b.ID != 4 || b.Source.MigrationID != 9 || b.Size != 120 || b.FinishedAt == nil {
+		t.Fatalf("unexpected batch: %+v", b)
+	}
+	if _, found, err := repo.FindSucceeded(context.Background(), "def"); err != nil || found {
+		t.Fatalf("expected not found, got found=%v err=%v", found, err)
+	}
+}
+
+func TestMigrationBatchRevert_DeletesInOneTransaction(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer sqlDB.Close()
+	repo := NewMigrationBatchRepo(sqlDB)
+
+	reverted := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT status FROM migration_batches WHERE id = \$1 FOR UPDATE`).WithArgs(int64(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("SUCCEEDED"))
+	mock.ExpectExec(`LOCK TABLE period_locks IN SHARE MODE`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`FROM period_locks l`).WithArgs(int64(3)).WillReturnRows(sqlmock.NewRows([]string{"period"}))
+	mock.ExpectExec(`DELETE FROM transactions WHERE batch_id = \$1`).WithArgs(int64(3)).WillReturnResult(sqlmock.NewResult(0, 12))
+	mock.ExpectQuery(`INSERT INTO migration_batch_reverts`).WithArgs(int64(3), "ana", "wrong file", 12).
+		WillReturnRows(sqlmock.NewRows([]string{"reverted_at"}).AddRow(reverted))
+	mock.ExpectExec(`UPDATE migration_batches SET status = 'REVERTED' WHERE id = \$1`).WithArgs(int64(3)).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	out, err := repo.Revert(context.Background(), domain.BatchRevert{BatchID: 3, RevertedBy: "ana", Reason: "wrong file"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !out.Found || out.Revert == nil || out.Revert.TransactionsDeleted != 12 || !out.Revert.RevertedAt.Equal(reverted) {
+		t.Fatalf("unexpected outcome: %+v", out)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestMigrationBatchRevert_LockedPeriod_RollsBack(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer sqlDB.Close()
+	repo := NewMigrationBatchRepo(sqlDB)
+
+	mock.ExpectBegin()
+	mock.ExpectQuery(`SELECT status FROM migration_batches`).WithArgs(int64(3)).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow("SUCCEEDED"))
+	mock.ExpectExec(`LOCK TABLE period_locks IN SHARE MODE`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`FROM period_locks l`).WithArgs(int64(3)).WillReturnRows(sqlmock.NewRows([]string{"period"}).AddRow("2024-06"))
+	mock.ExpectRollback()
+
+	out, err := repo.Revert(context.Background(), domain.BatchRevert{BatchID: 3, RevertedBy: "ana", Reason: "wrong file"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out.Revert != nil || out.LockedPeriod != "2024-06" {
+		t.Fatalf("unexpected outcome: %+v", out)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,485 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/ports/repositories"
+)
+
+type MigrationRepo struct {
+	DB *sql.DB
+}
+
+var _ repositories.MigrationRepository = (*MigrationRepo)(nil)
+
+func NewMigrationRepo(db *sql.DB) *MigrationRepo {
+	return &MigrationRepo{DB: db}
+}
+
+// rowErrorRecord is the JSONB shape of a row error stored in migrations.errors.
+type rowErrorRecord struct {
+	File    string `json:"file,omitempty"`
+	Sheet   string `json:"sheet,omitempty"`
+	Path    string `json:"path,omitempty"`
+	Row     int    `json:"row"`
+	Field   string `json:"field"`
+	Value   string `json:"value"`
+	Message string `json:"message"`
+	// Rule and Severity are set for errors of configured validation rules.
+	Rule     string `json:"rule,omitempty"`
+	Severity string `json:"severity,omitempty"`
+}
+
+// dialectRecord is the JSONB shape of migrations.dialect.
+type dialectRecord struct {
+	Delimiter        string `json:"delimiter"`
+	DecimalSeparator string `json:"decimal_separator"`
+}
+
+// errorSummaryRecord is the JSONB shape of migrations.error_summary.
+type errorSummaryRecord struct {
+	Total     int                `json:"total"`
+	Omitted   int                `json:"omitted"`
+	Groups    []errorGroupRecord `json:"groups"`
+	Ungrouped int                `json:"ungrouped,omitempty"`
+}
+
+type errorGroupRecord struct {
+	File       string `json:"file,omitempty"`
+	Field      string `json:"field"`
+	Message    string `json:"message"`
+	Count      int    `json:"count"`
+	SampleRows []int  `json:"sample_rows"`
+}
+
+// migrationOptionsRecord is the JSONB shape of migrations.options.
+type migrationOptionsRecord struct {
+	Format         string              `json:"format,omitempty"`
+	Compression    string              `json:"compression,omitempty"`
+	Mode           string              `json:"mode,omitempty"`
+	Idempotent     bool                `json:"idempotent,omitempty"`
+	Profile        string              `json:"profile,omitempty"`
+	ProfileColumns map[string][]string `json:"profile_columns,omitempty"`
+	DatetimeFormat string              `json:"datetime_format,omitempty"`
+	Timezone       string              `json:"timezone,omitempty"`
+	Locale         string              `json:"locale,omitempty"`
+	Sheet          string              `json:"sheet,omitempty"`
+	UserID         int64               `json:"user_id,omitempty"`
+	Encoding       string              `json:"encoding,omitempty"`
+	Delimiter      string              `json:"delimiter,omitempty"`
+	MaxErrors      int                 `json:"max_errors,omitempty"`
+	OverdraftFloor string              `json:"overdraft_floor,omitempty"`
+	UnknownUsers   string              `json:"unknown_users,omitempty"`
+	AmountRounding string              `json:"amount_rounding,omitempty"`
+	UploadedBy     string              `json:"uploaded_by,omitempty"`
+	ClientIP       string              `json:"client_ip,omitempty"`
+}
+
+const migrationColumns = `id, status, file_name, file_key, options, inserted, rejected, already_present, error_code, errors, error_summary, dialect, created_at, started_at, finished_at, attempt`
+
+func (r *MigrationRepo) Create(ctx context.Context, fileName, fileKey string, opts domain.MigrationOptions) (domain.Migration, error) {
+	optsJSON, err := marshalMigrationOptions(opts)
+	if err != nil {
+		return domain.Migration{}, err
+	}
+	row := r.DB.QueryRowContext(ctx,
+		`INSERT INTO migrations (file_name, file_key, options) VALUES ($1, $2, $3) RETURNING `+migrationColumns,
+		fileName, fileKey, optsJSON)
+	return scanMigration(row)
+}
+
+func (r *MigrationRepo) GetByID(ctx context.Context, id int64) (domain.Migration, bool, error) {
+	row := r.DB.QueryRowContext(ctx, `SELECT `+migrationColumns+` FROM migrations WHERE id = $1`, id)
+	m, err := scanMigration(row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return domain.Migration{}, false, nil
+		}
+		return domain.Migration{}, false, err
+	}
+	return m, true, nil
+}
+
+func (r *MigrationRepo) ClaimNext(ctx context.Context, staleAfter time.Duration) (domain.Migration, bool, error) {
+	// SKIP LOCKED lets concurrent workers pick different rows instead of blocking on the same one;
+	// the status transition happens in the same statement, so a row is never claimed twice.
+	// A stale PROCESSING row is one whose worker stopped sending heartbeats; its import was
+	// rolled back with its connection, but the row errors it logged are cleared here. The new
+	// attempt fences off that worker if it is still running.
+	const q = `
+WITH claimed AS (
+	UPDATE migrations SET status = 'PROCESSING', started_at = now(), heartbeat_at = now(), attempt = attempt + 1
+	WHERE id = (
+		SELECT id FROM migrations
+		WHERE status = 'PENDING'
+			OR ($1::bigint > 0 AND status = 'PROCESSING' AND COALESCE(heartbeat_at, started_at) < now() - $1::bigint * interval '1 millisecond')
+		ORDER BY created_at, id
+		FOR UPDATE SKIP LOCKED
+		LIMIT 1
+	)
+	RETURNING ` + migrationColumns + `
+), cleared AS (
+	DELETE FROM migration_row_errors WHERE migration_id IN (SELECT id FROM claimed)
+)
+SELECT ` + migrationColumns + ` FROM claimed`
+	m, err := scanMigration(r.DB.QueryRowContext(ctx, q, staleAfter.Milliseconds()))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return domain.Migration{}, false, nil
+		}
+		return domain.Migration{}, false, err
+	}
+	return m, true, nil
+}
+
+func (r *MigrationRepo) Heartbeat(ctx context.Context, id int64, attempt int) error {
+	return execHeld(r.DB.ExecContext(ctx, `UPDATE migrations SET heartbeat_at = now() WHERE id = $1 AND attempt = $2 AND status = 'PROCESSING'`, id, attempt))
+}
+
+func (r *MigrationRepo) Complete(ctx context.Context, id int64, attempt int, counts domain.MigrationCounts, items []domain.RowError, summary *domain.RowErrorSummary) error {
+	payload, err := marshalRowErrors(items)
+	if err != nil {
+		return err
+	}
+	summaryJSON, err := marshalErrorSummary(summary)
+	if err != nil {
+		return err
+	}
+	dialect, err := marshalDialect(counts.Dialect)
+	if err != nil {
+		return err
+	}
+	return execHeld(r.DB.ExecContext(ctx,
+		`UPDATE migrations SET status = 'COMPLETED', inserted = $3, rejected = $4, already_present = $5, errors = $6, dialect = $7, error_summary = $8, finished_at = now() WHERE id = $1 AND attempt = $2 AND status = 'PROCESSING'`,
+		id, attempt, counts.Inserted, counts.Rejected, counts.AlreadyPresent, payload, dialect, summaryJSON))
+}
+
+func (r *MigrationRepo) Fail(ctx context.Context, id int64, attempt int, code string, items []domain.RowError, summary *domain.RowErrorSummary) error {
+	payload, err := marshalRowErrors(items)
+	if err != nil {
+		return err
+	}
+	summaryJSON, err := marshalErrorSummary(summary)
+	if err != nil {
+		return err
+	}
+	return execHeld(r.DB.ExecContext(ctx,
+		`UPDATE migrations SET status = 'FAILED', error_code = $3, errors = $4, error_summary = $5, finished_at = now() WHERE id = $1 AND attempt = $2 AND status = 'PROCESSING'`,
+		id, attempt, code, payload, summaryJSON))
+}
+
+// execHeld maps a write that matched no row, because the migration is no longer PROCESSING
+// under the caller's attempt, to repositories.ErrLeaseLost.
+func execHeld(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return repositories.ErrLeaseLost
+	}
+	return nil
+}
+
+// rowErrorPage is how many logged row errors are inserted or read per statement.
+const rowErrorPage = 500
+
+// AddRowErrors inserts only while the migration is held under attempt. The row lock taken by the
+// check makes a concurrent reclaim, which clears the log, wait for the insert or reject it.
+func (r *MigrationRepo) AddRowErrors(ctx context.Context, id int64, attempt int, errs []domain.LoggedRowError) error {
+	for i := 0; i < len(errs); i += rowErrorPage {
+		end := min(i+rowErrorPage, len(errs))
+		var sb strings.Builder
+		args := []any{id, attempt}
+		sb.WriteString("INSERT INTO migration_row_errors (migration_id, run_row, error) SELECT $1, v.run_row, v.error FROM (VALUES ")
+		for j, e := range errs[i:end] {
+			if j > 0 {
+				sb.WriteString(",")
+			}
+			b, err := json.Marshal(toRowErrorRecord(e.RowError))
+			if err != nil {
+				return err
+			}
+			fmt.Fprintf(&sb, "($%d::integer,$%d::jsonb)", len(args)+1, len(args)+2)
+			args = append(args, e.RunRow, string(b))
+		}
+		sb.WriteString(") AS v(run_row, error) WHERE EXISTS (SELECT 1 FROM migrations WHERE id = $1 AND attempt = $2 AND status = 'PROCESSING' FOR SHARE)")
+		if err := execHeld(r.DB.ExecContext(ctx, sb.String(), args...)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EachRowError reads the log a page at a time, resuming after the last row error read, so no
+// connection is held while fn runs.
+func (r *MigrationRepo) EachRowError(ctx context.Context, id int64, fn func(domain.LoggedRowError) error) error {
+	var afterRow, afterID int64
+	for {
+		page, lastID, err := r.readRowErrors(ctx, id, afterRow, afterID)
+		if err != nil {
+			return err
+		}
+		for _, e := range page {
+			if err := fn(e); err != nil {
+				return err
+			}
+		}
+		if len(page) < rowErrorPage {
+			return nil
+		}
+		afterRow, afterID = int64(page[len(page)-1].RunRow), lastID
+	}
+}
+
+// readRowErrors returns the page of logged row errors after (afterRow, afterID) and the id of
+// its last one.
+func (r *MigrationRepo) readRowErrors(ctx context.Context, id, afterRow, afterID int64) ([]domain.LoggedRowError, int64, error) {
+	rows, err := r.DB.QueryContext(ctx,
+		`SELECT id, run_row, error FROM migration_row_errors WHERE migration_id = $1 AND (run_row, id) > ($2, $3) ORDER BY run_row, id LIMIT $4`,
+		id, afterRow, afterID, rowErrorPage)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer rows.Close()
+	var (
+		page   []domain.LoggedRowError
+		lastID int64
+	)
+	for rows.Next() {
+		var (
+			runRow  int
+			payload []byte
+			rec     rowErrorRecord
+		)
+		if err := rows.Scan(&lastID, &runRow, &payload); err != nil {
+			return nil, 0, err
+		}
+		if err := json.Unmarshal(payload, &rec); err != nil {
+			return nil, 0, err
+		}
+		page = append(page, domain.LoggedRowError{RunRow: runRow, RowError: rec.rowError()})
+	}
+	return page, lastID, rows.Err()
+}
+
+func marshalRowErrors(items []domain.RowError) (string, error) {
+	recs := make([]rowErrorRecord, 0, len(items))
+	for _, it := range items {
+		recs = append(recs, toRowErrorRecord(it))
+	}
+	b, err := json.Marshal(recs)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func unmarshalRowErrors(b []byte) ([]domain.RowError, error) {
+	if len(b) == 0 {
+		return nil, nil
+	}
+	var recs []rowErrorRecord
+	if err := json.Unmarshal(b, &recs); err != nil {
+		return nil, err
+	}
+	if len(recs) == 0 {
+		return nil, nil
+	}
+	items := make([]domain.RowError, 0, len(recs))
+	for _, rec := range recs {
+		items = append(items, rec.rowError())
+	}
+	return items, nil
+}
+
+func toRowErrorRecord(e domain.RowError) rowErrorRecord {
+	return rowErrorRecord{File: e.File, Sheet: e.Sheet, Path: e.Path, Row: e.Row, Field: e.Field, Value: e.Value, Message: e.Message, Rule: e.Rule, Severity: string(e.Severity)}
+}
+
+func (rec rowErrorRecord) rowError() domain.RowError {
+	return domain.RowError{File: rec.File, Sheet: rec.Sheet, Path: rec.Path, Row: rec.Row, Field: rec.Field, Value: rec.Value, Message: rec.Message, Rule: rec.Rule, Severity: domain.RuleSeverity(rec.Severity)}
+}
+
+// marshalErrorSummary returns the JSON of s, or nil (SQL NULL) when there is none.
+func marshalErrorSummary(s *domain.RowErrorSummary) (any, error) {
+	if s == nil {
+		return nil, nil
+	}
+	rec := errorSummaryRecord{Total: s.Total, Omitted: s.Omitted, Groups: make([]errorGroupRecord, 0, len(s.Groups)), Ungrouped: s.Ungrouped}
+	for _, g := range s.Groups {
+		rec.Groups = append(rec.Groups, errorGroupRecord{File: g.File, Field: g.Field, Message: g.Message, Count: g.Count, SampleRows: g.SampleRows})
+	}
+	b, err := json.Marshal(rec)
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func unmarshalErrorSummary(b []byte) (*domain.RowErrorSummary, error) {
+	if len(b) == 0 {
+		return nil, nil
+	}
+	var rec errorSummaryRecord
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return nil, err
+	}
+	s := &domain.RowErrorSummary{Total: rec.Total, Omitted: rec.Omitted, Groups: make([]domain.RowErrorGroup, 0, len(rec.Groups)), Ungrouped: rec.Ungrouped}
+	for _, g := range rec.Groups {
+		s.Groups = append(s.Groups, domain.RowErrorGroup{File: g.File, Field: g.Field, Message: g.Message, Count: g.Count, SampleRows: g.SampleRows})
+	}
+	return s, nil
+}
+
+// marshalDialect returns the JSON of d, or nil (SQL NULL) when there is none.
+func marshalDialect(d *domain.CSVDialect) (any, error) {
+	if d == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(dialectRecord{Delimiter: d.Delimiter, DecimalSeparator: d.DecimalSeparator})
+	if err != nil {
+		return nil, err
+	}
+	return string(b), nil
+}
+
+func unmarshalDialect(b []byte) (*domain.CSVDialect, error) {
+	if len(b) == 0 {
+		return nil, nil
+	}
+	var rec dialectRecord
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return nil, err
+	}
+	return &domain.CSVDialect{Delimiter: rec.Delimiter, DecimalSeparator: rec.DecimalSeparator}, nil
+}
+
+func marshalMigrationOptions(opts domain.MigrationOptions) (string, error) {
+	b, err := json.Marshal(migrationOptionsRecord{
+		Format:         string(opts.Format),
+		Compression:    string(opts.Compression),
+		Mode:           string(opts.Mode),
+		Idempotent:     opts.Idempotent,
+		Profile:        opts.Profile,
+		ProfileColumns: opts.ProfileColumns,
+		DatetimeFormat: string(opts.DatetimeFormat),
+		Timezone:       opts.Timezone,
+		Locale:         opts.Locale,
+		Sheet:          opts.Sheet,
+		UserID:         opts.UserID,
+		Encoding:       string(opts.Encoding),
+		Delimiter:      opts.Delimiter,
+		MaxErrors:      opts.MaxErrors,
+		OverdraftFloor: floorString(opts.OverdraftFloor),
+		UnknownUsers:   string(opts.UnknownUsers),
+		AmountRounding: string(opts.AmountRounding),
+		UploadedBy:     opts.Source.UploadedBy,
+		ClientIP:       opts.Source.ClientIP,
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func unmarshalMigrationOptions(b []byte) (domain.MigrationOptions, error) {
+	if len(b) == 0 {
+		return domain.MigrationOptions{}, nil
+	}
+	var rec migrationOptionsRecord
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return domain.MigrationOptions{}, err
+	}
+	var floor *decimal.Decimal
+	if rec.OverdraftFloor != "" {
+		d, err := decimal.NewFromString(rec.OverdraftFloor)
+		if err != nil {
+			return domain.MigrationOptions{}, err
+		}
+		floor = &d
+	}
+	return domain.MigrationOptions{
+		Format:         domain.InputFormat(rec.Format),
+		Compression:    domain.Compression(rec.Compression),
+		Mode:           domain.MigrationMode(rec.Mode),
+		Idempotent:     rec.Idempotent,
+		Profile:        rec.Profile,
+		ProfileColumns: rec.ProfileColumns,
+		DatetimeFormat: domain.DatetimeFormat(rec.DatetimeFormat),
+		Timezone:       rec.Timezone,
+		Locale:         rec.Locale,
+		Sheet:          rec.Sheet,
+		UserID:         rec.UserID,
+		Encoding:       domain.Encoding(rec.Encoding),
+		Delimiter:      rec.Delimiter,
+		MaxErrors:      rec.MaxErrors,
+		OverdraftFloor: floor,
+		UnknownUsers:   domain.UnknownUserPolicy(rec.UnknownUsers),
+		AmountRounding: domain.AmountRounding(rec.AmountRounding),
+		Source:         domain.UploadSource{UploadedBy: rec.UploadedBy, ClientIP: rec.ClientIP},
+	}, nil
+}
+
+// floorString is the stored form of an overdraft floor; empty when there is none.
+func floorString(d *decimal.Decimal) string {
+	if d == nil {
+		return ""
+	}
+	return d.String()
+}
+
+func scanMigration(row *sql.Row) (domain.Migration, error) {
+	var (
+		m          domain.Migration
+		status     string
+		optsJSON   []byte
+		errorsJSON []byte
+		summary    []byte
+		dialect    []byte
+		startedAt  sql.NullTime
+		finishedAt sql.NullTime
+	)
+	if err := row.Scan(&m.ID, &status, &m.FileName, &m.FileKey, &optsJSON, &m.Inserted, &m.Rejected, &m.AlreadyPresent, &m.ErrorCode, &errorsJSON, &summary, &dialect, &m.CreatedAt, &startedAt, &finishedAt, &m.Attempt); err != nil {
+		return domain.Migration{}, err
+	}
+	opts, err := unmarshalMigrationOptions(optsJSON)
+	if err != nil {
+		return domain.Migration{}, err
+	}
+	m.Options = opts
+	m.Status = domain.MigrationStatus(status)
+	m.CreatedAt = m.CreatedAt.UTC()
+	m.StartedAt = nullTimePtr(startedAt)
+	m.FinishedAt = nullTimePtr(finishedAt)
+	items, err := unmarshalRowErrors(errorsJSON)
+	if err != nil {
+		return domain.Migration{}, err
+	}
+	m.Errors = items
+	if m.Summary, err = unmarshalErrorSummary(summary); err != nil {
+		return domain.Migration{}, err
+	}
+	if m.Dialect, err = unmarshalDialect(dialect); err != nil {
+		return domain.Migration{}, err
+	}
+	return m, nil
+}
+
+func nullTimePtr(nt sql.NullTime) *time.Time {
+	if !nt.Valid {
+		return nil
+	}
+	t := nt.Time.UTC()
+	return &t
+}
@@ -0,0 +1,149 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/ports/repositories"
+	testinfra "stori-challenge/internal/shared/test"
+)
+
+func TestIntegration_Migration_Lifecycle(t *testing.T) {
+	db, err := testinfra.OpenTestDB(t.Name())
+	if err != nil {
+		t.Fatalf("open test db: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewMigrationRepo(db)
+	ctx := context.Background()
+
+	created, err := repo.Create(ctx, "data.csv", "key.csv", domain.MigrationOptions{})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if created.Status != domain.MigrationStatusPending {
+		t.Fatalf("expected PENDING, got %s", created.Status)
+	}
+
+	claimed, ok, err := repo.ClaimNext(ctx, time.Hour)
+	if err != nil || !ok {
+		t.Fatalf("claim: ok=%v err=%v", ok, err)
+	}
+	if claimed.ID != created.ID || claimed.Status != domain.MigrationStatusProcessing || claimed.StartedAt == nil {
+		t.Fatalf("unexpected claimed migration: %+v", claimed)
+	}
+
+	// Nothing else is pending and the claim is fresh, so a second claim must come back empty.
+	if _, ok, err := repo.ClaimNext(ctx, time.Hour); err != nil || ok {
+		t.Fatalf("expected no second claim, got ok=%v err=%v", ok, err)
+	}
+
+	items := []domain.RowError{{Row: 3, Field: "amount", Value: "x", Message: "not a valid number"}}
+	if err := repo.Fail(ctx, claimed.ID, claimed.Attempt, "validation_error", items, nil); err != nil {
+		t.Fatalf("fail: %v", err)
+	}
+	got, found, err := repo.GetByID(ctx, claimed.ID)
+	if err != nil || !found {
+		t.Fatalf("get: found=%v err=%v", found, err)
+	}
+	if got.Status != domain.MigrationStatusFailed || got.ErrorCode != "validation_error" || len(got.Errors) != 1 || got.FinishedAt == nil {
+		t.Fatalf("unexpected migration after fail: %+v", got)
+	}
+}
+
+func TestIntegration_Migration_RowErrorLog(t *testing.T) {
+	db, err := testinfra.OpenTestDB(t.Name())
+	if err != nil {
+		t.Fatalf("open test db: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewMigrationRepo(db)
+	ctx := context.Background()
+
+	if _, err := repo.Create(ctx, "data.zip", "key.zip", domain.MigrationOptions{}); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	m, ok, err := repo.ClaimNext(ctx, time.Hour)
+	if err != nil || !ok {
+		t.Fatalf("claim: ok=%v err=%v", ok, err)
+	}
+	errs := []domain.LoggedRowError{
+		{RunRow: 9, RowError: domain.RowError{File: "b.csv", Row: 2, Field: "id", Message: "id already exists in DB"}},
+		{RunRow: 3, RowError: domain.RowError{File: "a.csv", Row: 3, Field: "amount", Message: "not a valid number"}},
+	}
+	if err := repo.AddRowErrors(ctx, m.ID, m.Attempt, errs); err != nil {
+		t.Fatalf("add row errors: %v", err)
+	}
+	var rows []int
+	if err := repo.EachRowError(ctx, m.ID, func(e domain.LoggedRowError) error {
+		rows = append(rows, e.RunRow)
+		return nil
+	}); err != nil {
+		t.Fatalf("each row error: %v", err)
+	}
+	if len(rows) != 2 || rows[0] != 3 || rows[1] != 9 {
+		t.Fatalf("expected the log in run-row order, got %v", rows)
+	}
+}
+
+func TestIntegration_Migration_ReclaimsStaleProcessing(t *testing.T) {
+	db, err := testinfra.OpenTestDB(t.Name())
+	if err != nil {
+		t.Fatalf("open test db: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewMigrationRepo(db)
+	ctx := context.Background()
+
+	created, err := repo.Create(ctx, "data.csv", "key.csv", domain.MigrationOptions{})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	first, ok, err := repo.ClaimNext(ctx, time.Minute)
+	if err != nil || !ok {
+		t.Fatalf("claim: ok=%v err=%v", ok, err)
+	}
+	logged := []domain.LoggedRowError{{RunRow: 1, RowError: domain.RowError{Row: 1, Field: "id"}}}
+	if err := repo.AddRowErrors(ctx, created.ID, first.Attempt, logged); err != nil {
+		t.Fatalf("add row errors: %v", err)
+	}
+
+	// The worker stops sending heartbeats.
+	if _, err := db.ExecContext(ctx, `UPDATE migrations SET heartbeat_at = now() - interval '1 hour' WHERE id = $1`, created.ID); err != nil {
+		t.Fatalf("age heartbeat: %v", err)
+	}
+	if _, ok, err := repo.ClaimNext(ctx, 0); err != nil || ok {
+		t.Fatalf("expected no reclaim without a lease, got ok=%v err=%v", ok, err)
+	}
+	reclaimed, ok, err := repo.ClaimNext(ctx, time.Minute)
+	if err != nil || !ok || reclaimed.ID != created.ID || reclaimed.Attempt != first.Attempt+1 {
+		t.Fatalf("expected migration %d reclaimed, got %+v ok=%v err=%v", created.ID, reclaimed, ok, err)
+	}
+	n := 0
+	if err := repo.EachRowError(ctx, created.ID, func(domain.LoggedRowError) error { n++; return nil }); err != nil || n != 0 {
+		t.Fatalf("expected the logged errors cleared, got %d (%v)", n, err)
+	}
+
+	// The first worker is fenced off; only the new attempt records the outcome.
+	if err := repo.Heartbeat(ctx, created.ID, first.Attempt); !errors.Is(err, repositories.ErrLeaseLost) {
+		t.Fatalf("heartbeat of the old attempt: expected ErrLeaseLost, got %v", err)
+	}
+	if err := repo.AddRowErrors(ctx, created.ID, first.Attempt, logged); !errors.Is(err, repositories.ErrLeaseLost) {
+		t.Fatalf("add row errors of the old attempt: expected ErrLeaseLost, got %v", err)
+	}
+	if err := repo.Complete(ctx, created.ID, first.Attempt, domain.MigrationCounts{Inserted: 1}, nil, nil); !errors.Is(err, repositories.ErrLeaseLost) {
+		t.Fatalf("complete of the old attempt: expected ErrLeaseLost, got %v", err)
+	}
+	if err := repo.Complete(ctx, created.ID, reclaimed.Attempt, domain.MigrationCounts{Inserted: 2}, nil, nil); err != nil {
+		t.Fatalf("complete: %v", err)
+	}
+	if got, _, err := repo.GetByID(ctx, created.ID); err != nil || got.Inserted != 2 {
+		t.Fatalf("expected the new attempt's outcome, got %+v (%v)", got, err)
+	}
+}
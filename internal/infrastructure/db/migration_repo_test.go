@@ -0,0 +1,327 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"testing"
+	"time"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/ports/repositories"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+var migrationCols = []string{"id", "status", "file_name", "file_key", "options", "inserted", "rejected", "already_present", "error_code", "errors", "error_summary", "dialect", "created_at", "started_at", "finished_at", "attempt"}
+
+func TestMigrationCreate_ReturnsPending(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer sqlDB.Close()
+	repo := NewMigrationRepo(sqlDB)
+
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	queryRe := regexp.MustCompile(`INSERT INTO migrations \(file_name, file_key, options\) VALUES \(\$1, \$2, \$3\) RETURNING`)
+	opts := `{"mode":"partial","idempotent":true,"profile":"bank","profile_columns":{"id":["Reference"]},"uploaded_by":"ana","client_ip":"10.0.0.1"}`
+	mock.ExpectQuery(queryRe.String()).WithArgs("data.csv", "k.csv", opts).
+		WillReturnRows(sqlmock.NewRows(migrationCols).AddRow(int64(1), "PENDING", "data.csv", "k.csv", []byte(opts), 0, 0, 0, "", []byte(`[]`), nil, nil, created, nil, nil, 0))
+
+	src := domain.UploadSource{FileName: "data.csv", UploadedBy: "ana", ClientIP: "10.0.0.1"}
+	columns := map[string][]string{domain.ColumnID: {"Reference"}}
+	m, err := repo.Create(context.Background(), "data.csv", "k.csv", domain.MigrationOptions{Mode: domain.MigrationModePartial, Idempotent: true, Profile: "bank", ProfileColumns: columns, Source: src})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if m.ID != 1 || m.Status != domain.MigrationStatusPending || m.StartedAt != nil || len(m.Errors) != 0 || !m.Options.IsPartial() || !m.Options.Idempotent {
+		t.Fatalf("unexpected migration: %+v", m)
+	}
+	if m.Options.Source.UploadedBy != "ana" || m.Options.Source.ClientIP != "10.0.0.1" {
+		t.Fatalf("unexpected migration: %+v", m)
+	}
+	if m.Options.Profile != "bank" || len(m.Options.ProfileColumns[domain.ColumnID]) != 1 {
+		t.Fatalf("unexpected profile options: %+v", m.Options)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestMigrationGetByID_NotFound(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer sqlDB.Close()
+	repo := NewMigrationRepo(sqlDB)
+
+	queryRe := regexp.MustCompile(`SELECT .* FROM migrations WHERE id = \$1`)
+	mock.ExpectQuery(queryRe.String()).WithArgs(int64(7)).WillReturnRows(sqlmock.NewRows(migrationCols))
+
+	_, found, err := repo.GetByID(context.Background(), 7)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if found {
+		t.Fatalf("expected not found")
+	}
+}
+
+func TestMigrationGetByID_DecodesErrors(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer sqlDB.Close()
+	repo := NewMigrationRepo(sqlDB)
+
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	finished := created.Add(time.Minute)
+	errs := []byte(`[{"row":2,"field":"amount","value":"x","message":"not a valid number"},{"row":4,"field":"amount","value":"0","message":"amount must not be zero","rule":"non_zero","severity":"warning"}]`)
+	queryRe := regexp.MustCompile(`SELECT .* FROM migrations WHERE id = \$1`)
+	mock.ExpectQuery(queryRe.String()).WithArgs(int64(3)).
+		WillReturnRows(sqlmock.NewRows(migrationCols).AddRow(int64(3), "FAILED", "data.csv", "k.csv", []byte(`{}`), 0, 0, 0, "validation_error", errs, []byte(`{"total":1500,"omitted":500,"groups":[{"field":"amount","message":"not a valid number","count":1500,"sample_rows":[2]}]}`), []byte(`{"delimiter":";","decimal_separator":","}`), created, created, finished, 1))
+
+	m, found, err := repo.GetByID(context.Background(), 3)
+	if err != nil || !found {
+		t.Fatalf("unexpected result: found=%v err=%v", found, err)
+	}
+	if m.Status != domain.MigrationStatusFailed || m.ErrorCode != "validation_error" {
+		t.Fatalf("unexpected migration: %+v", m)
+	}
+	if len(m.Errors) != 2 || m.Errors[0].Row != 2 || m.Errors[0].Field != "amount" {
+		t.Fatalf("unexpected errors: %+v", m.Errors)
+	}
+	if m.Errors[1].Rule != "non_zero" || m.Errors[1].Severity != domain.RuleSeverityWarning {
+		t.Fatalf("unexpected rule error: %+v", m.Errors[1])
+	}
+	if m.FinishedAt == nil || !m.FinishedAt.Equal(finished) {
+		t.Fatalf("unexpected finished_at: %v", m.FinishedAt)
+	}
+	if m.Dialect == nil || m.Dialect.Delimiter != ";" || m.Dialect.DecimalSeparator != "," {
+		t.Fatalf("unexpected dialect: %+v", m.Dialect)
+	}
+	if m.Summary == nil || m.Summary.Total != 1500 || m.Summary.Omitted != 500 || len(m.Summary.Groups) != 1 || m.Summary.Groups[0].Count != 1500 {
+		t.Fatalf("unexpected summary: %+v", m.Summary)
+	}
+}
+
+func TestMigrationClaimNext_UsesSkipLocked(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer sqlDB.Close()
+	repo := NewMigrationRepo(sqlDB)
+
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	queryRe := regexp.MustCompile(`UPDATE migrations SET status = 'PROCESSING', started_at = now\(\), heartbeat_at = now\(\), attempt = attempt \+ 1.*WHERE status = 'PENDING'.*FOR UPDATE SKIP LOCKED`)
+	mock.ExpectQuery(queryRe.String()).WithArgs(int64(0)).
+		WillReturnRows(sqlmock.NewRows(migrationCols).AddRow(int64(4), "PROCESSING", "data.csv", "k.csv", []byte(`{}`), 0, 0, 0, "", []byte(`[]`), nil, nil, created, created, nil, 1))
+
+	m, ok, err := repo.ClaimNext(context.Background(), 0)
+	if err != nil || !ok {
+		t.Fatalf("unexpected result: ok=%v err=%v", ok, err)
+	}
+	if m.ID != 4 || m.Status != domain.MigrationStatusProcessing || m.Attempt != 1 {
+		t.Fatalf("unexpected migration: %+v", m)
+	}
+}
+
+func TestMigrationClaimNext_ReclaimsStaleProcessingAndClearsItsLog(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer sqlDB.Close()
+	repo := NewMigrationRepo(sqlDB)
+
+	created := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	queryRe := regexp.MustCompile(`status = 'PROCESSING' AND COALESCE\(heartbeat_at, started_at\) < now\(\) - \$1::bigint \* interval '1 millisecond'.*DELETE FROM migration_row_errors WHERE migration_id IN \(SELECT id FROM claimed\)`)
+	mock.ExpectQuery(queryRe.String()).WithArgs(int64(300000)).
+		WillReturnRows(sqlmock.NewRows(migrationCols).AddRow(int64(4), "PROCESSING", "data.csv", "k.csv", []byte(`{}`), 0, 0, 0, "", []byte(`[]`), nil, nil, created, created, nil, 1))
+
+	if _, ok, err := repo.ClaimNext(context.Background(), 5*time.Minute); err != nil || !ok {
+		t.Fatalf("unexpected result: ok=%v err=%v", ok, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestMigrationHeartbeat_OnlyTouchesTheHeldAttempt(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer sqlDB.Close()
+	repo := NewMigrationRepo(sqlDB)
+
+	stmt := regexp.QuoteMeta(`UPDATE migrations SET heartbeat_at = now() WHERE id = $1 AND attempt = $2 AND status = 'PROCESSING'`)
+	mock.ExpectExec(stmt).WithArgs(int64(4), 2).WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(stmt).WithArgs(int64(4), 1).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	if err := repo.Heartbeat(context.Background(), 4, 2); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := repo.Heartbeat(context.Background(), 4, 1); !errors.Is(err, repositories.ErrLeaseLost) {
+		t.Fatalf("expected ErrLeaseLost for an older attempt, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestMigrationClaimNext_EmptyQueue(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer sqlDB.Close()
+	repo := NewMigrationRepo(sqlDB)
+
+	mock.ExpectQuery(`UPDATE migrations`).WillReturnRows(sqlmock.NewRows(migrationCols))
+
+	_, ok, err := repo.ClaimNext(context.Background(), 0)
+	if err != nil || ok {
+		t.Fatalf("expected empty queue, got ok=%v err=%v", ok, err)
+	}
+}
+
+func TestMigrationFail_StoresErrorsAsJSON(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer sqlDB.Close()
+	repo := NewMigrationRepo(sqlDB)
+
+	stmtRe := regexp.MustCompile(`UPDATE migrations SET status = 'FAILED', error_code = \$3, errors = \$4, error_summary = \$5, finished_at = now\(\) WHERE id = \$1 AND attempt = \$2 AND status = 'PROCESSING'`)
+	mock.ExpectExec(stmtRe.String()).
+		WithArgs(int64(4), 1, "duplicate_id", `[{"row":1,"field":"id","value":"1","message":"id already exists in DB"}]`,
+			`{"total":1,"omitted":0,"groups":[{"field":"id","message":"id already exists in DB","count":1,"sample_rows":[1]}]}`).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	summary := &domain.RowErrorSummary{Total: 1, Groups: []domain.RowErrorGroup{{Field: "id", Message: "id already exists in DB", Count: 1, SampleRows: []int{1}}}}
+	err = repo.Fail(context.Background(), 4, 1, "duplicate_id", []domain.RowError{{Row: 1, Field: "id", Value: "1", Message: "id already exists in DB"}}, summary)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestMigrationComplete_UpdatesCountsAndErrors(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer sqlDB.Close()
+	repo := NewMigrationRepo(sqlDB)
+
+	stmtRe := regexp.MustCompile(`UPDATE migrations SET status = 'COMPLETED', inserted = \$3, rejected = \$4, already_present = \$5, errors = \$6, dialect = \$7, error_summary = \$8, finished_at = now\(\) WHERE id = \$1 AND attempt = \$2 AND status = 'PROCESSING'`)
+	mock.ExpectExec(stmtRe.String()).
+		WithArgs(int64(4), 1, 12, 1, 2, `[{"row":3,"field":"amount","value":"x","message":"not a valid number"}]`, `{"delimiter":";","decimal_separator":","}`, nil).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	items := []domain.RowError{{Row: 3, Field: "amount", Value: "x", Message: "not a valid number"}}
+	if err := repo.Complete(context.Background(), 4, 1, domain.MigrationCounts{Inserted: 12, Rejected: 1, AlreadyPresent: 2, Dialect: &domain.CSVDialect{Delimiter: ";", DecimalSeparator: ","}}, items, nil); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestMigrationAddRowErrors_InsertsWithRunRows(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer sqlDB.Close()
+	repo := NewMigrationRepo(sqlDB)
+
+	stmt := regexp.QuoteMeta(`INSERT INTO migration_row_errors (migration_id, run_row, error) SELECT $1, v.run_row, v.error FROM (VALUES ($3::integer,$4::jsonb),($5::integer,$6::jsonb)) AS v(run_row, error) WHERE EXISTS (SELECT 1 FROM migrations WHERE id = $1 AND attempt = $2 AND status = 'PROCESSING' FOR SHARE)`)
+	mock.ExpectExec(stmt).
+		WithArgs(int64(4), 1, 3, `{"row":3,"field":"amount","value":"x","message":"not a valid number"}`,
+			7, `{"file":"b.csv","row":2,"field":"id","value":"1","message":"id already exists in DB"}`).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+
+	errs := []domain.LoggedRowError{
+		{RunRow: 3, RowError: domain.RowError{Row: 3, Field: "amount", Value: "x", Message: "not a valid number"}},
+		{RunRow: 7, RowError: domain.RowError{File: "b.csv", Row: 2, Field: "id", Value: "1", Message: "id already exists in DB"}},
+	}
+	if err := repo.AddRowErrors(context.Background(), 4, 1, errs); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestMigrationWrites_LeaseLostWhenTheAttemptIsNotHeld(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer sqlDB.Close()
+	repo := NewMigrationRepo(sqlDB)
+
+	mock.ExpectExec(`INSERT INTO migration_row_errors`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE migrations SET status = 'FAILED'`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE migrations SET status = 'COMPLETED'`).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	ctx := context.Background()
+	if err := repo.AddRowErrors(ctx, 4, 1, []domain.LoggedRowError{{RunRow: 1, RowError: domain.RowError{Row: 1, Field: "id"}}}); !errors.Is(err, repositories.ErrLeaseLost) {
+		t.Fatalf("add row errors: expected ErrLeaseLost, got %v", err)
+	}
+	if err := repo.Fail(ctx, 4, 1, "validation_error", nil, nil); !errors.Is(err, repositories.ErrLeaseLost) {
+		t.Fatalf("fail: expected ErrLeaseLost, got %v", err)
+	}
+	if err := repo.Complete(ctx, 4, 1, domain.MigrationCounts{}, nil, nil); !errors.Is(err, repositories.ErrLeaseLost) {
+		t.Fatalf("complete: expected ErrLeaseLost, got %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestMigrationEachRowError_PagesInRunRowOrder(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer sqlDB.Close()
+	repo := NewMigrationRepo(sqlDB)
+
+	queryRe := regexp.QuoteMeta(`SELECT id, run_row, error FROM migration_row_errors WHERE migration_id = $1 AND (run_row, id) > ($2, $3) ORDER BY run_row, id LIMIT $4`)
+	full := sqlmock.NewRows([]string{"id", "run_row", "error"})
+	for i := 1; i <= rowErrorPage; i++ {
+		full.AddRow(int64(100+i), i, []byte(`{"row":1,"field":"amount","value":"x","message":"not a valid number"}`))
+	}
+	mock.ExpectQuery(queryRe).WithArgs(int64(4), int64(0), int64(0), rowErrorPage).WillReturnRows(full)
+	mock.ExpectQuery(queryRe).WithArgs(int64(4), int64(rowErrorPage), int64(100+rowErrorPage), rowErrorPage).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "run_row", "error"}).
+			AddRow(int64(900), rowErrorPage+1, []byte(`{"file":"b.csv","row":2,"field":"id","value":"1","message":"id already exists in DB"}`)))
+
+	var got []domain.LoggedRowError
+	err = repo.EachRowError(context.Background(), 4, func(e domain.LoggedRowError) error {
+		got = append(got, e)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != rowErrorPage+1 || got[0].RunRow != 1 || got[0].Field != "amount" {
+		t.Fatalf("unexpected log: %d errors, first %+v", len(got), got[0])
+	}
+	if last := got[rowErrorPage]; last.RunRow != rowErrorPage+1 || last.File != "b.csv" || last.Row != 2 {
+		t.Fatalf("unexpected last error: %+v", last)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
@@ -0,0 +1,94 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/ports/repositories"
+)
+
+type PeriodLockRepo struct {
+	DB *sql.DB
+}
+
+var _ repositories.PeriodLockRepository = (*PeriodLockRepo)(nil)
+
+func NewPeriodLockRepo(db *sql.DB) *PeriodLockRepo {
+	return &PeriodLockRepo{DB: db}
+}
+
+const periodLockColumns = `to_char(period, 'YYYY-MM'), locked_by, reason, created_at`
+
+func (r *PeriodLockRepo) Save(ctx context.Context, l domain.PeriodLock) (domain.PeriodLock, error) {
+	start, err := domain.ParsePeriod(l.Period)
+	if err != nil {
+		return domain.PeriodLock{}, err
+	}
+	tx, err := r.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return domain.PeriodLock{}, err
+	}
+	defer func() {
+		_ = tx.Rollback()
+	}()
+	// Waits for batch reverts, which hold a SHARE lock while they check the locked periods.
+	if _, err := tx.ExecContext(ctx, `LOCK TABLE period_locks IN SHARE ROW EXCLUSIVE MODE`); err != nil {
+		return domain.PeriodLock{}, err
+	}
+	row := tx.QueryRowContext(ctx, `
+INSERT INTO period_locks (period, locked_by, reason) VALUES ($1, $2, $3)
+ON CONFLICT (period) DO UPDATE SET locked_by = EXCLUDED.locked_by, reason = EXCLUDED.reason
+RETURNING `+periodLockColumns, start.Format(time.DateOnly), l.LockedBy, l.Reason)
+	saved, err := scanPeriodLock(row)
+	if err != nil {
+		return domain.PeriodLock{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return domain.PeriodLock{}, err
+	}
+	return saved, nil
+}
+
+func (r *PeriodLockRepo) List(ctx context.Context) ([]domain.PeriodLock, error) {
+	rows, err := r.DB.QueryContext(ctx, `SELECT `+periodLockColumns+` FROM period_locks ORDER BY period`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var out []domain.PeriodLock
+	for rows.Next() {
+		l, err := scanPeriodLock(rows)
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, l)
+	}
+	return out, rows.Err()
+}
+
+func (r *PeriodLockRepo) Delete(ctx context.Context, period string) (bool, error) {
+	start, err := domain.ParsePeriod(period)
+	if err != nil {
+		return false, err
+	}
+	res, err := r.DB.ExecContext(ctx, `DELETE FROM period_locks WHERE period = $1`, start.Format(time.DateOnly))
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
+
+func scanPeriodLock(row rowScanner) (domain.PeriodLock, error) {
+	var l domain.PeriodLock
+	if err := row.Scan(&l.Period, &l.LockedBy, &l.Reason, &l.CreatedAt); err != nil {
+		return domain.PeriodLock{}, err
+	}
+	l.CreatedAt = l.CreatedAt.UTC()
+	return l, nil
+}
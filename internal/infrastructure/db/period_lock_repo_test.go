@@ -0,0 +1,40 @@
+package db
+
+import (
+	"context"
+	"regexp"
+	"testing"
+	"time"
+
+	"stori-challenge/internal/domain"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestPeriodLockSave_StoresFirstDayOfMonth(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer sqlDB.Close()
+	repo := NewPeriodLockRepo(sqlDB)
+
+	created := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	queryRe := regexp.MustCompile(`INSERT INTO period_locks \(period, locked_by, reason\) VALUES \(\$1, \$2, \$3\)`)
+	mock.ExpectBegin()
+	mock.ExpectExec(`LOCK TABLE period_locks IN SHARE ROW EXCLUSIVE MODE`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(queryRe.String()).WithArgs("2024-06-01", "ana", "June closed").
+		WillReturnRows(sqlmock.NewRows([]string{"period", "locked_by", "reason", "created_at"}).AddRow("2024-06", "ana", "June closed", created))
+	mock.ExpectCommit()
+
+	l, err := repo.Save(context.Background(), domain.PeriodLock{Period: "2024-06", LockedBy: "ana", Reason: "June closed"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if l.Period != "2024-06" || !l.CreatedAt.Equal(created) {
+		t.Fatalf("unexpected lock: %+v", l)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
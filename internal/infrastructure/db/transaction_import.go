@@ -0,0 +1,324 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/ports/repositories"
+)
+
+// transactionImport stages rows in a temporary table that lives only inside its database transaction.
+// Its source_row is the row number of the run, used to report duplicates; file_row is the
+// row within the transaction's source file, stored as transactions.source_row.
+type transactionImport struct {
+	tx *sql.Tx
+	// perDayIndexed is set once the index StagedPerDay reads is created.
+	perDayIndexed bool
+}
+
+var _ repositories.TransactionImport = (*transactionImport)(nil)
+
+const createStagingTable = `
+CREATE TEMP TABLE staging_transactions (
+	id BIGINT PRIMARY KEY,
+	user_id BIGINT NOT NULL,
+	amount NUMERIC(18,2) NOT NULL,
+	type TEXT NOT NULL,
+	datetime TIMESTAMPTZ NOT NULL,
+	source_row INTEGER NOT NULL,
+	batch_id BIGINT,
+	source_file TEXT,
+	file_row INTEGER,
+	present BOOLEAN NOT NULL DEFAULT false,
+	rejected BOOLEAN NOT NULL DEFAULT false
+) ON COMMIT DROP`
+
+func (r *TransactionRepo) BeginImport(ctx context.Context) (repositories.TransactionImport, error) {
+	tx, err := r.DB.BeginTx(ctx, &sql.TxOptions{})
+	if err != nil {
+		return nil, err
+	}
+	if _, err := tx.ExecContext(ctx, createStagingTable); err != nil {
+		_ = tx.Rollback()
+		return nil, err
+	}
+	return &transactionImport{tx: tx}, nil
+}
+
+func (i *transactionImport) Stage(ctx context.Context, txs []domain.Transaction, rows []int) ([]repositories.StagedDuplicate, error) {
+	if len(txs) != len(rows) {
+		return nil, fmt.Errorf("stage: %d transactions but %d row numbers", len(txs), len(rows))
+	}
+	var dups []repositories.StagedDuplicate
+
+	// Duplicates inside the chunk are resolved here; ON CONFLICT only sees earlier chunks.
+	firstInChunk := make(map[int64]int, len(txs))
+	uniqTxs := make([]domain.Transaction, 0, len(txs))
+	uniqRows := make([]int, 0, len(rows))
+	for idx, t := range txs {
+		if first, ok := firstInChunk[t.ID]; ok {
+			dups = append(dups, repositories.StagedDuplicate{ID: t.ID, Row: rows[idx], FirstRow: first})
+			continue
+		}
+		firstInChunk[t.ID] = rows[idx]
+		uniqTxs = append(uniqTxs, t)
+		uniqRows = append(uniqRows, rows[idx])
+	}
+
+	const batchSize = 500
+	var skipped []int // indexes into uniqTxs that were already staged
+	for start := 0; start < len(uniqTxs); start += batchSize {
+		end := start + batchSize
+		if end > len(uniqTxs) {
+			end = len(uniqTxs)
+		}
+		staged, err := stageBatch(ctx, i.tx, uniqTxs[start:end], uniqRows[start:end])
+		if err != nil {
+			return nil, err
+		}
+		for k := start; k < end; k++ {
+			if !staged[uniqTxs[k].ID] {
+				skipped = append(skipped, k)
+			}
+		}
+	}
+	if len(skipped) == 0 {
+		return dups, nil
+	}
+
+	ids := make([]int64, 0, len(skipped))
+	for _, k := range skipped {
+		ids = append(ids, uniqTxs[k].ID)
+	}
+	firstRows, err := stagedRows(ctx, i.tx, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range skipped {
+		id := uniqTxs[k].ID
+		dups = append(dups, repositories.StagedDuplicate{ID: id, Row: uniqRows[k], FirstRow: firstRows[id]})
+	}
+	return dups, nil
+}
+
+// stageBatch inserts rows that are not staged yet and returns the ids it inserted.
+func stageBatch(ctx context.Context, tx *sql.Tx, txs []domain.Transaction, rows []int) (map[int64]bool, error) {
+	var (
+		sb   strings.Builder
+		args []any
+	)
+	sb.WriteString("INSERT INTO staging_transactions (id, user_id, amount, datetime, type, source_row, batch_id, source_file, file_row) VALUES ")
+	for i, t := range txs {
+		if i > 0 {
+			sb.WriteString(",")
+		}
+		// 9 placeholders per row
+		base := i*9 + 1
+		sb.WriteString(fmt.Sprintf("($%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d,$%d)", base, base+1, base+2, base+3, base+4, base+5, base+6, base+7, base+8))
+		amount, err := amountArg(t)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, t.ID, t.UserID, amount, t.DateTime.UTC(), string(t.Type), rows[i])
+		args = append(args, sourceArgs(t.Source)...)
+	}
+	sb.WriteString(" ON CONFLICT (id) DO NOTHING RETURNING id")
+	res, err := tx.QueryContext(ctx, sb.String(), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+	staged := make(map[int64]bool, len(txs))
+	for res.Next() {
+		var id int64
+		if err := res.Scan(&id); err != nil {
+			return nil, err
+		}
+		staged[id] = true
+	}
+	return staged, res.Err()
+}
+
+// stagedRows returns the source row of already staged ids.
+func stagedRows(ctx context.Context, tx *sql.Tx, ids []int64) (map[int64]int, error) {
+	res, err := tx.QueryContext(ctx, `SELECT id, source_row FROM staging_transactions WHERE id = ANY($1::bigint[])`, int64Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+	out := make(map[int64]int, len(ids))
+	for res.Next() {
+		var (
+			id  int64
+			row int
+		)
+		if err := res.Scan(&id, &row); err != nil {
+			return nil, err
+		}
+		out[id] = row
+	}
+	return out, res.Err()
+}
+
+func (i *transactionImport) ExistsByIDs(ctx context.Context, ids []int64) (map[int64]bool, error) {
+	return existsByIDs(ctx, i.tx, ids)
+}
+
+func (i *transactionImport) GetByIDs(ctx context.Context, ids []int64) (map[int64]domain.Transaction, error) {
+	return getByIDs(ctx, i.tx, ids)
+}
+
+func (i *transactionImport) MarkPresent(ctx context.Context, ids []int64) error {
+	return i.flag(ctx, "present", ids)
+}
+
+func (i *transactionImport) Reject(ctx context.Context, ids []int64) error {
+	return i.flag(ctx, "rejected", ids)
+}
+
+// flag sets the boolean column of the staged ids.
+func (i *transactionImport) flag(ctx context.Context, column string, ids []int64) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	_, err := i.tx.ExecContext(ctx, fmt.Sprintf(`UPDATE staging_transactions SET %s = true WHERE id = ANY($1::bigint[])`, column), int64Array(ids))
+	return err
+}
+
+// stagedPerDayQuery counts the staged rows of each user and UTC day, passed as two parallel arrays.
+const stagedPerDayQuery = `
+SELECT k.user_id, k.day, COUNT(*)
+FROM unnest($1::bigint[], $2::text[]) AS k(user_id, day)
+JOIN staging_transactions s ON s.user_id = k.user_id
+	AND s.datetime >= k.day::date::timestamp AT TIME ZONE 'UTC'
+	AND s.datetime < (k.day::date + 1)::timestamp AT TIME ZONE 'UTC'
+GROUP BY k.user_id, k.day`
+
+// StagedPerDay indexes the staging table by user and datetime the first time it runs, so each
+// chunk's lookup does not scan every row staged before it.
+func (i *transactionImport) StagedPerDay(ctx context.Context, keys []repositories.UserDay) (map[repositories.UserDay]int, error) {
+	out := make(map[repositories.UserDay]int)
+	if len(keys) == 0 {
+		return out, nil
+	}
+	if !i.perDayIndexed {
+		if _, err := i.tx.ExecContext(ctx, `CREATE INDEX staging_transactions_user_day ON staging_transactions (user_id, datetime)`); err != nil {
+			return nil, err
+		}
+		i.perDayIndexed = true
+	}
+	users := make([]int64, len(keys))
+	var days strings.Builder
+	days.WriteByte('{')
+	for k, key := range keys {
+		users[k] = key.UserID
+		if k > 0 {
+			days.WriteByte(',')
+		}
+		days.WriteString(key.Day)
+	}
+	days.WriteByte('}')
+	res, err := i.tx.QueryContext(ctx, stagedPerDayQuery, int64Array(users), days.String())
+	if err != nil {
+		return nil, err
+	}
+	defer res.Close()
+	for res.Next() {
+		var (
+			key repositories.UserDay
+			n   int
+		)
+		if err := res.Scan(&key.UserID, &key.Day, &n); err != nil {
+			return nil, err
+		}
+		out[key] = n
+	}
+	return out, res.Err()
+}
+
+func (i *transactionImport) RegisterUsers(ctx context.Context) (int, error) {
+	res, err := i.tx.ExecContext(ctx, `INSERT INTO users (id) SELECT DISTINCT user_id FROM staging_transactions WHERE NOT (present OR rejected) ON CONFLICT (id) DO NOTHING`)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+func (i *transactionImport) Commit(ctx context.Context) (int, error) {
+	res, err := i.tx.ExecContext(ctx, `INSERT INTO transactions (id, user_id, amount, datetime, type, batch_id, source_file, source_row) SELECT id, user_id, amount, datetime, type, batch_id, source_file, file_row FROM staging_transactions WHERE NOT (present OR rejected)`)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if err := i.tx.Commit(); err != nil {
+		return 0, err
+	}
+	return int(n), nil
+}
+
+func (i *transactionImport) Rollback() error {
+	err := i.tx.Rollback()
+	if err == sql.ErrTxDone {
+		return nil
+	}
+	return err
+}
+
+// belowFloorQuery replays, per user with staged rows, the stored transactions from the
+// earliest staged datetime on, preceded by one row with the balance before it, together with
+// the staged rows not marked present or rejected. Stored rows have no source_row.
+const belowFloorQuery = `
+WITH staged AS (
+	SELECT id, user_id, amount, datetime, source_row FROM staging_transactions WHERE NOT (present OR rejected)
+), since AS (
+	SELECT user_id, MIN(datetime) AS since FROM staged GROUP BY user_id
+), replay AS (
+	SELECT id, user_id, amount, datetime, source_row FROM staged
+	UNION ALL
+	SELECT t.id, t.user_id, t.amount, t.datetime, NULL FROM transactions t JOIN since s ON s.user_id = t.user_id AND t.datetime >= s.since
+	UNION ALL
+	SELECT 0, t.user_id, SUM(t.amount), '-infinity', NULL FROM transactions t JOIN since s ON s.user_id = t.user_id AND t.datetime < s.since GROUP BY t.user_id
+), running AS (
+	SELECT source_row, user_id, amount, SUM(amount) OVER (PARTITION BY user_id ORDER BY datetime, id ROWS UNBOUNDED PRECEDING) AS balance FROM replay
+)
+SELECT source_row, user_id, amount::text, balance::text FROM running
+WHERE source_row IS NOT NULL AND amount < 0 AND balance < $1::numeric
+ORDER BY source_row`
+
+func (i *transactionImport) BelowFloor(ctx context.Context, floor decimal.Decimal, fn func(repositories.FloorBreach) error) error {
+	res, err := i.tx.QueryContext(ctx, belowFloorQuery, floor.String())
+	if err != nil {
+		return err
+	}
+	defer res.Close()
+	for res.Next() {
+		var (
+			b              repositories.FloorBreach
+			amtStr, balStr string
+		)
+		if err := res.Scan(&b.Row, &b.UserID, &amtStr, &balStr); err != nil {
+			return err
+		}
+		if b.Amount, err = decimal.NewFromString(amtStr); err != nil {
+			return err
+		}
+		if b.Balance, err = decimal.NewFromString(balStr); err != nil {
+			return err
+		}
+		if err := fn(b); err != nil {
+			return err
+		}
+	}
+	return res.Err()
+}
@@ -0,0 +1,275 @@
+package db
+
+import (
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/ports/repositories"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/shopspring/decimal"
+)
+
+func TestBeginImport_CreatesTempTable(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer sqlDB.Close()
+	repo := NewTransactionRepo(sqlDB)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`CREATE TEMP TABLE staging_transactions .* ON COMMIT DROP`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	imp, err := repo.BeginImport(context.Background())
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := imp.Rollback(); err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestImportStage_ReportsDuplicates(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer sqlDB.Close()
+	repo := NewTransactionRepo(sqlDB)
+
+	dt := time.Date(2023, 10, 1, 0, 0, 0, 0, time.UTC)
+	txs := []domain.Transaction{
+		{ID: 1, UserID: 10, Amount: decimal.NewFromInt(1), DateTime: dt, Type: domain.TransactionTypeCredit, Source: domain.TransactionSource{BatchID: 3, Row: 5}},
+		{ID: 2, UserID: 10, Amount: decimal.NewFromInt(2), DateTime: dt, Type: domain.TransactionTypeCredit, Source: domain.TransactionSource{BatchID: 3, Row: 6}},
+		{ID: 1, UserID: 10, Amount: decimal.NewFromInt(3), DateTime: dt, Type: domain.TransactionTypeCredit}, // same chunk
+	}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`CREATE TEMP TABLE staging_transactions`).WillReturnResult(sqlmock.NewResult(0, 0))
+	insRe := regexp.MustCompile(`INSERT INTO staging_transactions \(id, user_id, amount, datetime, type, source_row, batch_id, source_file, file_row\) VALUES \(\$1,\$2,\$3,\$4,\$5,\$6,\$7,\$8,\$9\),\(\$10,\$11,\$12,\$13,\$14,\$15,\$16,\$17,\$18\) ON CONFLICT \(id\) DO NOTHING RETURNING id`)
+	mock.ExpectQuery(insRe.String()).
+		WithArgs(int64(1), int64(10), "1.00", dt, "credit", 5, int64(3), nil, 5, int64(2), int64(10), "2.00", dt, "credit", 6, int64(3), nil, 6).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(1))) // id 2 was staged by an earlier chunk
+	mock.ExpectQuery(`SELECT id, source_row FROM staging_transactions WHERE id = ANY\(\$1::bigint\[\]\)`).
+		WithArgs("{2}").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "source_row"}).AddRow(int64(2), 1))
+
+	imp, err := repo.BeginImport(context.Background())
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	dups, err := imp.Stage(context.Background(), txs, []int{5, 6, 7})
+	if err != nil {
+		t.Fatalf("stage: %v", err)
+	}
+	if len(dups) != 2 {
+		t.Fatalf("expected 2 duplicates, got %+v", dups)
+	}
+	if dups[0].ID != 1 || dups[0].Row != 7 || dups[0].FirstRow != 5 {
+		t.Fatalf("unexpected in-chunk duplicate: %+v", dups[0])
+	}
+	if dups[1].ID != 2 || dups[1].Row != 6 || dups[1].FirstRow != 1 {
+		t.Fatalf("unexpected cross-chunk duplicate: %+v", dups[1])
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestImportStage_UnfittedAmount_ErrorsWithoutRounding(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer sqlDB.Close()
+	repo := NewTransactionRepo(sqlDB)
+
+	t1 := domain.Transaction{ID: 1, UserID: 100, Amount: decimal.RequireFromString("10.005"), DateTime: time.Unix(0, 0).UTC(), Type: domain.TransactionTypeCredit}
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`CREATE TEMP TABLE staging_transactions`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectRollback()
+
+	imp, err := repo.BeginImport(context.Background())
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	_, err = imp.Stage(context.Background(), []domain.Transaction{t1}, []int{1})
+	if err == nil || !strings.Contains(err.Error(), "does not fit NUMERIC(18,2)") {
+		t.Fatalf("expected an amount error, got %v", err)
+	}
+	if err := imp.Rollback(); err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestImportCommit_MovesStagedRows(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer sqlDB.Close()
+	repo := NewTransactionRepo(sqlDB)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`CREATE TEMP TABLE staging_transactions`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`UPDATE staging_transactions SET present = true WHERE id = ANY\(\$1::bigint\[\]\)`).
+		WithArgs("{4,5}").WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec(`UPDATE staging_transactions SET rejected = true WHERE id = ANY\(\$1::bigint\[\]\)`).
+		WithArgs("{6}").WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(`INSERT INTO transactions \(id, user_id, amount, datetime, type, batch_id, source_file, source_row\) SELECT id, user_id, amount, datetime, type, batch_id, source_file, file_row FROM staging_transactions WHERE NOT \(present OR rejected\)`).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectCommit()
+
+	imp, err := repo.BeginImport(context.Background())
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	if err := imp.MarkPresent(context.Background(), []int64{4, 5}); err != nil {
+		t.Fatalf("mark present: %v", err)
+	}
+	if err := imp.Reject(context.Background(), []int64{6}); err != nil {
+		t.Fatalf("reject: %v", err)
+	}
+	n, err := imp.Commit(context.Background())
+	if err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	if n != 3 {
+		t.Fatalf("expected 3 inserted, got %d", n)
+	}
+	// Rollback after commit is a no-op.
+	if err := imp.Rollback(); err != nil {
+		t.Fatalf("rollback after commit: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestImportBelowFloor_ReportsBreachesInRowOrder(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer sqlDB.Close()
+	repo := NewTransactionRepo(sqlDB)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`CREATE TEMP TABLE staging_transactions`).WillReturnResult(sqlmock.NewResult(0, 0))
+	rows := sqlmock.NewRows([]string{"source_row", "user_id", "amount", "balance"}).
+		AddRow(2, int64(10), "-40.00", "-5.00").
+		AddRow(7, int64(20), "-1.50", "-1.50")
+	mock.ExpectQuery(`SUM\(amount\) OVER \(PARTITION BY user_id ORDER BY datetime, id ROWS UNBOUNDED PRECEDING\)[\s\S]*WHERE source_row IS NOT NULL AND amount < 0 AND balance < \$1::numeric\s+ORDER BY source_row`).
+		WithArgs("-1").WillReturnRows(rows)
+	mock.ExpectRollback()
+
+	imp, err := repo.BeginImport(context.Background())
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	var got []repositories.FloorBreach
+	err = imp.BelowFloor(context.Background(), decimal.NewFromInt(-1), func(b repositories.FloorBreach) error {
+		got = append(got, b)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("below floor: %v", err)
+	}
+	if len(got) != 2 || got[0].Row != 2 || got[0].UserID != 10 || got[0].Balance.StringFixed(2) != "-5.00" || got[1].Amount.StringFixed(2) != "-1.50" {
+		t.Fatalf("unexpected breaches: %+v", got)
+	}
+	_ = imp.Rollback()
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestImportStagedPerDay_IndexesOnceAndCountsByUserAndDay(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer sqlDB.Close()
+	repo := NewTransactionRepo(sqlDB)
+
+	query := `SELECT k.user_id, k.day, COUNT\(\*\) FROM unnest\(\$1::bigint\[\], \$2::text\[\]\) AS k\(user_id, day\)`
+	mock.ExpectBegin()
+	mock.ExpectExec(`CREATE TEMP TABLE staging_transactions`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE INDEX staging_transactions_user_day ON staging_transactions \(user_id, datetime\)`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(query).WithArgs("{10,20}", "{2024-06-01,2024-06-02}").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "day", "count"}).AddRow(int64(10), "2024-06-01", 3))
+	mock.ExpectQuery(query).WithArgs("{10}", "{2024-06-03}").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "day", "count"}))
+	mock.ExpectRollback()
+
+	imp, err := repo.BeginImport(context.Background())
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	counts, err := imp.StagedPerDay(context.Background(), []repositories.UserDay{{UserID: 10, Day: "2024-06-01"}, {UserID: 20, Day: "2024-06-02"}})
+	if err != nil {
+		t.Fatalf("staged per day: %v", err)
+	}
+	if len(counts) != 1 || counts[repositories.UserDay{UserID: 10, Day: "2024-06-01"}] != 3 {
+		t.Fatalf("unexpected counts: %v", counts)
+	}
+	if counts, err := imp.StagedPerDay(context.Background(), []repositories.UserDay{{UserID: 10, Day: "2024-06-03"}}); err != nil || len(counts) != 0 {
+		t.Fatalf("unexpected counts: %v, %v", counts, err)
+	}
+	if err := imp.Rollback(); err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestImportRegisterUsers_RunsInTheImport(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer sqlDB.Close()
+	repo := NewTransactionRepo(sqlDB)
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`CREATE TEMP TABLE staging_transactions`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`INSERT INTO users \(id\) SELECT DISTINCT user_id FROM staging_transactions WHERE NOT \(present OR rejected\) ON CONFLICT \(id\) DO NOTHING`).
+		WillReturnResult(sqlmock.NewResult(0, 2))
+	// Nothing is committed, so neither are the users.
+	mock.ExpectRollback()
+
+	imp, err := repo.BeginImport(context.Background())
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	if n, err := imp.RegisterUsers(context.Background()); err != nil || n != 2 {
+		t.Fatalf("expected 2 users registered, got %d, %v", n, err)
+	}
+	if err := imp.Rollback(); err != nil {
+		t.Fatalf("rollback: %v", err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestImportStage_LengthMismatch(t *testing.T) {
+	imp := &transactionImport{}
+	if _, err := imp.Stage(context.Background(), []domain.Transaction{{ID: 1}}, nil); err == nil {
+		t.Fatalf("expected error")
+	}
+}
@@ -4,7 +4,6 @@ import (
 	"context"
 	"database/sql"
 	"fmt"
-	"strings"
 	"time"
 
 	"github.com/shopspring/decimal"
@@ -23,19 +22,21 @@ func NewTransactionRepo(db *sql.DB) *TransactionRepo {
 }
 
 func (r *TransactionRepo) ExistsByIDs(ctx context.Context, ids []int64) (map[int64]bool, error) {
+	return existsByIDs(ctx, r.DB, ids)
+}
+
+// queryer is satisfied by both *sql.DB and *sql.Tx.
+type queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+func existsByIDs(ctx context.Context, q queryer, ids []int64) (map[int64]bool, error) {
 	result := make(map[int64]bool)
 	if len(ids) == 0 {
 		return result, nil
 	}
-	// Build placeholders $1,$2,...
-	ph := make([]string, len(ids))
-	args := make([]any, len(ids))
-	for i, id := range ids {
-		ph[i] = fmt.Sprintf("$%d", i+1)
-		args[i] = id
-	}
-	query := fmt.Sprintf(`SELECT id FROM transactions WHERE id IN (%s)`, strings.Join(ph, ","))
-	rows, err := r.DB.QueryContext(ctx, query, args...)
+	// One array parameter, so the number of ids is not bound by the placeholder limit.
+	rows, err := q.QueryContext(ctx, `SELECT id FROM transactions WHERE id = ANY($1::bigint[])`, int64Array(ids))
 	if err != nil {
 		return nil, err
 	}
@@ -50,55 +51,79 @@ func (r *TransactionRepo) ExistsByIDs(ctx context.Context, ids []int64) (map[int
 	return result, rows.Err()
 }
 
-func (r *TransactionRepo) BulkInsert(ctx context.Context, txs []domain.Transaction) error {
-	if len(txs) == 0 {
-		return nil
+func (r *TransactionRepo) GetByIDs(ctx context.Context, ids []int64) (map[int64]domain.Transaction, error) {
+	return getByIDs(ctx, r.DB, ids)
+}
+
+func getByIDs(ctx context.Context, q queryer, ids []int64) (map[int64]domain.Transaction, error) {
+	result := make(map[int64]domain.Transaction)
+	if len(ids) == 0 {
+		return result, nil
 	}
-	tx, err := r.DB.BeginTx(ctx, &sql.TxOptions{})
+	// amount is read as text so it is decoded without going through float64.
+	rows, err := q.QueryContext(ctx, `SELECT id, user_id, amount::text, datetime, type FROM transactions WHERE id = ANY($1::bigint[])`, int64Array(ids))
 	if err != nil {
-		return err
+		return nil, err
 	}
-	defer func() {
-		_ = tx.Rollback()
-	}()
-
-	// Batch multi-row insert to avoid too-large statements
-	const batchSize = 500
-	for i := 0; i < len(txs); i += batchSize {
-		end := i + batchSize
-		if end > len(txs) {
-			end = len(txs)
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			t       domain.Transaction
+			amtStr  string
+			typeStr string
+		)
+		if err := rows.Scan(&t.ID, &t.UserID, &amtStr, &t.DateTime, &typeStr); err != nil {
+			return nil, err
 		}
-		if err := insertBatch(ctx, tx, txs[i:end]); err != nil {
-			return err
+		if t.Amount, err = decimal.NewFromString(amtStr); err != nil {
+			return nil, err
 		}
+		t.DateTime = t.DateTime.UTC()
+		t.Type = domain.TransactionType(typeStr)
+		result[t.ID] = t
+	}
+	return result, rows.Err()
+}
+
+// amountArg is the stored form of the amount of t. Amounts reach the repository already fitted
+// to NUMERIC(18,2); one that is not is an error rather than being rounded by the database.
+func amountArg(t domain.Transaction) (string, error) {
+	if !t.Amount.Equal(t.Amount.Truncate(domain.AmountScale)) || t.Amount.Abs().GreaterThan(domain.MaxAmount) {
+		return "", fmt.Errorf("transaction %d: amount %s does not fit NUMERIC(18,2)", t.ID, t.Amount)
 	}
+	return t.Amount.StringFixed(domain.AmountScale), nil
+}
 
-	if err := tx.Commit(); err != nil {
-		return err
+// sourceArgs returns the batch_id, source_file and source_row values of a transaction; what
+// it does not have is NULL.
+func sourceArgs(src domain.TransactionSource) []any {
+	args := []any{nil, nil, nil}
+	if src.BatchID > 0 {
+		args[0] = src.BatchID
+	}
+	if src.File != "" {
+		args[1] = src.File
+	}
+	if src.Row > 0 {
+		args[2] = src.Row
 	}
-	return nil
+	return args
 }
 
-func insertBatch(ctx context.Context, tx *sql.Tx, txs []domain.Transaction) error {
+func (r *TransactionRepo) GetSource(ctx context.Context, id int64) (domain.TransactionSource, bool, error) {
 	var (
-		sb   strings.Builder
-		args []any
+		batchID sql.NullInt64
+		file    sql.NullString
+		row     sql.NullInt64
 	)
-	sb.WriteString("INSERT INTO transactions (id, user_id, amount, datetime, type) VALUES ")
-	for i, t := range txs {
-		if i > 0 {
-			sb.WriteString(",")
+	err := r.DB.QueryRowContext(ctx, `SELECT batch_id, source_file, source_row FROM transactions WHERE id = $1`, id).Scan(&batchID, &file, &row)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return domain.TransactionSource{}, false, nil
 		}
-		// 5 placeholders per row
-		base := i*5 + 1
-		sb.WriteString(fmt.Sprintf("($%d,$%d,$%d,$%d,$%d)", base, base+1, base+2, base+3, base+4))
-		args = append(args, t.ID, t.UserID, t.Amount.StringFixed(2), t.DateTime.UTC(), string(t.Type))
-	}
-	// Ensure timestamptz type correct by casting if needed
-	stmt := sb.String()
-	_, err := tx.ExecContext(ctx, stmt, args...)
-	return err
+		return domain.TransactionSource{}, false, err
+	}
+	return domain.TransactionSource{BatchID: batchID.Int64, File: file.String, Row: int(row.Int64)}, true, nil
 }
 
 func (r *TransactionRepo) UserHasAnyTransaction(ctx context.Context, userID int64) (bool, error) {
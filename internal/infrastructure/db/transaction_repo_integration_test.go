@@ -2,16 +2,18 @@ package db
 
 import (
 	"context"
+	"reflect"
 	"testing"
 	"time"
 
 	"stori-challenge/internal/domain"
+	"stori-challenge/internal/ports/repositories"
 	testinfra "stori-challenge/internal/shared/test"
 
 	"github.com/shopspring/decimal"
 )
 
-func TestIntegration_BulkInsert_And_ExistsByIDs(t *testing.T) {
+func TestIntegration_ExistsByIDs(t *testing.T) {
 	db, err := testinfra.OpenTestDB(t.Name())
 	if err != nil {
 		t.Fatalf("open test db: %v", err)
@@ -31,25 +33,25 @@ func TestIntegration_BulkInsert_And_ExistsByIDs(t *testing.T) {
 		t.Fatalf("expected none to exist initially, got %v", exists)
 	}
 
-	// insert
+	// seed
 	txs := []domain.Transaction{
 		{ID: 1001, UserID: 10, Amount: decimal.NewFromFloat(1.23), DateTime: time.Unix(0, 0).UTC(), Type: domain.TransactionTypeCredit},
 		{ID: 1002, UserID: 20, Amount: decimal.NewFromFloat(4.56), DateTime: time.Unix(10, 0).UTC(), Type: domain.TransactionTypeCredit},
 	}
-	if err := repo.BulkInsert(ctx, txs); err != nil {
-		t.Fatalf("bulkinsert: %v", err)
+	if err := testinfra.SeedTransactions(ctx, db, txs); err != nil {
+		t.Fatalf("seed: %v", err)
 	}
 	// verify
 	exists, err = repo.ExistsByIDs(ctx, ids)
 	if err != nil {
-		t.Fatalf("exists after insert: %v", err)
+		t.Fatalf("exists after seed: %v", err)
 	}
 	if len(exists) != 2 || !exists[1001] || !exists[1002] {
 		t.Fatalf("expected both to exist, got %v", exists)
 	}
 }
 
-func TestIntegration_BulkInsert_Duplicate_FailsAndRollsBack(t *testing.T) {
+func TestIntegration_Import_StageAndCommit(t *testing.T) {
 	db, err := testinfra.OpenTestDB(t.Name())
 	if err != nil {
 		t.Fatalf("open test db: %v", err)
@@ -59,24 +61,57 @@ func TestIntegration_BulkInsert_Duplicate_FailsAndRollsBack(t *testing.T) {
 	repo := NewTransactionRepo(db)
 	ctx := context.Background()
 
-	txs := []domain.Transaction{
-		{ID: 2001, UserID: 10, Amount: decimal.NewFromFloat(1), DateTime: time.Unix(0, 0).UTC(), Type: domain.TransactionTypeCredit},
-		{ID: 2001, UserID: 11, Amount: decimal.NewFromFloat(2), DateTime: time.Unix(10, 0).UTC(), Type: domain.TransactionTypeCredit}, // duplicate id
+	imp, err := repo.BeginImport(ctx)
+	if err != nil {
+		t.Fatalf("begin import: %v", err)
+	}
+	defer imp.Rollback()
+
+	chunk1 := []domain.Transaction{
+		{ID: 5001, UserID: 10, Amount: decimal.NewFromFloat(1.5), DateTime: time.Unix(0, 0).UTC(), Type: domain.TransactionTypeCredit},
+		{ID: 5002, UserID: 10, Amount: decimal.NewFromFloat(-2), DateTime: time.Unix(10, 0).UTC(), Type: domain.TransactionTypeDebit},
 	}
-	if err := repo.BulkInsert(ctx, txs); err == nil {
-		t.Fatalf("expected duplicate to fail")
+	if dups, err := imp.Stage(ctx, chunk1, []int{1, 2}); err != nil || len(dups) != 0 {
+		t.Fatalf("stage chunk1: dups=%v err=%v", dups, err)
 	}
-	// verify rollback: none should exist
-	exists, err := repo.ExistsByIDs(ctx, []int64{2001})
+	chunk2 := []domain.Transaction{
+		{ID: 5002, UserID: 11, Amount: decimal.NewFromFloat(3), DateTime: time.Unix(20, 0).UTC(), Type: domain.TransactionTypeCredit},
+		{ID: 5003, UserID: 11, Amount: decimal.NewFromFloat(4), DateTime: time.Unix(30, 0).UTC(), Type: domain.TransactionTypeCredit},
+	}
+	dups, err := imp.Stage(ctx, chunk2, []int{3, 4})
 	if err != nil {
-		t.Fatalf("exists after failure: %v", err)
+		t.Fatalf("stage chunk2: %v", err)
+	}
+	if len(dups) != 1 || dups[0].ID != 5002 || dups[0].Row != 3 || dups[0].FirstRow != 2 {
+		t.Fatalf("unexpected duplicates: %+v", dups)
+	}
+
+	// Staged rows are not in transactions until commit.
+	exists, err := imp.ExistsByIDs(ctx, []int64{5001})
+	if err != nil {
+		t.Fatalf("exists before commit: %v", err)
 	}
 	if len(exists) != 0 {
-		t.Fatalf("expected no rows after rollback, got %v", exists)
+		t.Fatalf("expected staged rows to be invisible, got %v", exists)
+	}
+
+	n, err := imp.Commit(ctx)
+	if err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	if n != 3 {
+		t.Fatalf("expected 3 inserted, got %d", n)
+	}
+	exists, err = repo.ExistsByIDs(ctx, []int64{5001, 5002, 5003})
+	if err != nil {
+		t.Fatalf("exists after commit: %v", err)
+	}
+	if len(exists) != 3 {
+		t.Fatalf("expected 3 rows, got %v", exists)
 	}
 }
 
-func TestIntegration_BulkInsert_BatchOver500_Succeeds(t *testing.T) {
+func TestIntegration_Import_BelowFloor_ReplaysStoredHistory(t *testing.T) {
 	db, err := testinfra.OpenTestDB(t.Name())
 	if err != nil {
 		t.Fatalf("open test db: %v", err)
@@ -86,28 +121,224 @@ func TestIntegration_BulkInsert_BatchOver500_Succeeds(t *testing.T) {
 	repo := NewTransactionRepo(db)
 	ctx := context.Background()
 
-	count := 501
-	txs := make([]domain.Transaction, 0, count)
-	ids := make([]int64, 0, count)
-	for i := 0; i < count; i++ {
-		id := int64(3000 + i)
-		ids = append(ids, id)
-		txs = append(txs, domain.Transaction{
-			ID:       id,
-			UserID:   999,
-			Amount:   decimal.NewFromFloat(1.11),
-			DateTime: time.Unix(int64(i), 0).UTC(),
-			Type:     domain.TransactionTypeCredit,
-		})
-	}
-	if err := repo.BulkInsert(ctx, txs); err != nil {
-		t.Fatalf("bulkinsert large: %v", err)
+	day := func(d int) time.Time { return time.Date(2024, 6, d, 0, 0, 0, 0, time.UTC) }
+	stored := []domain.Transaction{
+		{ID: 6001, UserID: 40, Amount: decimal.NewFromInt(50), DateTime: day(1), Type: domain.TransactionTypeCredit},
+		{ID: 6002, UserID: 40, Amount: decimal.NewFromInt(-30), DateTime: day(3), Type: domain.TransactionTypeDebit},
 	}
-	exists, err := repo.ExistsByIDs(ctx, ids)
+	if err := testinfra.SeedTransactions(ctx, db, stored); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	imp, err := repo.BeginImport(ctx)
+	if err != nil {
+		t.Fatalf("begin import: %v", err)
+	}
+	defer imp.Rollback()
+	// User 40 goes 50 -> 10 (row 2) -> -20 (stored 6002) -> -25 (row 1); user 41 stays at 5.
+	staged := []domain.Transaction{
+		{ID: 6003, UserID: 40, Amount: decimal.NewFromInt(-5), DateTime: day(4), Type: domain.TransactionTypeDebit},
+		{ID: 6004, UserID: 40, Amount: decimal.NewFromInt(-40), DateTime: day(2), Type: domain.TransactionTypeDebit},
+		{ID: 6005, UserID: 41, Amount: decimal.NewFromInt(5), DateTime: day(2), Type: domain.TransactionTypeCredit},
+	}
+	if _, err := imp.Stage(ctx, staged, []int{1, 2, 3}); err != nil {
+		t.Fatalf("stage: %v", err)
+	}
+	var got []repositories.FloorBreach
+	if err := imp.BelowFloor(ctx, decimal.NewFromInt(-10), func(b repositories.FloorBreach) error {
+		got = append(got, b)
+		return nil
+	}); err != nil {
+		t.Fatalf("below floor: %v", err)
+	}
+	if len(got) != 1 || got[0].Row != 1 || got[0].Balance.StringFixed(2) != "-25.00" {
+		t.Fatalf("unexpected breaches: %+v", got)
+	}
+}
+
+func TestIntegration_Import_MarkPresent_SkipsOnCommit(t *testing.T) {
+	db, err := testinfra.OpenTestDB(t.Name())
+	if err != nil {
+		t.Fatalf("open test db: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewTransactionRepo(db)
+	ctx := context.Background()
+
+	stored := domain.Transaction{ID: 6001, UserID: 10, Amount: decimal.NewFromFloat(1.5), DateTime: time.Unix(0, 0).UTC(), Type: domain.TransactionTypeCredit}
+	if err := testinfra.SeedTransactions(ctx, db, []domain.Transaction{stored}); err != nil {
+		t.Fatalf("seed: %v", err)
+	}
+
+	imp, err := repo.BeginImport(ctx)
+	if err != nil {
+		t.Fatalf("begin import: %v", err)
+	}
+	defer imp.Rollback()
+
+	got, err := imp.GetByIDs(ctx, []int64{6001, 6002})
+	if err != nil {
+		t.Fatalf("get by ids: %v", err)
+	}
+	if len(got) != 1 || !got[6001].Amount.Equal(stored.Amount) || !got[6001].DateTime.Equal(stored.DateTime) {
+		t.Fatalf("unexpected stored rows: %+v", got)
+	}
+
+	chunk := []domain.Transaction{
+		stored,
+		{ID: 6002, UserID: 10, Amount: decimal.NewFromFloat(2), DateTime: time.Unix(10, 0).UTC(), Type: domain.TransactionTypeCredit},
+	}
+	if _, err := imp.Stage(ctx, chunk, []int{1, 2}); err != nil {
+		t.Fatalf("stage: %v", err)
+	}
+	if err := imp.MarkPresent(ctx, []int64{6001}); err != nil {
+		t.Fatalf("mark present: %v", err)
+	}
+	n, err := imp.Commit(ctx)
+	if err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 inserted, got %d", n)
+	}
+}
+
+func TestIntegration_Import_Reject_StaysStagedButSkipsOnCommit(t *testing.T) {
+	db, err := testinfra.OpenTestDB(t.Name())
+	if err != nil {
+		t.Fatalf("open test db: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewTransactionRepo(db)
+	ctx := context.Background()
+
+	imp, err := repo.BeginImport(ctx)
+	if err != nil {
+		t.Fatalf("begin import: %v", err)
+	}
+	defer imp.Rollback()
+
+	chunk := []domain.Transaction{
+		{ID: 6101, UserID: 10, Amount: decimal.NewFromFloat(1), DateTime: time.Unix(0, 0).UTC(), Type: domain.TransactionTypeCredit},
+		{ID: 6102, UserID: 10, Amount: decimal.NewFromFloat(2), DateTime: time.Unix(10, 0).UTC(), Type: domain.TransactionTypeCredit},
+	}
+	if _, err := imp.Stage(ctx, chunk, []int{1, 2}); err != nil {
+		t.Fatalf("stage: %v", err)
+	}
+	if err := imp.Reject(ctx, []int64{6101}); err != nil {
+		t.Fatalf("reject: %v", err)
+	}
+	dups, err := imp.Stage(ctx, chunk[:1], []int{3})
+	if err != nil {
+		t.Fatalf("stage again: %v", err)
+	}
+	if len(dups) != 1 || dups[0].Row != 3 || dups[0].FirstRow != 1 {
+		t.Fatalf("expected the rejected id to still be staged, got %+v", dups)
+	}
+	n, err := imp.Commit(ctx)
+	if err != nil {
+		t.Fatalf("commit: %v", err)
+	}
+	if n != 1 {
+		t.Fatalf("expected 1 inserted, got %d", n)
+	}
+}
+
+func TestIntegration_Import_StagedPerDay_CountsUTCDays(t *testing.T) {
+	db, err := testinfra.OpenTestDB(t.Name())
+	if err != nil {
+		t.Fatalf("open test db: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewTransactionRepo(db)
+	ctx := context.Background()
+
+	imp, err := repo.BeginImport(ctx)
+	if err != nil {
+		t.Fatalf("begin import: %v", err)
+	}
+	defer imp.Rollback()
+
+	at := func(s string) time.Time {
+		tm, _ := time.Parse(time.RFC3339, s)
+		return tm
+	}
+	txs := []domain.Transaction{
+		{ID: 810001, UserID: 10, Amount: decimal.NewFromInt(1), DateTime: at("2024-06-01T00:00:00Z"), Type: domain.TransactionTypeCredit},
+		{ID: 810002, UserID: 10, Amount: decimal.NewFromInt(1), DateTime: at("2024-06-01T23:59:59Z"), Type: domain.TransactionTypeCredit},
+		// 2024-06-02 in UTC although it is still 2024-06-01 at the offset.
+		{ID: 810003, UserID: 10, Amount: decimal.NewFromInt(1), DateTime: at("2024-06-01T20:00:00-06:00"), Type: domain.TransactionTypeCredit},
+		{ID: 810004, UserID: 20, Amount: decimal.NewFromInt(1), DateTime: at("2024-06-01T12:00:00Z"), Type: domain.TransactionTypeCredit},
+	}
+	if _, err := imp.Stage(ctx, txs, []int{1, 2, 3, 4}); err != nil {
+		t.Fatalf("stage: %v", err)
+	}
+	if err := imp.Reject(ctx, []int64{810004}); err != nil {
+		t.Fatalf("reject: %v", err)
+	}
+	keys := []repositories.UserDay{{UserID: 10, Day: "2024-06-01"}, {UserID: 10, Day: "2024-06-02"}, {UserID: 20, Day: "2024-06-01"}, {UserID: 30, Day: "2024-06-01"}}
+	counts, err := imp.StagedPerDay(ctx, keys)
 	if err != nil {
-		t.Fatalf("exists after large insert: %v", err)
+		t.Fatalf("staged per day: %v", err)
+	}
+	want := map[repositories.UserDay]int{keys[0]: 2, keys[1]: 1, keys[2]: 1}
+	if !reflect.DeepEqual(counts, want) {
+		t.Fatalf("expected %v, got %v", want, counts)
+	}
+	if _, err := imp.StagedPerDay(ctx, keys[:1]); err != nil {
+		t.Fatalf("staged per day again: %v", err)
+	}
+}
+
+func TestIntegration_Import_MoreIDsThanPlaceholders(t *testing.T) {
+	db, err := testinfra.OpenTestDB(t.Name())
+	if err != nil {
+		t.Fatalf("open test db: %v", err)
+	}
+	defer db.Close()
+
+	repo := NewTransactionRepo(db)
+	ctx := context.Background()
+
+	imp, err := repo.BeginImport(ctx)
+	if err != nil {
+		t.Fatalf("begin import: %v", err)
+	}
+	defer imp.Rollback()
+
+	// More rows than the 65535 parameters Postgres allows in one statement.
+	const n = 70000
+	txs := make([]domain.Transaction, n)
+	rows := make([]int, n)
+	ids := make([]int64, n)
+	for i := range txs {
+		ids[i] = int64(700000 + i)
+		txs[i] = domain.Transaction{ID: ids[i], UserID: 10, Amount: decimal.NewFromInt(1), DateTime: time.Unix(int64(i), 0).UTC(), Type: domain.TransactionTypeCredit}
+		rows[i] = i + 1
+	}
+	if _, err := imp.Stage(ctx, txs, rows); err != nil {
+		t.Fatalf("stage: %v", err)
+	}
+	if got, err := imp.ExistsByIDs(ctx, ids); err != nil || len(got) != 0 {
+		t.Fatalf("exists by ids: %v (%d found)", err, len(got))
+	}
+	if got, err := imp.GetByIDs(ctx, ids); err != nil || len(got) != 0 {
+		t.Fatalf("get by ids: %v (%d found)", err, len(got))
+	}
+	dups, err := imp.Stage(ctx, txs, rows)
+	if err != nil || len(dups) != n || dups[n-1].FirstRow != n {
+		t.Fatalf("stage again: %v (%d duplicates)", err, len(dups))
+	}
+	if err := imp.MarkPresent(ctx, ids); err != nil {
+		t.Fatalf("mark present: %v", err)
+	}
+	if err := imp.Reject(ctx, ids); err != nil {
+		t.Fatalf("reject: %v", err)
 	}
-	if len(exists) != count {
-		t.Fatalf("expected %d existing, got %d", count, len(exists))
+	if inserted, err := imp.Commit(ctx); err != nil || inserted != 0 {
+		t.Fatalf("commit: %v (%d inserted)", err, inserted)
 	}
 }
@@ -3,6 +3,8 @@ package db
 import (
 	"context"
 	"regexp"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 
@@ -40,8 +42,8 @@ func TestExistsByIDs_ReturnsPresentMap(t *testing.T) {
 
 	ids := []int64{10, 20, 30}
 	rows := sqlmock.NewRows([]string{"id"}).AddRow(int64(20)).AddRow(int64(30))
-	queryRe := regexp.MustCompile(`SELECT id FROM transactions WHERE id IN \(\$1,\$2,\$3\)`)
-	mock.ExpectQuery(queryRe.String()).WithArgs(ids[0], ids[1], ids[2]).WillReturnRows(rows)
+	queryRe := regexp.MustCompile(`SELECT id FROM transactions WHERE id = ANY\(\$1::bigint\[\]\)`)
+	mock.ExpectQuery(queryRe.String()).WithArgs("{10,20,30}").WillReturnRows(rows)
 
 	got, err := repo.ExistsByIDs(context.Background(), ids)
 	if err != nil {
@@ -55,78 +57,38 @@ func TestExistsByIDs_ReturnsPresentMap(t *testing.T) {
 	}
 }
 
-func TestExistsByIDs_QueryError_Propagates(t *testing.T) {
+func TestGetByIDs_DecodesStoredRows(t *testing.T) {
 	sqlDB, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("failed to create sqlmock: %v", err)
 	}
 	defer sqlDB.Close()
-	repo := NewTransactionRepo(sqlDB)
 
-	ids := []int64{1}
-	queryRe := regexp.MustCompile(`SELECT id FROM transactions WHERE id IN \(\$1\)`)
-	mock.ExpectQuery(queryRe.String()).WithArgs(ids[0]).WillReturnError(assertErr)
+	repo := NewTransactionRepo(sqlDB)
 
-	_, err = repo.ExistsByIDs(context.Background(), ids)
-	if err == nil {
-		t.Fatalf("expected error, got nil")
-	}
-}
+	dt := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	rows := sqlmock.NewRows([]string{"id", "user_id", "amount", "datetime", "type"}).
+		AddRow(int64(20), int64(7), "-12.30", dt, "debit")
+	queryRe := regexp.MustCompile(`SELECT id, user_id, amount::text, datetime, type FROM transactions WHERE id = ANY\(\$1::bigint\[\]\)`)
+	mock.ExpectQuery(queryRe.String()).WithArgs("{10,20}").WillReturnRows(rows)
 
-func TestBulkInsert_Empty_Noop(t *testing.T) {
-	sqlDB, _, err := sqlmock.New()
+	got, err := repo.GetByIDs(context.Background(), []int64{10, 20})
 	if err != nil {
-		t.Fatalf("failed to create sqlmock: %v", err)
-	}
-	defer sqlDB.Close()
-	repo := NewTransactionRepo(sqlDB)
-	if err := repo.BulkInsert(context.Background(), nil); err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
-}
-
-func TestBulkInsert_TwoRows_Success(t *testing.T) {
-	sqlDB, mock, err := sqlmock.New()
-	if err != nil {
-		t.Fatalf("failed to create sqlmock: %v", err)
+	tx, ok := got[20]
+	if len(got) != 1 || !ok {
+		t.Fatalf("unexpected result map: %v", got)
 	}
-	defer sqlDB.Close()
-	repo := NewTransactionRepo(sqlDB)
-
-	t1 := domain.Transaction{
-		ID:       1,
-		UserID:   100,
-		Amount:   decimal.NewFromFloat(12.34),
-		DateTime: time.Date(2023, 10, 1, 15, 4, 5, 0, time.UTC),
-		Type:     domain.TransactionTypeCredit,
-	}
-	t2 := domain.Transaction{
-		ID:       2,
-		UserID:   200,
-		Amount:   decimal.NewFromFloat(56.78),
-		DateTime: time.Date(2023, 10, 2, 11, 0, 0, 0, time.UTC),
-		Type:     domain.TransactionTypeCredit,
-	}
-
-	mock.ExpectBegin()
-	// Match the INSERT statement shape; placeholders grow with rows.
-	stmtRe := regexp.MustCompile(`INSERT INTO transactions \(id, user_id, amount, datetime, type\) VALUES \(\$1,\$2,\$3,\$4,\$5\),\(\$6,\$7,\$8,\$9,\$10\)`)
-	mock.ExpectExec(stmtRe.String()).
-		WithArgs(
-			int64(1), int64(100), "12.34", t1.DateTime.UTC(), "credit",
-			int64(2), int64(200), "56.78", t2.DateTime.UTC(), "credit",
-		).WillReturnResult(sqlmock.NewResult(0, 2))
-	mock.ExpectCommit()
-
-	if err := repo.BulkInsert(context.Background(), []domain.Transaction{t1, t2}); err != nil {
-		t.Fatalf("unexpected error: %v", err)
+	if tx.UserID != 7 || !tx.Amount.Equal(decimal.RequireFromString("-12.3")) || !tx.DateTime.Equal(dt) || tx.Type != domain.TransactionTypeDebit {
+		t.Fatalf("unexpected transaction: %+v", tx)
 	}
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Fatalf("unmet expectations: %v", err)
 	}
 }
 
-func TestBulkInsert_BeginError_Propagates(t *testing.T) {
+func TestExistsByIDs_QueryError_Propagates(t *testing.T) {
 	sqlDB, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("failed to create sqlmock: %v", err)
@@ -134,14 +96,17 @@ func TestBulkInsert_BeginError_Propagates(t *testing.T) {
 	defer sqlDB.Close()
 	repo := NewTransactionRepo(sqlDB)
 
-	mock.ExpectBegin().WillReturnError(assertErr)
-	err = repo.BulkInsert(context.Background(), []domain.Transaction{{ID: 1, UserID: 1, Amount: decimal.NewFromInt(1), DateTime: time.Unix(0, 0).UTC()}})
+	ids := []int64{1}
+	queryRe := regexp.MustCompile(`SELECT id FROM transactions WHERE id = ANY\(\$1::bigint\[\]\)`)
+	mock.ExpectQuery(queryRe.String()).WithArgs("{1}").WillReturnError(assertErr)
+
+	_, err = repo.ExistsByIDs(context.Background(), ids)
 	if err == nil {
 		t.Fatalf("expected error, got nil")
 	}
 }
 
-func TestBulkInsert_ExecError_PropagatesAndRollbacks(t *testing.T) {
+func TestExistsByIDs_MoreIDsThanPlaceholders_BindsOneArray(t *testing.T) {
 	sqlDB, mock, err := sqlmock.New()
 	if err != nil {
 		t.Fatalf("failed to create sqlmock: %v", err)
@@ -149,44 +114,23 @@ func TestBulkInsert_ExecError_PropagatesAndRollbacks(t *testing.T) {
 	defer sqlDB.Close()
 	repo := NewTransactionRepo(sqlDB)
 
-	t1 := domain.Transaction{ID: 1, UserID: 100, Amount: decimal.NewFromInt(1), DateTime: time.Unix(0, 0).UTC(), Type: domain.TransactionTypeCredit}
-
-	mock.ExpectBegin()
-	stmtRe := regexp.MustCompile(`INSERT INTO transactions \(id, user_id, amount, datetime, type\) VALUES \(\$1,\$2,\$3,\$4,\$5\)`)
-	mock.ExpectExec(stmtRe.String()).
-		WithArgs(int64(1), int64(100), "1.00", t1.DateTime.UTC(), "credit").
-		WillReturnError(assertErr)
-	mock.ExpectRollback()
-
-	err = repo.BulkInsert(context.Background(), []domain.Transaction{t1})
-	if err == nil {
-		t.Fatalf("expected error, got nil")
-	}
-	if err := mock.ExpectationsWereMet(); err != nil {
-		t.Fatalf("unmet expectations: %v", err)
+	// Postgres allows at most 65535 parameters per statement.
+	ids := make([]int64, 70000)
+	lit := make([]string, len(ids))
+	for i := range ids {
+		ids[i] = int64(i + 1)
+		lit[i] = strconv.Itoa(i + 1)
 	}
-}
+	mock.ExpectQuery(`SELECT id FROM transactions WHERE id = ANY\(\$1::bigint\[\]\)`).
+		WithArgs("{" + strings.Join(lit, ",") + "}").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(69999)))
 
-func TestBulkInsert_CommitError_Propagates(t *testing.T) {
-	sqlDB, mock, err := sqlmock.New()
+	got, err := repo.ExistsByIDs(context.Background(), ids)
 	if err != nil {
-		t.Fatalf("failed to create sqlmock: %v", err)
+		t.Fatalf("unexpected error: %v", err)
 	}
-	defer sqlDB.Close()
-	repo := NewTransactionRepo(sqlDB)
-
-	t1 := domain.Transaction{ID: 1, UserID: 100, Amount: decimal.NewFromInt(1), DateTime: time.Unix(0, 0).UTC(), Type: domain.TransactionTypeCredit}
-
-	mock.ExpectBegin()
-	stmtRe := regexp.MustCompile(`INSERT INTO transactions \(id, user_id, amount, datetime, type\) VALUES \(\$1,\$2,\$3,\$4,\$5\)`)
-	mock.ExpectExec(stmtRe.String()).
-		WithArgs(int64(1), int64(100), "1.00", t1.DateTime.UTC(), "credit").
-		WillReturnResult(sqlmock.NewResult(0, 1))
-	mock.ExpectCommit().WillReturnError(assertErr)
-
-	err = repo.BulkInsert(context.Background(), []domain.Transaction{t1})
-	if err == nil {
-		t.Fatalf("expected error, got nil")
+	if len(got) != 1 || !got[69999] {
+		t.Fatalf("unexpected result: %v", got)
 	}
 	if err := mock.ExpectationsWereMet(); err != nil {
 		t.Fatalf("unmet expectations: %v", err)
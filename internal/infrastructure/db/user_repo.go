@@ -0,0 +1,66 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"strconv"
+	"strings"
+
+	"stori-challenge/internal/ports/repositories"
+)
+
+type UserRepo struct {
+	DB *sql.DB
+}
+
+var _ repositories.UserRepository = (*UserRepo)(nil)
+
+func NewUserRepo(db *sql.DB) *UserRepo {
+	return &UserRepo{DB: db}
+}
+
+func (r *UserRepo) Exists(ctx context.Context, id int64) (bool, error) {
+	var one int
+	if err := r.DB.QueryRowContext(ctx, `SELECT 1 FROM users WHERE id = $1`, id).Scan(&one); err != nil {
+		if err == sql.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (r *UserRepo) ExistingIDs(ctx context.Context, ids []int64) (map[int64]bool, error) {
+	result := make(map[int64]bool)
+	if len(ids) == 0 {
+		return result, nil
+	}
+	// One array parameter, so the number of ids is not bound by the placeholder limit.
+	rows, err := r.DB.QueryContext(ctx, `SELECT id FROM users WHERE id = ANY($1::bigint[])`, int64Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			return nil, err
+		}
+		result[id] = true
+	}
+	return result, rows.Err()
+}
+
+// int64Array formats ids as a Postgres array literal, e.g. {1,2,3}.
+func int64Array(ids []int64) string {
+	var sb strings.Builder
+	sb.WriteByte('{')
+	for i, id := range ids {
+		if i > 0 {
+			sb.WriteByte(',')
+		}
+		sb.WriteString(strconv.FormatInt(id, 10))
+	}
+	sb.WriteByte('}')
+	return sb.String()
+}
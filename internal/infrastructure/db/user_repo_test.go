@@ -0,0 +1,57 @@
+package db
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+)
+
+func TestUserExists(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer sqlDB.Close()
+	repo := NewUserRepo(sqlDB)
+
+	queryRe := regexp.MustCompile(`SELECT 1 FROM users WHERE id = \$1`)
+	mock.ExpectQuery(queryRe.String()).WithArgs(int64(10)).WillReturnRows(sqlmock.NewRows([]string{"?column?"}).AddRow(1))
+	mock.ExpectQuery(queryRe.String()).WithArgs(int64(20)).WillReturnError(sql.ErrNoRows)
+
+	if ok, err := repo.Exists(context.Background(), 10); err != nil || !ok {
+		t.Fatalf("expected user 10 to exist, got %v, %v", ok, err)
+	}
+	if ok, err := repo.Exists(context.Background(), 20); err != nil || ok {
+		t.Fatalf("expected user 20 not to exist, got %v, %v", ok, err)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
+
+func TestUserExistingIDs(t *testing.T) {
+	sqlDB, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("failed to create sqlmock: %v", err)
+	}
+	defer sqlDB.Close()
+	repo := NewUserRepo(sqlDB)
+
+	queryRe := regexp.MustCompile(`SELECT id FROM users WHERE id = ANY\(\$1::bigint\[\]\)`)
+	mock.ExpectQuery(queryRe.String()).WithArgs("{1,2,3}").
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow(int64(1)).AddRow(int64(3)))
+
+	got, err := repo.ExistingIDs(context.Background(), []int64{1, 2, 3})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 2 || !got[1] || got[2] || !got[3] {
+		t.Fatalf("unexpected ids: %v", got)
+	}
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Fatalf("unmet expectations: %v", err)
+	}
+}
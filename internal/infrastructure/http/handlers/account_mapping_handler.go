@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"net/http"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/infrastructure/http/responses"
+	"stori-challenge/internal/ports/services"
+	"stori-challenge/internal/shared"
+
+	"github.com/gin-gonic/gin"
+)
+
+type AccountMappingHandler struct {
+	Service services.AccountMappingService
+}
+
+func NewAccountMappingHandler(svc services.AccountMappingService) *AccountMappingHandler {
+	return &AccountMappingHandler{Service: svc}
+}
+
+// accountMappingRequest is the body of PUT /account-mappings/:account_id.
+type accountMappingRequest struct {
+	UserID int64 `json:"user_id"`
+}
+
+// PutAccountMapping
+// @Summary      Create or replace a statement account mapping
+// @Description  Assigns the transactions of a bank statement account (OFX ACCTID, camt IBAN or other id, MT940 :25:) to a user
+// @Tags         account-mappings
+// @Accept       json
+// @Produce      json
+// @Param        account_id  path      string  true  "Account id as reported by the statement"
+// @Success      200  {object}  responses.AccountMappingResponse
+// @Failure      400  {object}  responses.ErrorEnvelope
+// @Router       /account-mappings/{account_id} [put]
+func (h *AccountMappingHandler) PutAccountMapping(c *gin.Context) {
+	var req accountMappingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		CreateErrorResponse(c, shared.NewBadRequest("invalid_body", "body must be a JSON object with a user_id", err), nil)
+		return
+	}
+
+	m, svcErr := h.Service.Save(c.Request.Context(), domain.AccountMapping{AccountID: c.Param("account_id"), UserID: req.UserID})
+	if svcErr != nil {
+		CreateErrorResponse(c, svcErr, nil)
+		return
+	}
+	c.JSON(http.StatusOK, toAccountMappingResponse(m))
+}
+
+// GetAccountMapping
+// @Summary      Get a statement account mapping
+// @Tags         account-mappings
+// @Produce      json
+// @Param        account_id  path      string  true  "Account id as reported by the statement"
+// @Success      200  {object}  responses.AccountMappingResponse
+// @Failure      404  {object}  responses.ErrorEnvelope
+// @Router       /account-mappings/{account_id} [get]
+func (h *AccountMappingHandler) GetAccountMapping(c *gin.Context) {
+	m, svcErr := h.Service.Get(c.Request.Context(), c.Param("account_id"))
+	if svcErr != nil {
+		CreateErrorResponse(c, svcErr, nil)
+		return
+	}
+	c.JSON(http.StatusOK, toAccountMappingResponse(m))
+}
+
+// ListAccountMappings
+// @Summary      List statement account mappings
+// @Tags         account-mappings
+// @Produce      json
+// @Success      200  {object}  responses.AccountMappingListResponse
+// @Router       /account-mappings [get]
+func (h *AccountMappingHandler) ListAccountMappings(c *gin.Context) {
+	list, svcErr := h.Service.List(c.Request.Context())
+	if svcErr != nil {
+		CreateErrorResponse(c, svcErr, nil)
+		return
+	}
+	out := responses.AccountMappingListResponse{Mappings: make([]responses.AccountMappingResponse, 0, len(list))}
+	for _, m := range list {
+		out.Mappings = append(out.Mappings, toAccountMappingResponse(m))
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// DeleteAccountMapping
+// @Summary      Delete a statement account mapping
+// @Tags         account-mappings
+// @Param        account_id  path      string  true  "Account id as reported by the statement"
+// @Success      204
+// @Failure      404  {object}  responses.ErrorEnvelope
+// @Router       /account-mappings/{account_id} [delete]
+func (h *AccountMappingHandler) DeleteAccountMapping(c *gin.Context) {
+	if svcErr := h.Service.Delete(c.Request.Context(), c.Param("account_id")); svcErr != nil {
+		CreateErrorResponse(c, svcErr, nil)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func toAccountMappingResponse(m domain.AccountMapping) responses.AccountMappingResponse {
+	return responses.AccountMappingResponse{
+		AccountID: m.AccountID,
+		UserID:    m.UserID,
+		CreatedAt: m.CreatedAt,
+		UpdatedAt: m.UpdatedAt,
+	}
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/infrastructure/http/responses"
+	"stori-challenge/internal/shared"
+
+	"github.com/gin-gonic/gin"
+)
+
+type mockAccountMappingService struct {
+	SaveFn   func(ctx context.Context, m domain.AccountMapping) (domain.AccountMapping, error)
+	GetFn    func(ctx context.Context, accountID string) (domain.AccountMapping, error)
+	ListFn   func(ctx context.Context) ([]domain.AccountMapping, error)
+	DeleteFn func(ctx context.Context, accountID string) error
+}
+
+func (m *mockAccountMappingService) Save(ctx context.Context, am domain.AccountMapping) (domain.AccountMapping, error) {
+	return m.SaveFn(ctx, am)
+}
+
+func (m *mockAccountMappingService) Get(ctx context.Context, accountID string) (domain.AccountMapping, error) {
+	return m.GetFn(ctx, accountID)
+}
+
+func (m *mockAccountMappingService) List(ctx context.Context) ([]domain.AccountMapping, error) {
+	return m.ListFn(ctx)
+}
+
+func (m *mockAccountMappingService) Delete(ctx context.Context, accountID string) error {
+	return m.DeleteFn(ctx, accountID)
+}
+
+func TestPutAccountMapping_Success_Returns200(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPut, "/account-mappings/1234", strings.NewReader(`{"user_id":10}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "account_id", Value: "1234"}}
+
+	var got domain.AccountMapping
+	h := &AccountMappingHandler{Service: &mockAccountMappingService{
+		SaveFn: func(ctx context.Context, m domain.AccountMapping) (domain.AccountMapping, error) {
+			got = m
+			return m, nil
+		},
+	}}
+	h.PutAccountMapping(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status want 200 got %d", w.Code)
+	}
+	if got.AccountID != "1234" || got.UserID != 10 {
+		t.Fatalf("unexpected mapping passed to service: %+v", got)
+	}
+	var resp responses.AccountMappingResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.AccountID != "1234" || resp.UserID != 10 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func TestGetAccountMapping_NotFound_Returns404(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/account-mappings/1234", nil)
+	c.Params = gin.Params{{Key: "account_id", Value: "1234"}}
+	h := &AccountMappingHandler{Service: &mockAccountMappingService{
+		GetFn: func(ctx context.Context, accountID string) (domain.AccountMapping, error) {
+			return domain.AccountMapping{}, shared.NewNotFound("account_mapping_not_found", "account mapping not found", nil)
+		},
+	}}
+	h.GetAccountMapping(c)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status want 404 got %d", w.Code)
+	}
+}
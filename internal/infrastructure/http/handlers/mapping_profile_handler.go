@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"net/http"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/infrastructure/http/responses"
+	"stori-challenge/internal/ports/services"
+	"stori-challenge/internal/shared"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MappingProfileHandler struct {
+	Service services.MappingProfileService
+}
+
+func NewMappingProfileHandler(svc services.MappingProfileService) *MappingProfileHandler {
+	return &MappingProfileHandler{Service: svc}
+}
+
+// mappingProfileRequest is the body of PUT /mapping-profiles/:name.
+type mappingProfileRequest struct {
+	Columns map[string][]string `json:"columns"`
+}
+
+// PutMappingProfile
+// @Summary      Create or replace a header mapping profile
+// @Description  Maps canonical columns (id, user_id, amount, datetime) to the header names used by a partner export
+// @Tags         mapping-profiles
+// @Accept       json
+// @Produce      json
+// @Param        name  path      string  true  "Profile name"
+// @Success      200  {object}  responses.MappingProfileResponse
+// @Failure      400  {object}  responses.ErrorEnvelope
+// @Router       /mapping-profiles/{name} [put]
+func (h *MappingProfileHandler) PutMappingProfile(c *gin.Context) {
+	var req mappingProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		CreateErrorResponse(c, shared.NewBadRequest("invalid_body", "body must be a JSON object with a columns map", err), nil)
+		return
+	}
+
+	p, svcErr := h.Service.Save(c.Request.Context(), domain.MappingProfile{Name: c.Param("name"), Columns: req.Columns})
+	if svcErr != nil {
+		CreateErrorResponse(c, svcErr, nil)
+		return
+	}
+	c.JSON(http.StatusOK, toMappingProfileResponse(p))
+}
+
+// GetMappingProfile
+// @Summary      Get a header mapping profile
+// @Tags         mapping-profiles
+// @Produce      json
+// @Param        name  path      string  true  "Profile name"
+// @Success      200  {object}  responses.MappingProfileResponse
+// @Failure      404  {object}  responses.ErrorEnvelope
+// @Router       /mapping-profiles/{name} [get]
+func (h *MappingProfileHandler) GetMappingProfile(c *gin.Context) {
+	p, svcErr := h.Service.Get(c.Request.Context(), c.Param("name"))
+	if svcErr != nil {
+		CreateErrorResponse(c, svcErr, nil)
+		return
+	}
+	c.JSON(http.StatusOK, toMappingProfileResponse(p))
+}
+
+// ListMappingProfiles
+// @Summary      List header mapping profiles
+// @Tags         mapping-profiles
+// @Produce      json
+// @Success      200  {object}  responses.MappingProfileListResponse
+// @Router       /mapping-profiles [get]
+func (h *MappingProfileHandler) ListMappingProfiles(c *gin.Context) {
+	list, svcErr := h.Service.List(c.Request.Context())
+	if svcErr != nil {
+		CreateErrorResponse(c, svcErr, nil)
+		return
+	}
+	out := responses.MappingProfileListResponse{Profiles: make([]responses.MappingProfileResponse, 0, len(list))}
+	for _, p := range list {
+		out.Profiles = append(out.Profiles, toMappingProfileResponse(p))
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// DeleteMappingProfile
+// @Summary      Delete a header mapping profile
+// @Tags         mapping-profiles
+// @Param        name  path      string  true  "Profile name"
+// @Success      204
+// @Failure      404  {object}  responses.ErrorEnvelope
+// @Router       /mapping-profiles/{name} [delete]
+func (h *MappingProfileHandler) DeleteMappingProfile(c *gin.Context) {
+	if svcErr := h.Service.Delete(c.Request.Context(), c.Param("name")); svcErr != nil {
+		CreateErrorResponse(c, svcErr, nil)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func toMappingProfileResponse(p domain.MappingProfile) responses.MappingProfileResponse {
+	return responses.MappingProfileResponse{
+		Name:      p.Name,
+		Columns:   p.Columns,
+		CreatedAt: p.CreatedAt,
+		UpdatedAt: p.UpdatedAt,
+	}
+}
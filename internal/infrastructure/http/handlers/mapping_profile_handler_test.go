@@ -0,0 +1,129 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/infrastructure/http/responses"
+	"stori-challenge/internal/shared"
+
+	"github.com/gin-gonic/gin"
+)
+
+type mockMappingProfileService struct {
+	SaveFn   func(ctx context.Context, p domain.MappingProfile) (domain.MappingProfile, error)
+	GetFn    func(ctx context.Context, name string) (domain.MappingProfile, error)
+	ListFn   func(ctx context.Context) ([]domain.MappingProfile, error)
+	DeleteFn func(ctx context.Context, name string) error
+}
+
+func (m *mockMappingProfileService) Save(ctx context.Context, p domain.MappingProfile) (domain.MappingProfile, error) {
+	return m.SaveFn(ctx, p)
+}
+
+func (m *mockMappingProfileService) Get(ctx context.Context, name string) (domain.MappingProfile, error) {
+	return m.GetFn(ctx, name)
+}
+
+func (m *mockMappingProfileService) List(ctx context.Context) ([]domain.MappingProfile, error) {
+	return m.ListFn(ctx)
+}
+
+func (m *mockMappingProfileService) Delete(ctx context.Context, name string) error {
+	return m.DeleteFn(ctx, name)
+}
+
+func TestPutMappingProfile_InvalidBody_Returns400(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPut, "/mapping-profiles/bank", strings.NewReader("not json"))
+	c.Params = gin.Params{{Key: "name", Value: "bank"}}
+	h := &MappingProfileHandler{Service: &mockMappingProfileService{}}
+	h.PutMappingProfile(c)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status want 400 got %d", w.Code)
+	}
+}
+
+func TestPutMappingProfile_Success_Returns200(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPut, "/mapping-profiles/bank", strings.NewReader(`{"columns":{"id":["Reference"]}}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "name", Value: "bank"}}
+
+	var got domain.MappingProfile
+	h := &MappingProfileHandler{Service: &mockMappingProfileService{
+		SaveFn: func(ctx context.Context, p domain.MappingProfile) (domain.MappingProfile, error) {
+			got = p
+			return p, nil
+		},
+	}}
+	h.PutMappingProfile(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status want 200 got %d", w.Code)
+	}
+	if got.Name != "bank" || len(got.Columns["id"]) != 1 || got.Columns["id"][0] != "Reference" {
+		t.Fatalf("unexpected profile passed to service: %+v", got)
+	}
+	var resp responses.MappingProfileResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.Name != "bank" {
+		t.Fatalf("payload mismatch: %+v", resp)
+	}
+}
+
+func TestGetMappingProfile_NotFound_Returns404(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/mapping-profiles/x", nil)
+	c.Params = gin.Params{{Key: "name", Value: "x"}}
+	h := &MappingProfileHandler{Service: &mockMappingProfileService{
+		GetFn: func(ctx context.Context, name string) (domain.MappingProfile, error) {
+			return domain.MappingProfile{}, shared.NewNotFound("mapping_profile_not_found", "mapping profile not found", nil)
+		},
+	}}
+	h.GetMappingProfile(c)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status want 404 got %d", w.Code)
+	}
+}
+
+func TestListMappingProfiles_EmptyIsArray(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/mapping-profiles", nil)
+	h := &MappingProfileHandler{Service: &mockMappingProfileService{
+		ListFn: func(ctx context.Context) ([]domain.MappingProfile, error) { return nil, nil },
+	}}
+	h.ListMappingProfiles(c)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"profiles":[]`) {
+		t.Fatalf("unexpected response %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestDeleteMappingProfile_Returns204(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodDelete, "/mapping-profiles/bank", nil)
+	c.Params = gin.Params{{Key: "name", Value: "bank"}}
+	h := &MappingProfileHandler{Service: &mockMappingProfileService{
+		DeleteFn: func(ctx context.Context, name string) error { return nil },
+	}}
+	h.DeleteMappingProfile(c)
+	if c.Writer.Status() != http.StatusNoContent {
+		t.Fatalf("status want 204 got %d", c.Writer.Status())
+	}
+}
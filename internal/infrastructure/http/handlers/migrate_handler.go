@@ -6,7 +6,6 @@ import (
 	"stori-challenge/internal/infrastructure/http/responses"
 	"stori-challenge/internal/infrastructure/http/validators"
 	"stori-challenge/internal/ports/services"
-	"stori-challenge/internal/shared"
 
 	"github.com/gin-gonic/gin"
 )
@@ -20,64 +19,131 @@ func NewMigrateHandler(svc services.MigrationService) *MigrateHandler {
 }
 
 // PostMigrate
-// @Summary      Migrate transactions via CSV upload
-// @Description  Accepts a CSV file with columns id,user_id,amount,datetime and migrates transactions
+// @Summary      Migrate transactions via file upload
+// @Description  Accepts a CSV file with columns id,user_id,amount,datetime, or JSON Lines / a JSON array of objects with those fields, and migrates transactions
 // @Tags         migrate
 // @Accept       multipart/form-data
 // @Produce      json
-// @Param        file  formData  file  true  "CSV file"
+// @Param        file             formData  file    true   "CSV, JSON Lines (.jsonl, .ndjson), JSON array (.json), Excel (.xlsx), OFX/QFX, camt.053/054 XML or MT940 (.sta, .mt940) statement file; text formats may be gzipped (.csv.gz), or a .zip of CSV files"
+// @Param        mode             query     string  false  "strict (default) or partial"
+// @Param        idempotent       query     bool    false  "skip rows already stored with the same content"
+// @Param        profile          query     string  false  "name of a saved header mapping profile"
+// @Param        datetime_format  query     string  false  "rfc3339 (default), local, date, unix, unix_ms or auto"
+// @Param        timezone         query     string  false  "IANA time zone of datetimes without an offset (default UTC)"
+// @Param        locale           query     string  false  "locale of amounts, e.g. es-ES for 1.234,56 (default plain decimals)"
+// @Param        sheet            query     string  false  "worksheet name or 1-based index of an .xlsx file (default first sheet)"
+// @Param        user_id          query     int     false  "owner of every transaction of a bank statement upload (default: account mapping)"
+// @Param        encoding         query     string  false  "character set of text uploads: utf-8, utf-16le, utf-16be, windows-1252, iso-8859-1 (default: detected)"
+// @Param        delimiter        query     string  false  "CSV field delimiter: , ; | or tab (default: detected)"
+// @Param        max_errors       query     int     false  "row errors listed in detail, 1-10000 (default 1000); all of them are counted in the summary"
+// @Param        overdraft_floor  query     string  false  "reject the file if replaying it with each user's history takes a balance below this amount"
+// @Param        unknown_users    query     string  false  "rows of unregistered users: create registers them (default), reject rejects the rows"
+// @Param        amount_rounding  query     string  false  "amounts with more than 2 decimals: reject (default), half_even or half_up"
+// @Param        X-Uploaded-By    header    string  false  "uploader recorded on the migration batch"
 // @Success      201  {object}  shared.SuccessResponse
 // @Failure      400  {object}  shared.ErrorResponse
 // @Failure      409  {object}  shared.ErrorResponse
 // @Router       /migrate [post]
 func (h *MigrateHandler) PostMigrate(c *gin.Context) {
-	fileHeader, err := c.FormFile("file")
-	if err != nil {
-		CreateErrorResponse(c,
-			shared.NewBadRequest("missing_file", "file is required", nil),
-			[]responses.MigrateRowError{{
-				Row: 0, Field: "file", Value: "", Message: "file is required",
-			}},
-		)
+	f, fileHeader, ok := openUploadedFile(c, validators.MaxUploadSize)
+	if !ok {
 		return
 	}
-	if appErr := validators.ValidateFileMeta(fileHeader.Filename, fileHeader.Size); appErr != nil {
-		CreateErrorResponse(c,
-			appErr,
-			[]responses.MigrateRowError{{
-				Row: 0, Field: "file", Value: fileHeader.Filename, Message: appErr.Msg,
-			}},
-		)
+	defer f.Close()
+	opts, ok := parseMigrationOptions(c, fileHeader)
+	if !ok {
 		return
 	}
 
-	f, err := fileHeader.Open()
-	if err != nil {
-		CreateErrorResponse(c,
-			shared.NewBadRequest("file_open_failed", "unable to open uploaded file", nil),
-			[]responses.MigrateRowError{{
-				Row: 0, Field: "file", Value: fileHeader.Filename, Message: "unable to open uploaded file",
-			}},
-		)
+	res, svcErr := h.Service.Process(c.Request.Context(), f, opts)
+	if svcErr != nil {
+		CreateErrorResponseWithSummary(c, svcErr, toMigrateRowErrors(res.Errors), toRowErrorSummary(res.Summary))
+		return
+	}
+
+	c.JSON(http.StatusCreated, responses.MigrateSuccessResponse{
+		Inserted:       res.Inserted,
+		Rejected:       res.Rejected,
+		AlreadyPresent: res.AlreadyPresent,
+		Errors:         toMigrateRowErrors(res.Errors),
+		ErrorSummary:   toRowErrorSummary(res.Summary),
+		Warnings:       toMigrateRowErrors(res.Warnings),
+		Dialect:        toCSVDialect(res.Dialect),
+		BatchID:        res.BatchID,
+	})
+}
+
+// PostMigrateValidate
+// @Summary      Validate a migration file without writing
+// @Description  Runs the same validation and conflict checks as POST /migrate and returns row errors and dataset statistics
+// @Tags         migrate
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file             formData  file    true   "CSV, JSON Lines (.jsonl, .ndjson), JSON array (.json), Excel (.xlsx), OFX/QFX, camt.053/054 XML or MT940 (.sta, .mt940) statement file; text formats may be gzipped (.csv.gz), or a .zip of CSV files"
+// @Param        mode             query     string  false  "strict (default) or partial"
+// @Param        idempotent       query     bool    false  "skip rows already stored with the same content"
+// @Param        profile          query     string  false  "name of a saved header mapping profile"
+// @Param        datetime_format  query     string  false  "rfc3339 (default), local, date, unix, unix_ms or auto"
+// @Param        timezone         query     string  false  "IANA time zone of datetimes without an offset (default UTC)"
+// @Param        locale           query     string  false  "locale of amounts, e.g. es-ES for 1.234,56 (default plain decimals)"
+// @Param        sheet            query     string  false  "worksheet name or 1-based index of an .xlsx file (default first sheet)"
+// @Param        user_id          query     int     false  "owner of every transaction of a bank statement upload (default: account mapping)"
+// @Param        encoding         query     string  false  "character set of text uploads: utf-8, utf-16le, utf-16be, windows-1252, iso-8859-1 (default: detected)"
+// @Param        delimiter        query     string  false  "CSV field delimiter: , ; | or tab (default: detected)"
+// @Param        max_errors       query     int     false  "row errors listed in detail, 1-10000 (default 1000); all of them are counted in the summary"
+// @Param        overdraft_floor  query     string  false  "reject the file if replaying it with each user's history takes a balance below this amount"
+// @Param        unknown_users    query     string  false  "rows of unregistered users: create registers them (default), reject rejects the rows"
+// @Param        amount_rounding  query     string  false  "amounts with more than 2 decimals: reject (default), half_even or half_up"
+// @Success      200  {object}  responses.MigrateValidationResponse
+// @Failure      400  {object}  shared.ErrorResponse
+// @Router       /migrate/validate [post]
+func (h *MigrateHandler) PostMigrateValidate(c *gin.Context) {
+	f, fileHeader, ok := openUploadedFile(c, validators.MaxUploadSize)
+	if !ok {
 		return
 	}
 	defer f.Close()
+	opts, ok := parseMigrationOptions(c, fileHeader)
+	if !ok {
+		return
+	}
 
-	inserted, errItems, svcErr := h.Service.Process(c.Request.Context(), f)
+	report, svcErr := h.Service.Validate(c.Request.Context(), f, opts)
 	if svcErr != nil {
-		// Map []services.RowError -> []responses.MigrateRowError for details
-		out := make([]responses.MigrateRowError, 0, len(errItems))
-		for _, it := range errItems {
-			out = append(out, responses.MigrateRowError{
-				Row:     it.Row,
-				Field:   it.Field,
-				Value:   it.Value,
-				Message: it.Message,
-			})
-		}
-		CreateErrorResponse(c, svcErr, out)
+		CreateErrorResponseWithSummary(c, svcErr, toMigrateRowErrors(report.Errors), toRowErrorSummary(report.Summary))
 		return
 	}
 
-	c.JSON(http.StatusCreated, responses.MigrateSuccessResponse{Inserted: inserted})
+	c.JSON(http.StatusOK, toMigrateValidationResponse(report))
+}
+
+func toMigrateValidationResponse(report services.MigrationValidation) responses.MigrateValidationResponse {
+	stats := report.Stats
+	changes := make([]responses.UserBalanceChange, 0, len(stats.BalanceChanges))
+	for _, bc := range stats.BalanceChanges {
+		changeF, _ := bc.Change.Float64()
+		changes = append(changes, responses.UserBalanceChange{UserID: bc.UserID, Change: changeF})
+	}
+	credF, _ := stats.TotalCredits.Float64()
+	debF, _ := stats.TotalDebits.Float64()
+	return responses.MigrateValidationResponse{
+		Valid:          len(report.Errors) == 0,
+		Rows:           report.Rows,
+		WouldInsert:    report.WouldInsert,
+		Rejected:       report.Rejected,
+		AlreadyPresent: report.AlreadyPresent,
+		Errors:         toMigrateRowErrors(report.Errors),
+		ErrorSummary:   toRowErrorSummary(report.Summary),
+		Warnings:       toMigrateRowErrors(report.Warnings),
+		Dialect:        toCSVDialect(report.Dialect),
+		Stats: responses.MigrateStats{
+			Rows:           stats.Rows,
+			DistinctUsers:  stats.DistinctUsers,
+			DatetimeFrom:   stats.From,
+			DatetimeTo:     stats.To,
+			TotalCredits:   credF,
+			TotalDebits:    debF,
+			BalanceChanges: changes,
+		},
+	}
 }
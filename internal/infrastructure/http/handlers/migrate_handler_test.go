@@ -10,20 +10,38 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"stori-challenge/internal/domain"
 	"stori-challenge/internal/infrastructure/http/responses"
 	"stori-challenge/internal/ports/services"
 	"stori-challenge/internal/shared"
 
 	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
 )
 
 type mockMigrationService struct {
-	ProcessFn func(ctx context.Context, r io.Reader) (int, []services.RowError, error)
+	ProcessFn       func(ctx context.Context, r io.Reader, opts domain.MigrationOptions) (services.MigrationResult, error)
+	ProcessStreamFn func(ctx context.Context, r io.Reader, opts domain.MigrationOptions) (services.MigrationResult, error)
+	ValidateFn      func(ctx context.Context, r io.Reader, opts domain.MigrationOptions) (services.MigrationValidation, error)
+	ReportFn        func(ctx context.Context, r io.Reader, opts domain.MigrationOptions, errs services.ReportErrors, w io.Writer) error
 }
 
-func (m *mockMigrationService) Process(ctx context.Context, r io.Reader) (int, []services.RowError, error) {
-	return m.ProcessFn(ctx, r)
+func (m *mockMigrationService) Process(ctx context.Context, r io.Reader, opts domain.MigrationOptions) (services.MigrationResult, error) {
+	return m.ProcessFn(ctx, r, opts)
+}
+
+func (m *mockMigrationService) ProcessStream(ctx context.Context, r io.Reader, opts domain.MigrationOptions) (services.MigrationResult, error) {
+	return m.ProcessStreamFn(ctx, r, opts)
+}
+
+func (m *mockMigrationService) Validate(ctx context.Context, r io.Reader, opts domain.MigrationOptions) (services.MigrationValidation, error) {
+	return m.ValidateFn(ctx, r, opts)
+}
+
+func (m *mockMigrationService) WriteErrorReport(ctx context.Context, r io.Reader, opts domain.MigrationOptions, errs services.ReportErrors, w io.Writer) error {
+	return m.ReportFn(ctx, r, opts, errs, w)
 }
 
 func TestPostMigrate_MissingFile_Returns400(t *testing.T) {
@@ -32,7 +50,9 @@ func TestPostMigrate_MissingFile_Returns400(t *testing.T) {
 	c, _ := gin.CreateTestContext(w)
 	c.Request = httptest.NewRequest(http.MethodPost, "/migrate", nil)
 	h := &MigrateHandler{Service: &mockMigrationService{
-		ProcessFn: func(ctx context.Context, r io.Reader) (int, []services.RowError, error) { return 0, nil, nil },
+		ProcessFn: func(ctx context.Context, r io.Reader, opts domain.MigrationOptions) (services.MigrationResult, error) {
+			return services.MigrationResult{}, nil
+		},
 	}}
 	h.PostMigrate(c)
 	if w.Code != http.StatusBadRequest {
@@ -55,7 +75,9 @@ func TestPostMigrate_WrongExtension_Returns400(t *testing.T) {
 	c.Request = req
 
 	h := &MigrateHandler{Service: &mockMigrationService{
-		ProcessFn: func(ctx context.Context, r io.Reader) (int, []services.RowError, error) { return 0, nil, nil },
+		ProcessFn: func(ctx context.Context, r io.Reader, opts domain.MigrationOptions) (services.MigrationResult, error) {
+			return services.MigrationResult{}, nil
+		},
 	}}
 	h.PostMigrate(c)
 	if w.Code != http.StatusBadRequest {
@@ -82,8 +104,8 @@ func TestPostMigrate_ServiceError_MapsRowErrors(t *testing.T) {
 		{Row: 3, Field: "id", Value: "1", Message: "duplicate"},
 	}
 	h := &MigrateHandler{Service: &mockMigrationService{
-		ProcessFn: func(ctx context.Context, r io.Reader) (int, []services.RowError, error) {
-			return 0, rowErrs, shared.NewConflict("conflict", "conflict", nil)
+		ProcessFn: func(ctx context.Context, r io.Reader, opts domain.MigrationOptions) (services.MigrationResult, error) {
+			return services.MigrationResult{Errors: rowErrs}, shared.NewConflict("conflict", "conflict", nil)
 		},
 	}}
 	h.PostMigrate(c)
@@ -100,6 +122,49 @@ func TestPostMigrate_ServiceError_MapsRowErrors(t *testing.T) {
 	}
 }
 
+func TestPostMigrate_ValidationError_IncludesSummary(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", "data.csv")
+	_, _ = io.Copy(part, strings.NewReader("id,user_id,amount,datetime\n"))
+	_ = writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/migrate?max_errors=1", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	var gotMax int
+	h := &MigrateHandler{Service: &mockMigrationService{
+		ProcessFn: func(ctx context.Context, r io.Reader, opts domain.MigrationOptions) (services.MigrationResult, error) {
+			gotMax = opts.MaxErrors
+			return services.MigrationResult{
+				Errors: []services.RowError{
+					{Row: 2, Field: "amount", Value: "abc", Message: "not a valid number"},
+					{Row: 0, Field: "file", Value: "2", Message: "more row errors omitted"},
+				},
+				Summary: &services.RowErrorSummary{Total: 3, Omitted: 2, Groups: []services.RowErrorGroup{
+					{Field: "amount", Message: "not a valid number", Count: 3, SampleRows: []int{2, 5, 9}},
+				}},
+			}, shared.NewBadRequest("validation_error", "validation failed", nil)
+		},
+	}}
+	h.PostMigrate(c)
+	if w.Code != http.StatusBadRequest || gotMax != 1 {
+		t.Fatalf("status want 400 got %d, max_errors %d", w.Code, gotMax)
+	}
+	var env responses.ErrorEnvelope
+	if err := json.Unmarshal(w.Body.Bytes(), &env); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	sum := env.Error.Summary
+	if sum == nil || sum.Total != 3 || sum.Omitted != 2 || len(sum.Groups) != 1 || sum.Groups[0].Count != 3 || len(sum.Groups[0].SampleRows) != 3 {
+		t.Fatalf("unexpected summary: %+v", sum)
+	}
+}
+
 func TestPostMigrate_Success_Returns201(t *testing.T) {
 	gin.SetMode(gin.TestMode)
 	body := &bytes.Buffer{}
@@ -108,26 +173,214 @@ func TestPostMigrate_Success_Returns201(t *testing.T) {
 	_, _ = io.Copy(part, strings.NewReader("id,user_id,amount,datetime\n"))
 	_ = writer.Close()
 
+	req := httptest.NewRequest(http.MethodPost, "/migrate", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set(UploadedByHeader, "ana")
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	var src domain.UploadSource
+	h := &MigrateHandler{Service: &mockMigrationService{
+		ProcessFn: func(ctx context.Context, r io.Reader, opts domain.MigrationOptions) (services.MigrationResult, error) {
+			src = opts.Source
+			return services.MigrationResult{Inserted: 42, BatchID: 5}, nil
+		},
+	}}
+	h.PostMigrate(c)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status want 201 got %d", w.Code)
+	}
+	var ok responses.MigrateSuccessResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &ok); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if ok.Inserted != 42 || ok.BatchID != 5 {
+		t.Fatalf("unexpected response: %+v", ok)
+	}
+	if src.FileName != "data.csv" || src.UploadedBy != "ana" {
+		t.Fatalf("unexpected upload source: %+v", src)
+	}
+}
+
+func TestPostMigrate_JSONLines_PassesFormat(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", "events.ndjson")
+	_, _ = io.Copy(part, strings.NewReader(`{"id":1,"user_id":2,"amount":3.5,"datetime":"2024-06-01T00:00:00Z"}`+"\n"))
+	_ = writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/migrate", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	var got domain.InputFormat
+	h := &MigrateHandler{Service: &mockMigrationService{
+		ProcessFn: func(ctx context.Context, r io.Reader, opts domain.MigrationOptions) (services.MigrationResult, error) {
+			got = opts.Format
+			return services.MigrationResult{Inserted: 1}, nil
+		},
+	}}
+	h.PostMigrate(c)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status want 201 got %d", w.Code)
+	}
+	if got != domain.InputFormatJSONL {
+		t.Fatalf("format want jsonl got %q", got)
+	}
+}
+
+func TestPostMigrate_Zip_PassesCompressionAndReportsMemberFile(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", "batch.zip")
+	_, _ = part.Write([]byte("PK"))
+	_ = writer.Close()
+
 	req := httptest.NewRequest(http.MethodPost, "/migrate", body)
 	req.Header.Set("Content-Type", writer.FormDataContentType())
 	w := httptest.NewRecorder()
 	c, _ := gin.CreateTestContext(w)
 	c.Request = req
 
+	var got domain.MigrationOptions
 	h := &MigrateHandler{Service: &mockMigrationService{
-		ProcessFn: func(ctx context.Context, r io.Reader) (int, []services.RowError, error) {
-			return 42, nil, nil
+		ProcessFn: func(ctx context.Context, r io.Reader, opts domain.MigrationOptions) (services.MigrationResult, error) {
+			got = opts
+			return services.MigrationResult{Errors: []services.RowError{{File: "b.csv", Row: 2, Field: "amount", Value: "x", Message: "not a valid number"}}},
+				shared.NewBadRequest("validation_error", "validation failed", nil)
+		},
+	}}
+	h.PostMigrate(c)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status want 400 got %d", w.Code)
+	}
+	if got.Compression != domain.CompressionZip || got.Format != domain.InputFormatCSV {
+		t.Fatalf("unexpected options: %+v", got)
+	}
+	if !strings.Contains(w.Body.String(), `"file":"b.csv"`) {
+		t.Fatalf("expected member file in body: %s", w.Body.String())
+	}
+}
+
+func TestPostMigrate_PartialMode_ReturnsRejectedRows(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", "data.csv")
+	_, _ = io.Copy(part, strings.NewReader("id,user_id,amount,datetime\n"))
+	_ = writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/migrate?mode=partial", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	var gotOpts domain.MigrationOptions
+	h := &MigrateHandler{Service: &mockMigrationService{
+		ProcessFn: func(ctx context.Context, r io.Reader, opts domain.MigrationOptions) (services.MigrationResult, error) {
+			gotOpts = opts
+			return services.MigrationResult{
+				Inserted: 2,
+				Rejected: 1,
+				Errors:   []services.RowError{{Row: 3, Field: "amount", Value: "abc", Message: "must be a number"}},
+			}, nil
 		},
 	}}
 	h.PostMigrate(c)
 	if w.Code != http.StatusCreated {
 		t.Fatalf("status want 201 got %d", w.Code)
 	}
+	if !gotOpts.IsPartial() {
+		t.Fatalf("expected partial mode, got %+v", gotOpts)
+	}
 	var ok responses.MigrateSuccessResponse
 	if err := json.Unmarshal(w.Body.Bytes(), &ok); err != nil {
 		t.Fatalf("unmarshal: %v", err)
 	}
-	if ok.Inserted != 42 {
-		t.Fatalf("inserted want 42 got %d", ok.Inserted)
+	if ok.Inserted != 2 || ok.Rejected != 1 || len(ok.Errors) != 1 || ok.Errors[0].Row != 3 {
+		t.Fatalf("unexpected body: %+v", ok)
+	}
+}
+
+func TestPostMigrate_InvalidMode_Returns400(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", "data.csv")
+	_, _ = io.Copy(part, strings.NewReader("id,user_id,amount,datetime\n"))
+	_ = writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/migrate?mode=lenient", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	h := &MigrateHandler{Service: &mockMigrationService{
+		ProcessFn: func(ctx context.Context, r io.Reader, opts domain.MigrationOptions) (services.MigrationResult, error) {
+			t.Fatalf("service must not be called")
+			return services.MigrationResult{}, nil
+		},
+	}}
+	h.PostMigrate(c)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status want 400 got %d", w.Code)
+	}
+}
+
+func TestPostMigrateValidate_ReturnsReportAndStats(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", "data.csv")
+	_, _ = io.Copy(part, strings.NewReader("id,user_id,amount,datetime\n"))
+	_ = writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/migrate/validate", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	from := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	h := &MigrateHandler{Service: &mockMigrationService{
+		ValidateFn: func(ctx context.Context, r io.Reader, opts domain.MigrationOptions) (services.MigrationValidation, error) {
+			return services.MigrationValidation{
+				Rows:     3,
+				Rejected: 1,
+				Errors:   []services.RowError{{Row: 2, Field: "amount", Value: "abc", Message: "not a valid number"}},
+				Stats: services.MigrationStats{
+					Rows:          2,
+					DistinctUsers: 1,
+					From:          &from,
+					To:            &from,
+					TotalCredits:  decimal.RequireFromString("10.5"),
+					TotalDebits:   decimal.RequireFromString("2"),
+					BalanceChanges: []services.UserBalanceChange{
+						{UserID: 7, Change: decimal.RequireFromString("8.5")},
+					},
+				},
+			}, nil
+		},
+	}}
+	h.PostMigrateValidate(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status want 200 got %d", w.Code)
+	}
+	var got responses.MigrateValidationResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got.Valid || got.Rows != 3 || got.Rejected != 1 || len(got.Errors) != 1 {
+		t.Fatalf("unexpected report: %+v", got)
+	}
+	if got.Stats.TotalCredits != 10.5 || got.Stats.TotalDebits != 2 || len(got.Stats.BalanceChanges) != 1 || got.Stats.BalanceChanges[0].Change != 8.5 {
+		t.Fatalf("unexpected stats: %+v", got.Stats)
 	}
 }
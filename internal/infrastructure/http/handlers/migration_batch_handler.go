@@ -0,0 +1,190 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/infrastructure/http/responses"
+	"stori-challenge/internal/ports/services"
+	"stori-challenge/internal/shared"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MigrationBatchHandler struct {
+	Service services.MigrationBatchService
+}
+
+func NewMigrationBatchHandler(svc services.MigrationBatchService) *MigrationBatchHandler {
+	return &MigrationBatchHandler{Service: svc}
+}
+
+// GetMigrationBatch
+// @Summary      Get a migration batch
+// @Description  Returns the provenance of an upload: file name, SHA-256, size, uploader, timestamps and row counts
+// @Tags         migrate
+// @Produce      json
+// @Param        id   path      int  true  "Migration batch ID"
+// @Success      200  {object}  responses.MigrationBatchResponse
+// @Failure      400  {object}  responses.ErrorEnvelope
+// @Failure      404  {object}  responses.ErrorEnvelope
+// @Router       /migration-batches/{id} [get]
+func (h *MigrationBatchHandler) GetMigrationBatch(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		CreateErrorResponse(c, shared.NewBadRequest("invalid_migration_batch_id", "id must be a positive integer", nil), nil)
+		return
+	}
+
+	b, svcErr := h.Service.Get(c.Request.Context(), id)
+	if svcErr != nil {
+		CreateErrorResponse(c, svcErr, nil)
+		return
+	}
+	c.JSON(http.StatusOK, toMigrationBatchResponse(b))
+}
+
+// GetTransactionSource
+// @Summary      Get where a transaction was migrated from
+// @Description  Returns the source file and row of a migrated transaction with its migration batch
+// @Tags         migrate
+// @Produce      json
+// @Param        id   path      int  true  "Transaction ID"
+// @Success      200  {object}  responses.TransactionSourceResponse
+// @Failure      400  {object}  responses.ErrorEnvelope
+// @Failure      404  {object}  responses.ErrorEnvelope
+// @Router       /transactions/{id}/source [get]
+func (h *MigrationBatchHandler) GetTransactionSource(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		CreateErrorResponse(c, shared.NewBadRequest("invalid_transaction_id", "id must be a positive integer", nil), nil)
+		return
+	}
+
+	src, b, svcErr := h.Service.GetTransactionSource(c.Request.Context(), id)
+	if svcErr != nil {
+		CreateErrorResponse(c, svcErr, nil)
+		return
+	}
+	out := responses.TransactionSourceResponse{TransactionID: id, File: src.File, Row: src.Row}
+	if b != nil {
+		resp := toMigrationBatchResponse(*b)
+		out.Batch = &resp
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// revertRequest is the body of POST /migration-batches/:id/revert and DELETE /migrations/:id.
+type revertRequest struct {
+	RevertedBy string `json:"reverted_by"`
+	Reason     string `json:"reason"`
+}
+
+// PostRevertMigrationBatch
+// @Summary      Revert a migration batch
+// @Description  Deletes every transaction the batch inserted in one database transaction and records who reverted it and why. Refused when the batch is not SUCCEEDED or has a transaction in a locked period
+// @Tags         migrate
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Migration batch ID"
+// @Success      200  {object}  responses.BatchRevertResponse
+// @Failure      400  {object}  responses.ErrorEnvelope
+// @Failure      404  {object}  responses.ErrorEnvelope
+// @Failure      409  {object}  responses.ErrorEnvelope
+// @Router       /migration-batches/{id}/revert [post]
+func (h *MigrationBatchHandler) PostRevertMigrationBatch(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		CreateErrorResponse(c, shared.NewBadRequest("invalid_migration_batch_id", "id must be a positive integer", nil), nil)
+		return
+	}
+	rev, ok := bindRevertRequest(c)
+	if !ok {
+		return
+	}
+	rev.BatchID = id
+
+	out, svcErr := h.Service.Revert(c.Request.Context(), rev)
+	if svcErr != nil {
+		CreateErrorResponse(c, svcErr, nil)
+		return
+	}
+	c.JSON(http.StatusOK, toBatchRevertResponse(out))
+}
+
+// DeleteMigration
+// @Summary      Revert an asynchronous migration
+// @Description  Reverts the migration batch of the migration: deletes its transactions in one database transaction and records who reverted it and why
+// @Tags         migrate
+// @Accept       json
+// @Produce      json
+// @Param        id   path      int  true  "Migration ID"
+// @Success      200  {object}  responses.BatchRevertResponse
+// @Failure      400  {object}  responses.ErrorEnvelope
+// @Failure      404  {object}  responses.ErrorEnvelope
+// @Failure      409  {object}  responses.ErrorEnvelope
+// @Router       /migrations/{id} [delete]
+func (h *MigrationBatchHandler) DeleteMigration(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		CreateErrorResponse(c, shared.NewBadRequest("invalid_migration_id", "id must be a positive integer", nil), nil)
+		return
+	}
+	rev, ok := bindRevertRequest(c)
+	if !ok {
+		return
+	}
+
+	out, svcErr := h.Service.RevertMigration(c.Request.Context(), id, rev)
+	if svcErr != nil {
+		CreateErrorResponse(c, svcErr, nil)
+		return
+	}
+	c.JSON(http.StatusOK, toBatchRevertResponse(out))
+}
+
+func bindRevertRequest(c *gin.Context) (domain.BatchRevert, bool) {
+	var req revertRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		CreateErrorResponse(c, shared.NewBadRequest("invalid_body", "body must be a JSON object with reverted_by and reason", err), nil)
+		return domain.BatchRevert{}, false
+	}
+	return domain.BatchRevert{RevertedBy: req.RevertedBy, Reason: req.Reason}, true
+}
+
+func toBatchRevertResponse(rev domain.BatchRevert) responses.BatchRevertResponse {
+	return responses.BatchRevertResponse{
+		BatchID:             rev.BatchID,
+		RevertedBy:          rev.RevertedBy,
+		Reason:              rev.Reason,
+		TransactionsDeleted: rev.TransactionsDeleted,
+		RevertedAt:          rev.RevertedAt,
+	}
+}
+
+func toMigrationBatchResponse(b domain.MigrationBatch) responses.MigrationBatchResponse {
+	var revert *responses.BatchRevertResponse
+	if b.Revert != nil {
+		r := toBatchRevertResponse(*b.Revert)
+		revert = &r
+	}
+	return responses.MigrationBatchResponse{
+		ID:             b.ID,
+		MigrationID:    b.Source.MigrationID,
+		FileName:       b.Source.FileName,
+		SHA256:         b.SHA256,
+		SizeBytes:      b.Size,
+		UploadedBy:     b.Source.UploadedBy,
+		ClientIP:       b.Source.ClientIP,
+		Status:         string(b.Status),
+		Inserted:       b.Inserted,
+		Rejected:       b.Rejected,
+		AlreadyPresent: b.AlreadyPresent,
+		ErrorCode:      b.ErrorCode,
+		AmountRounding: string(b.AmountRounding),
+		StartedAt:      b.StartedAt,
+		FinishedAt:     b.FinishedAt,
+		Revert:         revert,
+	}
+}
@@ -0,0 +1,196 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/infrastructure/http/responses"
+	"stori-challenge/internal/shared"
+
+	"github.com/gin-gonic/gin"
+)
+
+type mockMigrationBatchService struct {
+	GetFn             func(ctx context.Context, id int64) (domain.MigrationBatch, error)
+	GetSourceFn       func(ctx context.Context, transactionID int64) (domain.TransactionSource, *domain.MigrationBatch, error)
+	RevertFn          func(ctx context.Context, rev domain.BatchRevert) (domain.BatchRevert, error)
+	RevertMigrationFn func(ctx context.Context, migrationID int64, rev domain.BatchRevert) (domain.BatchRevert, error)
+}
+
+func (m *mockMigrationBatchService) Get(ctx context.Context, id int64) (domain.MigrationBatch, error) {
+	return m.GetFn(ctx, id)
+}
+
+func (m *mockMigrationBatchService) GetTransactionSource(ctx context.Context, transactionID int64) (domain.TransactionSource, *domain.MigrationBatch, error) {
+	return m.GetSourceFn(ctx, transactionID)
+}
+
+func (m *mockMigrationBatchService) Revert(ctx context.Context, rev domain.BatchRevert) (domain.BatchRevert, error) {
+	return m.RevertFn(ctx, rev)
+}
+
+func (m *mockMigrationBatchService) RevertMigration(ctx context.Context, migrationID int64, rev domain.BatchRevert) (domain.BatchRevert, error) {
+	return m.RevertMigrationFn(ctx, migrationID, rev)
+}
+
+func TestGetMigrationBatch_InvalidID_Returns400(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/migration-batches/abc", nil)
+	c.Params = gin.Params{{Key: "id", Value: "abc"}}
+
+	h := &MigrationBatchHandler{Service: &mockMigrationBatchService{}}
+	h.GetMigrationBatch(c)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status want 400 got %d", w.Code)
+	}
+}
+
+func TestGetMigrationBatch_NotFound_Returns404(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/migration-batches/4", nil)
+	c.Params = gin.Params{{Key: "id", Value: "4"}}
+
+	h := &MigrationBatchHandler{Service: &mockMigrationBatchService{
+		GetFn: func(ctx context.Context, id int64) (domain.MigrationBatch, error) {
+			return domain.MigrationBatch{}, shared.NewNotFound("migration_batch_not_found", "migration batch not found", nil)
+		},
+	}}
+	h.GetMigrationBatch(c)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status want 404 got %d", w.Code)
+	}
+}
+
+func TestGetMigrationBatch_Success_ReturnsAmountRounding(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/migration-batches/4", nil)
+	c.Params = gin.Params{{Key: "id", Value: "4"}}
+
+	h := &MigrationBatchHandler{Service: &mockMigrationBatchService{
+		GetFn: func(ctx context.Context, id int64) (domain.MigrationBatch, error) {
+			return domain.MigrationBatch{ID: id, Source: domain.UploadSource{FileName: "data.csv"}, Status: domain.MigrationBatchSucceeded, AmountRounding: domain.AmountRoundingHalfUp}, nil
+		},
+	}}
+	h.GetMigrationBatch(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status want 200 got %d", w.Code)
+	}
+	var resp responses.MigrationBatchResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.ID != 4 || resp.AmountRounding != "half_up" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func TestGetTransactionSource_Success_ReturnsRowAndBatch(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/transactions/10/source", nil)
+	c.Params = gin.Params{{Key: "id", Value: "10"}}
+
+	h := &MigrationBatchHandler{Service: &mockMigrationBatchService{
+		GetSourceFn: func(ctx context.Context, transactionID int64) (domain.TransactionSource, *domain.MigrationBatch, error) {
+			b := domain.MigrationBatch{ID: 3, Source: domain.UploadSource{FileName: "jan.zip", UploadedBy: "ana"}, SHA256: "abc", Size: 120, Status: domain.MigrationBatchSucceeded}
+			return domain.TransactionSource{BatchID: 3, File: "a.csv", Row: 7}, &b, nil
+		},
+	}}
+	h.GetTransactionSource(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status want 200 got %d", w.Code)
+	}
+	var resp responses.TransactionSourceResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.TransactionID != 10 || resp.File != "a.csv" || resp.Row != 7 || resp.Batch == nil {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if resp.Batch.ID != 3 || resp.Batch.FileName != "jan.zip" || resp.Batch.SHA256 != "abc" || resp.Batch.UploadedBy != "ana" || resp.Batch.Status != "SUCCEEDED" {
+		t.Fatalf("unexpected batch: %+v", resp.Batch)
+	}
+}
+
+func TestPostRevertMigrationBatch_Success_Returns200(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/migration-batches/3/revert", strings.NewReader(`{"reverted_by":"ana","reason":"wrong file"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "id", Value: "3"}}
+
+	var got domain.BatchRevert
+	h := &MigrationBatchHandler{Service: &mockMigrationBatchService{
+		RevertFn: func(ctx context.Context, rev domain.BatchRevert) (domain.BatchRevert, error) {
+			got = rev
+			rev.TransactionsDeleted = 12
+			return rev, nil
+		},
+	}}
+	h.PostRevertMigrationBatch(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status want 200 got %d", w.Code)
+	}
+	if got.BatchID != 3 || got.RevertedBy != "ana" || got.Reason != "wrong file" {
+		t.Fatalf("unexpected revert passed to service: %+v", got)
+	}
+	var resp responses.BatchRevertResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.BatchID != 3 || resp.TransactionsDeleted != 12 {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func TestDeleteMigration_PeriodLocked_Returns409(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodDelete, "/migrations/12", strings.NewReader(`{"reverted_by":"ana","reason":"wrong file"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "id", Value: "12"}}
+
+	var gotID int64
+	h := &MigrationBatchHandler{Service: &mockMigrationBatchService{
+		RevertMigrationFn: func(ctx context.Context, migrationID int64, rev domain.BatchRevert) (domain.BatchRevert, error) {
+			gotID = migrationID
+			return domain.BatchRevert{}, shared.NewConflict("period_locked", "migration batch has transactions in locked period 2024-06", nil)
+		},
+	}}
+	h.DeleteMigration(c)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("status want 409 got %d", w.Code)
+	}
+	if gotID != 12 {
+		t.Fatalf("unexpected migration id: %d", gotID)
+	}
+}
+
+func TestDeleteMigration_InvalidBody_Returns400(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodDelete, "/migrations/12", strings.NewReader(`not json`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "id", Value: "12"}}
+
+	h := &MigrationBatchHandler{Service: &mockMigrationBatchService{}}
+	h.DeleteMigration(c)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status want 400 got %d", w.Code)
+	}
+}
@@ -0,0 +1,204 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/infrastructure/http/responses"
+	"stori-challenge/internal/infrastructure/http/validators"
+	"stori-challenge/internal/ports/services"
+	"stori-challenge/internal/shared"
+
+	"github.com/gin-gonic/gin"
+)
+
+type MigrationHandler struct {
+	Service services.MigrationJobService
+}
+
+func NewMigrationHandler(svc services.MigrationJobService) *MigrationHandler {
+	return &MigrationHandler{Service: svc}
+}
+
+// PostMigrateAsync
+// @Summary      Enqueue an asynchronous migration
+// @Description  Stores the file (up to 5GB) and records a PENDING migration streamed by a background worker
+// @Tags         migrate
+// @Accept       multipart/form-data
+// @Produce      json
+// @Param        file             formData  file    true   "CSV, JSON Lines (.jsonl, .ndjson), JSON array (.json), Excel (.xlsx), OFX/QFX, camt.053/054 XML or MT940 (.sta, .mt940) statement file; text formats may be gzipped (.csv.gz), or a .zip of CSV files"
+// @Param        mode             query     string  false  "strict (default) or partial"
+// @Param        idempotent       query     bool    false  "skip rows already stored with the same content"
+// @Param        profile          query     string  false  "name of a saved header mapping profile"
+// @Param        datetime_format  query     string  false  "rfc3339 (default), local, date, unix, unix_ms or auto"
+// @Param        timezone         query     string  false  "IANA time zone of datetimes without an offset (default UTC)"
+// @Param        locale           query     string  false  "locale of amounts, e.g. es-ES for 1.234,56 (default plain decimals)"
+// @Param        sheet            query     string  false  "worksheet name or 1-based index of an .xlsx file (default first sheet)"
+// @Param        user_id          query     int     false  "owner of every transaction of a bank statement upload (default: account mapping)"
+// @Param        encoding         query     string  false  "character set of text uploads: utf-8, utf-16le, utf-16be, windows-1252, iso-8859-1 (default: detected)"
+// @Param        delimiter        query     string  false  "CSV field delimiter: , ; | or tab (default: detected)"
+// @Param        max_errors       query     int     false  "row errors listed in detail, 1-10000 (default 1000); all of them are counted in the summary"
+// @Param        overdraft_floor  query     string  false  "reject the file if replaying it with each user's history takes a balance below this amount"
+// @Param        unknown_users    query     string  false  "rows of unregistered users: create registers them (default), reject rejects the rows"
+// @Param        amount_rounding  query     string  false  "amounts with more than 2 decimals: reject (default), half_even or half_up"
+// @Param        X-Uploaded-By    header    string  false  "uploader recorded on the migration batch"
+// @Success      202  {object}  responses.MigrateAsyncAcceptedResponse
+// @Failure      400  {object}  responses.ErrorEnvelope
+// @Failure      409  {object}  responses.ErrorEnvelope
+// @Router       /migrate-async [post]
+func (h *MigrationHandler) PostMigrateAsync(c *gin.Context) {
+	f, fileHeader, ok := openUploadedFile(c, validators.MaxAsyncUploadSize)
+	if !ok {
+		return
+	}
+	defer f.Close()
+	opts, ok := parseMigrationOptions(c, fileHeader)
+	if !ok {
+		return
+	}
+
+	m, svcErr := h.Service.Enqueue(c.Request.Context(), fileHeader.Filename, f, opts)
+	if svcErr != nil {
+		CreateErrorResponse(c, svcErr, nil)
+		return
+	}
+
+	c.Header("Location", "/v1/migrations/"+strconv.FormatInt(m.ID, 10))
+	c.JSON(http.StatusAccepted, responses.MigrateAsyncAcceptedResponse{ID: m.ID, Status: string(m.Status)})
+}
+
+// GetMigration
+// @Summary      Get an asynchronous migration
+// @Description  Returns status, counts and row-level errors of a migration
+// @Tags         migrate
+// @Produce      json
+// @Param        id   path      int  true  "Migration ID"
+// @Success      200  {object}  responses.MigrationResponse
+// @Failure      400  {object}  responses.ErrorEnvelope
+// @Failure      404  {object}  responses.ErrorEnvelope
+// @Router       /migrations/{id} [get]
+func (h *MigrationHandler) GetMigration(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		CreateErrorResponse(c, shared.NewBadRequest("invalid_migration_id", "id must be a positive integer", nil), nil)
+		return
+	}
+
+	m, svcErr := h.Service.Get(c.Request.Context(), id)
+	if svcErr != nil {
+		CreateErrorResponse(c, svcErr, nil)
+		return
+	}
+
+	c.JSON(http.StatusOK, toMigrationResponse(m))
+}
+
+// GetMigrationErrorsCSV
+// @Summary      Download the failed rows of a migration
+// @Description  Returns the rows of a finished migration that have errors, as in the uploaded file, with error_field and error_message columns appended so they can be fixed and uploaded again
+// @Tags         migrate
+// @Produce      text/csv
+// @Produce      json
+// @Param        id   path      int  true  "Migration ID"
+// @Success      200  {string}  string  "CSV report"
+// @Failure      400  {object}  responses.ErrorEnvelope
+// @Failure      404  {object}  responses.ErrorEnvelope
+// @Failure      409  {object}  responses.ErrorEnvelope
+// @Router       /migrations/{id}/errors.csv [get]
+func (h *MigrationHandler) GetMigrationErrorsCSV(c *gin.Context) {
+	id, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil || id <= 0 {
+		CreateErrorResponse(c, shared.NewBadRequest("invalid_migration_id", "id must be a positive integer", nil), nil)
+		return
+	}
+
+	// The report is streamed as it is written. Its headers only go out with the first bytes,
+	// so a failure before them is still reported as JSON; a later one cuts the download short.
+	rw := &reportWriter{c: c, fileName: fmt.Sprintf("migration-%d-errors.csv", id)}
+	if svcErr := h.Service.WriteErrorReport(c.Request.Context(), id, rw); svcErr != nil {
+		if !rw.started {
+			CreateErrorResponse(c, svcErr, nil)
+			return
+		}
+		_ = c.Error(svcErr)
+		c.Abort()
+		return
+	}
+	rw.start()
+}
+
+// reportWriter sends the headers of a CSV attachment on the first write.
+type reportWriter struct {
+	c        *gin.Context
+	fileName string
+	started  bool
+}
+
+func (w *reportWriter) Write(p []byte) (int, error) {
+	w.start()
+	return w.c.Writer.Write(p)
+}
+
+func (w *reportWriter) start() {
+	if w.started {
+		return
+	}
+	w.started = true
+	w.c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, w.fileName))
+	w.c.Header("Content-Type", "text/csv; charset=utf-8")
+	w.c.Status(http.StatusOK)
+}
+
+func toMigrationResponse(m domain.Migration) responses.MigrationResponse {
+	return responses.MigrationResponse{
+		ID:             m.ID,
+		Status:         string(m.Status),
+		FileName:       m.FileName,
+		Format:         string(m.Options.Format),
+		Compression:    string(m.Options.Compression),
+		Mode:           string(m.Options.Mode),
+		Inserted:       m.Inserted,
+		Idempotent:     m.Options.Idempotent,
+		Profile:        m.Options.Profile,
+		DatetimeFormat: string(m.Options.DatetimeFormat),
+		Timezone:       m.Options.Timezone,
+		Locale:         m.Options.Locale,
+		Sheet:          m.Options.Sheet,
+		UserID:         m.Options.UserID,
+		Encoding:       string(m.Options.Encoding),
+		Delimiter:      m.Options.Delimiter,
+		MaxErrors:      m.Options.MaxErrors,
+		OverdraftFloor: decimalString(m.Options.OverdraftFloor),
+		UnknownUsers:   string(m.Options.UnknownUsers),
+		AmountRounding: string(m.Options.AmountRounding),
+		UploadedBy:     m.Options.Source.UploadedBy,
+		Rejected:       m.Rejected,
+		AlreadyPresent: m.AlreadyPresent,
+		ErrorCount:     migrationErrorCount(m),
+		ErrorCode:      m.ErrorCode,
+		Errors:         toMigrateRowErrors(m.Errors),
+		ErrorSummary:   toRowErrorSummary(m.Summary),
+		Dialect:        toCSVDialect(m.Dialect),
+		CreatedAt:      m.CreatedAt,
+		StartedAt:      m.StartedAt,
+		FinishedAt:     m.FinishedAt,
+	}
+}
+
+// migrationErrorCount counts every row error of m from its summary, since the stored list is
+// capped by max_errors, plus the stored warnings. Migrations without a summary (no row errors,
+// or a file-level failure) count the stored list.
+func migrationErrorCount(m domain.Migration) int {
+	if m.Summary == nil {
+		return len(m.Errors)
+	}
+	n := m.Summary.Total
+	for _, e := range m.Errors {
+		if e.Severity == domain.RuleSeverityWarning {
+			n++
+		}
+	}
+	return n
+}
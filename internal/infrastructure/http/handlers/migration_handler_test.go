@@ -0,0 +1,270 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/infrastructure/http/responses"
+	"stori-challenge/internal/shared"
+
+	"github.com/gin-gonic/gin"
+)
+
+type mockMigrationJobService struct {
+	EnqueueFn func(ctx context.Context, fileName string, r io.Reader, opts domain.MigrationOptions) (domain.Migration, error)
+	GetFn     func(ctx context.Context, id int64) (domain.Migration, error)
+	ReportFn  func(ctx context.Context, id int64, w io.Writer) error
+}
+
+func (m *mockMigrationJobService) Enqueue(ctx context.Context, fileName string, r io.Reader, opts domain.MigrationOptions) (domain.Migration, error) {
+	return m.EnqueueFn(ctx, fileName, r, opts)
+}
+
+func (m *mockMigrationJobService) Get(ctx context.Context, id int64) (domain.Migration, error) {
+	return m.GetFn(ctx, id)
+}
+
+func (m *mockMigrationJobService) WriteErrorReport(ctx context.Context, id int64, w io.Writer) error {
+	return m.ReportFn(ctx, id, w)
+}
+
+func TestPostMigrateAsync_MissingFile_Returns400(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPost, "/migrate-async", nil)
+	h := &MigrationHandler{Service: &mockMigrationJobService{}}
+	h.PostMigrateAsync(c)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status want 400 got %d", w.Code)
+	}
+}
+
+func TestPostMigrateAsync_Success_Returns202(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	body := &bytes.Buffer{}
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("file", "data.csv")
+	_, _ = io.Copy(part, strings.NewReader("id,user_id,amount,datetime\n"))
+	_ = writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/migrate-async", body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	var gotName string
+	h := &MigrationHandler{Service: &mockMigrationJobService{
+		EnqueueFn: func(ctx context.Context, fileName string, r io.Reader, opts domain.MigrationOptions) (domain.Migration, error) {
+			gotName = fileName
+			return domain.Migration{ID: 5, Status: domain.MigrationStatusPending}, nil
+		},
+	}}
+	h.PostMigrateAsync(c)
+	if w.Code != http.StatusAccepted {
+		t.Fatalf("status want 202 got %d", w.Code)
+	}
+	if gotName != "data.csv" {
+		t.Fatalf("file name want data.csv got %q", gotName)
+	}
+	if loc := w.Header().Get("Location"); loc != "/v1/migrations/5" {
+		t.Fatalf("unexpected Location %q", loc)
+	}
+	var ok responses.MigrateAsyncAcceptedResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &ok); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if ok.ID != 5 || ok.Status != "PENDING" {
+		t.Fatalf("payload mismatch: %+v", ok)
+	}
+}
+
+func TestGetMigration_InvalidID_Returns400(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "abc"}}
+	c.Request = httptest.NewRequest(http.MethodGet, "/migrations/abc", nil)
+	h := &MigrationHandler{Service: &mockMigrationJobService{}}
+	h.GetMigration(c)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("status want 400 got %d", w.Code)
+	}
+}
+
+func TestGetMigration_NotFound_Returns404(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "9"}}
+	c.Request = httptest.NewRequest(http.MethodGet, "/migrations/9", nil)
+	h := &MigrationHandler{Service: &mockMigrationJobService{
+		GetFn: func(ctx context.Context, id int64) (domain.Migration, error) {
+			return domain.Migration{}, shared.NewNotFound("migration_not_found", "migration not found", nil)
+		},
+	}}
+	h.GetMigration(c)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status want 404 got %d", w.Code)
+	}
+}
+
+func TestGetMigration_Failed_ReturnsErrors(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "3"}}
+	c.Request = httptest.NewRequest(http.MethodGet, "/migrations/3", nil)
+	finished := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	h := &MigrationHandler{Service: &mockMigrationJobService{
+		GetFn: func(ctx context.Context, id int64) (domain.Migration, error) {
+			return domain.Migration{
+				ID:         id,
+				Status:     domain.MigrationStatusFailed,
+				FileName:   "data.csv",
+				ErrorCode:  "validation_error",
+				Errors:     []domain.RowError{{Row: 2, Field: "amount", Value: "x", Message: "not a valid number"}},
+				FinishedAt: &finished,
+			}, nil
+		},
+	}}
+	h.GetMigration(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status want 200 got %d", w.Code)
+	}
+	var got responses.MigrationResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got.Status != "FAILED" || got.ErrorCount != 1 || len(got.Errors) != 1 || got.Errors[0].Field != "amount" {
+		t.Fatalf("payload mismatch: %+v", got)
+	}
+}
+
+func TestGetMigration_CappedErrors_CountsFromSummary(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Params = []gin.Param{{Key: "id", Value: "3"}}
+	c.Request = httptest.NewRequest(http.MethodGet, "/migrations/3", nil)
+	h := &MigrationHandler{Service: &mockMigrationJobService{
+		GetFn: func(ctx context.Context, id int64) (domain.Migration, error) {
+			return domain.Migration{
+				ID:        id,
+				Status:    domain.MigrationStatusFailed,
+				FileName:  "data.csv",
+				ErrorCode: "validation_error",
+				Errors: []domain.RowError{
+					{Row: 2, Field: "amount", Value: "x", Message: "not a valid number"},
+					{Row: 0, Field: "file", Value: "2999", Message: "more row errors omitted"},
+					{Row: 5, Field: "amount", Value: "0", Message: "amount must not be zero", Severity: domain.RuleSeverityWarning},
+				},
+				Summary: &domain.RowErrorSummary{Total: 3000, Omitted: 2999, Groups: []domain.RowErrorGroup{
+					{Field: "amount", Message: "not a valid number", Count: 3000, SampleRows: []int{2, 3, 4, 6, 7}},
+				}},
+			}, nil
+		},
+	}}
+	h.GetMigration(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status want 200 got %d", w.Code)
+	}
+	var got responses.MigrationResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if got.ErrorCount != 3001 {
+		t.Fatalf("expected 3000 errors and 1 warning counted, got %d", got.ErrorCount)
+	}
+	if got.ErrorSummary == nil || got.ErrorSummary.Total != 3000 || got.ErrorSummary.Omitted != 2999 || len(got.ErrorSummary.Groups) != 1 {
+		t.Fatalf("unexpected summary: %+v", got.ErrorSummary)
+	}
+}
+
+func TestGetMigrationErrorsCSV_ReturnsAttachment(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/migrations/7/errors.csv", nil)
+	c.Params = gin.Params{{Key: "id", Value: "7"}}
+
+	h := &MigrationHandler{Service: &mockMigrationJobService{
+		ReportFn: func(ctx context.Context, id int64, w io.Writer) error {
+			if id != 7 {
+				t.Fatalf("unexpected id %d", id)
+			}
+			_, err := io.WriteString(w, "id,user_id,amount,datetime,error_field,error_message\n")
+			return err
+		},
+	}}
+	h.GetMigrationErrorsCSV(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status want 200 got %d", w.Code)
+	}
+	if ct := w.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Fatalf("unexpected Content-Type %q", ct)
+	}
+	if cd := w.Header().Get("Content-Disposition"); cd != `attachment; filename="migration-7-errors.csv"` {
+		t.Fatalf("unexpected Content-Disposition %q", cd)
+	}
+	if !strings.HasSuffix(w.Body.String(), ",error_field,error_message\n") {
+		t.Fatalf("unexpected body %q", w.Body.String())
+	}
+}
+
+func TestGetMigrationErrorsCSV_NotFinished_Returns409JSON(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/migrations/7/errors.csv", nil)
+	c.Params = gin.Params{{Key: "id", Value: "7"}}
+
+	h := &MigrationHandler{Service: &mockMigrationJobService{
+		ReportFn: func(ctx context.Context, id int64, w io.Writer) error {
+			return shared.NewConflict("migration_not_finished", "migration is still processing", nil)
+		},
+	}}
+	h.GetMigrationErrorsCSV(c)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("status want 409 got %d", w.Code)
+	}
+	var env responses.ErrorEnvelope
+	if err := json.Unmarshal(w.Body.Bytes(), &env); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if env.Error.Code != "migration_not_finished" {
+		t.Fatalf("unexpected error: %+v", env)
+	}
+}
+
+func TestGetMigrationErrorsCSV_FailureMidReport_KeepsStreamedRows(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodGet, "/migrations/7/errors.csv", nil)
+	c.Params = gin.Params{{Key: "id", Value: "7"}}
+
+	h := &MigrationHandler{Service: &mockMigrationJobService{
+		ReportFn: func(ctx context.Context, id int64, w io.Writer) error {
+			io.WriteString(w, "id,user_id,amount,datetime,error_field,error_message\n")
+			return shared.NewInternal("db_failure", "database error", nil)
+		},
+	}}
+	h.GetMigrationErrorsCSV(c)
+	if w.Code != http.StatusOK || !strings.HasPrefix(w.Header().Get("Content-Type"), "text/csv") {
+		t.Fatalf("expected the CSV response already sent, got %d %q", w.Code, w.Header().Get("Content-Type"))
+	}
+	if w.Body.String() != "id,user_id,amount,datetime,error_field,error_message\n" || len(c.Errors) != 1 || !c.IsAborted() {
+		t.Fatalf("unexpected body %q, errors %v", w.Body.String(), c.Errors)
+	}
+}
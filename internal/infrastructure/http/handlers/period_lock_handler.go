@@ -0,0 +1,95 @@
+package handlers
+
+import (
+	"net/http"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/infrastructure/http/responses"
+	"stori-challenge/internal/ports/services"
+	"stori-challenge/internal/shared"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PeriodLockHandler struct {
+	Service services.PeriodLockService
+}
+
+func NewPeriodLockHandler(svc services.PeriodLockService) *PeriodLockHandler {
+	return &PeriodLockHandler{Service: svc}
+}
+
+// periodLockRequest is the body of PUT /period-locks/:period.
+type periodLockRequest struct {
+	LockedBy string `json:"locked_by"`
+	Reason   string `json:"reason"`
+}
+
+// PutPeriodLock
+// @Summary      Lock a period
+// @Description  Closes a calendar month (UTC): migration batches with a transaction dated in it can no longer be reverted
+// @Tags         period-locks
+// @Accept       json
+// @Produce      json
+// @Param        period  path      string  true  "Month as YYYY-MM"
+// @Success      200  {object}  responses.PeriodLockResponse
+// @Failure      400  {object}  responses.ErrorEnvelope
+// @Router       /period-locks/{period} [put]
+func (h *PeriodLockHandler) PutPeriodLock(c *gin.Context) {
+	var req periodLockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		CreateErrorResponse(c, shared.NewBadRequest("invalid_body", "body must be a JSON object with locked_by and reason", err), nil)
+		return
+	}
+
+	l, svcErr := h.Service.Lock(c.Request.Context(), domain.PeriodLock{Period: c.Param("period"), LockedBy: req.LockedBy, Reason: req.Reason})
+	if svcErr != nil {
+		CreateErrorResponse(c, svcErr, nil)
+		return
+	}
+	c.JSON(http.StatusOK, toPeriodLockResponse(l))
+}
+
+// ListPeriodLocks
+// @Summary      List locked periods
+// @Tags         period-locks
+// @Produce      json
+// @Success      200  {object}  responses.PeriodLockListResponse
+// @Router       /period-locks [get]
+func (h *PeriodLockHandler) ListPeriodLocks(c *gin.Context) {
+	list, svcErr := h.Service.List(c.Request.Context())
+	if svcErr != nil {
+		CreateErrorResponse(c, svcErr, nil)
+		return
+	}
+	out := responses.PeriodLockListResponse{Locks: make([]responses.PeriodLockResponse, 0, len(list))}
+	for _, l := range list {
+		out.Locks = append(out.Locks, toPeriodLockResponse(l))
+	}
+	c.JSON(http.StatusOK, out)
+}
+
+// DeletePeriodLock
+// @Summary      Unlock a period
+// @Tags         period-locks
+// @Param        period  path      string  true  "Month as YYYY-MM"
+// @Success      204
+// @Failure      400  {object}  responses.ErrorEnvelope
+// @Failure      404  {object}  responses.ErrorEnvelope
+// @Router       /period-locks/{period} [delete]
+func (h *PeriodLockHandler) DeletePeriodLock(c *gin.Context) {
+	if svcErr := h.Service.Unlock(c.Request.Context(), c.Param("period")); svcErr != nil {
+		CreateErrorResponse(c, svcErr, nil)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+func toPeriodLockResponse(l domain.PeriodLock) responses.PeriodLockResponse {
+	return responses.PeriodLockResponse{
+		Period:    l.Period,
+		LockedBy:  l.LockedBy,
+		Reason:    l.Reason,
+		CreatedAt: l.CreatedAt,
+	}
+}
@@ -0,0 +1,78 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/infrastructure/http/responses"
+	"stori-challenge/internal/shared"
+
+	"github.com/gin-gonic/gin"
+)
+
+type mockPeriodLockService struct {
+	LockFn   func(ctx context.Context, l domain.PeriodLock) (domain.PeriodLock, error)
+	ListFn   func(ctx context.Context) ([]domain.PeriodLock, error)
+	UnlockFn func(ctx context.Context, period string) error
+}
+
+func (m *mockPeriodLockService) Lock(ctx context.Context, l domain.PeriodLock) (domain.PeriodLock, error) {
+	return m.LockFn(ctx, l)
+}
+
+func (m *mockPeriodLockService) List(ctx context.Context) ([]domain.PeriodLock, error) {
+	return m.ListFn(ctx)
+}
+
+func (m *mockPeriodLockService) Unlock(ctx context.Context, period string) error {
+	return m.UnlockFn(ctx, period)
+}
+
+func TestPutPeriodLock_Success_Returns200(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodPut, "/period-locks/2024-06", strings.NewReader(`{"locked_by":"ana","reason":"June closed"}`))
+	c.Request.Header.Set("Content-Type", "application/json")
+	c.Params = gin.Params{{Key: "period", Value: "2024-06"}}
+
+	h := &PeriodLockHandler{Service: &mockPeriodLockService{
+		LockFn: func(ctx context.Context, l domain.PeriodLock) (domain.PeriodLock, error) {
+			return l, nil
+		},
+	}}
+	h.PutPeriodLock(c)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status want 200 got %d", w.Code)
+	}
+	var resp responses.PeriodLockResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if resp.Period != "2024-06" || resp.LockedBy != "ana" || resp.Reason != "June closed" {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+}
+
+func TestDeletePeriodLock_NotLocked_Returns404(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = httptest.NewRequest(http.MethodDelete, "/period-locks/2024-06", nil)
+	c.Params = gin.Params{{Key: "period", Value: "2024-06"}}
+
+	h := &PeriodLockHandler{Service: &mockPeriodLockService{
+		UnlockFn: func(ctx context.Context, period string) error {
+			return shared.NewNotFound("period_lock_not_found", "period is not locked", nil)
+		},
+	}}
+	h.DeletePeriodLock(c)
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("status want 404 got %d", w.Code)
+	}
+}
@@ -0,0 +1,203 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/infrastructure/http/responses"
+	"stori-challenge/internal/infrastructure/http/validators"
+	"stori-challenge/internal/ports/services"
+	"stori-challenge/internal/shared"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Headers of the resumable upload protocol, modelled on tus (https://tus.io).
+const (
+	UploadLengthHeader   = "Upload-Length"
+	UploadOffsetHeader   = "Upload-Offset"
+	UploadMetadataHeader = "Upload-Metadata"
+	UploadChecksumHeader = "Upload-Checksum"
+	UploadExpiresHeader  = "Upload-Expires"
+)
+
+// ChunkContentType is the Content-Type of a chunk sent with PATCH.
+const ChunkContentType = "application/offset+octet-stream"
+
+type UploadHandler struct {
+	Service services.UploadService
+}
+
+func NewUploadHandler(svc services.UploadService) *UploadHandler {
+	return &UploadHandler{Service: svc}
+}
+
+// PostUpload
+// @Summary      Start a resumable upload
+// @Description  Creates an empty upload of a migration file that is then sent in chunks with PATCH and finalized into an asynchronous migration
+// @Tags         uploads
+// @Produce      json
+// @Param        Upload-Length    header    int     true   "size of the whole file in bytes (up to 5GB)"
+// @Param        Upload-Metadata  header    string  true   "comma-separated key and base64 value pairs; filename is required, filetype is the optional Content-Type of the file"
+// @Success      201  {object}  responses.UploadResponse
+// @Failure      400  {object}  responses.ErrorEnvelope
+// @Router       /uploads [post]
+func (h *UploadHandler) PostUpload(c *gin.Context) {
+	length, appErr := validators.ParseUploadLength(c.GetHeader(UploadLengthHeader))
+	if appErr != nil {
+		CreateErrorResponse(c, appErr, nil)
+		return
+	}
+	meta, appErr := validators.ParseUploadMetadata(c.GetHeader(UploadMetadataHeader))
+	if appErr != nil {
+		CreateErrorResponse(c, appErr, nil)
+		return
+	}
+	fileName := meta["filename"]
+	if appErr := validators.ValidateFileMetaWithLimit(fileName, length, validators.MaxAsyncUploadSize); appErr != nil {
+		CreateErrorResponse(c, appErr, []responses.MigrateRowError{{
+			Row: 0, Field: "file", Value: fileName, Message: appErr.Msg,
+		}})
+		return
+	}
+
+	u, svcErr := h.Service.Create(c.Request.Context(), fileName, meta["filetype"], length)
+	if svcErr != nil {
+		CreateErrorResponse(c, svcErr, nil)
+		return
+	}
+	c.Header("Location", "/v1/uploads/"+u.ID)
+	setUploadHeaders(c, u)
+	c.JSON(http.StatusCreated, toUploadResponse(u))
+}
+
+// HeadUpload
+// @Summary      Get the offset of a resumable upload
+// @Description  Returns how many bytes were received in the Upload-Offset header, to resume after a disconnect
+// @Tags         uploads
+// @Param        id   path      string  true  "Upload ID"
+// @Success      200
+// @Failure      404
+// @Router       /uploads/{id} [head]
+func (h *UploadHandler) HeadUpload(c *gin.Context) {
+	u, svcErr := h.Service.Get(c.Request.Context(), c.Param("id"))
+	if svcErr != nil {
+		CreateErrorResponse(c, svcErr, nil)
+		return
+	}
+	setUploadHeaders(c, u)
+	c.Header("Cache-Control", "no-store")
+	c.Status(http.StatusOK)
+}
+
+// PatchUpload
+// @Summary      Send a chunk of a resumable upload
+// @Description  Appends the request body at Upload-Offset, which must be the number of bytes received so far. A chunk is kept whole or not at all
+// @Tags         uploads
+// @Accept       application/offset+octet-stream
+// @Param        id               path      string  true   "Upload ID"
+// @Param        Upload-Offset    header    int     true   "offset of the chunk in the file"
+// @Param        Upload-Checksum  header    string  false  "algorithm (md5, sha1 or sha256) and base64 digest of the chunk"
+// @Success      204
+// @Failure      400  {object}  responses.ErrorEnvelope
+// @Failure      404  {object}  responses.ErrorEnvelope
+// @Failure      409  {object}  responses.ErrorEnvelope
+// @Router       /uploads/{id} [patch]
+func (h *UploadHandler) PatchUpload(c *gin.Context) {
+	if c.ContentType() != ChunkContentType {
+		CreateErrorResponse(c, shared.NewBadRequest("invalid_content_type", "chunks must be sent as "+ChunkContentType, nil), nil)
+		return
+	}
+	offset, appErr := validators.ParseUploadOffset(c.GetHeader(UploadOffsetHeader))
+	if appErr != nil {
+		CreateErrorResponse(c, appErr, nil)
+		return
+	}
+	checksum, appErr := validators.ParseUploadChecksum(c.GetHeader(UploadChecksumHeader))
+	if appErr != nil {
+		CreateErrorResponse(c, appErr, nil)
+		return
+	}
+
+	u, svcErr := h.Service.AppendChunk(c.Request.Context(), c.Param("id"), offset, c.Request.Body, checksum)
+	if svcErr != nil {
+		CreateErrorResponse(c, svcErr, nil)
+		return
+	}
+	setUploadHeaders(c, u)
+	c.Status(http.StatusNoContent)
+}
+
+// PostFinalizeUpload
+// @Summary      Finalize a resumable upload
+// @Description  Enqueues the complete file as an asynchronous migration, like POST /migrate-async, and discards the upload
+// @Tags         uploads
+// @Produce      json
+// @Param        id               path      string  true   "Upload ID"
+// @Param        mode             query     string  false  "strict (default) or partial"
+// @Param        idempotent       query     bool    false  "skip rows already stored with the same content"
+// @Param        profile          query     string  false  "name of a saved header mapping profile"
+// @Param        datetime_format  query     string  false  "rfc3339 (default), local, date, unix, unix_ms or auto"
+// @Param        timezone         query     string  false  "IANA time zone of datetimes without an offset (default UTC)"
+// @Param        locale           query     string  false  "locale of amounts, e.g. es-ES for 1.234,56 (default plain decimals)"
+// @Param        sheet            query     string  false  "worksheet name or 1-based index of an .xlsx file (default first sheet)"
+// @Param        user_id          query     int     false  "owner of every transaction of a bank statement upload (default: account mapping)"
+// @Param        encoding         query     string  false  "character set of text uploads: utf-8, utf-16le, utf-16be, windows-1252, iso-8859-1 (default: detected)"
+// @Param        delimiter        query     string  false  "CSV field delimiter: , ; | or tab (default: detected)"
+// @Param        max_errors       query     int     false  "row errors listed in detail, 1-10000 (default 1000); all of them are counted in the summary"
+// @Param        overdraft_floor  query     string  false  "reject the file if replaying it with each user's history takes a balance below this amount"
+// @Param        unknown_users    query     string  false  "rows of unregistered users: create registers them (default), reject rejects the rows"
+// @Param        amount_rounding  query     string  false  "amounts with more than 2 decimals: reject (default), half_even or half_up"
+// @Param        X-Uploaded-By    header    string  false  "uploader recorded on the migration batch"
+// @Success      202  {object}  responses.MigrateAsyncAcceptedResponse
+// @Failure      400  {object}  responses.ErrorEnvelope
+// @Failure      404  {object}  responses.ErrorEnvelope
+// @Failure      409  {object}  responses.ErrorEnvelope
+// @Router       /uploads/{id}/finalize [post]
+func (h *UploadHandler) PostFinalizeUpload(c *gin.Context) {
+	u, svcErr := h.Service.Get(c.Request.Context(), c.Param("id"))
+	if svcErr != nil {
+		CreateErrorResponse(c, svcErr, nil)
+		return
+	}
+	opts, ok := parseFileMigrationOptions(c, u.FileName, u.ContentType)
+	if !ok {
+		return
+	}
+
+	m, svcErr := h.Service.Finalize(c.Request.Context(), u.ID, opts)
+	if svcErr != nil {
+		CreateErrorResponse(c, svcErr, nil)
+		return
+	}
+	c.Header("Location", "/v1/migrations/"+strconv.FormatInt(m.ID, 10))
+	c.JSON(http.StatusAccepted, responses.MigrateAsyncAcceptedResponse{ID: m.ID, Status: string(m.Status)})
+}
+
+// DeleteUpload
+// @Summary      Cancel a resumable upload
+// @Description  Discards the upload and the bytes received
+// @Tags         uploads
+// @Param        id   path      string  true  "Upload ID"
+// @Success      204
+// @Failure      404  {object}  responses.ErrorEnvelope
+// @Router       /uploads/{id} [delete]
+func (h *UploadHandler) DeleteUpload(c *gin.Context) {
+	if svcErr := h.Service.Delete(c.Request.Context(), c.Param("id")); svcErr != nil {
+		CreateErrorResponse(c, svcErr, nil)
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// setUploadHeaders reports the state of u in the Upload-* headers.
+func setUploadHeaders(c *gin.Context, u domain.Upload) {
+	c.Header(UploadOffsetHeader, strconv.FormatInt(u.Offset, 10))
+	c.Header(UploadLengthHeader, strconv.FormatInt(u.Length, 10))
+	c.Header(UploadExpiresHeader, u.ExpiresAt.UTC().Format(http.TimeFormat))
+}
+
+func toUploadResponse(u domain.Upload) responses.UploadResponse {
+	return responses.UploadResponse{ID: u.ID, FileName: u.FileName, Length: u.Length, Offset: u.Offset, ExpiresAt: u.ExpiresAt}
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"context"
+	"encoding/base64"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/shared"
+
+	"github.com/gin-gonic/gin"
+)
+
+type mockUploadService struct {
+	CreateFn   func(ctx context.Context, fileName, contentType string, length int64) (domain.Upload, error)
+	GetFn      func(ctx context.Context, id string) (domain.Upload, error)
+	AppendFn   func(ctx context.Context, id string, offset int64, r io.Reader, checksum *domain.Checksum) (domain.Upload, error)
+	FinalizeFn func(ctx context.Context, id string, opts domain.MigrationOptions) (domain.Migration, error)
+}
+
+func (m *mockUploadService) Create(ctx context.Context, fileName, contentType string, length int64) (domain.Upload, error) {
+	return m.CreateFn(ctx, fileName, contentType, length)
+}
+
+func (m *mockUploadService) Get(ctx context.Context, id string) (domain.Upload, error) {
+	return m.GetFn(ctx, id)
+}
+
+func (m *mockUploadService) AppendChunk(ctx context.Context, id string, offset int64, r io.Reader, checksum *domain.Checksum) (domain.Upload, error) {
+	return m.AppendFn(ctx, id, offset, r, checksum)
+}
+
+func (m *mockUploadService) Finalize(ctx context.Context, id string, opts domain.MigrationOptions) (domain.Migration, error) {
+	return m.FinalizeFn(ctx, id, opts)
+}
+
+func (m *mockUploadService) Delete(ctx context.Context, id string) error { return nil }
+
+func (m *mockUploadService) PurgeExpired(ctx context.Context) (int, error) { return 0, nil }
+
+var uploadExpiry = time.Date(2024, 7, 2, 0, 0, 0, 0, time.UTC)
+
+func TestPostUpload_Returns201WithLocation(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	req := httptest.NewRequest(http.MethodPost, "/uploads", nil)
+	req.Header.Set("Upload-Length", "2048")
+	req.Header.Set("Upload-Metadata", "filename "+base64.StdEncoding.EncodeToString([]byte("big.csv.gz")))
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	h := &UploadHandler{Service: &mockUploadService{
+		CreateFn: func(ctx context.Context, fileName, contentType string, length int64) (domain.Upload, error) {
+			if fileName != "big.csv.gz" || length != 2048 {
+				t.Fatalf("unexpected create: %q %d", fileName, length)
+			}
+			return domain.Upload{ID: "abc", FileName: fileName, Length: length, ExpiresAt: uploadExpiry}, nil
+		},
+	}}
+	h.PostUpload(c)
+	if w.Code != http.StatusCreated {
+		t.Fatalf("status want 201 got %d; body=%s", w.Code, w.Body.String())
+	}
+	if w.Header().Get("Location") != "/v1/uploads/abc" || w.Header().Get("Upload-Offset") != "0" || w.Header().Get("Upload-Expires") != "Tue, 02 Jul 2024 00:00:00 GMT" {
+		t.Fatalf("unexpected headers: %v", w.Header())
+	}
+}
+
+func TestPostUpload_WrongExtension_Returns400(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	req := httptest.NewRequest(http.MethodPost, "/uploads", nil)
+	req.Header.Set("Upload-Length", "10")
+	req.Header.Set("Upload-Metadata", "filename "+base64.StdEncoding.EncodeToString([]byte("data.txt")))
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	(&UploadHandler{Service: &mockUploadService{}}).PostUpload(c)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "wrong_extension") {
+		t.Fatalf("expected 400 wrong_extension, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestPatchUpload_Returns204WithOffset(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	req := httptest.NewRequest(http.MethodPatch, "/uploads/abc", strings.NewReader("chunk"))
+	req.Header.Set("Content-Type", ChunkContentType)
+	req.Header.Set("Upload-Offset", "5")
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "id", Value: "abc"}}
+
+	h := &UploadHandler{Service: &mockUploadService{
+		AppendFn: func(ctx context.Context, id string, offset int64, r io.Reader, checksum *domain.Checksum) (domain.Upload, error) {
+			b, _ := io.ReadAll(r)
+			if id != "abc" || offset != 5 || string(b) != "chunk" || checksum != nil {
+				t.Fatalf("unexpected append: %s %d %q %v", id, offset, b, checksum)
+			}
+			return domain.Upload{ID: id, Length: 20, Offset: 10, ExpiresAt: uploadExpiry}, nil
+		},
+	}}
+	h.PatchUpload(c)
+	if c.Writer.Status() != http.StatusNoContent || w.Header().Get("Upload-Offset") != "10" {
+		t.Fatalf("expected 204 with offset 10, got %d %v", c.Writer.Status(), w.Header())
+	}
+}
+
+func TestPatchUpload_OffsetConflict_Returns409(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	req := httptest.NewRequest(http.MethodPatch, "/uploads/abc", strings.NewReader("chunk"))
+	req.Header.Set("Content-Type", ChunkContentType)
+	req.Header.Set("Upload-Offset", "0")
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	h := &UploadHandler{Service: &mockUploadService{
+		AppendFn: func(ctx context.Context, id string, offset int64, r io.Reader, checksum *domain.Checksum) (domain.Upload, error) {
+			return domain.Upload{}, shared.NewConflict("upload_offset_mismatch", "upload offset is 5", nil)
+		},
+	}}
+	h.PatchUpload(c)
+	if w.Code != http.StatusConflict {
+		t.Fatalf("status want 409 got %d", w.Code)
+	}
+}
+
+func TestPatchUpload_WrongContentType_Returns400(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	req := httptest.NewRequest(http.MethodPatch, "/uploads/abc", strings.NewReader("chunk"))
+	req.Header.Set("Content-Type", "text/csv")
+	req.Header.Set("Upload-Offset", "0")
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+
+	(&UploadHandler{Service: &mockUploadService{}}).PatchUpload(c)
+	if w.Code != http.StatusBadRequest || !strings.Contains(w.Body.String(), "invalid_content_type") {
+		t.Fatalf("expected 400 invalid_content_type, got %d %s", w.Code, w.Body.String())
+	}
+}
+
+func TestPostFinalizeUpload_Returns202(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	req := httptest.NewRequest(http.MethodPost, "/uploads/abc/finalize?mode=partial", nil)
+	w := httptest.NewRecorder()
+	c, _ := gin.CreateTestContext(w)
+	c.Request = req
+	c.Params = gin.Params{{Key: "id", Value: "abc"}}
+
+	h := &UploadHandler{Service: &mockUploadService{
+		GetFn: func(ctx context.Context, id string) (domain.Upload, error) {
+			return domain.Upload{ID: id, FileName: "data.jsonl", Length: 4, Offset: 4}, nil
+		},
+		FinalizeFn: func(ctx context.Context, id string, opts domain.MigrationOptions) (domain.Migration, error) {
+			if opts.Mode != domain.MigrationModePartial || opts.Format != domain.InputFormatJSONL || opts.Source.FileName != "data.jsonl" {
+				t.Fatalf("unexpected options: %+v", opts)
+			}
+			return domain.Migration{ID: 9, Status: domain.MigrationStatusPending}, nil
+		},
+	}}
+	h.PostFinalizeUpload(c)
+	if w.Code != http.StatusAccepted || w.Header().Get("Location") != "/v1/migrations/9" {
+		t.Fatalf("expected 202 with location, got %d %v", w.Code, w.Header())
+	}
+}
@@ -0,0 +1,154 @@
+package handlers
+
+import (
+	"mime/multipart"
+	"strings"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/infrastructure/http/responses"
+	"stori-challenge/internal/infrastructure/http/validators"
+	"stori-challenge/internal/ports/services"
+	"stori-challenge/internal/shared"
+
+	"github.com/gin-gonic/gin"
+	"github.com/shopspring/decimal"
+)
+
+// UploadedByHeader names the uploader recorded on the migration batch of an upload.
+const UploadedByHeader = "X-Uploaded-By"
+
+// openUploadedFile reads the "file" form field, validates its metadata against maxSize and opens it.
+// On failure it writes the error response and returns ok=false; callers must close the file otherwise.
+func openUploadedFile(c *gin.Context, maxSize int64) (multipart.File, *multipart.FileHeader, bool) {
+	fileHeader, err := c.FormFile("file")
+	if err != nil {
+		CreateErrorResponse(c,
+			shared.NewBadRequest("missing_file", "file is required", nil),
+			[]responses.MigrateRowError{{
+				Row: 0, Field: "file", Value: "", Message: "file is required",
+			}},
+		)
+		return nil, nil, false
+	}
+	if appErr := validators.ValidateFileMetaWithLimit(fileHeader.Filename, fileHeader.Size, maxSize); appErr != nil {
+		CreateErrorResponse(c,
+			appErr,
+			[]responses.MigrateRowError{{
+				Row: 0, Field: "file", Value: fileHeader.Filename, Message: appErr.Msg,
+			}},
+		)
+		return nil, nil, false
+	}
+
+	f, err := fileHeader.Open()
+	if err != nil {
+		CreateErrorResponse(c,
+			shared.NewBadRequest("file_open_failed", "unable to open uploaded file", nil),
+			[]responses.MigrateRowError{{
+				Row: 0, Field: "file", Value: fileHeader.Filename, Message: "unable to open uploaded file",
+			}},
+		)
+		return nil, nil, false
+	}
+	return f, fileHeader, true
+}
+
+// parseMigrationOptions reads migration options from the query string or, failing that, the form fields.
+// The input format comes from the uploaded file's Content-Type or extension.
+// On failure it writes the error response and returns ok=false.
+func parseMigrationOptions(c *gin.Context, fileHeader *multipart.FileHeader) (domain.MigrationOptions, bool) {
+	return parseFileMigrationOptions(c, fileHeader.Filename, fileHeader.Header.Get("Content-Type"))
+}
+
+// parseFileMigrationOptions is parseMigrationOptions for a file received some other way than
+// a multipart form, such as a finalized resumable upload.
+func parseFileMigrationOptions(c *gin.Context, fileName, contentType string) (domain.MigrationOptions, bool) {
+	opts, appErr := validators.ParseMigrationOptions(validators.MigrationParams{
+		FileName:       fileName,
+		ContentType:    contentType,
+		Mode:           queryOrForm(c, "mode"),
+		Idempotent:     queryOrForm(c, "idempotent"),
+		Profile:        queryOrForm(c, "profile"),
+		DatetimeFormat: queryOrForm(c, "datetime_format"),
+		Timezone:       queryOrForm(c, "timezone"),
+		Locale:         queryOrForm(c, "locale"),
+		Sheet:          queryOrForm(c, "sheet"),
+		UserID:         queryOrForm(c, "user_id"),
+		Encoding:       queryOrForm(c, "encoding"),
+		Delimiter:      queryOrForm(c, "delimiter"),
+		MaxErrors:      queryOrForm(c, "max_errors"),
+		OverdraftFloor: queryOrForm(c, "overdraft_floor"),
+		UnknownUsers:   queryOrForm(c, "unknown_users"),
+		AmountRounding: queryOrForm(c, "amount_rounding"),
+	})
+	if appErr != nil {
+		CreateErrorResponse(c, appErr, nil)
+		return domain.MigrationOptions{}, false
+	}
+	opts.Source = domain.UploadSource{
+		FileName:   fileName,
+		UploadedBy: strings.TrimSpace(c.GetHeader(UploadedByHeader)),
+		ClientIP:   c.ClientIP(),
+	}
+	return opts, true
+}
+
+func queryOrForm(c *gin.Context, key string) string {
+	if v, ok := c.GetQuery(key); ok {
+		return v
+	}
+	return c.PostForm(key)
+}
+
+// toMigrateRowErrors maps []services.RowError -> []responses.MigrateRowError for details.
+func toMigrateRowErrors(items []services.RowError) []responses.MigrateRowError {
+	out := make([]responses.MigrateRowError, 0, len(items))
+	for _, it := range items {
+		out = append(out, responses.MigrateRowError{
+			File:     it.File,
+			Sheet:    it.Sheet,
+			Path:     it.Path,
+			Row:      it.Row,
+			Field:    it.Field,
+			Value:    it.Value,
+			Message:  it.Message,
+			Rule:     it.Rule,
+			Severity: string(it.Severity),
+		})
+	}
+	return out
+}
+
+// toRowErrorSummary maps the grouped row errors; nil when there are none.
+func toRowErrorSummary(s *services.RowErrorSummary) *responses.RowErrorSummary {
+	if s == nil {
+		return nil
+	}
+	groups := make([]responses.RowErrorGroup, 0, len(s.Groups))
+	for _, g := range s.Groups {
+		groups = append(groups, responses.RowErrorGroup{
+			File:       g.File,
+			Field:      g.Field,
+			Message:    g.Message,
+			Count:      g.Count,
+			SampleRows: g.SampleRows,
+		})
+	}
+	return &responses.RowErrorSummary{Total: s.Total, Omitted: s.Omitted, Groups: groups, Ungrouped: s.Ungrouped}
+}
+
+// toCSVDialect maps the dialect a CSV upload was read with; nil for the other formats.
+func toCSVDialect(d *domain.CSVDialect) *responses.CSVDialect {
+	if d == nil {
+		return nil
+	}
+	return &responses.CSVDialect{Delimiter: d.Delimiter, DecimalSeparator: d.DecimalSeparator}
+}
+
+// decimalString formats an optional amount; empty when it is not set.
+func decimalString(d *decimal.Decimal) string {
+	if d == nil {
+		return ""
+	}
+	return d.String()
+}
@@ -13,6 +13,12 @@ import (
 // CreateErrorResponse maps an application error to an HTTP response using a generic envelope.
 // It uses the AppError code/message when available; otherwise falls back to a generic 500.
 func CreateErrorResponse(c *gin.Context, err error, details interface{}) {
+	CreateErrorResponseWithSummary(c, err, details, nil)
+}
+
+// CreateErrorResponseWithSummary is CreateErrorResponse for migrations, whose envelope also
+// groups the row errors listed in details.
+func CreateErrorResponseWithSummary(c *gin.Context, err error, details interface{}, summary *responses.RowErrorSummary) {
 	var ae *shared.AppError
 	if errors.As(err, &ae) {
 		status := http.StatusInternalServerError
@@ -31,6 +37,7 @@ func CreateErrorResponse(c *gin.Context, err error, details interface{}) {
 				Code:    ae.Code,
 				Message: ae.Msg,
 				Details: details,
+				Summary: summary,
 			},
 		})
 		return
@@ -42,6 +49,7 @@ func CreateErrorResponse(c *gin.Context, err error, details interface{}) {
 			Code:    "internal_error",
 			Message: "internal error",
 			Details: details,
+			Summary: summary,
 		},
 	})
 }
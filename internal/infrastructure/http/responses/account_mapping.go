@@ -0,0 +1,16 @@
+package responses
+
+import "time"
+
+// AccountMappingResponse is the payload for a single statement account mapping.
+type AccountMappingResponse struct {
+	AccountID string    `json:"account_id"`
+	UserID    int64     `json:"user_id"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// AccountMappingListResponse is the success payload for GET /account-mappings.
+type AccountMappingListResponse struct {
+	Mappings []AccountMappingResponse `json:"mappings"`
+}
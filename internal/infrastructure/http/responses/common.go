@@ -10,4 +10,6 @@ type ErrorBody struct {
 	Code    string      `json:"code"`
 	Message string      `json:"message"`
 	Details interface{} `json:"details,omitempty"`
+	// Summary groups the row errors of a migration, including the ones left out of Details.
+	Summary *RowErrorSummary `json:"summary,omitempty"`
 }
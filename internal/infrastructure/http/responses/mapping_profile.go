@@ -0,0 +1,16 @@
+package responses
+
+import "time"
+
+// MappingProfileResponse is the payload for a single header mapping profile.
+type MappingProfileResponse struct {
+	Name      string              `json:"name"`
+	Columns   map[string][]string `json:"columns"`
+	CreatedAt time.Time           `json:"created_at"`
+	UpdatedAt time.Time           `json:"updated_at"`
+}
+
+// MappingProfileListResponse is the success payload for GET /mapping-profiles.
+type MappingProfileListResponse struct {
+	Profiles []MappingProfileResponse `json:"profiles"`
+}
@@ -1,16 +1,95 @@
 package responses
 
+import "time"
+
 // MigrateSuccessResponse is the success payload for POST /migrate.
+// Rejected and Errors are only non-empty in partial mode, AlreadyPresent in idempotent mode.
 type MigrateSuccessResponse struct {
-	Inserted int `json:"inserted"`
+	Inserted       int               `json:"inserted"`
+	Rejected       int               `json:"rejected"`
+	AlreadyPresent int               `json:"already_present"`
+	Errors         []MigrateRowError `json:"errors,omitempty"`
+	ErrorSummary   *RowErrorSummary  `json:"error_summary,omitempty"`
+	// Warnings lists broken validation rules of warning severity; those rows were migrated.
+	Warnings []MigrateRowError `json:"warnings,omitempty"`
+	Dialect  *CSVDialect       `json:"dialect,omitempty"`
+	// BatchID is the migration batch recording the upload.
+	BatchID int64 `json:"batch_id,omitempty"`
+}
+
+// MigrateValidationResponse is the success payload for POST /migrate/validate.
+type MigrateValidationResponse struct {
+	Valid          bool              `json:"valid"`
+	Rows           int               `json:"rows"`
+	WouldInsert    int               `json:"would_insert"`
+	Rejected       int               `json:"rejected"`
+	AlreadyPresent int               `json:"already_present"`
+	Errors         []MigrateRowError `json:"errors"`
+	ErrorSummary   *RowErrorSummary  `json:"error_summary,omitempty"`
+	Warnings       []MigrateRowError `json:"warnings,omitempty"`
+	Stats          MigrateStats      `json:"stats"`
+	Dialect        *CSVDialect       `json:"dialect,omitempty"`
+}
+
+// CSVDialect is how a CSV upload was read.
+type CSVDialect struct {
+	Delimiter        string `json:"delimiter"`
+	DecimalSeparator string `json:"decimal_separator"`
+}
+
+// MigrateStats describes the rows that would be inserted.
+type MigrateStats struct {
+	Rows           int                 `json:"rows"`
+	DistinctUsers  int                 `json:"distinct_users"`
+	DatetimeFrom   *time.Time          `json:"datetime_from"`
+	DatetimeTo     *time.Time          `json:"datetime_to"`
+	TotalCredits   float64             `json:"total_credits"`
+	TotalDebits    float64             `json:"total_debits"`
+	BalanceChanges []UserBalanceChange `json:"balance_changes"`
+}
+
+// UserBalanceChange is the projected balance change for one user.
+type UserBalanceChange struct {
+	UserID int64   `json:"user_id"`
+	Change float64 `json:"change"`
 }
 
 // MigrateRowError is the HTTP DTO for row-level validation/conflict details.
 type MigrateRowError struct {
+	// File is set for rows of a .zip upload.
+	File string `json:"file,omitempty"`
+	// Sheet is set for rows of an .xlsx upload.
+	Sheet string `json:"sheet,omitempty"`
+	// Path is the XPath-style location of the element, for camt XML uploads.
+	Path    string `json:"path,omitempty"`
 	Row     int    `json:"row"`
 	Field   string `json:"field"`
 	Value   string `json:"value"`
 	Message string `json:"message"`
+	// Rule is the code of the configured validation rule that produced the error.
+	Rule string `json:"rule,omitempty"`
+	// Severity is "error" or "warning", for rule errors.
+	Severity string `json:"severity,omitempty"`
+}
+
+// RowErrorSummary groups every row error of a migration by file, field and message.
+type RowErrorSummary struct {
+	// Total is the number of row errors found.
+	Total int `json:"total"`
+	// Omitted is how many of them the detailed list leaves out (see max_errors).
+	Omitted int             `json:"omitted"`
+	Groups  []RowErrorGroup `json:"groups"`
+	// Ungrouped counts the errors of messages beyond the first 100 groups.
+	Ungrouped int `json:"ungrouped,omitempty"`
+}
+
+// RowErrorGroup counts the errors with the same file, field and message, with the first rows found.
+type RowErrorGroup struct {
+	File       string `json:"file,omitempty"`
+	Field      string `json:"field"`
+	Message    string `json:"message"`
+	Count      int    `json:"count"`
+	SampleRows []int  `json:"sample_rows"`
 }
 
 // MigrateErrorResponse is the error payload for POST /migrate.
@@ -0,0 +1,44 @@
+package responses
+
+import "time"
+
+// MigrateAsyncAcceptedResponse is the success payload for POST /migrate-async.
+type MigrateAsyncAcceptedResponse struct {
+	ID     int64  `json:"id"`
+	Status string `json:"status"`
+}
+
+// MigrationResponse is the success payload for GET /migrations/:id.
+type MigrationResponse struct {
+	ID             int64             `json:"id"`
+	Status         string            `json:"status"`
+	FileName       string            `json:"file_name"`
+	Format         string            `json:"format,omitempty"`
+	Compression    string            `json:"compression,omitempty"`
+	Mode           string            `json:"mode,omitempty"`
+	Idempotent     bool              `json:"idempotent"`
+	Profile        string            `json:"profile,omitempty"`
+	DatetimeFormat string            `json:"datetime_format,omitempty"`
+	Timezone       string            `json:"timezone,omitempty"`
+	Locale         string            `json:"locale,omitempty"`
+	Sheet          string            `json:"sheet,omitempty"`
+	UserID         int64             `json:"user_id,omitempty"`
+	Encoding       string            `json:"encoding,omitempty"`
+	Delimiter      string            `json:"delimiter,omitempty"`
+	MaxErrors      int               `json:"max_errors,omitempty"`
+	OverdraftFloor string            `json:"overdraft_floor,omitempty"`
+	UnknownUsers   string            `json:"unknown_users,omitempty"`
+	AmountRounding string            `json:"amount_rounding,omitempty"`
+	UploadedBy     string            `json:"uploaded_by,omitempty"`
+	Inserted       int               `json:"inserted"`
+	Rejected       int               `json:"rejected"`
+	AlreadyPresent int               `json:"already_present"`
+	ErrorCount     int               `json:"error_count"`
+	ErrorCode      string            `json:"error_code,omitempty"`
+	Errors         []MigrateRowError `json:"errors"`
+	ErrorSummary   *RowErrorSummary  `json:"error_summary,omitempty"`
+	Dialect        *CSVDialect       `json:"dialect,omitempty"`
+	CreatedAt      time.Time         `json:"created_at"`
+	StartedAt      *time.Time        `json:"started_at,omitempty"`
+	FinishedAt     *time.Time        `json:"finished_at,omitempty"`
+}
@@ -0,0 +1,44 @@
+package responses
+
+import "time"
+
+// MigrationBatchResponse is the success payload for GET /migration-batches/:id.
+type MigrationBatchResponse struct {
+	ID             int64      `json:"id"`
+	MigrationID    int64      `json:"migration_id,omitempty"`
+	FileName       string     `json:"file_name"`
+	SHA256         string     `json:"sha256,omitempty"`
+	SizeBytes      int64      `json:"size_bytes"`
+	UploadedBy     string     `json:"uploaded_by,omitempty"`
+	ClientIP       string     `json:"client_ip,omitempty"`
+	Status         string     `json:"status"`
+	Inserted       int        `json:"inserted"`
+	Rejected       int        `json:"rejected"`
+	AlreadyPresent int        `json:"already_present"`
+	ErrorCode      string     `json:"error_code,omitempty"`
+	AmountRounding string     `json:"amount_rounding,omitempty"`
+	StartedAt      time.Time  `json:"started_at"`
+	FinishedAt     *time.Time `json:"finished_at,omitempty"`
+	// Revert is set for REVERTED batches.
+	Revert *BatchRevertResponse `json:"revert,omitempty"`
+}
+
+// BatchRevertResponse is the audit record of a reverted batch, and the success payload of
+// POST /migration-batches/:id/revert and DELETE /migrations/:id.
+type BatchRevertResponse struct {
+	BatchID             int64     `json:"batch_id"`
+	RevertedBy          string    `json:"reverted_by"`
+	Reason              string    `json:"reason"`
+	TransactionsDeleted int       `json:"transactions_deleted"`
+	RevertedAt          time.Time `json:"reverted_at"`
+}
+
+// TransactionSourceResponse is the success payload for GET /transactions/:id/source.
+// File, Row and Batch are empty for transactions stored before batches were recorded.
+type TransactionSourceResponse struct {
+	TransactionID int64 `json:"transaction_id"`
+	// File is the member of a .zip upload the row came from; empty for other uploads.
+	File  string                  `json:"file,omitempty"`
+	Row   int                     `json:"row,omitempty"`
+	Batch *MigrationBatchResponse `json:"batch,omitempty"`
+}
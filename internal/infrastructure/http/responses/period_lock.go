@@ -0,0 +1,16 @@
+package responses
+
+import "time"
+
+// PeriodLockResponse is the payload for a single locked period.
+type PeriodLockResponse struct {
+	Period    string    `json:"period"`
+	LockedBy  string    `json:"locked_by"`
+	Reason    string    `json:"reason"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// PeriodLockListResponse is the success payload for GET /period-locks.
+type PeriodLockListResponse struct {
+	Locks []PeriodLockResponse `json:"locks"`
+}
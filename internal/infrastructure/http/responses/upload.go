@@ -0,0 +1,12 @@
+package responses
+
+import "time"
+
+// UploadResponse describes a resumable upload; its state is also sent in the Upload-* headers.
+type UploadResponse struct {
+	ID        string    `json:"id"`
+	FileName  string    `json:"file_name"`
+	Length    int64     `json:"length"`
+	Offset    int64     `json:"offset"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
@@ -0,0 +1,126 @@
+package validators
+
+import (
+	"strconv"
+	"strings"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/shared"
+
+	"github.com/shopspring/decimal"
+)
+
+// MigrationParams holds the raw migration options sent with an upload.
+type MigrationParams struct {
+	// FileName and ContentType describe the uploaded file and select its format and compression.
+	FileName       string
+	ContentType    string
+	Mode           string
+	Idempotent     string
+	Profile        string
+	DatetimeFormat string
+	Timezone       string
+	Locale         string
+	Sheet          string
+	UserID         string
+	Encoding       string
+	Delimiter      string
+	MaxErrors      string
+	OverdraftFloor string
+	UnknownUsers   string
+	AmountRounding string
+}
+
+// ParseMigrationOptions validates raw upload parameters and returns the migration options.
+// Empty values fall back to defaults (strict mode, RFC3339 datetimes in UTC).
+func ParseMigrationOptions(p MigrationParams) (domain.MigrationOptions, *shared.AppError) {
+	var opts domain.MigrationOptions
+	if p.FileName != "" || p.ContentType != "" {
+		opts.Format = DetectInputFormat(p.FileName, p.ContentType)
+		opts.Compression = DetectCompression(p.FileName, p.ContentType)
+	}
+	switch strings.ToLower(strings.TrimSpace(p.Mode)) {
+	case "", string(domain.MigrationModeStrict):
+		opts.Mode = domain.MigrationModeStrict
+	case string(domain.MigrationModePartial):
+		opts.Mode = domain.MigrationModePartial
+	default:
+		return domain.MigrationOptions{}, shared.NewBadRequest("invalid_mode", "mode must be strict or partial", nil)
+	}
+	if v := strings.TrimSpace(p.Idempotent); v != "" {
+		b, err := strconv.ParseBool(v)
+		if err != nil {
+			return domain.MigrationOptions{}, shared.NewBadRequest("invalid_idempotent", "idempotent must be true or false", err)
+		}
+		opts.Idempotent = b
+	}
+	opts.Profile = strings.TrimSpace(p.Profile)
+	if v := strings.ToLower(strings.TrimSpace(p.DatetimeFormat)); v != "" {
+		opts.DatetimeFormat = domain.DatetimeFormat(v)
+		if !opts.DatetimeFormat.IsValid() {
+			return domain.MigrationOptions{}, shared.NewBadRequest("invalid_datetime_format", "datetime_format must be one of rfc3339, local, date, unix, unix_ms or auto", nil)
+		}
+	}
+	if v := strings.TrimSpace(p.Timezone); v != "" {
+		if _, err := domain.LoadTimezone(v); err != nil {
+			return domain.MigrationOptions{}, shared.NewBadRequest("invalid_timezone", "timezone must be an IANA time zone such as America/Mexico_City", err)
+		}
+		opts.Timezone = v
+	}
+	if v := strings.TrimSpace(p.Locale); v != "" {
+		if _, ok := domain.LookupNumberFormat(v); !ok {
+			return domain.MigrationOptions{}, shared.NewBadRequest("invalid_locale", "locale must be a supported language tag such as en-US, es-MX or es-ES", nil)
+		}
+		opts.Locale = v
+	}
+	if v := strings.TrimSpace(p.Encoding); v != "" {
+		enc, ok := domain.ParseEncoding(v)
+		if !ok {
+			return domain.MigrationOptions{}, shared.NewBadRequest("invalid_encoding", "encoding must be one of utf-8, utf-16le, utf-16be, windows-1252 or iso-8859-1", nil)
+		}
+		opts.Encoding = enc
+	}
+	// A tab is whitespace, so the delimiter is not trimmed.
+	if p.Delimiter != "" {
+		d, ok := domain.ParseDelimiter(p.Delimiter)
+		if !ok {
+			return domain.MigrationOptions{}, shared.NewBadRequest("invalid_delimiter", "delimiter must be one of , ; | or tab", nil)
+		}
+		opts.Delimiter = d
+	}
+	opts.Sheet = strings.TrimSpace(p.Sheet)
+	if v := strings.TrimSpace(p.UserID); v != "" {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			return domain.MigrationOptions{}, shared.NewBadRequest("invalid_user_id", "user_id must be a positive integer", err)
+		}
+		opts.UserID = id
+	}
+	if v := strings.TrimSpace(p.MaxErrors); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > domain.MaxErrorsLimit {
+			return domain.MigrationOptions{}, shared.NewBadRequest("invalid_max_errors", "max_errors must be an integer between 1 and "+strconv.Itoa(domain.MaxErrorsLimit), err)
+		}
+		opts.MaxErrors = n
+	}
+	if v := strings.TrimSpace(p.OverdraftFloor); v != "" {
+		floor, err := decimal.NewFromString(v)
+		if err != nil {
+			return domain.MigrationOptions{}, shared.NewBadRequest("invalid_overdraft_floor", "overdraft_floor must be a decimal amount such as 0 or -500.00", err)
+		}
+		opts.OverdraftFloor = &floor
+	}
+	if v := strings.ToLower(strings.TrimSpace(p.UnknownUsers)); v != "" {
+		opts.UnknownUsers = domain.UnknownUserPolicy(v)
+		if !opts.UnknownUsers.IsValid() {
+			return domain.MigrationOptions{}, shared.NewBadRequest("invalid_unknown_users", "unknown_users must be create or reject", nil)
+		}
+	}
+	if v := strings.ToLower(strings.TrimSpace(p.AmountRounding)); v != "" {
+		opts.AmountRounding = domain.AmountRounding(v)
+		if !opts.AmountRounding.IsValid() {
+			return domain.MigrationOptions{}, shared.NewBadRequest("invalid_amount_rounding", "amount_rounding must be reject, half_even or half_up", nil)
+		}
+	}
+	return opts, nil
+}
@@ -0,0 +1,169 @@
+package validators
+
+import (
+	"testing"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/shared"
+)
+
+func TestParseMigrationOptions_DefaultsToStrict(t *testing.T) {
+	opts, err := ParseMigrationOptions(MigrationParams{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if opts.Mode != domain.MigrationModeStrict {
+		t.Fatalf("expected strict, got %q", opts.Mode)
+	}
+}
+
+func TestParseMigrationOptions_Partial_CaseInsensitive(t *testing.T) {
+	opts, err := ParseMigrationOptions(MigrationParams{Mode: " Partial "})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !opts.IsPartial() {
+		t.Fatalf("expected partial, got %q", opts.Mode)
+	}
+}
+
+func TestParseMigrationOptions_UnknownMode_ReturnsInvalidMode(t *testing.T) {
+	_, err := ParseMigrationOptions(MigrationParams{Mode: "lenient"})
+	if err == nil {
+		t.Fatalf("expected error")
+	}
+	if err.Kind != shared.BadRequestKind || err.Code != "invalid_mode" {
+		t.Fatalf("unexpected app error: kind=%s code=%s", err.Kind, err.Code)
+	}
+}
+
+func TestParseMigrationOptions_Idempotent(t *testing.T) {
+	opts, err := ParseMigrationOptions(MigrationParams{Mode: "partial", Idempotent: "true"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !opts.Idempotent || !opts.IsPartial() {
+		t.Fatalf("unexpected options: %+v", opts)
+	}
+	_, err = ParseMigrationOptions(MigrationParams{Idempotent: "maybe"})
+	if err == nil || err.Code != "invalid_idempotent" {
+		t.Fatalf("expected invalid_idempotent, got %v", err)
+	}
+}
+
+func TestParseMigrationOptions_DatetimeFormatAndTimezone(t *testing.T) {
+	opts, err := ParseMigrationOptions(MigrationParams{DatetimeFormat: "Local", Timezone: " America/Mexico_City "})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if opts.DatetimeFormat != domain.DatetimeFormatLocal || opts.Timezone != "America/Mexico_City" {
+		t.Fatalf("unexpected options: %+v", opts)
+	}
+	_, err = ParseMigrationOptions(MigrationParams{DatetimeFormat: "iso"})
+	if err == nil || err.Code != "invalid_datetime_format" {
+		t.Fatalf("expected invalid_datetime_format, got %v", err)
+	}
+	for _, tz := range []string{"Mexico City", "Local"} {
+		_, err = ParseMigrationOptions(MigrationParams{Timezone: tz})
+		if err == nil || err.Code != "invalid_timezone" {
+			t.Fatalf("expected invalid_timezone for %q, got %v", tz, err)
+		}
+	}
+}
+
+func TestParseMigrationOptions_Locale(t *testing.T) {
+	opts, err := ParseMigrationOptions(MigrationParams{Locale: "es-MX"})
+	if err != nil || opts.Locale != "es-MX" {
+		t.Fatalf("unexpected result: %+v, %v", opts, err)
+	}
+	_, err = ParseMigrationOptions(MigrationParams{Locale: "klingon"})
+	if err == nil || err.Code != "invalid_locale" {
+		t.Fatalf("expected invalid_locale, got %v", err)
+	}
+}
+
+func TestParseMigrationOptions_Encoding(t *testing.T) {
+	opts, err := ParseMigrationOptions(MigrationParams{Encoding: "CP1252"})
+	if err != nil || opts.Encoding != domain.EncodingWindows1252 {
+		t.Fatalf("unexpected result: %+v, %v", opts, err)
+	}
+	_, err = ParseMigrationOptions(MigrationParams{Encoding: "ebcdic"})
+	if err == nil || err.Code != "invalid_encoding" {
+		t.Fatalf("expected invalid_encoding, got %v", err)
+	}
+}
+
+func TestParseMigrationOptions_Delimiter(t *testing.T) {
+	for in, want := range map[string]string{";": ";", "\t": "\t", "Tab": "\t", "pipe": "|", " comma ": ","} {
+		opts, err := ParseMigrationOptions(MigrationParams{Delimiter: in})
+		if err != nil || opts.Delimiter != want {
+			t.Fatalf("%q: unexpected result: %+v, %v", in, opts, err)
+		}
+	}
+	_, err := ParseMigrationOptions(MigrationParams{Delimiter: ":"})
+	if err == nil || err.Code != "invalid_delimiter" {
+		t.Fatalf("expected invalid_delimiter, got %v", err)
+	}
+}
+
+func TestParseMigrationOptions_UserID(t *testing.T) {
+	opts, err := ParseMigrationOptions(MigrationParams{UserID: " 42 "})
+	if err != nil || opts.UserID != 42 {
+		t.Fatalf("unexpected result: %+v, %v", opts, err)
+	}
+	for _, v := range []string{"0", "-3", "abc"} {
+		_, err = ParseMigrationOptions(MigrationParams{UserID: v})
+		if err == nil || err.Code != "invalid_user_id" {
+			t.Fatalf("%q: expected invalid_user_id, got %v", v, err)
+		}
+	}
+}
+
+func TestParseMigrationOptions_MaxErrors(t *testing.T) {
+	opts, err := ParseMigrationOptions(MigrationParams{MaxErrors: " 50 "})
+	if err != nil || opts.MaxErrors != 50 {
+		t.Fatalf("unexpected result: %+v, %v", opts, err)
+	}
+	for _, v := range []string{"0", "10001", "many"} {
+		_, err = ParseMigrationOptions(MigrationParams{MaxErrors: v})
+		if err == nil || err.Code != "invalid_max_errors" {
+			t.Fatalf("%q: expected invalid_max_errors, got %v", v, err)
+		}
+	}
+}
+
+func TestParseMigrationOptions_OverdraftFloor(t *testing.T) {
+	opts, err := ParseMigrationOptions(MigrationParams{OverdraftFloor: " -500.50 "})
+	if err != nil || opts.OverdraftFloor == nil || opts.OverdraftFloor.String() != "-500.5" {
+		t.Fatalf("unexpected result: %+v, %v", opts, err)
+	}
+	if opts, _ = ParseMigrationOptions(MigrationParams{}); opts.OverdraftFloor != nil {
+		t.Fatalf("expected no floor by default, got %v", opts.OverdraftFloor)
+	}
+	_, err = ParseMigrationOptions(MigrationParams{OverdraftFloor: "zero"})
+	if err == nil || err.Code != "invalid_overdraft_floor" {
+		t.Fatalf("expected invalid_overdraft_floor, got %v", err)
+	}
+}
+
+func TestParseMigrationOptions_UnknownUsers(t *testing.T) {
+	opts, err := ParseMigrationOptions(MigrationParams{UnknownUsers: " Reject "})
+	if err != nil || opts.UnknownUsers != domain.UnknownUsersReject {
+		t.Fatalf("unexpected result: %+v, %v", opts, err)
+	}
+	_, err = ParseMigrationOptions(MigrationParams{UnknownUsers: "ignore"})
+	if err == nil || err.Code != "invalid_unknown_users" {
+		t.Fatalf("expected invalid_unknown_users, got %v", err)
+	}
+}
+
+func TestParseMigrationOptions_AmountRounding(t *testing.T) {
+	opts, err := ParseMigrationOptions(MigrationParams{AmountRounding: "HALF_EVEN"})
+	if err != nil || opts.AmountRounding != domain.AmountRoundingHalfEven {
+		t.Fatalf("unexpected result: %+v, %v", opts, err)
+	}
+	_, err = ParseMigrationOptions(MigrationParams{AmountRounding: "truncate"})
+	if err == nil || err.Code != "invalid_amount_rounding" {
+		t.Fatalf("expected invalid_amount_rounding, got %v", err)
+	}
+}
@@ -1,25 +1,117 @@
 package validators
 
 import (
+	"mime"
 	"path/filepath"
+	"stori-challenge/internal/domain"
 	"stori-challenge/internal/shared"
 	"strings"
 )
 
 const MaxUploadSize = 5 * 1024 * 1024 // 5MB
 
+// MaxAsyncUploadSize is the limit for POST /migrate-async, which streams the file instead of loading it.
+const MaxAsyncUploadSize = 5 * 1024 * 1024 * 1024 // 5GB
+
 // ValidateFileMeta validates basic file metadata and returns an AppError
 // with a specific code and message when validation fails. On success, returns nil.
 func ValidateFileMeta(filename string, size int64) *shared.AppError {
+	return ValidateFileMetaWithLimit(filename, size, MaxUploadSize)
+}
+
+// ValidateFileMetaWithLimit is ValidateFileMeta with a caller-provided size limit in bytes.
+func ValidateFileMetaWithLimit(filename string, size int64, maxSize int64) *shared.AppError {
 	if filename == "" {
 		return shared.NewBadRequest("missing_file", "file is required", nil)
 	}
-	if size > MaxUploadSize {
+	if size > maxSize {
 		return shared.NewBadRequest("file_too_large", "file too large", nil)
 	}
-	ext := strings.ToLower(filepath.Ext(filename))
-	if ext != ".csv" {
-		return shared.NewBadRequest("wrong_extension", "file must have .csv extension", nil)
+	name, compression := splitCompression(filename)
+	if compression == domain.CompressionZip {
+		return nil
+	}
+	f, ok := formatsByExtension[strings.ToLower(filepath.Ext(name))]
+	if !ok || (compression == domain.CompressionGzip && f == domain.InputFormatXLSX) {
+		return shared.NewBadRequest("wrong_extension", "file must have .csv, .jsonl, .ndjson, .json, .xlsx, .ofx, .qfx, .xml, .sta or .mt940 extension, a .gz of one of the text formats, or be a .zip of CSV files", nil)
 	}
 	return nil
 }
+
+// splitCompression strips a .gz or .zip extension from filename and reports the compression.
+func splitCompression(filename string) (string, domain.Compression) {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".gz":
+		return strings.TrimSuffix(filename, filepath.Ext(filename)), domain.CompressionGzip
+	case ".zip":
+		return strings.TrimSuffix(filename, filepath.Ext(filename)), domain.CompressionZip
+	}
+	return filename, domain.CompressionNone
+}
+
+var compressionsByContentType = map[string]domain.Compression{
+	"application/gzip":             domain.CompressionGzip,
+	"application/x-gzip":           domain.CompressionGzip,
+	"application/zip":              domain.CompressionZip,
+	"application/x-zip-compressed": domain.CompressionZip,
+}
+
+var formatsByExtension = map[string]domain.InputFormat{
+	".csv":    domain.InputFormatCSV,
+	".jsonl":  domain.InputFormatJSONL,
+	".ndjson": domain.InputFormatJSONL,
+	".json":   domain.InputFormatJSON,
+	".xlsx":   domain.InputFormatXLSX,
+	".ofx":    domain.InputFormatOFX,
+	".qfx":    domain.InputFormatOFX,
+	".xml":    domain.InputFormatCAMT,
+	".sta":    domain.InputFormatMT940,
+	".mt940":  domain.InputFormatMT940,
+}
+
+var formatsByContentType = map[string]domain.InputFormat{
+	"text/csv":             domain.InputFormatCSV,
+	"application/x-ndjson": domain.InputFormatJSONL,
+	"application/jsonl":    domain.InputFormatJSONL,
+	"application/json":     domain.InputFormatJSON,
+	"application/vnd.openxmlformats-officedocument.spreadsheetml.sheet": domain.InputFormatXLSX,
+	"application/x-ofx":        domain.InputFormatOFX,
+	"application/vnd.intu.qfx": domain.InputFormatOFX,
+	"application/xml":          domain.InputFormatCAMT,
+	"text/xml":                 domain.InputFormatCAMT,
+}
+
+// DetectInputFormat picks the format of an upload from its Content-Type when that names a
+// supported format, otherwise from the file extension under any .gz. Unknown inputs, zip
+// archives included, default to CSV.
+func DetectInputFormat(filename, contentType string) domain.InputFormat {
+	if mt, _, err := mime.ParseMediaType(contentType); err == nil {
+		if f, ok := formatsByContentType[strings.ToLower(mt)]; ok {
+			return f
+		}
+	}
+	name, compression := splitCompression(filename)
+	if compression == domain.CompressionZip {
+		return domain.InputFormatCSV
+	}
+	if f, ok := formatsByExtension[strings.ToLower(filepath.Ext(name))]; ok {
+		return f
+	}
+	return domain.InputFormatCSV
+}
+
+// DetectCompression picks the compression of an upload from a .gz or .zip extension, or else
+// from its Content-Type. A known plain extension wins over the Content-Type, since clients
+// commonly send .xlsx workbooks as application/zip.
+func DetectCompression(filename, contentType string) domain.Compression {
+	if _, c := splitCompression(filename); c != domain.CompressionNone {
+		return c
+	}
+	if _, ok := formatsByExtension[strings.ToLower(filepath.Ext(filename))]; ok {
+		return domain.CompressionNone
+	}
+	if mt, _, err := mime.ParseMediaType(contentType); err == nil {
+		return compressionsByContentType[strings.ToLower(mt)]
+	}
+	return domain.CompressionNone
+}
@@ -3,6 +3,7 @@ package validators
 import (
 	"testing"
 
+	"stori-challenge/internal/domain"
 	"stori-challenge/internal/shared"
 )
 
@@ -49,3 +50,90 @@ func TestValidateFileMeta_ExactlyMaxSize_Accepted(t *testing.T) {
 		t.Fatalf("expected nil, got %v", err)
 	}
 }
+
+func TestValidateFileMetaWithLimit_AsyncLimitAllowsLargeFiles(t *testing.T) {
+	if err := ValidateFileMetaWithLimit("data.csv", MaxUploadSize+1, MaxAsyncUploadSize); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	err := ValidateFileMetaWithLimit("data.csv", MaxAsyncUploadSize+1, MaxAsyncUploadSize)
+	if err == nil || err.Code != "file_too_large" {
+		t.Fatalf("expected file_too_large, got %v", err)
+	}
+}
+
+func TestValidateFileMeta_JSONExtensions_Accepted(t *testing.T) {
+	for _, name := range []string{"data.jsonl", "data.NDJSON", "data.json", "bank.ofx", "bank.qfx", "camt053.xml", "june.sta"} {
+		if err := ValidateFileMeta(name, 10); err != nil {
+			t.Fatalf("%s: expected nil, got %v", name, err)
+		}
+	}
+}
+
+func TestValidateFileMeta_XLSX_AcceptedButNotXLS(t *testing.T) {
+	if err := ValidateFileMeta("ledger.xlsx", 10); err != nil {
+		t.Fatalf("expected nil, got %v", err)
+	}
+	if err := ValidateFileMeta("ledger.xls", 10); err == nil || err.Code != "wrong_extension" {
+		t.Fatalf("expected wrong_extension, got %v", err)
+	}
+}
+
+func TestValidateFileMeta_CompressedUploads(t *testing.T) {
+	for _, name := range []string{"data.csv.gz", "events.JSONL.GZ", "batch.zip"} {
+		if err := ValidateFileMeta(name, 10); err != nil {
+			t.Fatalf("%s: expected nil, got %v", name, err)
+		}
+	}
+	for _, name := range []string{"data.gz", "ledger.xlsx.gz", "data.txt.gz"} {
+		if err := ValidateFileMeta(name, 10); err == nil || err.Code != "wrong_extension" {
+			t.Fatalf("%s: expected wrong_extension, got %v", name, err)
+		}
+	}
+}
+
+func TestDetectCompression(t *testing.T) {
+	cases := []struct {
+		name, contentType string
+		want              domain.Compression
+	}{
+		{"data.csv", "", domain.CompressionNone},
+		{"data.csv.gz", "text/csv", domain.CompressionGzip},
+		{"batch.ZIP", "", domain.CompressionZip},
+		{"upload", "application/x-gzip", domain.CompressionGzip},
+		{"ledger.xlsx", "application/zip", domain.CompressionNone},
+	}
+	for _, tc := range cases {
+		if got := DetectCompression(tc.name, tc.contentType); got != tc.want {
+			t.Fatalf("%s (%s): want %q got %q", tc.name, tc.contentType, tc.want, got)
+		}
+	}
+	if got := DetectInputFormat("events.ndjson.gz", "application/gzip"); got != domain.InputFormatJSONL {
+		t.Fatalf("expected jsonl under .gz, got %s", got)
+	}
+}
+
+func TestDetectInputFormat_ContentTypeThenExtension(t *testing.T) {
+	cases := []struct {
+		name, contentType string
+		want              domain.InputFormat
+	}{
+		{"data.csv", "", domain.InputFormatCSV},
+		{"data.jsonl", "application/octet-stream", domain.InputFormatJSONL},
+		{"data.json", "", domain.InputFormatJSON},
+		{"data.json", "application/x-ndjson; charset=utf-8", domain.InputFormatJSONL},
+		{"data.csv", "application/json", domain.InputFormatJSON},
+		{"Ledger.XLSX", "application/octet-stream", domain.InputFormatXLSX},
+		{"upload", "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet", domain.InputFormatXLSX},
+		{"statement.QFX", "", domain.InputFormatOFX},
+		{"statement", "application/x-ofx", domain.InputFormatOFX},
+		{"camt053.XML", "", domain.InputFormatCAMT},
+		{"statement.xml", "text/xml; charset=utf-8", domain.InputFormatCAMT},
+		{"june.STA", "text/plain", domain.InputFormatMT940},
+		{"june.mt940", "", domain.InputFormatMT940},
+	}
+	for _, tc := range cases {
+		if got := DetectInputFormat(tc.name, tc.contentType); got != tc.want {
+			t.Fatalf("%s (%s): want %s got %s", tc.name, tc.contentType, tc.want, got)
+		}
+	}
+}
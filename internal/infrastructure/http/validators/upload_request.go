@@ -0,0 +1,73 @@
+package validators
+
+import (
+	"encoding/base64"
+	"strconv"
+	"strings"
+
+	"stori-challenge/internal/domain"
+	"stori-challenge/internal/shared"
+)
+
+// ParseUploadLength reads the Upload-Length header of a new resumable upload.
+func ParseUploadLength(v string) (int64, *shared.AppError) {
+	n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	if err != nil || n <= 0 {
+		return 0, shared.NewBadRequest("invalid_upload_length", "Upload-Length must be a positive number of bytes", err)
+	}
+	return n, nil
+}
+
+// ParseUploadOffset reads the Upload-Offset header of a chunk.
+func ParseUploadOffset(v string) (int64, *shared.AppError) {
+	n, err := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+	if err != nil || n < 0 {
+		return 0, shared.NewBadRequest("invalid_upload_offset", "Upload-Offset must be a non-negative number of bytes", err)
+	}
+	return n, nil
+}
+
+// ParseUploadMetadata reads an Upload-Metadata header: comma-separated pairs of a key and a
+// base64 value, e.g. "filename dHgu Y3N2,filetype dGV4dC9jc3Y=". A key may come without a value.
+func ParseUploadMetadata(v string) (map[string]string, *shared.AppError) {
+	out := make(map[string]string)
+	if strings.TrimSpace(v) == "" {
+		return out, nil
+	}
+	for _, pair := range strings.Split(v, ",") {
+		key, value, _ := strings.Cut(strings.TrimSpace(pair), " ")
+		if key == "" {
+			return nil, shared.NewBadRequest("invalid_upload_metadata", "Upload-Metadata must be comma-separated key and base64 value pairs", nil)
+		}
+		b, err := base64.StdEncoding.DecodeString(strings.TrimSpace(value))
+		if err != nil {
+			return nil, shared.NewBadRequest("invalid_upload_metadata", "Upload-Metadata value of "+key+" is not base64", err)
+		}
+		out[key] = string(b)
+	}
+	return out, nil
+}
+
+// ParseUploadChecksum reads an Upload-Checksum header, an algorithm and the base64 digest of
+// the chunk, e.g. "sha256 n4bQgYhMfWWaL+qgxVrQFaO/TxsrC4Is0V1sFbDwCgg=". Empty means no checksum.
+func ParseUploadChecksum(v string) (*domain.Checksum, *shared.AppError) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return nil, nil
+	}
+	alg, digest, ok := strings.Cut(v, " ")
+	if !ok {
+		return nil, shared.NewBadRequest("invalid_checksum", "Upload-Checksum must be an algorithm and a base64 digest", nil)
+	}
+	c := domain.Checksum{Algorithm: strings.ToLower(alg)}
+	h, ok := c.NewHash()
+	if !ok {
+		return nil, shared.NewBadRequest("unsupported_checksum_algorithm", "checksum algorithm must be md5, sha1 or sha256", nil)
+	}
+	sum, err := base64.StdEncoding.DecodeString(strings.TrimSpace(digest))
+	if err != nil || len(sum) != h.Size() {
+		return nil, shared.NewBadRequest("invalid_checksum", "Upload-Checksum digest is not a base64 "+c.Algorithm+" digest", err)
+	}
+	c.Sum = sum
+	return &c, nil
+}
@@ -0,0 +1,52 @@
+package validators
+
+import (
+	"encoding/base64"
+	"testing"
+)
+
+func TestParseUploadMetadata(t *testing.T) {
+	meta, err := ParseUploadMetadata("filename " + base64.StdEncoding.EncodeToString([]byte("data.csv")) + ",filetype " + base64.StdEncoding.EncodeToString([]byte("text/csv")) + ",empty")
+	if err != nil || meta["filename"] != "data.csv" || meta["filetype"] != "text/csv" || meta["empty"] != "" {
+		t.Fatalf("unexpected metadata: %v, %v", meta, err)
+	}
+	if _, err := ParseUploadMetadata("filename data.csv"); err == nil || err.Code != "invalid_upload_metadata" {
+		t.Fatalf("expected invalid_upload_metadata, got %v", err)
+	}
+}
+
+func TestParseUploadChecksum(t *testing.T) {
+	if c, err := ParseUploadChecksum(""); c != nil || err != nil {
+		t.Fatalf("expected no checksum, got %v, %v", c, err)
+	}
+	c, err := ParseUploadChecksum("SHA1 " + base64.StdEncoding.EncodeToString(make([]byte, 20)))
+	if err != nil || c.Algorithm != "sha1" || len(c.Sum) != 20 {
+		t.Fatalf("unexpected checksum: %+v, %v", c, err)
+	}
+	cases := map[string]string{
+		"sha256":                "invalid_checksum",
+		"sha256 AAAA":           "invalid_checksum",
+		"crc32 " + "AAAAAA==":   "unsupported_checksum_algorithm",
+		"sha256 not-base64!!!!": "invalid_checksum",
+	}
+	for in, code := range cases {
+		if _, err := ParseUploadChecksum(in); err == nil || err.Code != code {
+			t.Errorf("%q: expected %s, got %v", in, code, err)
+		}
+	}
+}
+
+func TestParseUploadLengthAndOffset(t *testing.T) {
+	if n, err := ParseUploadLength("1024"); err != nil || n != 1024 {
+		t.Fatalf("unexpected length: %d, %v", n, err)
+	}
+	if _, err := ParseUploadLength("0"); err == nil || err.Code != "invalid_upload_length" {
+		t.Fatalf("expected invalid_upload_length, got %v", err)
+	}
+	if n, err := ParseUploadOffset("0"); err != nil || n != 0 {
+		t.Fatalf("unexpected offset: %d, %v", n, err)
+	}
+	if _, err := ParseUploadOffset("-1"); err == nil || err.Code != "invalid_upload_offset" {
+		t.Fatalf("expected invalid_upload_offset, got %v", err)
+	}
+}
@@ -0,0 +1,177 @@
+package rules
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"stori-challenge/internal/domain"
+	portrules "stori-challenge/internal/ports/rules"
+
+	"github.com/shopspring/decimal"
+	"gopkg.in/yaml.v3"
+)
+
+// FileSource reads validation rules from a YAML or JSON file and reloads them whenever the
+// file changes, so thresholds can be updated without a deploy. When a changed file cannot be
+// loaded the previous rules stay in effect and the error is logged.
+type FileSource struct {
+	Path string
+
+	mu      sync.Mutex
+	loaded  bool
+	modTime time.Time
+	size    int64
+	rules   domain.RuleSet
+}
+
+var _ portrules.Source = (*FileSource)(nil)
+
+// NewFileSource returns a source for the rules file at path. The file is read on first use.
+func NewFileSource(path string) *FileSource {
+	return &FileSource{Path: path}
+}
+
+// DefaultPath returns MIGRATION_RULES_FILE; empty means no configurable rules.
+func DefaultPath() string {
+	return os.Getenv("MIGRATION_RULES_FILE")
+}
+
+// Rules returns the rules in the file, reloading it if it changed since the last call. It only
+// fails when no valid version of the file was ever loaded.
+func (s *FileSource) Rules(ctx context.Context) (domain.RuleSet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	info, err := os.Stat(s.Path)
+	if err != nil {
+		return s.keep(fmt.Errorf("read rules file: %w", err))
+	}
+	if s.loaded && info.ModTime().Equal(s.modTime) && info.Size() == s.size {
+		return s.rules, nil
+	}
+	data, err := os.ReadFile(s.Path)
+	if err != nil {
+		return s.keep(fmt.Errorf("read rules file: %w", err))
+	}
+	set, err := Parse(data)
+	if err != nil {
+		return s.keep(fmt.Errorf("rules file %s: %w", s.Path, err))
+	}
+	s.rules, s.loaded = set, true
+	s.modTime, s.size = info.ModTime(), info.Size()
+	return set, nil
+}
+
+// keep falls back to the last loaded rules after a failed reload.
+func (s *FileSource) keep(err error) (domain.RuleSet, error) {
+	if !s.loaded {
+		return domain.RuleSet{}, err
+	}
+	log.Printf("validation rules: keeping the previous rules: %v", err)
+	return s.rules, nil
+}
+
+// ruleFile is the layout of a rules file. JSON is read with the same decoder, since it is valid YAML.
+type ruleFile struct {
+	Rules []ruleRecord `yaml:"rules"`
+}
+
+type ruleRecord struct {
+	Code     string `yaml:"code"`
+	Type     string `yaml:"type"`
+	Severity string `yaml:"severity"`
+	Message  string `yaml:"message"`
+	// Min and Max are read as strings so amounts keep their exact decimal value.
+	Min      string              `yaml:"min"`
+	Max      string              `yaml:"max"`
+	UserIDs  []userIDRangeRecord `yaml:"user_ids"`
+	Earliest string              `yaml:"earliest"`
+	Limit    int                 `yaml:"limit"`
+}
+
+type userIDRangeRecord struct {
+	Min int64 `yaml:"min"`
+	Max int64 `yaml:"max"`
+}
+
+// Parse decodes and validates a YAML or JSON rules file. Unknown keys are rejected so a
+// misspelt threshold is not silently ignored.
+func Parse(data []byte) (domain.RuleSet, error) {
+	var f ruleFile
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&f); err != nil && !errors.Is(err, io.EOF) {
+		return domain.RuleSet{}, err
+	}
+	set := domain.RuleSet{Rules: make([]domain.ValidationRule, 0, len(f.Rules))}
+	for i, rec := range f.Rules {
+		r, err := rec.toDomain()
+		if err != nil {
+			return domain.RuleSet{}, fmt.Errorf("rule %d: %w", i+1, err)
+		}
+		set.Rules = append(set.Rules, r)
+	}
+	if err := set.Validate(); err != nil {
+		return domain.RuleSet{}, err
+	}
+	return set, nil
+}
+
+func (rec ruleRecord) toDomain() (domain.ValidationRule, error) {
+	r := domain.ValidationRule{
+		Code:     rec.Code,
+		Type:     domain.RuleType(rec.Type),
+		Severity: domain.RuleSeverity(strings.ToLower(rec.Severity)),
+		Message:  rec.Message,
+		Limit:    rec.Limit,
+	}
+	if r.Severity == "" {
+		r.Severity = domain.RuleSeverityError
+	}
+	var err error
+	if r.MinAmount, err = parseAmount(rec.Min); err != nil {
+		return r, fmt.Errorf("min: %w", err)
+	}
+	if r.MaxAmount, err = parseAmount(rec.Max); err != nil {
+		return r, fmt.Errorf("max: %w", err)
+	}
+	for _, u := range rec.UserIDs {
+		r.UserIDs = append(r.UserIDs, domain.UserIDRange{Min: u.Min, Max: u.Max})
+	}
+	if rec.Earliest != "" {
+		if r.Earliest, err = parseEarliest(rec.Earliest); err != nil {
+			return r, err
+		}
+	}
+	return r, nil
+}
+
+func parseAmount(s string) (*decimal.Decimal, error) {
+	if s == "" {
+		return nil, nil
+	}
+	d, err := decimal.NewFromString(s)
+	if err != nil {
+		return nil, fmt.Errorf("not a valid amount %q", s)
+	}
+	return &d, nil
+}
+
+// parseEarliest accepts an RFC 3339 datetime or a date, which means midnight UTC.
+func parseEarliest(s string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t.UTC(), nil
+	}
+	if t, err := time.Parse(time.DateOnly, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("earliest: not an RFC 3339 datetime or YYYY-MM-DD date: %q", s)
+}
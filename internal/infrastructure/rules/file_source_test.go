@@ -0,0 +1,123 @@
+package rules
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"stori-challenge/internal/domain"
+)
+
+const yamlRules = `rules:
+  - code: amount_limits
+    type: amount_range
+    min: -5000.50
+    max: 10000
+  - code: known_users
+    type: user_id_range
+    severity: warning
+    user_ids:
+      - {min: 1, max: 999}
+  - code: cutover
+    type: earliest_datetime
+    earliest: 2015-01-01
+    message: before the 2015 cutover
+`
+
+func TestParse_YAML(t *testing.T) {
+	set, err := Parse([]byte(yamlRules))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(set.Rules) != 3 {
+		t.Fatalf("expected 3 rules, got %+v", set.Rules)
+	}
+	amount := set.Rules[0]
+	if amount.Severity != domain.RuleSeverityError || amount.MinAmount.String() != "-5000.5" || amount.MaxAmount.String() != "10000" {
+		t.Fatalf("unexpected amount rule: %+v", amount)
+	}
+	if users := set.Rules[1]; users.Severity != domain.RuleSeverityWarning || len(users.UserIDs) != 1 || users.UserIDs[0].Max != 999 {
+		t.Fatalf("unexpected user rule: %+v", users)
+	}
+	if cutover := set.Rules[2]; !cutover.Earliest.Equal(time.Date(2015, 1, 1, 0, 0, 0, 0, time.UTC)) || cutover.Message != "before the 2015 cutover" {
+		t.Fatalf("unexpected datetime rule: %+v", cutover)
+	}
+}
+
+func TestParse_JSON(t *testing.T) {
+	set, err := Parse([]byte(`{"rules": [{"code": "daily", "type": "max_per_user_per_day", "limit": 50}, {"code": "nz", "type": "non_zero_amount"}]}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(set.Rules) != 2 || set.Rules[0].Limit != 50 || set.Rules[1].Type != domain.RuleTypeNonZeroAmount {
+		t.Fatalf("unexpected rules: %+v", set.Rules)
+	}
+}
+
+func TestParse_Invalid(t *testing.T) {
+	cases := map[string]string{
+		"unknown key":      "rules:\n  - code: a\n    type: amount_range\n    maximum: 10\n",
+		"unknown type":     "rules:\n  - code: a\n    type: amount_limit\n",
+		"missing param":    "rules:\n  - code: a\n    type: max_per_user_per_day\n",
+		"duplicate code":   "rules:\n  - code: a\n    type: non_zero_amount\n  - code: a\n    type: non_zero_amount\n",
+		"bad severity":     "rules:\n  - code: a\n    type: non_zero_amount\n    severity: info\n",
+		"bad amount":       "rules:\n  - code: a\n    type: amount_range\n    min: ten\n",
+		"bad earliest":     "rules:\n  - code: a\n    type: earliest_datetime\n    earliest: yesterday\n",
+		"empty user range": "rules:\n  - code: a\n    type: user_id_range\n    user_ids: [{min: 10, max: 1}]\n",
+	}
+	for name, data := range cases {
+		if _, err := Parse([]byte(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestParse_EmptyFileHasNoRules(t *testing.T) {
+	set, err := Parse(nil)
+	if err != nil || len(set.Rules) != 0 {
+		t.Fatalf("expected no rules, got %+v, %v", set, err)
+	}
+}
+
+func TestFileSource_ReloadsChangedFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	write := func(content string, mod time.Time) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(path, mod, mod); err != nil {
+			t.Fatal(err)
+		}
+	}
+	ctx := context.Background()
+	start := time.Now().Add(-time.Hour)
+	write("rules:\n  - code: daily\n    type: max_per_user_per_day\n    limit: 10\n", start)
+
+	src := NewFileSource(path)
+	set, err := src.Rules(ctx)
+	if err != nil || set.Rules[0].Limit != 10 {
+		t.Fatalf("expected limit 10, got %+v, %v", set, err)
+	}
+
+	write("rules:\n  - code: daily\n    type: max_per_user_per_day\n    limit: 20\n", start.Add(time.Minute))
+	if set, err = src.Rules(ctx); err != nil || set.Rules[0].Limit != 20 {
+		t.Fatalf("expected the reloaded limit 20, got %+v, %v", set, err)
+	}
+
+	// A broken edit keeps the last valid rules.
+	write("rules:\n  - code: daily\n    type: max_per_user_per_day\n    limit: -1\n", start.Add(2*time.Minute))
+	if set, err = src.Rules(ctx); err != nil || set.Rules[0].Limit != 20 {
+		t.Fatalf("expected the previous limit 20, got %+v, %v", set, err)
+	}
+}
+
+func TestFileSource_MissingFile(t *testing.T) {
+	_, err := NewFileSource(filepath.Join(t.TempDir(), "missing.yaml")).Rules(context.Background())
+	if err == nil || !strings.Contains(err.Error(), "read rules file") {
+		t.Fatalf("expected a read error, got %v", err)
+	}
+}
@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	portstorage "stori-challenge/internal/ports/storage"
+)
+
+// LocalFileStore saves files in a directory on local disk. Several instances can share it
+// through a mounted volume.
+type LocalFileStore struct {
+	Dir string
+}
+
+var _ portstorage.FileStore = (*LocalFileStore)(nil)
+
+// NewLocalFileStore returns a store rooted at dir, creating the directory if needed.
+func NewLocalFileStore(dir string) (*LocalFileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create storage dir: %w", err)
+	}
+	return &LocalFileStore{Dir: dir}, nil
+}
+
+// DefaultDir returns MIGRATIONS_STORAGE_DIR or a directory under the OS temp dir.
+func DefaultDir() string {
+	if dir := os.Getenv("MIGRATIONS_STORAGE_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join(os.TempDir(), "stori-migrations")
+}
+
+func (s *LocalFileStore) Save(ctx context.Context, name string, r io.Reader) (string, error) {
+	key, err := newKey(name)
+	if err != nil {
+		return "", err
+	}
+	// Write to a temp name first so a partially written file is never visible under its key.
+	tmp, err := os.CreateTemp(s.Dir, ".upload-*")
+	if err != nil {
+		return "", err
+	}
+	if _, err := io.Copy(tmp, r); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+	if err := os.Rename(tmp.Name(), filepath.Join(s.Dir, key)); err != nil {
+		_ = os.Remove(tmp.Name())
+		return "", err
+	}
+	return key, nil
+}
+
+func (s *LocalFileStore) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	// Keys are flat names; reject anything that could escape the storage dir.
+	if key == "" || key != filepath.Base(key) || strings.HasPrefix(key, ".") {
+		return nil, fmt.Errorf("invalid storage key %q", key)
+	}
+	return os.Open(filepath.Join(s.Dir, key))
+}
+
+// newKey returns a random file name that keeps the original extension.
+func newKey(name string) (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf) + strings.ToLower(filepath.Ext(filepath.Base(name))), nil
+}
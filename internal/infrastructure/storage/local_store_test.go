@@ -0,0 +1,59 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestLocalFileStore_SaveAndOpen_RoundTrip(t *testing.T) {
+	store, err := NewLocalFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	ctx := context.Background()
+	key, err := store.Save(ctx, "Data.CSV", strings.NewReader("id,user_id,amount,datetime\n"))
+	if err != nil {
+		t.Fatalf("save: %v", err)
+	}
+	if !strings.HasSuffix(key, ".csv") {
+		t.Fatalf("expected key to keep extension, got %q", key)
+	}
+	rc, err := store.Open(ctx, key)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer rc.Close()
+	b, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	if string(b) != "id,user_id,amount,datetime\n" {
+		t.Fatalf("content mismatch: %q", string(b))
+	}
+}
+
+func TestLocalFileStore_Save_GeneratesUniqueKeys(t *testing.T) {
+	store, err := NewLocalFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	k1, _ := store.Save(context.Background(), "a.csv", strings.NewReader("x"))
+	k2, _ := store.Save(context.Background(), "a.csv", strings.NewReader("y"))
+	if k1 == "" || k1 == k2 {
+		t.Fatalf("expected distinct keys, got %q and %q", k1, k2)
+	}
+}
+
+func TestLocalFileStore_Open_RejectsTraversal(t *testing.T) {
+	store, err := NewLocalFileStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	for _, key := range []string{"", "../etc/passwd", "sub/file.csv", ".upload-123"} {
+		if _, err := store.Open(context.Background(), key); err == nil {
+			t.Fatalf("expected error for key %q", key)
+		}
+	}
+}
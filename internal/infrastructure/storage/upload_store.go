@@ -0,0 +1,279 @@
+package storage
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"stori-challenge/internal/domain"
+	portstorage "stori-challenge/internal/ports/storage"
+)
+
+// LocalUploadStore stages resumable uploads in a directory on local disk. Each upload is a
+// <id>.json metadata file and a <id>.part file with the bytes received, whose size is the
+// offset and whose modification time is the last activity. Chunks are written to a temp file
+// first, so a chunk that is interrupted or fails its checksum never reaches the .part file.
+// A claimed upload has its metadata file renamed to <id>.claimed.
+type LocalUploadStore struct {
+	Dir string
+	// mu serializes appends and claims, so concurrent chunks for the same offset cannot both be
+	// added and an upload cannot be claimed twice.
+	mu sync.Mutex
+}
+
+var _ portstorage.UploadStore = (*LocalUploadStore)(nil)
+
+// uploadRecord is the JSON metadata file of an upload.
+type uploadRecord struct {
+	FileName    string    `json:"file_name"`
+	ContentType string    `json:"content_type,omitempty"`
+	Length      int64     `json:"length"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// NewLocalUploadStore returns a store rooted at dir, creating the directory if needed.
+func NewLocalUploadStore(dir string) (*LocalUploadStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("create upload dir: %w", err)
+	}
+	return &LocalUploadStore{Dir: dir}, nil
+}
+
+// DefaultUploadDir returns UPLOADS_STORAGE_DIR or an uploads directory under DefaultDir.
+func DefaultUploadDir() string {
+	if dir := os.Getenv("UPLOADS_STORAGE_DIR"); dir != "" {
+		return dir
+	}
+	return filepath.Join(DefaultDir(), "uploads")
+}
+
+func (s *LocalUploadStore) Create(ctx context.Context, u domain.Upload) error {
+	if !validUploadID(u.ID) {
+		return fmt.Errorf("invalid upload id %q", u.ID)
+	}
+	b, err := json.Marshal(uploadRecord{FileName: u.FileName, ContentType: u.ContentType, Length: u.Length, CreatedAt: u.CreatedAt})
+	if err != nil {
+		return err
+	}
+	part, err := os.OpenFile(s.partPath(u.ID), os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if err := part.Close(); err != nil {
+		return err
+	}
+	// The metadata file is written last and makes the upload visible.
+	if err := writeFileAtomic(s.Dir, s.metaPath(u.ID), b); err != nil {
+		_ = os.Remove(s.partPath(u.ID))
+		return err
+	}
+	return nil
+}
+
+func (s *LocalUploadStore) Get(ctx context.Context, id string) (domain.Upload, bool, error) {
+	if !validUploadID(id) {
+		return domain.Upload{}, false, nil
+	}
+	return s.load(id, s.metaPath(id))
+}
+
+// load reads the upload id from its metadata file at metaPath and its .part file.
+func (s *LocalUploadStore) load(id, metaPath string) (domain.Upload, bool, error) {
+	b, err := os.ReadFile(metaPath)
+	if errors.Is(err, os.ErrNotExist) {
+		return domain.Upload{}, false, nil
+	}
+	if err != nil {
+		return domain.Upload{}, false, err
+	}
+	var rec uploadRecord
+	if err := json.Unmarshal(b, &rec); err != nil {
+		return domain.Upload{}, false, fmt.Errorf("decode upload %s: %w", id, err)
+	}
+	st, err := os.Stat(s.partPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return domain.Upload{}, false, nil
+	}
+	if err != nil {
+		return domain.Upload{}, false, err
+	}
+	return domain.Upload{
+		ID:          id,
+		FileName:    rec.FileName,
+		ContentType: rec.ContentType,
+		Length:      rec.Length,
+		Offset:      st.Size(),
+		CreatedAt:   rec.CreatedAt,
+		UpdatedAt:   st.ModTime(),
+	}, true, nil
+}
+
+func (s *LocalUploadStore) List(ctx context.Context) ([]domain.Upload, error) {
+	var out []domain.Upload
+	for _, ext := range []string{".json", ".claimed"} {
+		names, err := filepath.Glob(filepath.Join(s.Dir, "*"+ext))
+		if err != nil {
+			return nil, err
+		}
+		for _, name := range names {
+			id := strings.TrimSuffix(filepath.Base(name), ext)
+			if !validUploadID(id) {
+				continue
+			}
+			u, found, err := s.load(id, name)
+			if err != nil {
+				return nil, err
+			}
+			if found {
+				out = append(out, u)
+			}
+		}
+	}
+	return out, nil
+}
+
+func (s *LocalUploadStore) Append(ctx context.Context, id string, offset int64, r io.Reader, commit func(n int64) error) (int64, error) {
+	if !validUploadID(id) {
+		return 0, fmt.Errorf("invalid upload id %q", id)
+	}
+	tmp, err := os.CreateTemp(s.Dir, ".chunk-*")
+	if err != nil {
+		return 0, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	n, err := io.Copy(tmp, r)
+	if err != nil {
+		return 0, err
+	}
+	if err := commit(n); err != nil {
+		return 0, err
+	}
+	if _, err := tmp.Seek(0, io.SeekStart); err != nil {
+		return 0, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	part, err := os.OpenFile(s.partPath(id), os.O_WRONLY, 0)
+	if err != nil {
+		return 0, err
+	}
+	st, err := part.Stat()
+	if err != nil {
+		_ = part.Close()
+		return 0, err
+	}
+	if st.Size() != offset {
+		_ = part.Close()
+		return 0, portstorage.ErrOffsetMismatch
+	}
+	if _, err := part.Seek(offset, io.SeekStart); err != nil {
+		_ = part.Close()
+		return 0, err
+	}
+	if _, err := io.Copy(part, tmp); err != nil {
+		// Drop what was written of the chunk so the offset stays where the client expects it.
+		_ = part.Truncate(offset)
+		_ = part.Close()
+		return 0, err
+	}
+	if err := part.Close(); err != nil {
+		return 0, err
+	}
+	return offset + n, nil
+}
+
+func (s *LocalUploadStore) Open(ctx context.Context, id string) (io.ReadCloser, error) {
+	if !validUploadID(id) {
+		return nil, fmt.Errorf("invalid upload id %q", id)
+	}
+	return os.Open(s.partPath(id))
+}
+
+// Claim renames the metadata file, which fails for an upload that is unknown or was claimed first.
+func (s *LocalUploadStore) Claim(ctx context.Context, id string) (bool, error) {
+	if !validUploadID(id) {
+		return false, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	err := os.Rename(s.metaPath(id), s.claimPath(id))
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *LocalUploadStore) Release(ctx context.Context, id string) error {
+	if !validUploadID(id) {
+		return fmt.Errorf("invalid upload id %q", id)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return os.Rename(s.claimPath(id), s.metaPath(id))
+}
+
+func (s *LocalUploadStore) Delete(ctx context.Context, id string) error {
+	if !validUploadID(id) {
+		return nil
+	}
+	// The metadata goes first so a half-deleted upload is no longer found.
+	for _, path := range []string{s.metaPath(id), s.claimPath(id), s.partPath(id)} {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *LocalUploadStore) metaPath(id string) string {
+	return filepath.Join(s.Dir, id+".json")
+}
+
+func (s *LocalUploadStore) claimPath(id string) string {
+	return filepath.Join(s.Dir, id+".claimed")
+}
+
+func (s *LocalUploadStore) partPath(id string) string {
+	return filepath.Join(s.Dir, id+".part")
+}
+
+// validUploadID reports whether id is a lowercase hex token, so it cannot escape the directory.
+func validUploadID(id string) bool {
+	if id == "" || id != strings.ToLower(id) {
+		return false
+	}
+	_, err := hex.DecodeString(id)
+	return err == nil
+}
+
+// writeFileAtomic writes b to a temp file in dir and renames it to path.
+func writeFileAtomic(dir, path string, b []byte) error {
+	tmp, err := os.CreateTemp(dir, ".meta-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(b); err != nil {
+		_ = tmp.Close()
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		_ = os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"stori-challenge/internal/domain"
+	portstorage "stori-challenge/internal/ports/storage"
+)
+
+const testUploadID = "0123456789abcdef0123456789abcdef"
+
+func newTestUploadStore(t *testing.T) *LocalUploadStore {
+	t.Helper()
+	store, err := NewLocalUploadStore(t.TempDir())
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	created := time.Date(2024, 7, 1, 0, 0, 0, 0, time.UTC)
+	u := domain.Upload{ID: testUploadID, FileName: "data.csv", ContentType: "text/csv", Length: 10, CreatedAt: created}
+	if err := store.Create(context.Background(), u); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	return store
+}
+
+func accept(n int64) error { return nil }
+
+func TestLocalUploadStore_AppendChunksAndOpen(t *testing.T) {
+	store := newTestUploadStore(t)
+	ctx := context.Background()
+
+	if off, err := store.Append(ctx, testUploadID, 0, strings.NewReader("hello"), accept); err != nil || off != 5 {
+		t.Fatalf("append: %d, %v", off, err)
+	}
+	if off, err := store.Append(ctx, testUploadID, 5, strings.NewReader("world"), accept); err != nil || off != 10 {
+		t.Fatalf("append: %d, %v", off, err)
+	}
+	u, found, err := store.Get(ctx, testUploadID)
+	if err != nil || !found {
+		t.Fatalf("get: %v, %v", found, err)
+	}
+	if u.Offset != 10 || u.Length != 10 || u.FileName != "data.csv" || u.ContentType != "text/csv" || u.UpdatedAt.IsZero() {
+		t.Fatalf("unexpected upload: %+v", u)
+	}
+	rc, err := store.Open(ctx, testUploadID)
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	defer rc.Close()
+	if b, _ := io.ReadAll(rc); string(b) != "helloworld" {
+		t.Fatalf("content mismatch: %q", b)
+	}
+}
+
+func TestLocalUploadStore_Append_RejectedChunkIsNotKept(t *testing.T) {
+	store := newTestUploadStore(t)
+	ctx := context.Background()
+
+	reject := errors.New("checksum mismatch")
+	if _, err := store.Append(ctx, testUploadID, 0, strings.NewReader("hello"), func(n int64) error { return reject }); !errors.Is(err, reject) {
+		t.Fatalf("expected the commit error, got %v", err)
+	}
+	if u, _, _ := store.Get(ctx, testUploadID); u.Offset != 0 {
+		t.Fatalf("expected offset 0, got %d", u.Offset)
+	}
+}
+
+func TestLocalUploadStore_Append_OffsetMismatch(t *testing.T) {
+	store := newTestUploadStore(t)
+	ctx := context.Background()
+
+	if _, err := store.Append(ctx, testUploadID, 3, strings.NewReader("hello"), accept); !errors.Is(err, portstorage.ErrOffsetMismatch) {
+		t.Fatalf("expected ErrOffsetMismatch, got %v", err)
+	}
+}
+
+func TestLocalUploadStore_ListAndDelete(t *testing.T) {
+	store := newTestUploadStore(t)
+	ctx := context.Background()
+
+	list, err := store.List(ctx)
+	if err != nil || len(list) != 1 || list[0].ID != testUploadID {
+		t.Fatalf("unexpected list: %+v, %v", list, err)
+	}
+	if err := store.Delete(ctx, testUploadID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, found, _ := store.Get(ctx, testUploadID); found {
+		t.Fatalf("expected the upload to be gone")
+	}
+	if err := store.Delete(ctx, testUploadID); err != nil {
+		t.Fatalf("deleting twice: %v", err)
+	}
+}
+
+func TestLocalUploadStore_RejectsInvalidIDs(t *testing.T) {
+	store := newTestUploadStore(t)
+	for _, id := range []string{"", "../x", "0123ABCD", "zz"} {
+		if _, found, err := store.Get(context.Background(), id); found || err != nil {
+			t.Fatalf("%q: expected not found, got %v, %v", id, found, err)
+		}
+		if _, err := store.Open(context.Background(), id); err == nil {
+			t.Fatalf("%q: expected an open error", id)
+		}
+	}
+}
+
+func TestLocalUploadStore_ClaimOnce(t *testing.T) {
+	store := newTestUploadStore(t)
+	ctx := context.Background()
+
+	if claimed, err := store.Claim(ctx, testUploadID); err != nil || !claimed {
+		t.Fatalf("claim: %v, %v", claimed, err)
+	}
+	if claimed, err := store.Claim(ctx, testUploadID); err != nil || claimed {
+		t.Fatalf("expected a second claim to fail, got %v, %v", claimed, err)
+	}
+	if _, found, _ := store.Get(ctx, testUploadID); found {
+		t.Fatalf("expected a claimed upload to be hidden")
+	}
+	if list, err := store.List(ctx); err != nil || len(list) != 1 || list[0].FileName != "data.csv" {
+		t.Fatalf("expected the claimed upload listed, got %+v, %v", list, err)
+	}
+
+	if err := store.Release(ctx, testUploadID); err != nil {
+		t.Fatalf("release: %v", err)
+	}
+	if _, found, _ := store.Get(ctx, testUploadID); !found {
+		t.Fatalf("expected a released upload to be found")
+	}
+	if claimed, _ := store.Claim(ctx, testUploadID); !claimed {
+		t.Fatalf("expected a released upload to be claimed again")
+	}
+	if err := store.Delete(ctx, testUploadID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if list, _ := store.List(ctx); len(list) != 0 {
+		t.Fatalf("expected a deleted claimed upload to be gone, got %+v", list)
+	}
+	if claimed, err := store.Claim(ctx, testUploadID); err != nil || claimed {
+		t.Fatalf("expected an unknown upload not to be claimed, got %v, %v", claimed, err)
+	}
+}
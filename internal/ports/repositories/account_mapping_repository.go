@@ -0,0 +1,18 @@
+package repositories
+
+import (
+	"context"
+
+	"stori-challenge/internal/domain"
+)
+
+type AccountMappingRepository interface {
+	// Save creates or replaces the mapping for m.AccountID and returns it with its timestamps.
+	Save(ctx context.Context, m domain.AccountMapping) (domain.AccountMapping, error)
+	// GetByAccount returns the mapping and true, or false if the account is not mapped.
+	GetByAccount(ctx context.Context, accountID string) (domain.AccountMapping, bool, error)
+	// List returns every mapping ordered by account id.
+	List(ctx context.Context) ([]domain.AccountMapping, error)
+	// Delete removes the mapping and reports whether it existed.
+	Delete(ctx context.Context, accountID string) (bool, error)
+}
@@ -0,0 +1,18 @@
+package repositories
+
+import (
+	"context"
+
+	"stori-challenge/internal/domain"
+)
+
+type MappingProfileRepository interface {
+	// Save creates or replaces the profile with p.Name and returns it with its timestamps.
+	Save(ctx context.Context, p domain.MappingProfile) (domain.MappingProfile, error)
+	// GetByName returns the profile and true, or false if it does not exist.
+	GetByName(ctx context.Context, name string) (domain.MappingProfile, bool, error)
+	// List returns every profile ordered by name.
+	List(ctx context.Context) ([]domain.MappingProfile, error)
+	// Delete removes the profile and reports whether it existed.
+	Delete(ctx context.Context, name string) (bool, error)
+}
@@ -0,0 +1,37 @@
+package repositories
+
+import (
+	"context"
+
+	"stori-challenge/internal/domain"
+)
+
+type MigrationBatchRepository interface {
+	// Create records a PROCESSING batch for b.Source and returns it with its id and start time.
+	Create(ctx context.Context, b domain.MigrationBatch) (domain.MigrationBatch, error)
+	// Finish records the outcome of a PROCESSING batch: status, digest, size, counts and error code.
+	Finish(ctx context.Context, b domain.MigrationBatch) error
+	// GetByID returns the batch and true, or false if it does not exist.
+	GetByID(ctx context.Context, id int64) (domain.MigrationBatch, bool, error)
+	// FindSucceeded returns a SUCCEEDED batch whose content has the given SHA-256, if any.
+	FindSucceeded(ctx context.Context, sha256 string) (domain.MigrationBatch, bool, error)
+	// GetByMigrationID returns the batch of an asynchronous migration and true, or false if it has none.
+	GetByMigrationID(ctx context.Context, migrationID int64) (domain.MigrationBatch, bool, error)
+	// Revert deletes the transactions of a SUCCEEDED batch, stores rev as its audit record and
+	// marks it REVERTED, all in one database transaction. When the outcome shows the batch
+	// cannot be reverted nothing is changed.
+	Revert(ctx context.Context, rev domain.BatchRevert) (BatchRevertOutcome, error)
+	// GetRevert returns the audit record of a reverted batch and true, or false if it was not reverted.
+	GetRevert(ctx context.Context, batchID int64) (domain.BatchRevert, bool, error)
+}
+
+// BatchRevertOutcome is the result of MigrationBatchRepository.Revert. The batch was reverted
+// when Revert holds the stored record.
+type BatchRevertOutcome struct {
+	Found bool
+	// Status is the status the batch had; only SUCCEEDED batches are reverted.
+	Status domain.MigrationBatchStatus
+	// LockedPeriod is the first locked period (YYYY-MM) holding a transaction of the batch.
+	LockedPeriod string
+	Revert       *domain.BatchRevert
+}
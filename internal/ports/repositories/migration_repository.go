@@ -0,0 +1,42 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"stori-challenge/internal/domain"
+)
+
+// ErrLeaseLost is returned by the writes of a migration's worker when the migration is no longer
+// PROCESSING under the attempt the worker claimed, e.g. because another worker reclaimed it.
+var ErrLeaseLost = errors.New("migration lease lost")
+
+type MigrationRepository interface {
+	// Create inserts a new PENDING migration and returns it with its generated id and timestamps.
+	Create(ctx context.Context, fileName, fileKey string, opts domain.MigrationOptions) (domain.Migration, error)
+	// GetByID returns the migration and true, or false if it does not exist.
+	GetByID(ctx context.Context, id int64) (domain.Migration, bool, error)
+	// ClaimNext atomically moves the oldest PENDING migration to PROCESSING and returns it with
+	// a new Attempt, which the claiming worker passes to every later write.
+	// A PROCESSING migration without a heartbeat for longer than staleAfter is claimed again,
+	// its logged row errors cleared, since the worker that had it is gone; zero never reclaims.
+	// Concurrent callers (even from different instances) never claim the same migration.
+	// Returns false when there is nothing to claim.
+	ClaimNext(ctx context.Context, staleAfter time.Duration) (domain.Migration, bool, error)
+	// Heartbeat records that the worker processing a migration is still alive.
+	// Heartbeat, Complete, Fail and AddRowErrors return ErrLeaseLost, and write nothing, unless
+	// the migration is PROCESSING under attempt.
+	Heartbeat(ctx context.Context, id int64, attempt int) error
+	// Complete marks a PROCESSING migration as COMPLETED with its counts. items holds the rows
+	// rejected in partial mode and summary groups all of them; nil when there are none.
+	Complete(ctx context.Context, id int64, attempt int, counts domain.MigrationCounts, items []domain.RowError, summary *domain.RowErrorSummary) error
+	// Fail marks a PROCESSING migration as FAILED with an error code, row-level details and
+	// the summary of every row error, which may be nil.
+	Fail(ctx context.Context, id int64, attempt int, code string, items []domain.RowError, summary *domain.RowErrorSummary) error
+	// AddRowErrors appends errs to the full row error log of a migration, kept when its
+	// stored list leaves some out.
+	AddRowErrors(ctx context.Context, id int64, attempt int, errs []domain.LoggedRowError) error
+	// EachRowError calls fn with the logged row errors of a migration, ordered by run row.
+	EachRowError(ctx context.Context, id int64, fn func(domain.LoggedRowError) error) error
+}
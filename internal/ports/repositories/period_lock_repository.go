@@ -0,0 +1,17 @@
+package repositories
+
+import (
+	"context"
+
+	"stori-challenge/internal/domain"
+)
+
+type PeriodLockRepository interface {
+	// Save locks l.Period, replacing who locked it and why if it already was, and returns it
+	// with its creation time.
+	Save(ctx context.Context, l domain.PeriodLock) (domain.PeriodLock, error)
+	// List returns every lock ordered by period.
+	List(ctx context.Context) ([]domain.PeriodLock, error)
+	// Delete unlocks the period and reports whether it was locked.
+	Delete(ctx context.Context, period string) (bool, error)
+}
@@ -11,8 +11,13 @@ import (
 type TransactionRepository interface {
 	// ExistsByIDs returns a map of id -> true for any ids that already exist.
 	ExistsByIDs(ctx context.Context, ids []int64) (map[int64]bool, error)
-	// BulkInsert inserts all transactions in a single transaction (all-or-nothing).
-	BulkInsert(ctx context.Context, txs []domain.Transaction) error
+	// GetByIDs returns the stored transactions for any ids that already exist, keyed by id.
+	GetByIDs(ctx context.Context, ids []int64) (map[int64]domain.Transaction, error)
+	// BeginImport opens a staged import, the only way transactions are inserted.
+	// Nothing becomes visible until Commit succeeds.
+	BeginImport(ctx context.Context) (TransactionImport, error)
+	// GetSource returns where a stored transaction came from, or false if it does not exist.
+	GetSource(ctx context.Context, id int64) (domain.TransactionSource, bool, error)
 	// UserHasAnyTransaction returns true if the user has at least one transaction (any datetime).
 	UserHasAnyTransaction(ctx context.Context, userID int64) (bool, error)
 	// GetUserBalanceSummary returns the aggregated balance and totals within [from, to].
@@ -21,3 +26,57 @@ type TransactionRepository interface {
 	// - totalCredits: SUM(amount) for type = 'credit'
 	GetUserBalanceSummary(ctx context.Context, userID int64, from, to time.Time) (balance decimal.Decimal, totalDebits decimal.Decimal, totalCredits decimal.Decimal, err error)
 }
+
+// TransactionImport stages transactions chunk by chunk inside one database transaction
+// and moves them into the transactions table on Commit (all-or-nothing).
+type TransactionImport interface {
+	// Stage writes a chunk to the staging area. rows holds the source row number of each transaction.
+	// It returns the rows whose id was already staged, by an earlier chunk or earlier in the same chunk;
+	// those rows are not staged.
+	Stage(ctx context.Context, txs []domain.Transaction, rows []int) ([]StagedDuplicate, error)
+	// ExistsByIDs is TransactionRepository.ExistsByIDs run inside the import transaction.
+	ExistsByIDs(ctx context.Context, ids []int64) (map[int64]bool, error)
+	// GetByIDs is TransactionRepository.GetByIDs run inside the import transaction.
+	GetByIDs(ctx context.Context, ids []int64) (map[int64]domain.Transaction, error)
+	// MarkPresent flags staged ids that are already stored with the same content; Commit skips them.
+	MarkPresent(ctx context.Context, ids []int64) error
+	// Reject flags staged ids whose rows were rejected after staging. They still count as staged
+	// for Stage, but BelowFloor, RegisterUsers and Commit skip them.
+	Reject(ctx context.Context, ids []int64) error
+	// StagedPerDay returns how many rows are staged, rejected ones included, for each user and
+	// UTC calendar day of keys; keys without staged rows are left out.
+	StagedPerDay(ctx context.Context, keys []UserDay) (map[UserDay]int, error)
+	// BelowFloor replays the stored transactions of every user with staged rows together with the
+	// rows Commit would insert, per user in datetime and id order, and calls fn, in row order, for
+	// each staged debit that leaves the user's balance below floor.
+	BelowFloor(ctx context.Context, floor decimal.Decimal, fn func(FloorBreach) error) error
+	// RegisterUsers registers, as part of the import, the users of the staged rows not marked
+	// present or rejected that are not users yet, and returns how many it created.
+	RegisterUsers(ctx context.Context) (int, error)
+	// Commit moves every staged transaction not marked present or rejected into the transactions table
+	// and returns how many were inserted.
+	Commit(ctx context.Context) (int, error)
+	// Rollback discards the import. It is a no-op after Commit.
+	Rollback() error
+}
+
+// FloorBreach is a staged debit that takes its user's balance below the overdraft floor.
+type FloorBreach struct {
+	Row     int
+	UserID  int64
+	Amount  decimal.Decimal
+	Balance decimal.Decimal
+}
+
+// UserDay is a user and a UTC calendar day, as 2006-01-02.
+type UserDay struct {
+	UserID int64
+	Day    string
+}
+
+// StagedDuplicate identifies a row whose id was already staged at FirstRow.
+type StagedDuplicate struct {
+	ID       int64
+	Row      int
+	FirstRow int
+}
@@ -0,0 +1,10 @@
+package repositories
+
+import "context"
+
+type UserRepository interface {
+	// Exists reports whether the user is registered.
+	Exists(ctx context.Context, id int64) (bool, error)
+	// ExistingIDs returns a map of id -> true for the ids that are registered users.
+	ExistingIDs(ctx context.Context, ids []int64) (map[int64]bool, error)
+}
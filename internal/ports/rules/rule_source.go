@@ -0,0 +1,13 @@
+package rules
+
+import (
+	"context"
+
+	"stori-challenge/internal/domain"
+)
+
+// Source provides the validation rules migrations apply. The rules may change between calls,
+// so a migration fetches them once and uses that set for the whole run.
+type Source interface {
+	Rules(ctx context.Context) (domain.RuleSet, error)
+}
@@ -0,0 +1,18 @@
+package services
+
+import (
+	"context"
+
+	"stori-challenge/internal/domain"
+)
+
+// AccountMappingService is the input port for managing which user a statement account belongs to.
+type AccountMappingService interface {
+	// Save validates and stores a mapping.
+	Save(ctx context.Context, m domain.AccountMapping) (domain.AccountMapping, error)
+	// Get returns the mapping or a NotFound error.
+	Get(ctx context.Context, accountID string) (domain.AccountMapping, error)
+	List(ctx context.Context) ([]domain.AccountMapping, error)
+	// Delete removes the mapping or returns a NotFound error.
+	Delete(ctx context.Context, accountID string) error
+}
@@ -0,0 +1,18 @@
+package services
+
+import (
+	"context"
+
+	"stori-challenge/internal/domain"
+)
+
+// MappingProfileService is the input port for managing CSV header mapping profiles.
+type MappingProfileService interface {
+	// Save validates and stores a profile; column keys must be canonical transaction columns.
+	Save(ctx context.Context, p domain.MappingProfile) (domain.MappingProfile, error)
+	// Get returns the profile or a NotFound error.
+	Get(ctx context.Context, name string) (domain.MappingProfile, error)
+	List(ctx context.Context) ([]domain.MappingProfile, error)
+	// Delete removes the profile or returns a NotFound error.
+	Delete(ctx context.Context, name string) error
+}
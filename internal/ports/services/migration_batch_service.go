@@ -0,0 +1,23 @@
+package services
+
+import (
+	"context"
+
+	"stori-challenge/internal/domain"
+)
+
+// MigrationBatchService is the input port for auditing where migrated transactions came from.
+type MigrationBatchService interface {
+	// Get returns the batch or a NotFound error.
+	Get(ctx context.Context, id int64) (domain.MigrationBatch, error)
+	// GetTransactionSource returns the file and row a transaction was migrated from, with its
+	// batch; the batch is nil for transactions stored before batches were recorded. A missing
+	// transaction is a NotFound error.
+	GetTransactionSource(ctx context.Context, transactionID int64) (domain.TransactionSource, *domain.MigrationBatch, error)
+	// Revert deletes the transactions of batch rev.BatchID in one database transaction and
+	// returns the stored audit record. RevertedBy and Reason are required; a batch that is not
+	// SUCCEEDED, or has a transaction in a locked period, is a Conflict error.
+	Revert(ctx context.Context, rev domain.BatchRevert) (domain.BatchRevert, error)
+	// RevertMigration reverts the batch of an asynchronous migration.
+	RevertMigration(ctx context.Context, migrationID int64, rev domain.BatchRevert) (domain.BatchRevert, error)
+}
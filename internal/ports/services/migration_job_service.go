@@ -0,0 +1,23 @@
+package services
+
+import (
+	"context"
+	"io"
+
+	"stori-challenge/internal/domain"
+)
+
+// MigrationJobService is the input port for the POST /migrate-async and GET /migrations/{id} use cases.
+type MigrationJobService interface {
+	// Enqueue stores the uploaded file and records a PENDING migration for the worker.
+	// opts are persisted with the migration and applied when it is processed.
+	Enqueue(ctx context.Context, fileName string, r io.Reader, opts domain.MigrationOptions) (domain.Migration, error)
+	// Get returns the migration with its status, counts and row errors.
+	// Returns not found if the migration does not exist.
+	Get(ctx context.Context, id int64) (domain.Migration, error)
+	// WriteErrorReport writes the rows of a finished migration that have errors to w as CSV,
+	// in their original form with error_field and error_message columns appended.
+	// Returns not found if the migration does not exist or has no row errors, and conflict
+	// while it is still pending or processing.
+	WriteErrorReport(ctx context.Context, id int64, w io.Writer) error
+}
@@ -3,21 +3,123 @@ package services
 import (
 	"context"
 	"io"
+	"time"
+
+	"stori-challenge/internal/domain"
+
+	"github.com/shopspring/decimal"
 )
 
 // RowError represents a single validation/conflict detail for a CSV row in the migration use case.
-type RowError struct {
-	Row     int
-	Field   string
-	Value   string
-	Message string
+type RowError = domain.RowError
+
+// RowErrorSummary groups every row error of a migration, including the ones left out of the
+// detailed list.
+type RowErrorSummary = domain.RowErrorSummary
+
+// RowErrorGroup counts the errors with the same file, field and message.
+type RowErrorGroup = domain.RowErrorGroup
+
+// MigrationResult summarizes a migration. On error, Errors holds the row-level details.
+type MigrationResult struct {
+	// Inserted is the number of rows written to the database.
+	Inserted int
+	// Rejected is the number of rows skipped in partial mode.
+	Rejected int
+	// AlreadyPresent is the number of rows skipped in idempotent mode because they are already stored.
+	AlreadyPresent int
+	// Errors lists validation or conflict details, ordered by row.
+	Errors []RowError
+	// Dialect is how a CSV upload was read, detected or given; nil for the other formats.
+	Dialect *domain.CSVDialect
+	// BatchID is the migration batch recording the upload; zero when batches are not recorded.
+	BatchID int64
+	// Summary groups the row errors; nil when there are none.
+	Summary *RowErrorSummary
+	// Warnings lists the broken validation rules of warning severity; those rows are not rejected.
+	Warnings []RowError
+	// ErrorLog holds every row error when Errors leaves some out; nil otherwise. ProcessStream
+	// sets it and the caller closes it.
+	ErrorLog RowErrorLog
+}
+
+// RowErrorLog holds every row error of a streamed run, beyond the ones kept in memory.
+type RowErrorLog interface {
+	// Each calls fn with every row error, in no particular order.
+	Each(fn func(domain.LoggedRowError) error) error
+	// Close releases the log.
+	Close() error
+}
+
+// ReportErrors are the row errors an error report lists.
+type ReportErrors struct {
+	// List holds the row errors stored with the migration.
+	List []RowError
+	// Each, when set, is used instead of List: it calls fn with every row error of the
+	// migration ordered by run row, for migrations whose stored list leaves some out.
+	Each func(fn func(domain.LoggedRowError) error) error
+}
+
+// MigrationValidation is the outcome of a dry run: what a migration with the same input would do.
+type MigrationValidation struct {
+	// Rows is the number of data rows read from the file.
+	Rows int
+	// WouldInsert is the number of rows a real migration with the same options would insert.
+	WouldInsert int
+	// Rejected is the number of rows with at least one error.
+	Rejected int
+	// AlreadyPresent is the number of rows already stored with the same content (idempotent mode).
+	AlreadyPresent int
+	// Errors lists validation or conflict details, ordered by row.
+	Errors []RowError
+	// Summary groups the row errors; nil when there are none.
+	Summary *RowErrorSummary
+	// Warnings lists the broken validation rules of warning severity; those rows are not rejected.
+	Warnings []RowError
+	// Stats describes the rows that pass validation and conflict checks.
+	Stats MigrationStats
+	// Dialect is how a CSV upload was read, detected or given; nil for the other formats.
+	Dialect *domain.CSVDialect
+}
+
+// MigrationStats summarizes a set of transactions.
+type MigrationStats struct {
+	Rows          int
+	DistinctUsers int
+	// From and To bound the transaction datetimes; nil when there are no rows.
+	From, To     *time.Time
+	TotalCredits decimal.Decimal
+	// TotalDebits is reported as a positive amount, like the balance endpoint.
+	TotalDebits decimal.Decimal
+	// BalanceChanges holds the net amount per user, ordered by user id.
+	BalanceChanges []UserBalanceChange
+}
+
+// UserBalanceChange is the projected balance change for one user.
+type UserBalanceChange struct {
+	UserID int64
+	Change decimal.Decimal
 }
 
 // MigrationService is the input port for the POST /migrate use case.
 type MigrationService interface {
-	// Process reads a CSV stream and returns:
-	// - inserted: number of inserted rows on success
-	// - items: row-level validation or conflict details
+	// Process reads a CSV, JSON Lines or JSON array stream (opts.Format) and returns:
+	// - result: inserted/rejected counts and row-level validation or conflict details
 	// - err: typed error indicating class (BadRequest/Conflict/NotFound/Internal)
-	Process(ctx context.Context, r io.Reader) (inserted int, items []RowError, err error)
+	// In strict mode any failing row rejects the whole file; in partial mode valid rows are inserted
+	// and the failing ones are reported in result.Errors. Every failing field of a row is reported;
+	// result.Errors holds at most opts.MaxErrors of them and result.Summary groups them all.
+	Process(ctx context.Context, r io.Reader, opts domain.MigrationOptions) (result MigrationResult, err error)
+	// ProcessStream has the same contract as Process for inputs too large to hold in memory:
+	// rows are validated and staged in chunks and only become visible once the whole stream is processed.
+	// Row errors beyond opts.MaxErrors are not kept in memory; they go to result.ErrorLog.
+	ProcessStream(ctx context.Context, r io.Reader, opts domain.MigrationOptions) (result MigrationResult, err error)
+	// Validate runs the same validation and conflict checks as Process without writing anything.
+	// Row errors are part of the report; err is only returned when the file cannot be read at all
+	// (BadRequest) or the database check fails (Internal).
+	Validate(ctx context.Context, r io.Reader, opts domain.MigrationOptions) (MigrationValidation, error)
+	// WriteErrorReport reads the file a migration with opts processed and writes its rows that
+	// errs point at to w as CSV, with error_field and error_message columns appended. File-level
+	// errors (row 0) have no row to report.
+	WriteErrorReport(ctx context.Context, r io.Reader, opts domain.MigrationOptions, errs ReportErrors, w io.Writer) error
 }
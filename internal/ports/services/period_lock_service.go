@@ -0,0 +1,16 @@
+package services
+
+import (
+	"context"
+
+	"stori-challenge/internal/domain"
+)
+
+// PeriodLockService is the input port for closing months so their migrations cannot be reverted.
+type PeriodLockService interface {
+	// Lock validates and stores a lock.
+	Lock(ctx context.Context, l domain.PeriodLock) (domain.PeriodLock, error)
+	List(ctx context.Context) ([]domain.PeriodLock, error)
+	// Unlock removes the lock or returns a NotFound error.
+	Unlock(ctx context.Context, period string) error
+}
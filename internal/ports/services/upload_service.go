@@ -0,0 +1,28 @@
+package services
+
+import (
+	"context"
+	"io"
+
+	"stori-challenge/internal/domain"
+)
+
+// UploadService is the input port for the resumable upload use cases (/uploads).
+type UploadService interface {
+	// Create starts an empty upload of a file of length bytes.
+	Create(ctx context.Context, fileName, contentType string, length int64) (domain.Upload, error)
+	// Get returns the upload with the number of bytes received.
+	// Returns not found if the upload does not exist or expired.
+	Get(ctx context.Context, id string) (domain.Upload, error)
+	// AppendChunk adds the content of r at offset, which must be the bytes received so far.
+	// When checksum is set the chunk is only kept if its digest matches.
+	AppendChunk(ctx context.Context, id string, offset int64, r io.Reader, checksum *domain.Checksum) (domain.Upload, error)
+	// Finalize enqueues the complete file as an asynchronous migration with opts and discards
+	// the upload. Returns conflict while bytes are missing.
+	Finalize(ctx context.Context, id string, opts domain.MigrationOptions) (domain.Migration, error)
+	// Delete discards the upload. Returns not found if it does not exist or expired.
+	Delete(ctx context.Context, id string) error
+	// PurgeExpired discards the uploads that received nothing within the expiry and returns
+	// how many were removed.
+	PurgeExpired(ctx context.Context) (int, error)
+}
@@ -0,0 +1,14 @@
+package storage
+
+import (
+	"context"
+	"io"
+)
+
+// FileStore keeps uploaded migration files until a worker processes them.
+type FileStore interface {
+	// Save persists the content under a new key derived from name and returns the key.
+	Save(ctx context.Context, name string, r io.Reader) (key string, err error)
+	// Open returns a reader for a previously saved file. Callers must close it.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+}
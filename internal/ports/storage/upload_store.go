@@ -0,0 +1,37 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"stori-challenge/internal/domain"
+)
+
+// ErrOffsetMismatch is returned by UploadStore.Append when the upload no longer ends at the
+// offset the chunk was sent for, e.g. because another request appended to it first.
+var ErrOffsetMismatch = errors.New("upload offset mismatch")
+
+// UploadStore stages the chunks of resumable uploads until they are finalized.
+type UploadStore interface {
+	// Create records a new empty upload under u.ID.
+	Create(ctx context.Context, u domain.Upload) error
+	// Get returns the upload with its Offset and UpdatedAt; found is false for unknown ids.
+	Get(ctx context.Context, id string) (u domain.Upload, found bool, err error)
+	// List returns every staged upload.
+	List(ctx context.Context) ([]domain.Upload, error)
+	// Append stages the content of r and, when commit accepts its size n, adds it at offset,
+	// which must be the current end of the upload. Nothing is added when commit fails.
+	// It returns the new offset.
+	Append(ctx context.Context, id string, offset int64, r io.Reader, commit func(n int64) error) (int64, error)
+	// Open returns a reader over the received bytes. Callers must close it.
+	Open(ctx context.Context, id string) (io.ReadCloser, error)
+	// Claim marks the upload as being finalized, so Get no longer finds it and only one caller
+	// hands it to a migration. claimed is false when the upload is unknown or already claimed.
+	// List still returns claimed uploads, so a claim left by a crashed process expires.
+	Claim(ctx context.Context, id string) (claimed bool, err error)
+	// Release undoes Claim, so the upload can be finalized again.
+	Release(ctx context.Context, id string) error
+	// Delete discards the upload, claimed or not; deleting an unknown upload is not an error.
+	Delete(ctx context.Context, id string) error
+}
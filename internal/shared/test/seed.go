@@ -0,0 +1,20 @@
+package test
+
+import (
+	"context"
+	"database/sql"
+
+	"stori-challenge/internal/domain"
+)
+
+// SeedTransactions stores txs directly in the transactions table, as earlier migrations would
+// have, so a test starts from a known history.
+func SeedTransactions(ctx context.Context, db *sql.DB, txs []domain.Transaction) error {
+	for _, t := range txs {
+		if _, err := db.ExecContext(ctx, `INSERT INTO transactions (id, user_id, amount, datetime, type) VALUES ($1, $2, $3, $4, $5)`,
+			t.ID, t.UserID, t.Amount.StringFixed(domain.AmountScale), t.DateTime.UTC(), string(t.Type)); err != nil {
+			return err
+		}
+	}
+	return nil
+}